@@ -0,0 +1,651 @@
+// Package cgen generates a C header from a schema: a struct for every message and object and functions that
+// encode and decode them in the wire format. The generated code never allocates: messages are encoded into a
+// buffer supplied by the caller, decoded binaries point into the payload and decoded arrays are carved from an
+// arena supplied by the caller. It only needs a C99 compiler and the standard headers.
+package cgen
+
+import (
+	_ "embed"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// runtime.h holds the reader, writer and arena the generated code is built on
+//
+//go:embed runtime.h
+var runtime string
+
+// keywords of C, fields named after them get an underscore appended
+var keywords = map[string]bool{
+	"auto": true, "bool": true, "break": true, "case": true, "char": true, "const": true, "continue": true,
+	"default": true, "do": true, "double": true, "else": true, "enum": true, "extern": true, "false": true,
+	"float": true, "for": true, "goto": true, "if": true, "inline": true, "int": true, "long": true,
+	"register": true, "restrict": true, "return": true, "short": true, "signed": true, "sizeof": true,
+	"static": true, "struct": true, "switch": true, "true": true, "typedef": true, "union": true,
+	"unsigned": true, "void": true, "volatile": true, "while": true,
+}
+
+type Options struct {
+	Source string // file the schema was read from, named in the header of the generated file
+}
+
+type generator struct {
+	names map[string]string // C identifier to the schema name it was derived from
+	buf   strings.Builder
+	depth int // indentation of the next line
+}
+
+// Generate returns the C header for the schema and everything it imports
+func Generate(s schema.Schema, opts Options) ([]byte, error) {
+	g := generator{
+		names: make(map[string]string),
+	}
+
+	schemas := s.Files()
+
+	err := g.checkNames(schemas)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	guard := "SCHEMAIPC_GENERATED_H"
+
+	if s.Package != "" {
+		guard = strings.ToUpper(cName(s.Package)) + "_SCHEMA_H"
+	} else if opts.Source != "" {
+		guard = strings.ToUpper(cName(strings.TrimSuffix(path.Base(opts.Source), path.Ext(opts.Source)))) + "_SCHEMA_H"
+	}
+
+	g.buf.WriteString("/* Code generated by schemagen")
+
+	if opts.Source != "" {
+		fmt.Fprintf(&g.buf, " from %s", opts.Source)
+	}
+
+	g.buf.WriteString(". DO NOT EDIT. */\n\n")
+	g.line("#ifndef %s", guard)
+	g.line("#define %s", guard)
+	g.line("")
+	g.buf.WriteString(runtime)
+	g.line("")
+
+	fingerprint := r.Fingerprint()
+	bytes := make([]string, len(fingerprint))
+
+	for i, b := range fingerprint {
+		bytes[i] = fmt.Sprintf("0x%02x", b)
+	}
+
+	prefix := "SCHEMA"
+
+	if s.Package != "" {
+		prefix = strings.ToUpper(cName(s.Package))
+	}
+
+	g.line("/* %s_FINGERPRINT initializes the uint8_t[32] sent in Hello, the server rejects other schemas */", prefix)
+	g.line("#define %s_FINGERPRINT { %s }", prefix, strings.Join(bytes, ", "))
+	g.line("")
+
+	g.messageIDs(schemas, &r)
+
+	for _, file := range schemas {
+		g.constants(file.Constants)
+		g.typeAliases(file.Types)
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			g.message(message)
+		}
+	}
+
+	g.line("#endif")
+
+	return []byte(g.buf.String()), nil
+}
+
+// snakeName turns a name into snake case, e.g. PlaceOrder becomes place_order
+func snakeName(name string) string {
+	var b strings.Builder
+
+	runes := []rune(name)
+
+	for i, c := range runes {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(c))
+	}
+
+	return b.String()
+}
+
+// cName turns a declared name into a C identifier, the package becomes a prefix, e.g. shop.PlaceOrder becomes
+// shop_place_order
+func cName(name string) string {
+	parts := strings.Split(name, ".")
+
+	for i, part := range parts {
+		parts[i] = snakeName(part)
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// Name returns the C identifier of a declared name, e.g. shop_place_order for shop.PlaceOrder
+func Name(name string) string {
+	return cName(name)
+}
+
+// FieldName returns the struct member of a field, fields that are optional also get a has_ member and
+// arrays a _len member named after the field
+func FieldName(field schema.MessageField) string {
+	return fieldName(field)
+}
+
+// fieldName returns the struct member of a field
+func fieldName(field schema.MessageField) string {
+	if keywords[field.Name] {
+		return field.Name + "_"
+	}
+
+	return field.Name
+}
+
+func (g *generator) declare(ident, name string) error {
+	if strings.HasPrefix(ident, "ipc_") {
+		return fmt.Errorf("%s generates the C identifier %s, which is reserved for the runtime", name, ident)
+	}
+
+	if existing, exists := g.names[ident]; exists && existing != name {
+		return fmt.Errorf("%s and %s both generate the C identifier %s", existing, name, ident)
+	}
+
+	g.names[ident] = name
+
+	return nil
+}
+
+// checkNames reports declarations that would generate the same C identifier
+func (g *generator) checkNames(schemas []schema.Schema) error {
+	for _, file := range schemas {
+		for _, constant := range file.Constants {
+			err := g.declare(cName(constant.Name), constant.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, alias := range file.Types {
+			err := g.declare(cName(alias.Name), alias.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// messages are declared by signature, an inbound and an outbound message can't share a name
+		for _, message := range file.Messages {
+			err := g.declare(cName(message.Name), message.Signature())
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// line writes a line of code at the current indentation, a line ending with { opens a block and a line
+// starting with } closes one
+func (g *generator) line(format string, args ...any) {
+	code := fmt.Sprintf(format, args...)
+
+	if strings.HasPrefix(code, "}") {
+		g.depth--
+	}
+
+	if code != "" {
+		g.buf.WriteString(strings.Repeat("    ", g.depth))
+	}
+
+	g.buf.WriteString(code)
+	g.buf.WriteString("\n")
+
+	if strings.HasSuffix(code, "{") {
+		g.depth++
+	}
+}
+
+// directions returns the directions a message is registered under
+func directions(message schema.SchemaMessage) []schema.MessageDirection {
+	switch message.Direction {
+	case schema.InboundMessage, schema.OutboundMessage:
+		return []schema.MessageDirection{message.Direction}
+	case schema.DuplexMessage:
+		return []schema.MessageDirection{schema.InboundMessage, schema.OutboundMessage}
+	default:
+		return nil
+	}
+}
+
+func (g *generator) messageIDs(schemas []schema.Schema, r *schema.MessageDescriptorRegistry) {
+	var count int
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			dirs := directions(message)
+
+			if len(dirs) == 0 {
+				continue
+			}
+
+			id := r.UserSignatureMap[schema.Signature(dirs[0], message.Name)]
+
+			// compressed payloads are raw deflate streams, which the caller inflates before decoding
+			if message.Options.Compress {
+				g.line("#define %s_ID %du /* compressed */", strings.ToUpper(cName(message.Name)), id)
+			} else {
+				g.line("#define %s_ID %du", strings.ToUpper(cName(message.Name)), id)
+			}
+
+			count++
+		}
+	}
+
+	if count > 0 {
+		g.line("")
+	}
+}
+
+func (g *generator) constants(constants []schema.Constant) {
+	for _, constant := range constants {
+		g.line("#define %s %du", strings.ToUpper(cName(constant.Name)), constant.Value)
+	}
+
+	if len(constants) > 0 {
+		g.line("")
+	}
+}
+
+func (g *generator) typeAliases(aliases []schema.TypeAlias) {
+	var count int
+
+	for _, alias := range aliases {
+		// arrays are declared as a pointer and a length, which a typedef can't hold
+		if alias.Field.Type == schema.TypeArray {
+			continue
+		}
+
+		g.line("typedef %s;", declaration(alias.Field, cName(alias.Name)))
+		count++
+	}
+
+	if count > 0 {
+		g.line("")
+	}
+}
+
+func nestedMessage(extra any) schema.SchemaMessage {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e.Message
+	case schema.SchemaMessage:
+		return e
+	default:
+		return schema.SchemaMessage{}
+	}
+}
+
+// flatten returns the fields of a message with the fields of embedded objects spliced in, the same as they
+// appear on the wire
+func flatten(fields []schema.MessageField) []schema.MessageField {
+	var res []schema.MessageField
+
+	for _, field := range fields {
+		if field.Embedded {
+			res = append(res, flatten(nestedMessage(field.Extra).Fields)...)
+		} else {
+			res = append(res, field)
+		}
+	}
+
+	return res
+}
+
+// countOptional counts the optional fields of a message, including those of included objects
+func countOptional(fields []schema.MessageField) int {
+	var count int
+
+	for _, field := range flatten(fields) {
+		if field.Optional {
+			count++
+		}
+	}
+
+	return count
+}
+
+// intType returns the C type of an integer type
+func intType(t schema.FieldType) (string, bool) {
+	switch t {
+	case schema.TypeUInt64:
+		return "uint64_t", true
+	case schema.TypeInt64:
+		return "int64_t", true
+	case schema.TypeUInt32:
+		return "uint32_t", true
+	case schema.TypeInt32:
+		return "int32_t", true
+	case schema.TypeUInt16:
+		return "uint16_t", true
+	case schema.TypeInt16:
+		return "int16_t", true
+	default:
+		return "", false
+	}
+}
+
+// wireType returns the unsigned type an integer is written as
+func wireType(t schema.FieldType) string {
+	switch t {
+	case schema.TypeUInt64, schema.TypeInt64:
+		return "u64"
+	case schema.TypeUInt32, schema.TypeInt32:
+		return "u32"
+	default:
+		return "u16"
+	}
+}
+
+// declaration declares name with the type of a field, arrays are declared as a pointer to their elements
+func declaration(field schema.MessageField, name string) string {
+	if field.TypeAlias != "" && field.Type != schema.TypeArray {
+		return cName(field.TypeAlias) + " " + name
+	}
+
+	if t, ok := intType(field.Type); ok {
+		return t + " " + name
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		return fmt.Sprintf("uint8_t %s[%d]", name, field.Extra.(int))
+	case schema.TypeDynamicBinary, schema.TypeLongBinary:
+		return "ipc_bytes " + name
+	case schema.TypeObject:
+		return cName(field.ObjectName()) + " " + name
+	case schema.TypeArray:
+		{
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				// a pointer to fixed binaries needs parentheses, e.g. uint8_t (*tags)[4]
+				if elem.Type == schema.TypeFixedBinary && elem.TypeAlias == "" {
+					return declaration(elem, "(*"+name+")")
+				}
+
+				return declaration(elem, "*"+name)
+			}
+
+			return cName(field.ObjectName()) + " *" + name
+		}
+	default:
+		return "void *" + name
+	}
+}
+
+// message writes the struct of a message or object and the functions that write and read it, messages also
+// get encode and decode functions
+func (g *generator) message(message schema.SchemaMessage) {
+	name := cName(message.Name)
+	fields := flatten(message.Fields)
+
+	if message.Direction == schema.ObjectDef {
+		g.line("/* %s is the object %s */", name, message.Name)
+	} else {
+		g.line("/* %s is the %s message %s */", name, message.Direction.ToString(), message.Name)
+	}
+
+	if message.Deprecated {
+		g.line("/* deprecated: the message is deprecated in the schema */")
+	}
+
+	g.line("typedef struct %s {", name)
+
+	for _, field := range fields {
+		var comment string
+
+		if field.Deprecated {
+			comment = " /* deprecated */"
+		}
+
+		if field.Optional {
+			g.line("bool has_%s;", field.Name)
+		}
+
+		g.line("%s;%s", declaration(field, fieldName(field)), comment)
+
+		if field.Type == schema.TypeArray {
+			g.line("size_t %s_len;", field.Name)
+		}
+	}
+
+	// C doesn't allow empty structs
+	if len(fields) == 0 {
+		g.line("uint8_t unused;")
+	}
+
+	g.line("} %s;", name)
+	g.line("")
+
+	g.writeFunction(message, name, fields)
+	g.readFunction(message, name, fields)
+
+	if message.Direction == schema.ObjectDef {
+		return
+	}
+
+	g.line("/* %s_encode writes m to buf, len is set to the length of the payload */", name)
+	g.line("static inline ipc_status %s_encode(const %s *m, uint8_t *buf, size_t cap, size_t *len)", name, name)
+	g.line("{")
+	g.line("ipc_writer w = ipc_writer_init(buf, cap);")
+	g.line("")
+	g.line("%s_write(&w, m);", name)
+	g.line("*len = w.len;")
+	g.line("")
+	g.line("return w.err;")
+	g.line("}")
+	g.line("")
+
+	g.line("/* %s_decode reads m from payload, its binaries point into payload and its arrays into arena */", name)
+	g.line("static inline ipc_status %s_decode(%s *m, const uint8_t *payload, size_t len, ipc_arena *arena)", name, name)
+	g.line("{")
+	g.line("ipc_reader r = ipc_reader_init(payload, len, arena);")
+	g.line("")
+	g.line("%s_read(&r, m);", name)
+	g.line("")
+	g.line("return r.err;")
+	g.line("}")
+	g.line("")
+}
+
+func cBool(b bool) string {
+	if b {
+		return "true"
+	}
+
+	return "false"
+}
+
+func (g *generator) writeFunction(message schema.SchemaMessage, name string, fields []schema.MessageField) {
+	extensible := cBool(message.Extensible)
+
+	g.line("static inline void %s_write(ipc_writer *w, const %s *m)", name, name)
+	g.line("{")
+	g.line("ipc_message_start start = ipc_begin_message(w, %d, %s);", countOptional(message.Fields), extensible)
+	g.line("")
+
+	if len(fields) == 0 {
+		g.line("(void)m;")
+	}
+
+	var opt int
+
+	for _, field := range fields {
+		value := "m->" + fieldName(field)
+
+		if !field.Optional {
+			g.writeValue(value, field)
+			continue
+		}
+
+		g.line("if (m->has_%s) {", field.Name)
+		g.line("ipc_set_optional(w, start, %d);", opt)
+		g.writeValue(value, field)
+		g.line("}")
+
+		opt++
+	}
+
+	g.line("")
+	g.line("ipc_end_message(w, start, %s);", extensible)
+	g.line("}")
+	g.line("")
+}
+
+func (g *generator) writeValue(expr string, field schema.MessageField) {
+	if t, ok := intType(field.Type); ok {
+		wire := wireType(field.Type)
+
+		if t[0] == 'u' {
+			g.line("ipc_put_%s(w, %s);", wire, expr)
+		} else {
+			g.line("ipc_put_%s(w, (%s_t)%s);", wire, "uint"+wire[1:], expr)
+		}
+
+		return
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		g.line("ipc_put_raw(w, %s, %d);", expr, field.Extra.(int))
+	case schema.TypeDynamicBinary:
+		g.line("ipc_put_binary(w, %s);", expr)
+	case schema.TypeLongBinary:
+		g.line("ipc_put_long_binary(w, %s);", expr)
+	case schema.TypeObject:
+		g.line("%s_write(w, &%s);", cName(field.ObjectName()), expr)
+	case schema.TypeArray:
+		{
+			g.line("ipc_put_array_len(w, %s_len);", expr)
+			g.line("for (size_t i = 0; i < %s_len; i++) {", expr)
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				g.writeValue(expr+"[i]", elem)
+			} else {
+				g.line("%s_write(w, &%s[i]);", cName(field.ObjectName()), expr)
+			}
+
+			g.line("}")
+		}
+	}
+}
+
+func (g *generator) readFunction(message schema.SchemaMessage, name string, fields []schema.MessageField) {
+	extensible := cBool(message.Extensible)
+
+	g.line("static inline void %s_read(ipc_reader *r, %s *m)", name, name)
+	g.line("{")
+	g.line("ipc_frame frame = ipc_begin_frame(r, %d, %s);", countOptional(message.Fields), extensible)
+	g.line("")
+	g.line("memset(m, 0, sizeof *m);")
+
+	for _, field := range fields {
+		value := "m->" + fieldName(field)
+
+		if !field.Optional {
+			// a required field sent by an older peer is missing from an extensible message
+			if message.Extensible {
+				g.line("ipc_check_required(r, &frame);")
+			}
+
+			g.readValue(value, field)
+			continue
+		}
+
+		g.line("m->has_%s = ipc_present(r, &frame);", field.Name)
+		g.line("if (m->has_%s) {", field.Name)
+		g.readValue(value, field)
+		g.line("}")
+	}
+
+	g.line("")
+	g.line("ipc_end_frame(r, &frame, %s);", extensible)
+	g.line("}")
+	g.line("")
+}
+
+func (g *generator) readValue(expr string, field schema.MessageField) {
+	if t, ok := intType(field.Type); ok {
+		wire := wireType(field.Type)
+
+		if t[0] == 'u' {
+			g.line("%s = ipc_get_%s(r);", expr, wire)
+		} else {
+			g.line("%s = (%s)ipc_get_%s(r);", expr, t, wire)
+		}
+
+		return
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		g.line("ipc_get_fixed(r, %s, %d);", expr, field.Extra.(int))
+	case schema.TypeDynamicBinary:
+		g.line("%s = ipc_get_binary(r);", expr)
+	case schema.TypeLongBinary:
+		g.line("%s = ipc_get_long_binary(r);", expr)
+	case schema.TypeObject:
+		g.line("%s_read(r, &%s);", cName(field.ObjectName()), expr)
+	case schema.TypeArray:
+		{
+			g.line("%s_len = ipc_get_array_len(r);", expr)
+			g.line("%s = ipc_alloc(r, %s_len, sizeof *%s);", expr, expr, expr)
+			g.line("for (size_t i = 0; i < %s_len && r->err == IPC_OK; i++) {", expr)
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				g.readValue(expr+"[i]", elem)
+			} else {
+				g.line("%s_read(r, &%s[i]);", cName(field.ObjectName()), expr)
+			}
+
+			g.line("}")
+		}
+	}
+}
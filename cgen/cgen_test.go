@@ -0,0 +1,119 @@
+package cgen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestGenerateIsUpToDate(t *testing.T) {
+	files := map[string]string{
+		"../examples/shop/shop.schema": "../examples/shop/shop.h",
+		"../golden/golden.schema":      "../golden/c/golden.h",
+	}
+
+	for source, generated := range files {
+		s, err := schema.ParseFile(source)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, err := Generate(s, Options{Source: source[strings.LastIndex(source, "/")+1:]})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := os.ReadFile(generated)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(code, expected) {
+			t.Errorf("%s is out of date, run go generate ./examples/shop ./golden", strings.TrimPrefix(generated, "../"))
+		}
+	}
+}
+
+func TestGenerateMatchesRegistry(t *testing.T) {
+	s, err := schema.ParseFile("../examples/shop/shop.schema")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("#define SHOP_PLACE_ORDER_ID %du", r.UserSignatureMap["inbound shop.PlaceOrder"]),
+		fmt.Sprintf("#define SHOP_PING_ID %du", r.UserSignatureMap["inbound shop.Ping"]),
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(code), line) {
+			t.Errorf("expected the generated code to contain %q", line)
+		}
+	}
+}
+
+func TestGenerateNameCollision(t *testing.T) {
+	tests := map[string]string{
+		"inbound user_name {\n  uint32 REQUIRED id\n}\n\noutbound UserName {\n  uint32 REQUIRED id\n}": "inbound user_name and outbound UserName",
+		"const max_size = 1\n\nconst MaxSize = 2":                                                      "max_size and MaxSize",
+		"package ipc\n\noutbound Bytes {\n  uint32 REQUIRED id\n}":                                     "reserved for the runtime",
+	}
+
+	for source, message := range tests {
+		s, err := schema.Parse([]byte(source))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = Generate(s, Options{})
+
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q, got %v", message, err)
+		}
+	}
+}
+
+func TestGenerateCompiles(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+
+	if err != nil {
+		t.Skip("cc is not installed")
+	}
+
+	out, err := exec.Command(cc, "-std=c99", "-Wall", "-Wextra", "-Wpedantic", "-Werror", "-fsyntax-only", "-x", "c", filepath.Join("..", "examples", "shop", "shop.h")).CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
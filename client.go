@@ -0,0 +1,402 @@
+package schemaipc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var ErrSchemaMismatch = errors.New("server schema doesn't match the client schema")
+var ErrFingerprintMismatch = errors.New("server schema doesn't match its fingerprint")
+var ErrUnexpectedMessage = errors.New("server sent an unexpected message")
+var ErrNotEstablished = errors.New("connection is not established, call Dial or Handshake first")
+
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("protocol error: %s", e.Message)
+}
+
+type Client struct {
+	Schema     schema.Schema
+	SchemaPath string // schema file registered after Schema, along with the files it imports
+	// Decode and encode with the descriptors advertised by the server when its schema differs from ours,
+	// fields are matched by name so both sides only have to agree on the names and types of shared fields
+	TolerantReader bool
+	MaxMessageSize uint32 // 0 means unlimited
+	// With encoder.DecodeCopy []byte fields of messages and responses are copied out of the payload, and the
+	// payloads of messages are read into a buffer that is reused for the next one
+	DecodeMode encoder.DecodeMode
+	Registry   schema.MessageDescriptorRegistry
+	// Type aliases and constants of the server's schema, filled in by the handshake if the server exports them
+	PeerTypes     []schema.TypeAlias
+	PeerConstants []schema.Constant
+	peer          *schema.MessageDescriptorRegistry
+	conn          net.Conn
+	handlers      map[string]schema.HandlerFunc
+	handlersMu    sync.RWMutex
+	writeMu       writeLock
+	calls         map[uint32]chan rpcResult
+	streams       map[uint32]*streamQueue
+	callsMu       sync.Mutex
+	callsAborted  bool
+	lastCallID    uint32
+	payloadBuf    []byte
+}
+
+func (c *Client) Dial(network, address string) error {
+	conn, err := net.Dial(network, address)
+
+	if err != nil {
+		return err
+	}
+
+	err = c.Handshake(conn)
+
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) Handshake(conn net.Conn) error {
+	c.conn = conn
+
+	err := c.Registry.RegisterInternal()
+
+	if err != nil {
+		return err
+	}
+
+	err = c.Registry.RegisterSchema(c.Schema)
+
+	if err != nil {
+		return err
+	}
+
+	if c.SchemaPath != "" {
+		parsed, err := schema.ParseFile(c.SchemaPath)
+
+		if err != nil {
+			return err
+		}
+
+		err = c.Registry.RegisterSchema(parsed)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fingerprint := c.Registry.Fingerprint()
+
+	err = c.writeMessage(c.internalDescriptor("inbound Hello"), helloInbound{
+		MinVersion:  MinProtocolVersion,
+		CurrVersion: ProtocolVersion,
+		Fingerprint: fingerprint[:],
+	})
+
+	if err != nil {
+		return err
+	}
+
+	header, err := readHeader(conn)
+
+	if err != nil {
+		return err
+	}
+
+	payload, err := readPayload(conn, header.PacketLength)
+
+	if err != nil {
+		return err
+	}
+
+	if header.MessageType == c.internalDescriptor("outbound ProtocolError").ID {
+		return c.decodeProtocolError(payload)
+	}
+
+	helloDescriptor := c.internalDescriptor("outbound Hello")
+
+	if header.MessageType != helloDescriptor.ID {
+		return ErrUnexpectedMessage
+	}
+
+	var hello helloOutbound
+
+	reader := encoder.NewReader(payload, helloDescriptor)
+	err = reader.Decode(&hello)
+
+	if err != nil {
+		return err
+	}
+
+	if hello.CurrVersion < MinProtocolVersion || hello.MinVersion > ProtocolVersion {
+		return ErrUnsupportedVersion
+	}
+
+	c.PeerTypes, c.PeerConstants, err = decodeHelloDefinitions(&c.Registry, hello)
+
+	if err != nil {
+		return err
+	}
+
+	if hello.Fingerprint == fingerprint {
+		c.peer = &c.Registry
+		return nil
+	}
+
+	if !c.TolerantReader {
+		return ErrSchemaMismatch
+	}
+
+	return c.registerPeer(hello)
+}
+
+func (c *Client) registerPeer(hello helloOutbound) error {
+	peer := &schema.MessageDescriptorRegistry{}
+
+	err := peer.RegisterInternal()
+
+	if err != nil {
+		return err
+	}
+
+	descriptors, err := decodeHelloSchema(peer, hello.Schema)
+
+	if err != nil {
+		return err
+	}
+
+	err = peer.RegisterPeerSchema(descriptors, decodeHelloMethods(hello.Methods))
+
+	if err != nil {
+		return err
+	}
+
+	if peer.Fingerprint() != hello.Fingerprint {
+		return ErrFingerprintMismatch
+	}
+
+	for signature, id := range c.Registry.UserSignatureMap {
+		peerID, exists := peer.UserSignatureMap[signature]
+
+		if !exists {
+			continue
+		}
+
+		local := c.Registry.Descriptors[id].Message
+		descriptor := peer.Descriptors[peerID]
+
+		err = schema.CheckFieldTypes(local, descriptor.Message)
+
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSchemaMismatch, err)
+		}
+
+		// fields renamed on either side are matched through their aliases
+		descriptor.Message = schema.MapFieldNames(local, descriptor.Message)
+		peer.Descriptors[peerID] = descriptor
+	}
+
+	c.peer = peer
+
+	return nil
+}
+
+func (c *Client) internalDescriptor(signature string) schema.MessageDescriptor {
+	return c.Registry.Descriptors[c.Registry.InternalSignatureMap[signature]]
+}
+
+func (c *Client) decodeProtocolError(payload []byte) error {
+	var res protocolError
+
+	reader := encoder.NewReader(payload, c.internalDescriptor("outbound ProtocolError"))
+	err := reader.Decode(&res)
+
+	if err != nil {
+		return err
+	}
+
+	return &RemoteError{
+		Message: string(res.Message),
+	}
+}
+
+func (c *Client) writeMessage(descriptor schema.MessageDescriptor, v any) error {
+	frame, err := encodeFrame(descriptor, v)
+
+	if err != nil {
+		return err
+	}
+
+	defer releaseFrame(frame)
+
+	c.writeMu.Lock(descriptor.Message.Options.Priority)
+	defer c.writeMu.Unlock()
+
+	_, err = c.conn.Write(frame.Bytes())
+
+	return err
+}
+
+// Send encodes an inbound message with the server's descriptor and sends it
+func (c *Client) Send(signature string, v any) error {
+	if !strings.HasPrefix(signature, "inbound ") {
+		return ErrWriteInvalidDirection
+	}
+
+	if c.peer == nil {
+		return ErrNotEstablished
+	}
+
+	id, exists := c.peer.UserSignatureMap[signature]
+
+	if !exists {
+		return ErrUnknownSignature
+	}
+
+	return c.writeMessage(c.peer.Descriptors[id], v)
+}
+
+// Handle registers a handler for an outbound message, it can be called before or after the handshake
+func (c *Client) Handle(signature string, handler schema.HandlerFunc) {
+	if !strings.HasPrefix(signature, "outbound ") {
+		panic("invalid direction (must be outbound)")
+	}
+
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	if c.handlers == nil {
+		c.handlers = make(map[string]schema.HandlerFunc)
+	}
+
+	if c.handlers[signature] != nil {
+		s := fmt.Sprintf("message (%s) is already registered", signature)
+		panic(s)
+	}
+
+	c.handlers[signature] = handler
+}
+
+func (c *Client) handler(signature string) schema.HandlerFunc {
+	c.handlersMu.RLock()
+	defer c.handlersMu.RUnlock()
+
+	return c.handlers[signature]
+}
+
+// Run reads messages from the server and dispatches them to their handlers until the connection is closed
+func (c *Client) Run() error {
+	if c.peer == nil {
+		return ErrNotEstablished
+	}
+
+	defer c.abortCalls()
+
+	for {
+		err := c.nextMessage()
+
+		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) nextMessage() error {
+	header, err := readHeader(c.conn)
+
+	if err != nil {
+		return err
+	}
+
+	descriptor, exists := c.peer.Descriptors[header.MessageType]
+
+	if !exists || descriptor.Message.Direction == schema.InboundMessage || descriptor.Message.Direction == schema.ObjectDef {
+		return ErrUnexpectedMessage
+	}
+
+	limit := messageLimit(descriptor, c.MaxMessageSize)
+
+	if isEnvelope(descriptor) {
+		// the response inside is checked against the limit of its method once it's decoded
+		limit = envelopeLimit(descriptor, c.peer, false, c.MaxMessageSize)
+	}
+
+	if limit != 0 && header.PacketLength > limit {
+		return ErrMsgLength
+	}
+
+	if descriptor.Internal {
+		payload, err := readPayload(c.conn, header.PacketLength)
+
+		if err != nil {
+			return err
+		}
+
+		switch descriptor.Message.Name {
+		case "ProtocolError":
+			return c.decodeProtocolError(payload)
+		case "RpcResponse", "RpcError":
+			return c.handleRPCResult(descriptor, payload)
+		case "StreamData", "StreamEnd", "StreamError":
+			return c.handleStreamMessage(descriptor, payload)
+		}
+
+		return nil
+	}
+
+	handler := c.handler(schema.Signature(schema.OutboundMessage, descriptor.Message.Name))
+
+	if handler == nil {
+		// Ignore messages that don't have a handler
+		_, err = io.CopyN(io.Discard, c.conn, int64(header.PacketLength))
+		return err
+	}
+
+	var payload []byte
+
+	if c.DecodeMode == encoder.DecodeCopy {
+		payload, err = reusePayload(c.conn, &c.payloadBuf, header.PacketLength)
+	} else {
+		payload, err = readPayload(c.conn, header.PacketLength)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	payload, err = decodePayload(descriptor, payload, limit)
+
+	if err != nil {
+		return err
+	}
+
+	reader := encoder.NewReader(payload, descriptor)
+	reader.SetMode(c.DecodeMode)
+
+	return handler(&reader, c)
+}
+
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return ErrNotEstablished
+	}
+
+	return c.conn.Close()
+}
@@ -0,0 +1,502 @@
+package schemaipc
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+const serverRevision = `
+inbound Greet {
+  uint32 OPTIONAL age
+  binary REQUIRED name
+}
+
+outbound Greeting {
+  uint32 REQUIRED id
+  binary REQUIRED text
+  array(int16) OPTIONAL lucky
+}
+`
+
+const clientRevision = `
+inbound Greet {
+  binary REQUIRED name
+}
+
+outbound Greeting {
+  binary REQUIRED text
+}
+`
+
+type greet struct {
+	Age  uint32 `ipc:"age"`
+	Name string `ipc:"name"`
+}
+
+type greeting struct {
+	ID    uint32  `ipc:"id"`
+	Text  string  `ipc:"text"`
+	Lucky []int16 `ipc:"lucky"`
+}
+
+func parseTestSchema(t *testing.T, src string) schema.Schema {
+	s, err := schema.Parse([]byte(src))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func startTestServer(t *testing.T, src string) *Server {
+	s := &Server{
+		Schema:         parseTestSchema(t, src),
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	s.Register("inbound Greet", func(r schema.Reader, c schema.Conn) error {
+		var req greet
+
+		err := r.Decode(&req)
+
+		if err != nil {
+			return err
+		}
+
+		return c.(*Conn).Send("outbound Greeting", greeting{
+			ID:    1,
+			Text:  "hello " + req.Name,
+			Lucky: []int16{7, 13},
+		})
+	})
+
+	return s
+}
+
+func TestClientTolerantReader(t *testing.T) {
+	s := startTestServer(t, serverRevision)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema:         parseTestSchema(t, clientRevision),
+		TolerantReader: true,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := make(chan string, 1)
+
+	c.Handle("outbound Greeting", func(r schema.Reader, conn schema.Conn) error {
+		var msg struct {
+			Text string `ipc:"text"`
+		}
+
+		err := r.Decode(&msg)
+
+		if err != nil {
+			return err
+		}
+
+		res <- msg.Text
+
+		return conn.(*Client).Close()
+	})
+
+	err = c.Send("inbound Greet", struct {
+		Name string `ipc:"name"`
+	}{Name: "world"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Run()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text := <-res; text != "hello world" {
+		t.Errorf("unexpected greeting: %q", text)
+	}
+}
+
+func TestClientSchemaMismatch(t *testing.T) {
+	s := startTestServer(t, serverRevision)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema: parseTestSchema(t, clientRevision),
+	}
+
+	err := c.Handshake(client)
+
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("expected schema mismatch, got %v", err)
+	}
+}
+
+func TestClientConflictingFieldTypes(t *testing.T) {
+	s := startTestServer(t, serverRevision)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema:         parseTestSchema(t, "inbound Greet {\n  uint64 REQUIRED name\n}"),
+		TolerantReader: true,
+	}
+
+	err := c.Handshake(client)
+
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("expected schema mismatch, got %v", err)
+	}
+}
+
+func TestClientRenamedFields(t *testing.T) {
+	s := startTestServer(t, serverRevision)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	// the client renamed its fields ahead of the server
+	c := Client{
+		Schema:         parseTestSchema(t, "inbound Greet {\n  binary REQUIRED fullName aka name\n}\n\noutbound Greeting {\n  binary REQUIRED message aka text\n}"),
+		TolerantReader: true,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := make(chan string, 1)
+
+	c.Handle("outbound Greeting", func(r schema.Reader, conn schema.Conn) error {
+		var msg struct {
+			Message string `ipc:"message"`
+		}
+
+		err := r.Decode(&msg)
+
+		if err != nil {
+			return err
+		}
+
+		res <- msg.Message
+
+		return conn.(*Client).Close()
+	})
+
+	err = c.Send("inbound Greet", struct {
+		FullName string `ipc:"fullName"`
+	}{FullName: "world"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Run()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text := <-res; text != "hello world" {
+		t.Errorf("unexpected greeting: %q", text)
+	}
+}
+
+func TestClientPeerDefinitions(t *testing.T) {
+	s := &Server{
+		Schema:            parseTestSchema(t, "const MaxNameLen = 64\ntype Name = binary(MaxNameLen)\n\ninbound Greet {\n  Name REQUIRED name\n}"),
+		MaxMessageSize:    1024,
+		ExportDefinitions: true,
+	}
+
+	s.Init()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema: s.Schema,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.PeerTypes) != 1 || c.PeerTypes[0].Name != "Name" || c.PeerTypes[0].Field.TypeString() != "binary(64)" {
+		t.Errorf("unexpected types: %+v", c.PeerTypes)
+	}
+
+	if len(c.PeerConstants) != 1 || c.PeerConstants[0] != (schema.Constant{Name: "MaxNameLen", Value: 64}) {
+		t.Errorf("unexpected constants: %+v", c.PeerConstants)
+	}
+}
+
+const optionsRevision = `
+@maxSize(4KiB) @compress
+inbound Upload {
+  long_binary REQUIRED data
+}
+
+@priority(high)
+outbound Ack {
+  uint32 REQUIRED size
+}
+`
+
+func TestClientMessageOptions(t *testing.T) {
+	s := &Server{
+		Schema:         parseTestSchema(t, optionsRevision),
+		MaxMessageSize: 64,
+	}
+
+	s.Init()
+
+	s.Register("inbound Upload", func(r schema.Reader, c schema.Conn) error {
+		var req struct {
+			Data []byte `ipc:"data"`
+		}
+
+		err := r.Decode(&req)
+
+		if err != nil {
+			return err
+		}
+
+		return c.(*Conn).Send("outbound Ack", struct {
+			Size uint32 `ipc:"size"`
+		}{Size: uint32(len(req.Data))})
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	// the options are learned from the server's Hello
+	c := Client{
+		Schema:         parseTestSchema(t, strings.NewReplacer("@maxSize(4KiB) @compress", "", "@priority(high)", "").Replace(optionsRevision)),
+		TolerantReader: true,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options := c.peer.Descriptors[c.peer.UserSignatureMap["inbound Upload"]].Message.Options; options.MaxSize != 4096 || !options.Compress {
+		t.Errorf("unexpected options: %+v", options)
+	}
+
+	res := make(chan uint32, 1)
+
+	c.Handle("outbound Ack", func(r schema.Reader, conn schema.Conn) error {
+		var msg struct {
+			Size uint32 `ipc:"size"`
+		}
+
+		err := r.Decode(&msg)
+
+		if err != nil {
+			return err
+		}
+
+		res <- msg.Size
+
+		return conn.(*Client).Close()
+	})
+
+	type upload struct {
+		Data []byte `ipc:"data"`
+	}
+
+	err = c.Send("inbound Upload", upload{Data: make([]byte, 5000)})
+
+	if !errors.Is(err, ErrMsgLength) {
+		t.Errorf("expected an upload over @maxSize to be rejected, got %v", err)
+	}
+
+	// larger than the server's MaxMessageSize, but compressed and within @maxSize
+	err = c.Send("inbound Upload", upload{Data: make([]byte, 3000)})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Run()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := <-res; size != 3000 {
+		t.Errorf("unexpected size: %d", size)
+	}
+}
+
+func TestClientNotEstablished(t *testing.T) {
+	var c Client
+
+	if err := c.Send("inbound Greet", struct{}{}); !errors.Is(err, ErrNotEstablished) {
+		t.Errorf("Send: expected ErrNotEstablished, got %v", err)
+	}
+
+	if err := c.Call("Greeter.Greet", struct{}{}, &struct{}{}); !errors.Is(err, ErrNotEstablished) {
+		t.Errorf("Call: expected ErrNotEstablished, got %v", err)
+	}
+
+	if _, err := c.OpenStream("Logs.Tail"); !errors.Is(err, ErrNotEstablished) {
+		t.Errorf("OpenStream: expected ErrNotEstablished, got %v", err)
+	}
+
+	if err := c.Run(); !errors.Is(err, ErrNotEstablished) {
+		t.Errorf("Run: expected ErrNotEstablished, got %v", err)
+	}
+
+	if err := c.Close(); !errors.Is(err, ErrNotEstablished) {
+		t.Errorf("Close: expected ErrNotEstablished, got %v", err)
+	}
+}
+
+const echoSchema = `
+inbound Echo {
+  binary REQUIRED data
+}
+
+outbound Echoed {
+  binary REQUIRED data
+}
+`
+
+type echo struct {
+	Data []byte `ipc:"data"`
+}
+
+// with encoder.DecodeCopy both sides read every message into the same buffer, the []byte fields handlers keep
+// must not alias it
+func TestDecodeCopy(t *testing.T) {
+	s := &Server{
+		Schema:     parseTestSchema(t, echoSchema),
+		DecodeMode: encoder.DecodeCopy,
+	}
+
+	s.Init()
+
+	requests := make(chan []byte, 2)
+
+	s.Register("inbound Echo", func(r schema.Reader, c schema.Conn) error {
+		var req echo
+
+		err := r.Decode(&req)
+
+		if err != nil {
+			return err
+		}
+
+		requests <- req.Data
+
+		return c.(*Conn).Send("outbound Echoed", req)
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema:     s.Schema,
+		DecodeMode: encoder.DecodeCopy,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(chan []byte, 2)
+
+	c.Handle("outbound Echoed", func(r schema.Reader, conn schema.Conn) error {
+		var msg echo
+
+		err := r.Decode(&msg)
+
+		if err != nil {
+			return err
+		}
+
+		responses <- msg.Data
+
+		if len(responses) == cap(responses) {
+			return conn.(*Client).Close()
+		}
+
+		return nil
+	})
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.Run()
+	}()
+
+	// the same length, so the second message is read over the first
+	for _, data := range []string{"first", "again"} {
+		err = c.Send("inbound Echo", echo{Data: []byte(data)})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = <-done
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, received := range []chan []byte{requests, responses} {
+		if first, second := <-received, <-received; string(first) != "first" || string(second) != "again" {
+			t.Errorf("expected first and again, got %q and %q", first, second)
+		}
+	}
+
+	if c.payloadBuf == nil {
+		t.Error("expected the client to reuse its payload buffer")
+	}
+}
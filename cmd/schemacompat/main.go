@@ -0,0 +1,67 @@
+// Command schemacompat compares two versions of a schema and exits with status 1
+// if the new version contains changes that break peers still on the old version, or with
+// status 2 if either version can't be parsed or registered.
+//
+//	schemacompat [-all] old.schema new.schema
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func main() {
+	all := flag.Bool("all", false, "also print changes that are safe")
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: schemacompat [-all] old.schema new.schema")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	oldSchema, err := schema.ParseFile(flag.Arg(0))
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	newSchema, err := schema.ParseFile(flag.Arg(1))
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	changes, err := schema.CheckCompatibility(oldSchema, newSchema)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	breaking := 0
+
+	for _, change := range changes {
+		if change.Breaking() {
+			breaking++
+			fmt.Printf("BREAKING %s\n", change)
+		} else if *all {
+			fmt.Printf("safe     %s\n", change)
+		}
+	}
+
+	if breaking > 0 {
+		fmt.Printf("%d breaking change(s)\n", breaking)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,92 @@
+// Command schemagen generates code for a schema file, it is meant to be run by go generate:
+//
+//	//go:generate schemagen -o shop.go shop.schema
+//
+//	schemagen [-lang go|ts|py|c|rust] [-pkg name] [-o file] file.schema
+//
+// The package defaults to $GOPACKAGE, which go generate sets, and the output to stdout. The package is
+// only used by Go code, -lang ts generates a TypeScript client and -lang py a Python one, -lang c
+// generates a C header and -lang rust a Rust module with the messages and their codecs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/benjamin-larsen/goschemaipc/cgen"
+	"github.com/benjamin-larsen/goschemaipc/gogen"
+	"github.com/benjamin-larsen/goschemaipc/pygen"
+	"github.com/benjamin-larsen/goschemaipc/rustgen"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+	"github.com/benjamin-larsen/goschemaipc/tsgen"
+)
+
+func main() {
+	lang := flag.String("lang", "go", "language of the generated code (go, ts, py, c, rust)")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated code")
+	out := flag.String("o", "", "output file, stdout if empty")
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: schemagen [-lang go|ts|py|c|rust] [-pkg name] [-o file] file.schema")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	s, err := schema.ParseFile(flag.Arg(0))
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var code []byte
+
+	switch *lang {
+	case "go":
+		code, err = gogen.Generate(s, gogen.Options{
+			Package: *pkg,
+			Source:  flag.Arg(0),
+		})
+	case "ts":
+		code, err = tsgen.Generate(s, tsgen.Options{
+			Source: flag.Arg(0),
+		})
+	case "py":
+		code, err = pygen.Generate(s, pygen.Options{
+			Source: flag.Arg(0),
+		})
+	case "c":
+		code, err = cgen.Generate(s, cgen.Options{
+			Source: flag.Arg(0),
+		})
+	case "rust":
+		code, err = rustgen.Generate(s, rustgen.Options{
+			Source: flag.Arg(0),
+		})
+	default:
+		err = fmt.Errorf("unsupported language: %s", *lang)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(code)
+	} else {
+		err = os.WriteFile(*out, code, 0644)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,11 +1,12 @@
 package schemaipc
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"sync"
 
 	"github.com/benjamin-larsen/goschemaipc/encoder"
 	"github.com/benjamin-larsen/goschemaipc/schema"
@@ -19,6 +20,9 @@ var ErrMsgReadLength = errors.New("invalid payload length")
 var ErrSentInvalidDirection = errors.New("client attempted to send a outbound message")
 var ErrInvalidDescriptor = errors.New("client attempted to send a unknown message")
 
+var ErrWriteInvalidDirection = errors.New("attempted to send a inbound message")
+var ErrUnknownSignature = errors.New("unknown message signature")
+
 type ConnState int
 
 const (
@@ -35,43 +39,86 @@ type Conn struct {
 	server *Server
 	conn net.Conn
 	state ConnState
+	writeMu writeLock
+	streams map[uint32]*streamQueue
+	streamsMu sync.Mutex
+	payloadBuf []byte // reused for the payloads of user messages with encoder.DecodeCopy
 }
 
-var nullHeader = ProtocolHeader{}
-
 func (c *Conn) readHeader() (ProtocolHeader, error) {
-	var rawHeader [8]byte
+	return readHeader(c.conn)
+}
+
+func (c *Conn) readPaylod(len uint32, internal bool) ([]byte, error) {
+	// internal messages are always decoded with encoder.DecodeZeroCopy
+	if !internal && c.server.DecodeMode == encoder.DecodeCopy {
+		return reusePayload(c.conn, &c.payloadBuf, len)
+	}
 
-	bytesRead, err := io.ReadFull(c.conn, rawHeader[:])
+	return readPayload(c.conn, len)
+}
 
-	if err != nil {
-		return nullHeader, err
+func (c *Conn) writeMessage(descriptor schema.MessageDescriptor, v any) error {
+	if descriptor.Message.Direction != schema.OutboundMessage && descriptor.Message.Direction != schema.DuplexMessage {
+		return ErrWriteInvalidDirection
 	}
 
-	if bytesRead != 8 {
-		return nullHeader, ErrHeaderLength
+	frame, err := encodeFrame(descriptor, v)
+
+	if err != nil {
+		return err
 	}
 
-	return ProtocolHeader{
-		PacketLength: binary.LittleEndian.Uint32(rawHeader[:4]),
-		MessageType: binary.LittleEndian.Uint32(rawHeader[4:]),
-	}, nil
+	defer releaseFrame(frame)
+
+	c.writeMu.Lock(descriptor.Message.Options.Priority)
+	defer c.writeMu.Unlock()
+
+	_, err = c.conn.Write(frame.Bytes())
+
+	return err
 }
 
-func (c *Conn) readPaylod(len uint32) ([]byte, error) {
-	payload := make([]byte, len)
+// Send encodes a user-defined outbound message and sends it to the client
+func (c *Conn) Send(signature string, v any) error {
+	if !strings.HasPrefix(signature, "outbound ") {
+		return ErrWriteInvalidDirection
+	}
 
-	bytesRead, err := io.ReadFull(c.conn, payload)
+	id, exists := c.server.Registry.UserSignatureMap[signature]
 
-	if err != nil {
-		return nil, err
+	if !exists {
+		return ErrUnknownSignature
 	}
 
-	if bytesRead != int(len) {
-		return nil, ErrMsgReadLength
+	return c.writeMessage(c.server.Registry.Descriptors[id], v)
+}
+
+func (c *Conn) sendInternal(signature string, v any) error {
+	id, exists := c.server.Registry.InternalSignatureMap[signature]
+
+	if !exists {
+		return ErrUnknownSignature
 	}
 
-	return payload, nil
+	return c.writeMessage(c.server.Registry.Descriptors[id], v)
+}
+
+// sendEnvelope sends an internal message carrying the payload of message, at the priority of message
+func (c *Conn) sendEnvelope(signature string, message schema.MessageDescriptor, v any) error {
+	id, exists := c.server.Registry.InternalSignatureMap[signature]
+
+	if !exists {
+		return ErrUnknownSignature
+	}
+
+	return c.writeMessage(carrying(c.server.Registry.Descriptors[id], message), v)
+}
+
+func (c *Conn) sendProtocolError(message string) error {
+	return c.sendInternal("outbound ProtocolError", protocolError{
+		Message: []byte(message),
+	})
 }
 
 type TestX struct {
@@ -88,16 +135,6 @@ func (c *Conn) nextMessage() error {
 		return err
 	}
 
-	if header.PacketLength > c.server.MaxMessageSize {
-		switch (c.server.MessageOverflowPolicy) {
-		case MessageOverflowDiscard:
-			io.CopyN(io.Discard, c.conn, int64(header.PacketLength))
-			return nil
-		case MessageOverflowTerminate:
-			return ErrMsgLength
-		}
-	}
-
 	descriptor, exists := c.server.Registry.Descriptors[header.MessageType]
 
 	if !exists {
@@ -118,19 +155,55 @@ func (c *Conn) nextMessage() error {
 		return ErrInvalidDescriptor
 	}
 
+	// messages can set their own limit with @maxSize, everything else is limited by the server
+	limit := messageLimit(descriptor, c.server.MaxMessageSize)
+
+	if isEnvelope(descriptor) {
+		// the request inside is checked against the limit of its method once it's decoded
+		limit = envelopeLimit(descriptor, &c.server.Registry, true, c.server.MaxMessageSize)
+	}
+
+	if limit != 0 && header.PacketLength > limit {
+		switch (c.server.MessageOverflowPolicy) {
+		case MessageOverflowDiscard:
+			io.CopyN(io.Discard, c.conn, int64(header.PacketLength))
+			return nil
+		case MessageOverflowTerminate:
+			return ErrMsgLength
+		}
+	}
+
+	if descriptor.Message.Deprecated {
+		c.server.reportDeprecated(descriptor, "")
+	}
+
 	if descriptor.Handler == nil {
 		// Ignore User-defined Schemas that don't have handler
 		io.CopyN(io.Discard, c.conn, int64(header.PacketLength))
 		return nil
 	}
 
-	payload, err := c.readPaylod(header.PacketLength)
+	payload, err := c.readPaylod(header.PacketLength, descriptor.Internal)
+
+	if err != nil {
+		return err
+	}
+
+	payload, err = decodePayload(descriptor, payload, limit)
+
+	if err == ErrMsgLength && c.server.MessageOverflowPolicy == MessageOverflowDiscard {
+		return nil
+	}
 
 	if err != nil {
 		return err
 	}
 
 	reader := encoder.NewReader(payload, descriptor)
+	reader.SetMode(c.server.DecodeMode)
+	reader.OnDeprecated(func(field schema.MessageField) {
+		c.server.reportDeprecated(descriptor, field.Name)
+	})
 
 	err = descriptor.Handler(&reader, c)
 
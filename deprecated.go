@@ -0,0 +1,47 @@
+package schemaipc
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func deprecatedKey(descriptor schema.MessageDescriptor, field string) string {
+	key := descriptor.Message.Signature()
+
+	if field != "" {
+		key += "." + field
+	}
+
+	return key
+}
+
+func (s *Server) reportDeprecated(descriptor schema.MessageDescriptor, field string) {
+	key := deprecatedKey(descriptor, field)
+
+	counter, loaded := s.deprecatedUsage.LoadOrStore(key, new(atomic.Uint64))
+	counter.(*atomic.Uint64).Add(1)
+
+	if s.OnDeprecated != nil {
+		s.OnDeprecated(descriptor, field)
+		return
+	}
+
+	// only the first use is logged, DeprecatedUsage has the counts
+	if !loaded {
+		log.Printf("Received deprecated %s", key)
+	}
+}
+
+// DeprecatedUsage returns how many times each deprecated message ("inbound Name") or field ("inbound Name.field") was received
+func (s *Server) DeprecatedUsage() map[string]uint64 {
+	usage := make(map[string]uint64)
+
+	s.deprecatedUsage.Range(func(key, value any) bool {
+		usage[key.(string)] = value.(*atomic.Uint64).Load()
+		return true
+	})
+
+	return usage
+}
@@ -0,0 +1,264 @@
+package docgen
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+type Options struct {
+	Title           string
+	IncludeInternal bool // document the internal protocol messages (Hello, Ping, ...) as well
+}
+
+type fieldDoc struct {
+	Name       string
+	Type       string
+	TypeLink   string // anchor of the referenced object, empty for non-object types
+	Optional   bool
+	Deprecated bool
+	Aliases    []string
+	FixedSize  uint32
+}
+
+type messageDoc struct {
+	ID         uint32
+	HasID      bool // nested objects that aren't registered on their own don't have a wire ID
+	Name       string
+	Signature  string
+	Direction  string
+	Anchor     string
+	Internal   bool
+	Deprecated bool
+	Extensible bool
+	MaxSize    uint32 // 0 if the message uses the connection's limit
+	Priority   string // empty for normal priority
+	Compress   bool
+	Reserved   []string
+	FixedSize  uint32
+	Fields     []fieldDoc
+}
+
+type page struct {
+	Title    string
+	Messages []messageDoc
+	Objects  []messageDoc
+}
+
+func anchor(prefix, name string) string {
+	var b strings.Builder
+
+	b.WriteString(prefix)
+	b.WriteByte('-')
+
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+
+	return b.String()
+}
+
+func nestedDescriptor(extra any) (schema.MessageDescriptor, bool) {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e, true
+	case schema.SchemaMessage:
+		return schema.MessageDescriptor{
+			Message:       e,
+			OptionalCount: e.CountOptional(),
+		}, true
+	default:
+		return schema.MessageDescriptor{}, false
+	}
+}
+
+// documentedObject is an object that is on the page, objects with the same name but different fields each get
+// their own section
+type documentedObject struct {
+	message schema.SchemaMessage
+	anchor  string
+}
+
+type builder struct {
+	page    page
+	objects map[string][]documentedObject // object name to the objects documented under it
+}
+
+func (b *builder) object(name string, descriptor schema.MessageDescriptor, id uint32, hasID bool) string {
+	for _, documented := range b.objects[name] {
+		if reflect.DeepEqual(documented.message, descriptor.Message) {
+			return documented.anchor
+		}
+	}
+
+	a := anchor("object", name)
+
+	if n := len(b.objects[name]); n > 0 {
+		a += "-" + strconv.Itoa(n+1)
+	}
+
+	b.objects[name] = append(b.objects[name], documentedObject{message: descriptor.Message, anchor: a})
+
+	// reserve the slot before walking the fields so the order follows first use
+	idx := len(b.page.Objects)
+	b.page.Objects = append(b.page.Objects, messageDoc{})
+
+	doc := b.message(name, descriptor)
+	doc.ID = id
+	doc.HasID = hasID
+	doc.Signature = schema.Signature(schema.ObjectDef, name)
+	doc.Direction = schema.ObjectDef.ToString()
+	doc.Anchor = a
+
+	b.page.Objects[idx] = doc
+
+	return a
+}
+
+func (b *builder) message(name string, descriptor schema.MessageDescriptor) messageDoc {
+	doc := messageDoc{
+		Name:       name,
+		Internal:   descriptor.Internal,
+		Deprecated: descriptor.Message.Deprecated,
+		Extensible: descriptor.Message.Extensible,
+		MaxSize:    descriptor.Message.Options.MaxSize,
+		Compress:   descriptor.Message.Options.Compress,
+		Reserved:   descriptor.Message.Reserved,
+		FixedSize:  descriptor.GetFixedSize(),
+		Fields:     make([]fieldDoc, 0, len(descriptor.Message.Fields)),
+	}
+
+	if descriptor.Message.Options.Priority != schema.PriorityNormal {
+		doc.Priority = descriptor.Message.Options.Priority.ToString()
+	}
+
+	for _, field := range descriptor.Message.Fields {
+		f := fieldDoc{
+			Name:       field.Name,
+			Type:       field.TypeString(),
+			Optional:   field.Optional,
+			Deprecated: field.Deprecated,
+			Aliases:    field.Aliases,
+			FixedSize:  fixedSize(field),
+		}
+
+		nested, ok := nestedDescriptor(field.Extra)
+
+		if ok && (field.Type == schema.TypeObject || field.Type == schema.TypeArray) {
+			objName := nested.Message.Name
+
+			if objName == "" {
+				// anonymous objects get documented under the field that declares them
+				objName = name + "." + field.Name
+			}
+
+			f.TypeLink = b.object(objName, nested, 0, false)
+
+			if nested.Message.Name == "" {
+				f.Type = strings.Replace(f.Type, "object", objName, 1)
+			}
+		}
+
+		doc.Fields = append(doc.Fields, f)
+	}
+
+	return doc
+}
+
+func fixedSize(field schema.MessageField) uint32 {
+	if field.Type == schema.TypeObject {
+		nested, ok := nestedDescriptor(field.Extra)
+
+		if !ok {
+			return 0
+		}
+
+		return nested.GetFixedSize()
+	}
+
+	return field.Type.GetFixedSize(field.Extra)
+}
+
+func buildPage(r *schema.MessageDescriptorRegistry, opts Options) page {
+	b := builder{
+		page: page{
+			Title: opts.Title,
+		},
+		objects: make(map[string][]documentedObject),
+	}
+
+	if b.page.Title == "" {
+		b.page.Title = "Schema Reference"
+	}
+
+	ids := make([]uint32, 0, len(r.Descriptors))
+
+	for id := range r.Descriptors {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// register top-level objects first so that they keep their wire ID when referenced by messages
+	for _, id := range ids {
+		descriptor := r.Descriptors[id]
+
+		if descriptor.Internal && !opts.IncludeInternal {
+			continue
+		}
+
+		if descriptor.Message.Direction == schema.ObjectDef {
+			b.object(descriptor.Message.Name, descriptor, id, true)
+		}
+	}
+
+	for _, id := range ids {
+		descriptor := r.Descriptors[id]
+
+		if descriptor.Internal && !opts.IncludeInternal {
+			continue
+		}
+
+		if descriptor.Message.Direction == schema.ObjectDef {
+			continue
+		}
+
+		doc := b.message(descriptor.Message.Name, descriptor)
+		doc.ID = id
+		doc.HasID = true
+		doc.Signature = descriptor.Message.Signature()
+		doc.Direction = descriptor.Message.Direction.ToString()
+		doc.Anchor = anchor(doc.Direction, descriptor.Message.Name)
+
+		b.page.Messages = append(b.page.Messages, doc)
+	}
+
+	return b.page
+}
+
+// Registry registers the schema (along with the internal schema) into a new registry, so that the
+// generated documentation carries the same wire IDs a server using the schema would assign
+func Registry(s schema.Schema) (*schema.MessageDescriptorRegistry, error) {
+	r := &schema.MessageDescriptorRegistry{}
+
+	err := r.RegisterInternal()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,173 @@
+package docgen
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const testSchema = `
+package docs
+
+object Point {
+  int16 REQUIRED x
+  int16 OPTIONAL y
+}
+
+object Path {
+  array(Point) REQUIRED points
+  @deprecated
+  binary OPTIONAL label
+}
+
+reserved 40
+
+@extensible @maxSize(4KiB) @priority(high) @compress
+inbound Draw {
+  Path REQUIRED path
+  Point OPTIONAL origin
+  array(binary(4)) REQUIRED tags
+  uint32 REQUIRED color aka colour
+  reserved width
+}
+
+@deprecated
+outbound Drawn {
+  uint64 REQUIRED id
+}
+`
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		err := os.WriteFile(path, got, 0644)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		t.Errorf("docgen/testdata/%s is out of date, run go test ./docgen -update and check the diff\n%s", name, got)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	s, err := schema.Parse([]byte(testSchema))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var md, html bytes.Buffer
+
+	err = WriteSchemaMarkdown(&md, s, Options{Title: "Drawing"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteSchemaHTML(&html, s, Options{Title: "Drawing"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "reference.md", md.Bytes())
+	checkGolden(t, "reference.html", html.Bytes())
+}
+
+// anonymous objects and names with pipes can't be declared in the DSL, but in registries read from JSON or
+// derived from Go structs
+func anonymousObject(fields ...schema.MessageField) schema.SchemaMessage {
+	return schema.SchemaMessage{Direction: schema.ObjectDef, Fields: fields}
+}
+
+func TestObjectsWithTheSameName(t *testing.T) {
+	s := schema.Schema{
+		Messages: []schema.SchemaMessage{
+			{
+				Direction: schema.InboundMessage,
+				Name:      "Move",
+				Fields: []schema.MessageField{
+					{Name: "to", Type: schema.TypeObject, Extra: anonymousObject(schema.MessageField{Name: "x", Type: schema.TypeInt16})},
+				},
+			},
+			{
+				Direction: schema.OutboundMessage,
+				Name:      "Move",
+				Fields: []schema.MessageField{
+					{Name: "to", Type: schema.TypeObject, Extra: anonymousObject(schema.MessageField{Name: "x", Type: schema.TypeInt32})},
+					{Name: "from", Type: schema.TypeObject, Extra: anonymousObject(schema.MessageField{Name: "x", Type: schema.TypeInt32})},
+				},
+			},
+		},
+	}
+
+	r, err := Registry(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := buildPage(r, Options{})
+
+	var anchors []string
+
+	for _, object := range p.Objects {
+		anchors = append(anchors, object.Anchor)
+	}
+
+	// both messages declare Move.to, with different fields
+	expected := []string{"object-move-to", "object-move-to-2", "object-move-from"}
+
+	if strings.Join(anchors, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected objects %v, got %v", expected, anchors)
+	}
+
+	if link := p.Messages[1].Fields[0].TypeLink; link != "object-move-to-2" {
+		t.Errorf("expected outbound Move.to to link to its own object, got %s", link)
+	}
+}
+
+func TestMarkdownEscapesPipes(t *testing.T) {
+	s := schema.Schema{
+		Messages: []schema.SchemaMessage{
+			{
+				Direction: schema.InboundMessage,
+				Name:      "Filter",
+				Fields: []schema.MessageField{
+					{Name: "a|b", Type: schema.TypeUInt32, Aliases: []string{"a||b"}},
+				},
+			},
+		},
+	}
+
+	var md bytes.Buffer
+
+	err := WriteSchemaMarkdown(&md, s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := "| `a\\|b` (aka `a\\|\\|b`) | `uint32` | no | 4 |\n"
+
+	if !strings.Contains(md.String(), row) {
+		t.Errorf("expected the row %q in:\n%s", row, md.String())
+	}
+}
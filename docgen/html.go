@@ -0,0 +1,99 @@
+package docgen
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var htmlTemplate = template.Must(template.New("page").Funcs(template.FuncMap{"yesNo": yesNo}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+td.num, th.num { text-align: right; }
+code { background: #f3f3f3; padding: 0 3px; }
+section { margin-bottom: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if .Messages}}
+<h2>Messages</h2>
+<table>
+<tr><th class="num">ID</th><th>Signature</th><th>Direction</th><th class="num">Fixed Size</th></tr>
+{{- range .Messages}}
+<tr><td class="num">{{.ID}}</td><td><a href="#{{.Anchor}}"><code>{{.Signature}}</code></a></td><td>{{.Direction}}</td><td class="num">{{.FixedSize}}</td></tr>
+{{- end}}
+</table>
+{{- range .Messages}}{{template "message" .}}{{end}}
+{{- end}}
+{{- if .Objects}}
+<h2>Objects</h2>
+{{- range .Objects}}{{template "message" .}}{{end}}
+{{- end}}
+</body>
+</html>
+{{define "message"}}
+<section id="{{.Anchor}}">
+<h3><code>{{.Signature}}</code></h3>
+<ul>
+{{- if .HasID}}
+<li>Wire ID: <code>{{.ID}}</code></li>
+{{- end}}
+<li>Direction: {{.Direction}}</li>
+{{- if .Internal}}
+<li>Internal: yes</li>
+{{- end}}
+{{- if .Deprecated}}
+<li>Deprecated: yes</li>
+{{- end}}
+{{- if .Extensible}}
+<li>Extensible: yes</li>
+{{- end}}
+{{- if .MaxSize}}
+<li>Max Size: {{.MaxSize}} bytes</li>
+{{- end}}
+{{- if .Priority}}
+<li>Priority: {{.Priority}}</li>
+{{- end}}
+{{- if .Compress}}
+<li>Compressed: yes</li>
+{{- end}}
+{{- if .Reserved}}
+<li>Reserved Fields: {{range $i, $name := .Reserved}}{{if $i}}, {{end}}<code>{{$name}}</code>{{end}}</li>
+{{- end}}
+<li>Fixed Size: {{.FixedSize}} bytes</li>
+</ul>
+{{- if .Fields}}
+<table>
+<tr><th>Field</th><th>Type</th><th>Optional</th><th class="num">Fixed Size</th></tr>
+{{- range .Fields}}
+<tr><td><code>{{.Name}}</code>{{if .Aliases}} (aka {{range $i, $a := .Aliases}}{{if $i}}, {{end}}<code>{{$a}}</code>{{end}}){{end}}{{if .Deprecated}} (deprecated){{end}}</td><td>{{if .TypeLink}}<a href="#{{.TypeLink}}"><code>{{.Type}}</code></a>{{else}}<code>{{.Type}}</code>{{end}}</td><td>{{yesNo .Optional}}</td><td class="num">{{.FixedSize}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p><em>No fields.</em></p>
+{{- end}}
+</section>
+{{- end}}
+`))
+
+func WriteHTML(w io.Writer, r *schema.MessageDescriptorRegistry, opts Options) error {
+	return htmlTemplate.Execute(w, buildPage(r, opts))
+}
+
+func WriteSchemaHTML(w io.Writer, s schema.Schema, opts Options) error {
+	r, err := Registry(s)
+
+	if err != nil {
+		return err
+	}
+
+	return WriteHTML(w, r, opts)
+}
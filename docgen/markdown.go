@@ -0,0 +1,137 @@
+package docgen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func WriteMarkdown(w io.Writer, r *schema.MessageDescriptorRegistry, opts Options) error {
+	p := buildPage(r, opts)
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "# %s\n\n", p.Title)
+
+	if len(p.Messages) > 0 {
+		fmt.Fprint(bw, "## Messages\n\n")
+		fmt.Fprint(bw, "| ID | Signature | Direction | Fixed Size |\n")
+		fmt.Fprint(bw, "| --: | --- | --- | --: |\n")
+
+		for _, m := range p.Messages {
+			fmt.Fprintf(bw, "| %d | [`%s`](#%s) | %s | %d |\n", m.ID, cell(m.Signature), m.Anchor, m.Direction, m.FixedSize)
+		}
+
+		fmt.Fprint(bw, "\n")
+
+		for _, m := range p.Messages {
+			writeMarkdownMessage(bw, m)
+		}
+	}
+
+	if len(p.Objects) > 0 {
+		fmt.Fprint(bw, "## Objects\n\n")
+
+		for _, m := range p.Objects {
+			writeMarkdownMessage(bw, m)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func WriteSchemaMarkdown(w io.Writer, s schema.Schema, opts Options) error {
+	r, err := Registry(s)
+
+	if err != nil {
+		return err
+	}
+
+	return WriteMarkdown(w, r, opts)
+}
+
+func writeMarkdownMessage(w io.Writer, m messageDoc) {
+	fmt.Fprintf(w, "<a id=\"%s\"></a>\n\n### `%s`\n\n", m.Anchor, m.Signature)
+
+	if m.HasID {
+		fmt.Fprintf(w, "- Wire ID: `%d`\n", m.ID)
+	}
+
+	fmt.Fprintf(w, "- Direction: %s\n", m.Direction)
+
+	if m.Internal {
+		fmt.Fprint(w, "- Internal: yes\n")
+	}
+
+	if m.Deprecated {
+		fmt.Fprint(w, "- Deprecated: yes\n")
+	}
+
+	if m.Extensible {
+		fmt.Fprint(w, "- Extensible: yes\n")
+	}
+
+	if m.MaxSize != 0 {
+		fmt.Fprintf(w, "- Max Size: %d bytes\n", m.MaxSize)
+	}
+
+	if m.Priority != "" {
+		fmt.Fprintf(w, "- Priority: %s\n", m.Priority)
+	}
+
+	if m.Compress {
+		fmt.Fprint(w, "- Compressed: yes\n")
+	}
+
+	if len(m.Reserved) > 0 {
+		fmt.Fprintf(w, "- Reserved Fields: `%s`\n", strings.Join(m.Reserved, "`, `"))
+	}
+
+	fmt.Fprintf(w, "- Fixed Size: %d bytes\n\n", m.FixedSize)
+
+	if len(m.Fields) == 0 {
+		fmt.Fprint(w, "_No fields._\n\n")
+		return
+	}
+
+	fmt.Fprint(w, "| Field | Type | Optional | Fixed Size |\n")
+	fmt.Fprint(w, "| --- | --- | --- | --: |\n")
+
+	for _, f := range m.Fields {
+		typ := fmt.Sprintf("`%s`", f.Type)
+
+		if f.TypeLink != "" {
+			typ = fmt.Sprintf("[%s](#%s)", typ, f.TypeLink)
+		}
+
+		name := fmt.Sprintf("`%s`", f.Name)
+
+		if len(f.Aliases) > 0 {
+			name += fmt.Sprintf(" (aka `%s`)", strings.Join(f.Aliases, "`, `"))
+		}
+
+		if f.Deprecated {
+			name += " (deprecated)"
+		}
+
+		fmt.Fprintf(w, "| %s | %s | %s | %d |\n", cell(name), cell(typ), yesNo(f.Optional), f.FixedSize)
+	}
+
+	fmt.Fprint(w, "\n")
+}
+
+// cell escapes the pipes in the contents of a table cell, which would end the cell even inside a code span.
+// The DSL doesn't allow them in names, but registries read from JSON or derived from Go structs may have them.
+func cell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
@@ -2,35 +2,33 @@ package encoder
 
 import (
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"reflect"
 	"sync"
+	"testing"
 	"unsafe"
 
 	"github.com/benjamin-larsen/goschemaipc/schema"
 )
 
-var ErrOutOfBounds = errors.New("out of bounds")
-var ErrOptionalCorrupted = errors.New("optional count is corrupted")
-var ErrTypeCorrupted = errors.New("message type is corrupted")
-var ErrInvalidResultObject = errors.New("invalid result object (expected *Struct)")
+// expReader is the offset based decoder of the removed exp/encoder package, kept as the baseline the compiled
+// plans are benchmarked against. It doesn't decode arrays and ignores errors of fields.
 
-type Reader struct {
+type expReader struct {
 	buffer       []byte
 	pos          uint32
 	availableLen uint32
 }
 
-func NewReader(buffer []byte) Reader {
-	return Reader{
+func newExpReader(buffer []byte) expReader {
+	return expReader{
 		buffer:       buffer,
 		pos:          0,
 		availableLen: uint32(len(buffer)),
 	}
 }
 
-func (r *Reader) ReadBytes(n uint32) ([]byte, error) {
+func (r *expReader) ReadBytes(n uint32) ([]byte, error) {
 	if n > r.availableLen {
 		return nil, ErrOutOfBounds
 	}
@@ -47,7 +45,7 @@ func (r *Reader) ReadBytes(n uint32) ([]byte, error) {
 	return result, nil
 }
 
-func (r *Reader) ReadUInt16() (uint16, error) {
+func (r *expReader) ReadUInt16() (uint16, error) {
 	bytes, err := r.ReadBytes(2)
 
 	if err != nil {
@@ -57,7 +55,7 @@ func (r *Reader) ReadUInt16() (uint16, error) {
 	return binary.LittleEndian.Uint16(bytes), nil
 }
 
-func (r *Reader) ReadInt16() (int16, error) {
+func (r *expReader) ReadInt16() (int16, error) {
 	bytes, err := r.ReadBytes(2)
 
 	if err != nil {
@@ -67,7 +65,7 @@ func (r *Reader) ReadInt16() (int16, error) {
 	return int16(binary.LittleEndian.Uint16(bytes)), nil
 }
 
-func (r *Reader) ReadUInt32() (uint32, error) {
+func (r *expReader) ReadUInt32() (uint32, error) {
 	bytes, err := r.ReadBytes(4)
 
 	if err != nil {
@@ -77,7 +75,7 @@ func (r *Reader) ReadUInt32() (uint32, error) {
 	return binary.LittleEndian.Uint32(bytes), nil
 }
 
-func (r *Reader) ReadInt32() (int32, error) {
+func (r *expReader) ReadInt32() (int32, error) {
 	bytes, err := r.ReadBytes(4)
 
 	if err != nil {
@@ -87,7 +85,7 @@ func (r *Reader) ReadInt32() (int32, error) {
 	return int32(binary.LittleEndian.Uint32(bytes)), nil
 }
 
-func (r *Reader) ReadUInt64() (uint64, error) {
+func (r *expReader) ReadUInt64() (uint64, error) {
 	bytes, err := r.ReadBytes(8)
 
 	if err != nil {
@@ -97,7 +95,7 @@ func (r *Reader) ReadUInt64() (uint64, error) {
 	return binary.LittleEndian.Uint64(bytes), nil
 }
 
-func (r *Reader) ReadInt64() (int64, error) {
+func (r *expReader) ReadInt64() (int64, error) {
 	bytes, err := r.ReadBytes(8)
 
 	if err != nil {
@@ -107,43 +105,31 @@ func (r *Reader) ReadInt64() (int64, error) {
 	return int64(binary.LittleEndian.Uint64(bytes)), nil
 }
 
-func GetOpt(opt uint32, optList []byte) bool {
-	// same as rawBitPos % 8 but optimized
-	bitPos := opt & 7
-
-	// same as rawBitPos / 8 but optimized
-	bytePos := opt >> 3
-
-	bitMask := byte(1 << bitPos)
-
-	return (optList[bytePos] & bitMask) != 0
-}
-
-type fieldMapEntry struct {
-	Offset uintptr
-	Kind reflect.Kind
-	ElemKind reflect.Kind
+type expFieldEntry struct {
+	Offset    uintptr
+	Kind      reflect.Kind
+	ElemKind  reflect.Kind
 	ElemCount int // for Array
 	IsPointer bool
 }
 
-type fieldMap map[string]fieldMapEntry // field name (protocol) to field offset (struct)
+type expFieldMap map[string]expFieldEntry // field name (protocol) to field offset (struct)
 
-var typeCache sync.Map // map[reflect.Type]fieldMap2
+var expTypeCache sync.Map // map[reflect.Type]expFieldMap
 
-func computeFieldMap(t reflect.Type) (fieldMap, error) {
-	cached, exists := typeCache.Load(t)
+func expComputeFieldMap(t reflect.Type) (expFieldMap, error) {
+	cached, exists := expTypeCache.Load(t)
 
 	if exists {
-		return cached.(fieldMap), nil
+		return cached.(expFieldMap), nil
 	}
 
 	numField := t.NumField()
-	fMap := make(fieldMap, numField)
+	fMap := make(expFieldMap, numField)
 
 	for i := 0; i < numField; i++ {
 		field := t.Field(i)
-		tag := field.Tag.Get("ipc")
+		tag, _ := schema.StructTag(field.Tag.Get("ipc"))
 
 		if tag == "" {
 			continue
@@ -172,27 +158,27 @@ func computeFieldMap(t reflect.Type) (fieldMap, error) {
 		if ftKind == reflect.Slice || ftKind == reflect.Array {
 			elemKind = ft.Elem().Kind()
 		}
-		
+
 		// set count for fixed slices
 		if ftKind == reflect.Array {
 			elemCount = ft.Len()
 		}
 
-		fMap[tag] = fieldMapEntry{
-			Offset: field.Offset,
-			Kind: ft.Kind(),
-			ElemKind: elemKind,
+		fMap[tag] = expFieldEntry{
+			Offset:    field.Offset,
+			Kind:      ft.Kind(),
+			ElemKind:  elemKind,
 			ElemCount: elemCount,
 			IsPointer: isPointer,
 		}
 	}
 
-	cached, _ = typeCache.LoadOrStore(t, fMap)
+	cached, _ = expTypeCache.LoadOrStore(t, fMap)
 
-	return cached.(fieldMap), nil
+	return cached.(expFieldMap), nil
 }
 
-func (r *Reader) Decode(descriptor schema.MessageDescriptor, res any) error {
+func (r *expReader) Decode(descriptor schema.MessageDescriptor, res any) error {
 	// Setup reflection
 
 	vPtr := reflect.ValueOf(res)
@@ -203,7 +189,7 @@ func (r *Reader) Decode(descriptor schema.MessageDescriptor, res any) error {
 	}
 
 	t := v.Type()
-	fMap, err := computeFieldMap(t)
+	fMap, err := expComputeFieldMap(t)
 
 	if err != nil {
 		return err
@@ -211,7 +197,7 @@ func (r *Reader) Decode(descriptor schema.MessageDescriptor, res any) error {
 
 	// Start Decoding
 
-	basePtr := v.UnsafeAddr()
+	basePtr := unsafe.Pointer(v.UnsafeAddr())
 
 	optBytes := descriptor.OptFlagLength()
 
@@ -263,7 +249,7 @@ TypeFixedBinary, TypeDynamicBinary and TypeLongBinary can be Array (must be exac
 
 // TODO: check types
 // Check IsValid and CanSet
-func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr uintptr) error {
+func (r *expReader) decodeSingle(field schema.MessageField, fMap expFieldMap, basePtr unsafe.Pointer) error {
 	fEntry, exists := fMap[field.Name]
 
 	switch field.Type {
@@ -281,7 +267,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*[]byte)(unsafe.Pointer(basePtr + fEntry.Offset)) = bytes
+			*(*[]byte)(unsafe.Add(basePtr, fEntry.Offset)) = bytes
 
 			break
 		}
@@ -304,7 +290,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*[]byte)(unsafe.Pointer(basePtr + fEntry.Offset)) = bytes
+			*(*[]byte)(unsafe.Add(basePtr, fEntry.Offset)) = bytes
 
 			break
 		}
@@ -327,7 +313,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*[]byte)(unsafe.Pointer(basePtr + fEntry.Offset)) = bytes
+			*(*[]byte)(unsafe.Add(basePtr, fEntry.Offset)) = bytes
 
 			break
 		}
@@ -344,7 +330,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*uint64)(unsafe.Pointer(basePtr + fEntry.Offset)) = num
+			*(*uint64)(unsafe.Add(basePtr, fEntry.Offset)) = num
 
 			break
 		}
@@ -361,7 +347,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*int64)(unsafe.Pointer(basePtr + fEntry.Offset)) = num
+			*(*int64)(unsafe.Add(basePtr, fEntry.Offset)) = num
 
 			break
 		}
@@ -378,7 +364,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*uint32)(unsafe.Pointer(basePtr + fEntry.Offset)) = num
+			*(*uint32)(unsafe.Add(basePtr, fEntry.Offset)) = num
 
 			break
 		}
@@ -395,7 +381,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*int32)(unsafe.Pointer(basePtr + fEntry.Offset)) = num
+			*(*int32)(unsafe.Add(basePtr, fEntry.Offset)) = num
 
 			break
 		}
@@ -412,7 +398,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*uint16)(unsafe.Pointer(basePtr + fEntry.Offset)) = num
+			*(*uint16)(unsafe.Add(basePtr, fEntry.Offset)) = num
 
 			break
 		}
@@ -429,7 +415,7 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 			}
 
 			// do checks before, and if pointer need to make a new heap allocation
-			*(*int16)(unsafe.Pointer(basePtr + fEntry.Offset)) = num
+			*(*int16)(unsafe.Add(basePtr, fEntry.Offset)) = num
 
 			break
 		}
@@ -441,3 +427,22 @@ func (r *Reader) decodeSingle(field schema.MessageField, fMap fieldMap, basePtr
 
 	return nil
 }
+
+func BenchmarkDecodeBaseline(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var res sampleStruct
+
+		reader := newExpReader(sampleBuf)
+		err := reader.Decode(sampleDesc, &res)
+
+		if err != nil {
+			b.Error(err)
+		}
+
+		err = validateRes(res)
+
+		if err != nil {
+			b.Error(err)
+		}
+	}
+}
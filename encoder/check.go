@@ -0,0 +1,65 @@
+package encoder
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// CheckType verifies up front that values of type t can be encoded and decoded with the descriptor:
+// every required field must map to a struct field, and mapped fields must be of a compatible kind
+func CheckType(descriptor schema.MessageDescriptor, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return ErrInvalidResultPointer
+	}
+
+	fMap, err := computeFieldMap(t)
+
+	if err != nil {
+		return err
+	}
+
+	for _, field := range descriptor.Message.Fields {
+		fIdx, exists := fMap.lookup(field)
+
+		if !exists {
+			if !field.Optional {
+				return fmt.Errorf("%w: %s has no field for %s.%s", ErrRequiredNotPresent, t, descriptor.Message.Name, field.Name)
+			}
+
+			continue
+		}
+
+		fType := t.FieldByIndex(fIdx).Type
+
+		err = checkKind(field, fType)
+
+		if err != nil {
+			return err
+		}
+
+		switch e := field.Extra.(type) {
+		case schema.MessageDescriptor:
+			{
+				if field.Type == schema.TypeArray {
+					fType = fType.Elem()
+				}
+
+				err = CheckType(e, fType)
+			}
+		case schema.MessageField:
+			err = checkKind(e, fType.Elem())
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
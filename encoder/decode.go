@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"sync"
 	"unsafe"
 
@@ -13,16 +14,31 @@ import (
 
 var ErrOutOfBounds = errors.New("out of bounds")
 var ErrOptionalCorrupted = errors.New("optional count is corrupted")
+var ErrMissingRequired = errors.New("required field is missing from message")
 var ErrTypeCorrupted = errors.New("message type is corrupted")
 var ErrInvalidResultObject = errors.New("invalid result object (expected *struct)")
 var ErrInvalidResultPointer = errors.New("invalid result poinetr (expected struct)")
 var ErrInvalidByteKind = errors.New("invalid field kind (expected Array ([N]byte), Slice ([]byte) or string)")
+var ErrFieldKindMismatch = errors.New("field kind doesn't match the message field type")
+
+// DecodeMode chooses whether binary fields decoded into []byte alias the payload
+type DecodeMode uint8
+
+const (
+	// DecodeZeroCopy stores binary fields decoded into []byte as slices of the payload, so they are only valid
+	// as long as the payload isn't reused or modified. Strings and arrays are copied either way.
+	DecodeZeroCopy DecodeMode = iota
+	// DecodeCopy copies binary fields out of the payload, so the payload can be reused once Decode returns
+	DecodeCopy
+)
 
 type Reader struct {
 	buffer       []byte
 	descriptor   schema.MessageDescriptor
 	pos          uint32
 	len          uint32
+	mode         DecodeMode
+	onDeprecated func(field schema.MessageField)
 }
 
 func NewReader(buffer []byte, descriptor schema.MessageDescriptor) Reader {
@@ -34,6 +50,17 @@ func NewReader(buffer []byte, descriptor schema.MessageDescriptor) Reader {
 	}
 }
 
+// OnDeprecated sets a callback that is invoked for every deprecated field present in the decoded message
+func (r *Reader) OnDeprecated(fn func(field schema.MessageField)) {
+	r.onDeprecated = fn
+}
+
+// SetMode sets whether decoded []byte fields alias the payload, DecodeZeroCopy is the default
+func (r *Reader) SetMode(mode DecodeMode) {
+	r.mode = mode
+}
+
+// ReadBytes reads n bytes, the result always points into the buffer of the reader
 func (r *Reader) ReadBytes(n uint32) ([]byte, error) {
 	if n > (r.len - r.pos) {
 		return nil, ErrOutOfBounds
@@ -122,7 +149,7 @@ func GetOpt(opt uint32, optList []byte) bool {
 	return (optList[bytePos] & bitMask) != 0
 }
 
-type fieldMap map[string]int // field name (protocol) to field index (struct)
+type fieldMap map[string][]int // field name (protocol) to field index path (struct), embedded structs add to the path
 
 var typeCache sync.Map // map[reflect.Type]fieldMap
 
@@ -133,24 +160,13 @@ func computeFieldMap(t reflect.Type) (fieldMap, error) {
 		return cached.(fieldMap), nil
 	}
 
-	numField := t.NumField()
-	fMap := make(fieldMap, numField)
-
-	for i := 0; i < numField; i++ {
-		field := t.Field(i)
-		tag := field.Tag.Get("ipc")
-
-		if tag == "" {
-			continue
-		}
-
-		_, exists := fMap[tag]
+	fMap := make(fieldMap, t.NumField())
+	depths := make(map[string]int, t.NumField())
 
-		if exists {
-			return nil, fmt.Errorf("duplicate struct tag: %s", tag)
-		}
+	err := fMap.addFields(t, nil, depths)
 
-		fMap[tag] = i
+	if err != nil {
+		return nil, err
 	}
 
 	cached, _ = typeCache.LoadOrStore(t, fMap)
@@ -158,383 +174,213 @@ func computeFieldMap(t reflect.Type) (fieldMap, error) {
 	return cached.(fieldMap), nil
 }
 
-func (r *Reader) Decode(res any) error {
-	r.pos = 0
-
-	// Setup reflection
-
-	vPtr := reflect.ValueOf(res)
-
-	if vPtr.Kind() != reflect.Ptr {
-		return ErrInvalidResultObject
-	}
-
-	return r.decodeStruct(r.descriptor, vPtr.Elem())
-}
-
-func (r *Reader) decodeStruct(descriptor schema.MessageDescriptor, v reflect.Value) error {
-	if v.Kind() != reflect.Struct {
-		return ErrInvalidResultPointer
-	}
-
-	t := v.Type()
-	fMap, err := computeFieldMap(t)
-
-	if err != nil {
-		return err
-	}
-
-	// Start Decoding
-
-	optBytes := descriptor.OptFlagLength()
-
-	optList, err := r.ReadBytes(optBytes)
+// addFields maps the tagged fields of t, then the fields of its untagged embedded structs.
+// Like promoted fields in Go, a field shadows fields of the same name in deeper embedded structs.
+func (fMap fieldMap) addFields(t reflect.Type, path []int, depths map[string]int) error {
+	var embedded []int
 
-	if err != nil {
-		return err
-	}
-
-	var optCounter uint32 = 0
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, _ := schema.StructTag(field.Tag.Get("ipc"))
 
-	for _, field := range descriptor.Message.Fields {
-		if field.Optional {
-			if optCounter >= optBytes {
-				return ErrOptionalCorrupted
+		if tag == "" {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				embedded = append(embedded, i)
 			}
 
-			opt := optCounter
-			optCounter++
-
-			if !GetOpt(opt, optList) {
-				continue
-			}
+			continue
 		}
 
-		fIdx, exists := fMap[field.Name]
-		f := reflect.Value{}
+		depth, exists := depths[tag]
 
-		if exists {
-			f = v.Field(fIdx)
+		if exists && depth == len(path) {
+			return fmt.Errorf("duplicate struct tag: %s", tag)
 		}
 
-		err := r.decodeSingle(field, f)
-
-		if err != nil {
-			return err
+		if exists && depth < len(path) {
+			continue
 		}
-	}
 
-	return nil
-}
+		fMap[tag] = append(slices.Clip(path), i)
+		depths[tag] = len(path)
+	}
 
-/*
-		TypeFixedBinary
-TypeDynamicBinary
-TypeLongBinary
-TypeUInt64
-TypeInt64
-TypeUInt32
-TypeInt32
-TypeUInt16
-TypeInt16
-*/
-
-func setBytes(bytes []byte, v reflect.Value) error {
-	addr := unsafe.Pointer(v.UnsafeAddr())
-	kind := v.Kind()
-
-	switch kind {
-	case reflect.Array:
-		{
-			byteLen := len(bytes)
-			arrLen := v.Len()
-
-			if byteLen != arrLen {
-				return ErrWrongLen
-			}
+	for _, i := range embedded {
+		err := fMap.addFields(t.Field(i).Type, append(slices.Clip(path), i), depths)
 
-			copy(
-				unsafe.Slice((*byte)(addr), arrLen),
-				bytes,
-			)
-			break
-		}
-	case reflect.Slice:
-		{
-			// check that elem is byte too
-			*(*[]byte)(addr) = bytes
-			break
-		}
-	case reflect.String:
-		{
-			*(*string)(addr) = string(bytes)
-			break
-		}
-	default:
-		{
-			return ErrInvalidByteKind
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-// TODO: check types
-// Check IsValid and CanSet
-func (r *Reader) decodeSingle(field schema.MessageField, f reflect.Value) error {
-	switch field.Type {
-	case schema.TypeFixedBinary:
-		{
-			fLen := field.Extra.(int)
-			bytes, err := r.ReadBytes(uint32(fLen))
+// lookup finds the struct field for a schema field by its name, then by its aliases
+func (fMap fieldMap) lookup(field schema.MessageField) ([]int, bool) {
+	fIdx, exists := fMap[field.Name]
 
-			if err != nil {
-				return err
-			}
+	if exists {
+		return fIdx, true
+	}
 
-			if !f.IsValid() {
-				return nil
-			}
+	for _, alias := range field.Aliases {
+		fIdx, exists = fMap[alias]
 
-			err = setBytes(bytes, f)
+		if exists {
+			return fIdx, true
+		}
+	}
 
-			if err != nil {
-				return err
-			}
+	return nil, false
+}
 
-			break
-		}
-	case schema.TypeDynamicBinary:
-		{
-			len, err := r.ReadUInt16()
+func (r *Reader) Decode(res any) error {
+	r.pos = 0
 
-			if err != nil {
-				return err
-			}
+	if u, ok := res.(Unmarshaler); ok && useGenerated(u.IPCLayout(), r.descriptor) {
+		// generated code gets a copy, so r doesn't escape to the heap when decoding with a plan
+		generated := *r
+		err := u.UnmarshalIPC(&generated)
+		*r = generated
 
-			bytes, err := r.ReadBytes(uint32(len))
+		return err
+	}
 
-			if err != nil {
-				return err
-			}
+	vPtr := reflect.ValueOf(res)
 
-			if !f.IsValid() {
-				return nil
-			}
+	if vPtr.Kind() != reflect.Ptr {
+		return ErrInvalidResultObject
+	}
 
-			err = setBytes(bytes, f)
+	// a nil pointer means there is nothing to decode into, the message is only read to skip past it
+	var t reflect.Type
+	var p unsafe.Pointer
 
-			if err != nil {
-				return err
-			}
+	if !vPtr.IsNil() {
+		t = vPtr.Type().Elem()
+		p = vPtr.UnsafePointer()
 
-			break
+		if t.Kind() != reflect.Struct {
+			return ErrInvalidResultPointer
 		}
-	case schema.TypeLongBinary:
-		{
-			len, err := r.ReadUInt32()
+	}
 
-			if err != nil {
-				return err
-			}
+	plan, err := loadDecodePlan(&r.descriptor, t)
 
-			bytes, err := r.ReadBytes(uint32(len))
+	if err != nil {
+		return err
+	}
 
-			if err != nil {
-				return err
-			}
+	return r.decodeMessage(plan, p)
+}
 
-			if !f.IsValid() {
-				return nil
-			}
+// decodeMessage decodes a message or object into the struct at p, or only skips it if p is nil
+func (r *Reader) decodeMessage(plan *decodePlan, p unsafe.Pointer) error {
+	optBytes := plan.optBytes
 
-			err = setBytes(bytes, f)
+	// bounds of the message, extensible messages may be shorter or longer than the descriptor
+	var end, outerLen uint32
 
-			if err != nil {
-				return err
-			}
+	if plan.extensible {
+		bodyLen, err := r.ReadUInt32()
 
-			break
+		if err != nil {
+			return err
 		}
-	case schema.TypeUInt64:
-		{
-			num, err := r.ReadUInt64()
 
-			if err != nil {
-				return err
-			}
-
-			if !f.IsValid() {
-				return nil
-			}
-
-			f.SetUint(num)
-
-			break
+		if bodyLen > (r.len - r.pos) {
+			return ErrOutOfBounds
 		}
-	case schema.TypeInt64:
-		{
-			num, err := r.ReadInt64()
-
-			if err != nil {
-				return err
-			}
 
-			if !f.IsValid() {
-				return nil
-			}
+		end = r.pos + bodyLen
+		outerLen = r.len
+		r.len = end
 
-			f.SetInt(num)
+		flagLen, err := r.ReadBytes(1)
 
-			break
+		if err != nil {
+			return err
 		}
-	case schema.TypeUInt32:
-		{
-			num, err := r.ReadUInt32()
 
-			if err != nil {
-				return err
-			}
+		// the sender might know about more or less optional fields than we do
+		optBytes = uint32(flagLen[0])
+	}
 
-			if !f.IsValid() {
-				return nil
-			}
+	optList, err := r.ReadBytes(optBytes)
 
-			f.SetUint(uint64(num))
+	if err != nil {
+		return err
+	}
 
-			break
-		}
-	case schema.TypeInt32:
-		{
-			num, err := r.ReadInt32()
+	var optCounter uint32 = 0
 
-			if err != nil {
-				return err
-			}
+	for i := range plan.fields {
+		fd := &plan.fields[i]
 
-			if !f.IsValid() {
-				return nil
+		if fd.optional {
+			if optCounter >= plan.optionalCount {
+				return ErrOptionalCorrupted
 			}
 
-			f.SetInt(int64(num))
-
-			break
-		}
-	case schema.TypeUInt16:
-		{
-			num, err := r.ReadUInt16()
-
-			if err != nil {
-				return err
-			}
+			opt := optCounter
+			optCounter++
 
-			if !f.IsValid() {
-				return nil
+			if opt >= optBytes*8 || !GetOpt(opt, optList) {
+				continue
 			}
-
-			f.SetUint(uint64(num))
-
-			break
+		} else if plan.extensible && r.pos == end {
+			// sent by a peer that doesn't know about this field yet
+			return ErrMissingRequired
 		}
-	case schema.TypeInt16:
-		{
-			num, err := r.ReadInt16()
-
-			if err != nil {
-				return err
-			}
-
-			if !f.IsValid() {
-				return nil
-			}
 
-			f.SetInt(int64(num))
-
-			break
+		if fd.deprecated && r.onDeprecated != nil {
+			r.onDeprecated(*fd.field)
 		}
-	case schema.TypeObject:
-		{
-			subFields := field.Extra.(schema.MessageDescriptor)
-
-			if !f.IsValid() {
-				// need to skip bytes here
-				return nil
-			}
-
-			err := r.decodeStruct(subFields, f)
 
-			if err != nil {
-				return err
-			}
+		var fp unsafe.Pointer
 
-			break
+		if fd.mapped && p != nil {
+			fp = unsafe.Add(p, fd.offset)
 		}
-	case schema.TypeArray:
-		{
-			lenU32, err := r.ReadUInt16()
-
-			if err != nil {
-				return err
-			}
-
-			arrLen := int(lenU32)
-
-			if !f.IsValid() {
-				// need to skip bytes here
-				return nil
-			}
-
-			switch e := field.Extra.(type) {
-			case schema.MessageField: {
-				itemSize := e.Type.GetFixedSize(e.Extra) * uint32(arrLen)
-
-				if itemSize > (r.len - r.pos) {
-					return ErrOutOfBounds
-				}
-
-				slice := reflect.MakeSlice(f.Type(), arrLen, arrLen)
-
-				f.Set(slice)
 
-				for i := 0; i < arrLen; i++ {
-					item := slice.Index(i)
+		err := r.decodeValue(&fd.value, fp)
 
-					err := r.decodeSingle(e, item)
-
-					if err != nil {
-						return err
-					}
-				}
-			}
-			case schema.MessageDescriptor: {
-				itemSize := e.GetFixedSize() * uint32(arrLen)
-
-				if itemSize > (r.len - r.pos) {
-					return ErrOutOfBounds
-				}
-
-				slice := reflect.MakeSlice(f.Type(), arrLen, arrLen)
-
-				f.Set(slice)
+		if err != nil {
+			return err
+		}
+	}
 
-				for i := 0; i < arrLen; i++ {
-					item := slice.Index(i)
+	if plan.extensible {
+		// skip trailing fields sent by a peer with a newer schema
+		r.pos = end
+		r.len = outerLen
+	}
 
-					err := r.decodeStruct(e, item)
+	return nil
+}
 
-					if err != nil {
-						return err
-					}
-				}
-			}
-			}
+func checkKind(field schema.MessageField, t reflect.Type) error {
+	kind := t.Kind()
+	var ok bool
 
-			break
-		}
+	switch field.Type {
+	case schema.TypeFixedBinary, schema.TypeDynamicBinary, schema.TypeLongBinary:
+		ok = kind == reflect.String || ((kind == reflect.Slice || kind == reflect.Array) && t.Elem().Kind() == reflect.Uint8)
+	case schema.TypeUInt64, schema.TypeUInt32, schema.TypeUInt16:
+		ok = kind >= reflect.Uint && kind <= reflect.Uintptr
+	case schema.TypeInt64, schema.TypeInt32, schema.TypeInt16:
+		ok = kind >= reflect.Int && kind <= reflect.Int64
+	case schema.TypeObject:
+		ok = kind == reflect.Struct
+	case schema.TypeArray:
+		ok = kind == reflect.Slice
 	default:
-		{
-			return ErrTypeCorrupted
-		}
+		ok = true
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: %s is %s, but %s is %s", ErrFieldKindMismatch, field.Name, field.TypeString(), t, kind)
 	}
 
 	return nil
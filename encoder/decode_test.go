@@ -1,12 +1,13 @@
 package encoder
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
 	"slices"
 	"testing"
 
-	exp "github.com/benjamin-larsen/goschemaipc/exp/encoder"
 	"github.com/benjamin-larsen/goschemaipc/schema"
 )
 
@@ -109,9 +110,10 @@ type messageDescriptor struct {
 }
 
 type helloOutbound struct {
-	MinVersion int32               `ipc:"minVersion"`
-	Version    int32               `ipc:"currVersion"`
-	Schema     []messageDescriptor `ipc:"schema"`
+	MinVersion  int32               `ipc:"minVersion"`
+	Version     int32               `ipc:"currVersion"`
+	Fingerprint [32]byte            `ipc:"fingerprint"`
+	Schema      []messageDescriptor `ipc:"schema"`
 }
 
 var sampleDesc = schema.MessageDescriptor{
@@ -140,14 +142,20 @@ var sampleBuf = []byte{
 }
 
 var sampleBuf2 = []byte{
+	0x00,                   // optional flags            (no types or constants)
 	0x00, 0x00, 0x00, 0x00, // minVersion                (0)
 	0x00, 0x00, 0x00, 0x00, // currVersion               (0)
+	// fingerprint
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	0x01, 0x00, // schema [length]           (1)
 
 	// schema[0]
 	0x02, 0x00, 0x00, 0x00, // schema[0].id              (2)
 	0x01, 0x00, //             schema[0].internal        (false)
 	0x01, 0x00, //             schema[0].direction       (outbound)
+	0x00, 0x00, //             schema[0].flags           (none)
+	0x00, 0x00, 0x00, 0x00, // schema[0].maxSize         (default)
 	0x0d, 0x00, //             schema[0].name [length]   (13)
 	// schema[0].name (ProtocolError)
 	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x45, 0x72, 0x72, 0x6f, 0x72,
@@ -160,6 +168,9 @@ var sampleBuf2 = []byte{
 	0x01, 0x00, //   schema[0].fields[0].type            (TypeDynamicBinary)
 	0x00, 0x00, 0x00, 0x00, // fields[0].extra [length]  (7)
 	0x00, 0x00, // fields[0].optional                    (false)
+	0x00, 0x00, // fields[0].aliases [length]            (0)
+
+	0x00, 0x00, // methods [length]          (0)
 }
 
 var expectedBin = []byte{
@@ -242,8 +253,8 @@ func TestA(t *testing.T) {
 func TestB(t *testing.T) {
 	var res sampleStruct
 
-	reader := exp.NewReader(sampleBuf)
-	err := reader.Decode(sampleDesc, &res)
+	reader := NewReader(sampleBuf, sampleDesc)
+	err := reader.Decode(&res)
 
 	if err != nil {
 		t.Error(err)
@@ -304,8 +315,8 @@ func BenchmarkDecodeB(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		var res sampleStruct
 
-		reader := exp.NewReader(sampleBuf)
-		err := reader.Decode(sampleDesc, &res)
+		reader := NewReader(sampleBuf, sampleDesc)
+		err := reader.Decode(&res)
 
 		if err != nil {
 			b.Error(err)
@@ -318,3 +329,483 @@ func BenchmarkDecodeB(b *testing.B) {
 		}
 	}
 }
+
+var evolutionV1 = schema.SchemaMessage{
+	Direction:  schema.InboundMessage,
+	Name:       "evolving",
+	Extensible: true,
+	Fields: []schema.MessageField{
+		{Name: "id", Type: schema.TypeUInt32},
+		{Name: "name", Type: schema.TypeDynamicBinary, Optional: true},
+	},
+}
+
+var evolutionV2 = schema.SchemaMessage{
+	Direction:  schema.InboundMessage,
+	Name:       "evolving",
+	Extensible: true,
+	Fields: []schema.MessageField{
+		{Name: "id", Type: schema.TypeUInt32},
+		{Name: "name", Type: schema.TypeDynamicBinary, Optional: true},
+		{Name: "tags", Type: schema.TypeArray, Extra: schema.MessageField{Type: schema.TypeUInt16}, Optional: true},
+		{Name: "score", Type: schema.TypeInt64, Optional: true},
+	},
+}
+
+type evolvingV1 struct {
+	ID   uint32 `ipc:"id"`
+	Name string `ipc:"name"`
+}
+
+type evolvingV2 struct {
+	ID    uint32   `ipc:"id"`
+	Name  string   `ipc:"name"`
+	Tags  []uint16 `ipc:"tags"`
+	Score int64    `ipc:"score"`
+}
+
+type evolvingEnvelope struct {
+	Inner evolvingV1 `ipc:"inner"`
+	After uint16     `ipc:"after"`
+}
+
+func descriptorOf(message schema.SchemaMessage) schema.MessageDescriptor {
+	return schema.MessageDescriptor{
+		Message:       message,
+		OptionalCount: message.CountOptional(),
+	}
+}
+
+func TestEvolutionNewerSender(t *testing.T) {
+	buf, err := Encode(descriptorOf(evolutionV2), evolvingV2{ID: 7, Name: "seven", Tags: []uint16{1, 2}, Score: -3})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res evolvingV1
+
+	reader := NewReader(buf, descriptorOf(evolutionV1))
+	err = reader.Decode(&res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ID != 7 || res.Name != "seven" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestEvolutionOlderSender(t *testing.T) {
+	buf, err := Encode(descriptorOf(evolutionV1), evolvingV1{ID: 7, Name: "seven"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res evolvingV2
+
+	reader := NewReader(buf, descriptorOf(evolutionV2))
+	err = reader.Decode(&res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ID != 7 || res.Name != "seven" || res.Tags != nil || res.Score != 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestEvolutionNestedObject(t *testing.T) {
+	envelope := func(inner schema.SchemaMessage) schema.MessageDescriptor {
+		return descriptorOf(schema.SchemaMessage{
+			Direction: schema.InboundMessage,
+			Name:      "envelope",
+			Fields: []schema.MessageField{
+				{Name: "inner", Type: schema.TypeObject, Extra: descriptorOf(inner)},
+				{Name: "after", Type: schema.TypeUInt16},
+			},
+		})
+	}
+
+	type envelopeV2 struct {
+		Inner evolvingV2 `ipc:"inner"`
+		After uint16     `ipc:"after"`
+	}
+
+	buf, err := Encode(envelope(evolutionV2), envelopeV2{Inner: evolvingV2{ID: 1, Score: 99}, After: 42})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res evolvingEnvelope
+
+	reader := NewReader(buf, envelope(evolutionV1))
+	err = reader.Decode(&res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Inner.ID != 1 || res.After != 42 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+type requestHeader struct {
+	RequestID uint32 `ipc:"requestId"`
+	Tenant    string `ipc:"tenant"`
+}
+
+type chargeRequest struct {
+	requestHeader
+	Amount uint64 `ipc:"amount"`
+	// shadows the tenant of the embedded header
+	Tenant []byte `ipc:"tenant"`
+}
+
+func TestEmbeddedStruct(t *testing.T) {
+	descriptor := descriptorOf(schema.SchemaMessage{
+		Direction: schema.InboundMessage,
+		Name:      "Charge",
+		Fields: []schema.MessageField{
+			{Name: "requestId", Type: schema.TypeUInt32},
+			{Name: "tenant", Type: schema.TypeDynamicBinary},
+			{Name: "amount", Type: schema.TypeUInt64},
+		},
+	})
+
+	buf, err := Encode(descriptor, chargeRequest{
+		requestHeader: requestHeader{RequestID: 9, Tenant: "ignored"},
+		Amount:        100,
+		Tenant:        []byte("acme"),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res chargeRequest
+
+	reader := NewReader(buf, descriptor)
+	err = reader.Decode(&res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.RequestID != 9 || res.Amount != 100 || string(res.Tenant) != "acme" || res.requestHeader.Tenant != "" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+type widenedItem struct {
+	Code [2]byte `ipc:"code"`
+	Qty  int     `ipc:"qty"`
+}
+
+type widenedOrder struct {
+	ID    uint64        `ipc:"id"`
+	Delta int8          `ipc:"delta"`
+	Note  string        `ipc:"note"`
+	Items []widenedItem `ipc:"items"`
+	Tags  []uint        `ipc:"tags"`
+}
+
+func TestDecodePlanKinds(t *testing.T) {
+	item := schema.SchemaMessage{
+		Name: "Item",
+		Fields: []schema.MessageField{
+			{Name: "code", Type: schema.TypeFixedBinary, Extra: 2},
+			{Name: "qty", Type: schema.TypeInt16},
+		},
+	}
+
+	descriptor := descriptorOf(schema.SchemaMessage{
+		Direction: schema.InboundMessage,
+		Name:      "Order",
+		Fields: []schema.MessageField{
+			{Name: "id", Type: schema.TypeUInt16},
+			{Name: "delta", Type: schema.TypeInt32},
+			{Name: "note", Type: schema.TypeLongBinary, Optional: true},
+			{Name: "skipped", Type: schema.TypeArray, Extra: descriptorOf(item)},
+			{Name: "items", Type: schema.TypeArray, Extra: descriptorOf(item)},
+			{Name: "tags", Type: schema.TypeArray, Extra: schema.MessageField{Type: schema.TypeUInt32}},
+		},
+	})
+
+	type source struct {
+		widenedOrder
+		Skipped []widenedItem `ipc:"skipped"`
+	}
+
+	value := source{
+		widenedOrder: widenedOrder{
+			ID:    65535,
+			Delta: -2,
+			Note:  "note",
+			Items: []widenedItem{{Code: [2]byte{'a', 'b'}, Qty: -3}, {Code: [2]byte{'c', 'd'}, Qty: 4}},
+			Tags:  []uint{1, 4294967295},
+		},
+		Skipped: []widenedItem{{Code: [2]byte{'x', 'y'}, Qty: 5}},
+	}
+
+	buf, err := Encode(descriptor, value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decoded twice, the second time with the cached plan
+	for i := 0; i < 2; i++ {
+		var res widenedOrder
+
+		reader := NewReader(buf, descriptor)
+		err = reader.Decode(&res)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(res, value.widenedOrder) {
+			t.Errorf("unexpected result: %+v", res)
+		}
+	}
+
+	// a nil pointer only skips the message
+	reader := NewReader(buf, descriptor)
+	err = reader.Decode((*widenedOrder)(nil))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodePlanKindMismatch(t *testing.T) {
+	descriptor := descriptorOf(schema.SchemaMessage{
+		Direction: schema.InboundMessage,
+		Name:      "Mismatch",
+		Fields: []schema.MessageField{
+			{Name: "id", Type: schema.TypeUInt32},
+			{Name: "name", Type: schema.TypeDynamicBinary, Optional: true},
+		},
+	})
+
+	type mismatch struct {
+		ID   uint32 `ipc:"id"`
+		Name int    `ipc:"name"`
+	}
+
+	// the mismatch only fails once the field is present
+	buf, err := Encode(descriptor, evolvingV1{ID: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res mismatch
+
+	reader := NewReader(buf, descriptor)
+	err = reader.Decode(&res)
+
+	if err != nil || res.ID != 1 {
+		t.Fatalf("unexpected result: %+v, %v", res, err)
+	}
+
+	buf, err = Encode(descriptor, evolvingV1{ID: 1, Name: "one"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader = NewReader(buf, descriptor)
+	err = reader.Decode(&res)
+
+	if !errors.Is(err, ErrFieldKindMismatch) {
+		t.Errorf("expected ErrFieldKindMismatch, got %v", err)
+	}
+}
+
+func TestDecodeModes(t *testing.T) {
+	descriptor := descriptorOf(evolutionV2)
+
+	type binaries struct {
+		Name []byte   `ipc:"name"`
+		Tags []uint16 `ipc:"tags"`
+	}
+
+	for _, mode := range []DecodeMode{DecodeZeroCopy, DecodeCopy} {
+		buf, err := Encode(descriptor, evolvingV2{ID: 1, Name: "name"})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res binaries
+
+		reader := NewReader(buf, descriptor)
+		reader.SetMode(mode)
+		err = reader.Decode(&res)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the generated code reads binaries with ReadBinary
+		reader = NewReader(buf, descriptor)
+		reader.SetMode(mode)
+		// past the extensible header, the optional flags and the id
+		reader.ReadBytes(schema.ExtensibleHeaderSize + 1 + 4)
+
+		name, err := reader.ReadBinary()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// reusing the payload only changes the decoded fields if they alias it
+		clear(buf)
+
+		aliased := mode == DecodeZeroCopy
+
+		if (string(res.Name) != "name") != aliased || (string(name) != "name") != aliased {
+			t.Errorf("mode %d: expected the fields to alias the payload: %t, got %q and %q", mode, aliased, res.Name, name)
+		}
+	}
+}
+
+func TestDecodePlanPerDescriptor(t *testing.T) {
+	current := schema.SchemaMessage{
+		Direction: schema.InboundMessage,
+		Name:      "Renamed",
+		Fields: []schema.MessageField{
+			{Name: "id", Type: schema.TypeUInt32},
+			{Name: "title", Type: schema.TypeDynamicBinary},
+		},
+	}
+
+	// the same layout, registered elsewhere with the title deprecated and known by its old name
+	previous := current
+	previous.Fields = slices.Clone(current.Fields)
+	previous.Fields[1].Deprecated = true
+	previous.Fields[1].Aliases = []string{"name"}
+
+	type renamed struct {
+		ID   uint32 `ipc:"id"`
+		Name string `ipc:"name"`
+	}
+
+	buf, err := Encode(descriptorOf(current), struct {
+		ID    uint32 `ipc:"id"`
+		Title string `ipc:"title"`
+	}{ID: 1, Title: "title"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, message := range []schema.SchemaMessage{current, previous} {
+		descriptor := descriptorOf(message)
+		descriptor.Layout = message.Layout()
+
+		var deprecated []string
+		var res renamed
+
+		reader := NewReader(buf, descriptor)
+		reader.OnDeprecated(func(field schema.MessageField) {
+			deprecated = append(deprecated, field.Name)
+		})
+
+		err = reader.Decode(&res)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// only the plan of the previous descriptor knows about the deprecation and the alias
+		known := message.Fields[1].Deprecated
+
+		if (len(deprecated) == 1) != known || (res.Name == "title") != known {
+			t.Errorf("%v: unexpected deprecated fields %v and result %+v", message.Fields[1], deprecated, res)
+		}
+	}
+}
+
+func TestDecodePlanCacheHandshakes(t *testing.T) {
+	local := schema.SchemaMessage{
+		Direction: schema.InboundMessage,
+		Name:      "Renamed",
+		Fields: []schema.MessageField{
+			{Name: "id", Type: schema.TypeUInt32},
+			{Name: "name", Type: schema.TypeDynamicBinary, Aliases: []string{"title"}},
+		},
+	}
+
+	peer := local
+	peer.Fields = slices.Clone(local.Fields)
+	peer.Fields[1] = schema.MessageField{Name: "title", Type: schema.TypeDynamicBinary}
+
+	type renamed struct {
+		ID   uint32 `ipc:"id"`
+		Name string `ipc:"name"`
+	}
+
+	buf, err := Encode(descriptorOf(local), renamed{ID: 1, Name: "name"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decode := func(message schema.SchemaMessage) renamed {
+		var res renamed
+
+		reader := NewReader(buf, descriptorOf(message))
+		err := reader.Decode(&res)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	decode(schema.MapFieldNames(local, peer))
+	plans := decodePlans.len()
+
+	// every handshake builds the peer's descriptors anew, they share no memory with the last ones
+	for i := 0; i < 100; i++ {
+		decode(schema.MapFieldNames(local, peer))
+	}
+
+	if decodePlans.len() != plans {
+		t.Errorf("expected %d cached plans after the handshakes, got %d", plans, decodePlans.len())
+	}
+
+	// a descriptor edited in place gets a plan of its own
+	message := schema.MapFieldNames(local, peer)
+
+	if res := decode(message); res.Name != "name" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	message.Fields[1].Aliases = nil
+
+	if res := decode(message); res.Name != "" {
+		t.Errorf("expected the field to be dropped without its alias, got %+v", res)
+	}
+}
+
+func TestPlanCacheBounded(t *testing.T) {
+	var cache planCache
+
+	for i := 0; i < maxPlans*2; i++ {
+		cache.store(planKey{shape: uint64(i), t: skipType}, nil)
+	}
+
+	if cache.len() > maxPlans {
+		t.Errorf("expected at most %d cached plans, got %d", maxPlans, cache.len())
+	}
+}
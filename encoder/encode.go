@@ -17,6 +17,7 @@ var ErrWrongLen = errors.New("fixed binary field: wrong length")
 var ErrLenTooBig16 = errors.New("binary field: length too long (must not be more than 65,535 bytes)")
 var ErrLenTooBig32 = errors.New("binary field: length too long (must not be more than 4.29 GB)")
 var ErrArrLenTooBig = errors.New("array field: length too long (must not be more than 65,535 elements)")
+var ErrTooManyOptional = errors.New("extensible message: too many optional fields (must not be more than 2,040)")
 
 type Writer struct {
 	buffer []byte
@@ -40,309 +41,248 @@ func (w *Writer) GrowBytes(n uint32) (uint32, error) {
 	return currPos, nil
 }
 
-func Encode(descriptor schema.MessageDescriptor, res any) (bytes []byte, err error) {
-	writer := Writer{
-		buffer: make([]byte, 0, descriptor.GetFixedSize()),
+// valuePointer returns the type of the struct v holds or points to and a pointer to it, values that aren't
+// pointers are copied since they can't be addressed
+func valuePointer(v any) (reflect.Type, unsafe.Pointer, error) {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil, ErrInvalidResultPointer
+		}
+
+		rv = rv.Elem()
 	}
 
-	v := reflect.ValueOf(res)
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, ErrInvalidResultPointer
+	}
 
-	if v.Kind() != reflect.Struct {
-		return nil, ErrInvalidResultPointer
+	if !rv.CanAddr() {
+		addressable := reflect.New(rv.Type()).Elem()
+		addressable.Set(rv)
+		rv = addressable
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			log.Println("Panic Occured in Encode", r)
+	return rv.Type(), rv.Addr().UnsafePointer(), nil
+}
 
-			str, ok := r.(string)
+// Size returns the exact number of bytes Encode produces for v, v is a struct or a pointer to one
+func Size(descriptor schema.MessageDescriptor, v any) (int, error) {
+	t, p, err := valuePointer(v)
 
-			bytes = nil
+	if err != nil {
+		return 0, err
+	}
 
-			if ok {
-				err = fmt.Errorf("panic: %s", str)
-			} else {
-				err = fmt.Errorf("panic: a panic occured")
-			}
-		}
-	}()
+	plan, err := loadEncodePlan(&descriptor, t)
+
+	if err != nil {
+		return 0, err
+	}
 
-	err = writer.encodeStruct(descriptor, v)
+	size, err := plan.size(p)
+
+	return int(size), err
+}
+
+// Encode encodes v, a struct or a pointer to one, the payload is allocated once with its exact size.
+// Passing a pointer saves copying the value.
+func Encode(descriptor schema.MessageDescriptor, res any) ([]byte, error) {
+	buf, err := AppendEncode(nil, descriptor, res)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return slices.Clip(writer.buffer), nil
+	return slices.Clip(buf), nil
 }
 
-func (w *Writer) SetOpt(opt uint32, offset uint32) {
-	// same as rawBitPos % 8 but optimized
-	bitPos := opt & 7
+// AppendEncode appends the encoding of v to dst and returns the extended buffer. If dst lacks the capacity
+// it is grown once by exactly the size of the message, so encoding into a reused buffer doesn't allocate.
+// On error dst is returned as it was, though bytes past its length may have been written.
+func AppendEncode(dst []byte, descriptor schema.MessageDescriptor, res any) (bytes []byte, err error) {
+	if m, ok := res.(Marshaler); ok && useGenerated(m.IPCLayout(), descriptor) {
+		var size int
 
-	// same as rawBitPos / 8 but optimized
-	bytePos := offset + (opt >> 3)
+		if sizer, ok := res.(Sizer); ok {
+			size = sizer.SizeIPC()
+		} else {
+			size, err = Size(descriptor, res)
 
-	bitMask := byte(1 << bitPos)
+			if err != nil {
+				return dst, err
+			}
+		}
 
-	w.buffer[bytePos] |= bitMask
-}
+		writer := Writer{
+			buffer: grow(dst, size),
+		}
 
-func (w *Writer) encodeStruct(descriptor schema.MessageDescriptor, v reflect.Value) error {
-	if v.Kind() != reflect.Struct {
-		return ErrInvalidResultPointer
-	}
+		err = m.MarshalIPC(&writer)
 
-	t := v.Type()
-	fMap, err := computeFieldMap(t)
+		if err != nil {
+			return dst, err
+		}
 
-	if err != nil {
-		return err
+		return writer.buffer, nil
 	}
 
-	// Start Decoding
-
-	optBytes := descriptor.OptFlagLength()
-
-	optListOffset, err := w.GrowBytes(optBytes)
+	t, p, err := valuePointer(res)
 
 	if err != nil {
-		return err
+		return dst, err
 	}
 
-	var optCounter uint32 = 0
-
-	for _, field := range descriptor.Message.Fields {
-		fIdx, exists := fMap[field.Name]
-		f := reflect.Value{}
+	plan, err := loadEncodePlan(&descriptor, t)
 
-		if exists {
-			f = v.Field(fIdx)
+	if err != nil {
+		return dst, err
+	}
 
-			// Re-use "exists" variable for optional check
-			exists = !f.IsZero()
-		}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Panic Occured in Encode", r)
 
-		if field.Optional {
-			if optCounter >= optBytes {
-				return ErrOptionalCorrupted
-			}
+			str, ok := r.(string)
 
-			opt := optCounter
-			optCounter++
+			bytes = dst
 
-			if exists {
-				w.SetOpt(opt, optListOffset)
+			if ok {
+				err = fmt.Errorf("panic: %s", str)
 			} else {
-				continue
+				err = fmt.Errorf("panic: a panic occured")
 			}
 		}
+	}()
 
-		err := w.encodeSingle(field, f)
+	size, err := plan.size(p)
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return dst, err
 	}
 
-	return nil
-}
-
-func getBytes(v reflect.Value) ([]byte, error) {
-	addr := unsafe.Pointer(v.UnsafeAddr())
-	kind := v.Kind()
+	buf, err := appendMessage(grow(dst, int(size)), plan, p)
 
-	switch kind {
-	case reflect.Array:
-		{
-			arrLen := v.Len()
-
-			return ([]byte)(unsafe.Slice((*byte)(addr), arrLen)), nil
-		}
-	case reflect.Slice:
-		{
-			// check that elem is byte too
-			return *(*[]byte)(addr), nil
-		}
-	case reflect.String:
-		{
-			str := *(*string)(addr)
-			return []byte(str), nil
-		}
-	default:
-		{
-			return nil, ErrInvalidByteKind
-		}
+	if err != nil {
+		return dst, err
 	}
+
+	return buf, nil
 }
 
-func (w *Writer) encodeSingle(field schema.MessageField, f reflect.Value) error {
-	// Future Note: use a default value instead of failing
-	if !f.IsValid() {
-		return ErrRequiredNotPresent
+// grow returns dst with room for n more bytes, allocating exactly that much if it lacks the capacity
+func grow(dst []byte, n int) []byte {
+	if cap(dst)-len(dst) >= n {
+		return dst
 	}
 
-	switch field.Type {
-	case schema.TypeFixedBinary:
-		{
-			expectedLen := field.Extra.(int)
-			bytes, err := getBytes(f)
+	buf := make([]byte, len(dst), len(dst)+n)
+	copy(buf, dst)
 
-			if err != nil {
-				return err
-			}
+	return buf
+}
 
-			if len(bytes) != expectedLen {
-				return ErrWrongLen
-			}
+// Encode appends the encoding of v to the writer, on error the writer is left as it was
+func (w *Writer) Encode(descriptor schema.MessageDescriptor, v any) error {
+	buf, err := AppendEncode(w.buffer, descriptor, v)
 
-			w.buffer = append(w.buffer, bytes...)
+	if err != nil {
+		return err
+	}
 
-			break
-		}
-	case schema.TypeDynamicBinary:
-		{
-			bytes, err := getBytes(f)
+	w.buffer = buf
 
-			if err != nil {
-				return err
-			}
+	return nil
+}
 
-			byteLen := len(bytes)
+// Write appends p to the writer, so compressed payloads can be written into it
+func (w *Writer) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
 
-			if byteLen > 65535 {
-				return ErrLenTooBig16
-			}
+	return len(p), nil
+}
 
-			w.buffer = binary.LittleEndian.AppendUint16(w.buffer, uint16(byteLen))
-			w.buffer = append(w.buffer, bytes...)
+// Bytes returns what was written since the last Reset, it is only valid until the writer is written to again
+func (w *Writer) Bytes() []byte {
+	return w.buffer
+}
 
-			break
-		}
-	case schema.TypeLongBinary:
-		{
-			bytes, err := getBytes(f)
+func (w *Writer) Len() int {
+	return len(w.buffer)
+}
 
-			if err != nil {
-				return err
-			}
+// Reset empties the writer but keeps its buffer, so a pooled writer encodes without allocating
+func (w *Writer) Reset() {
+	w.buffer = w.buffer[:0]
+}
 
-			byteLen := len(bytes)
+func (w *Writer) SetOpt(opt uint32, offset uint32) {
+	// same as rawBitPos % 8 but optimized
+	bitPos := opt & 7
 
-			if byteLen > 4294967295 {
-				return ErrLenTooBig32
-			}
+	// same as rawBitPos / 8 but optimized
+	bytePos := offset + (opt >> 3)
 
-			w.buffer = binary.LittleEndian.AppendUint32(w.buffer, uint32(byteLen))
-			w.buffer = append(w.buffer, bytes...)
+	bitMask := byte(1 << bitPos)
 
-			break
-		}
-	case schema.TypeUInt64:
-		{
-			num := f.Uint()
-			w.buffer = binary.LittleEndian.AppendUint64(w.buffer, num)
+	w.buffer[bytePos] |= bitMask
+}
 
-			break
-		}
-	case schema.TypeInt64:
-		{
-			num := f.Int()
-			w.buffer = binary.LittleEndian.AppendUint64(w.buffer, uint64(num))
+// appendMessage appends the message or object stored at p to buf
+func appendMessage(buf []byte, plan *encodePlan, p unsafe.Pointer) ([]byte, error) {
+	optBytes := plan.optBytes
+	headerOffset := len(buf)
 
-			break
+	if plan.extensible {
+		if optBytes > 255 {
+			return buf, ErrTooManyOptional
 		}
-	case schema.TypeUInt32:
-		{
-			num := uint32(f.Uint())
-			w.buffer = binary.LittleEndian.AppendUint32(w.buffer, num)
 
-			break
-		}
-	case schema.TypeInt32:
-		{
-			num := int32(f.Int())
-			w.buffer = binary.LittleEndian.AppendUint32(w.buffer, uint32(num))
+		buf = append(buf, 0, 0, 0, 0, byte(optBytes))
+	}
 
-			break
-		}
-	case schema.TypeUInt16:
-		{
-			num := uint16(f.Uint())
-			w.buffer = binary.LittleEndian.AppendUint16(w.buffer, num)
+	optListOffset := len(buf)
+	buf = append(buf, make([]byte, optBytes)...)
 
-			break
-		}
-	case schema.TypeInt16:
-		{
-			num := int16(f.Int())
-			w.buffer = binary.LittleEndian.AppendUint16(w.buffer, uint16(num))
+	var optCounter uint32 = 0
+	var err error
 
-			break
-		}
-	case schema.TypeObject:
-		{
+	for i := range plan.fields {
+		fe := &plan.fields[i]
 
-			subFields := field.Extra.(schema.MessageDescriptor)
+		if fe.optional {
+			if optCounter >= plan.optionalCount {
+				return buf, ErrOptionalCorrupted
+			}
 
-			err := w.encodeStruct(subFields, f)
+			opt := optCounter
+			optCounter++
 
-			if err != nil {
-				return err
+			if !fe.mapped || fe.isZero(unsafe.Add(p, fe.offset)) {
+				continue
 			}
 
-			break
+			buf[optListOffset+int(opt>>3)] |= 1 << (opt & 7)
 		}
-	case schema.TypeArray:
-		{
-			arrLen := f.Len()
-
-			if arrLen > 65535 {
-				return ErrArrLenTooBig
-			}
 
-			w.buffer = binary.LittleEndian.AppendUint16(w.buffer, uint16(arrLen))
+		if fe.err != nil {
+			return buf, fe.err
+		}
 
-			switch e := field.Extra.(type) {
-			case schema.MessageField: {
-				for i := 0; i < arrLen; i++ {
-					item := f.Index(i)
+		buf, err = fe.encode(buf, unsafe.Add(p, fe.offset))
 
-					err := w.encodeSingle(e, item)
+		if err != nil {
+			return buf, err
+		}
+	}
 
-					if err != nil {
-						return err
-					}
-				}
-			}
-			case schema.SchemaMessage: {
-				desc := schema.MessageDescriptor{
-					ID:            0,
-					Message:       e,
-					OptionalCount: e.CountOptional(),
-					Internal:      false,
-					Handler:       nil,
-				}
-
-				for i := 0; i < arrLen; i++ {
-					item := f.Index(i)
-
-					err := w.encodeStruct(desc, item)
-
-					if err != nil {
-						return err
-					}
-				}
-			}
-			}
+	if plan.extensible {
+		// length of everything after the length prefix itself
+		bodyLen := uint32(len(buf) - headerOffset - 4)
 
-			break
-		}
-	default:
-		{
-			return ErrTypeCorrupted
-		}
+		binary.LittleEndian.PutUint32(buf[headerOffset:], bodyLen)
 	}
 
-	return nil
-}
\ No newline at end of file
+	return buf, nil
+}
@@ -0,0 +1,292 @@
+package encoder
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestSizeMatchesEncode(t *testing.T) {
+	tests := map[string]struct {
+		descriptor schema.MessageDescriptor
+		value      any
+	}{
+		"sample":            {sampleDesc, &sampleStruct{Fixed: expectedBin, Dynamic: []byte("dynamic"), Array: []int16{1, 2, 3}}},
+		"extensible":        {descriptorOf(evolutionV2), evolvingV2{ID: 7, Name: "seven", Tags: []uint16{1, 2}, Score: -3}},
+		"extensible absent": {descriptorOf(evolutionV2), evolvingV2{ID: 7}},
+		"embedded":          {descriptorOf(evolutionV1), struct{ evolvingV1 }{evolvingV1{ID: 1, Name: "one"}}},
+	}
+
+	for name, test := range tests {
+		size, err := Size(test.descriptor, test.value)
+
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		buf, err := Encode(test.descriptor, test.value)
+
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if size != len(buf) || cap(buf) != len(buf) {
+			t.Errorf("%s: Size returned %d, encoded %d bytes with a capacity of %d", name, size, len(buf), cap(buf))
+		}
+	}
+}
+
+func TestSizeErrors(t *testing.T) {
+	_, err := Size(sampleDesc, &evolvingV1{})
+
+	if !errors.Is(err, ErrRequiredNotPresent) {
+		t.Errorf("expected ErrRequiredNotPresent, got %v", err)
+	}
+
+	_, err = Size(sampleDesc, 1)
+
+	if !errors.Is(err, ErrInvalidResultPointer) {
+		t.Errorf("expected ErrInvalidResultPointer, got %v", err)
+	}
+}
+
+func TestEncodeAllocatesOnce(t *testing.T) {
+	value := &evolvingV2{ID: 7, Name: "seven", Tags: []uint16{1, 2}, Score: -3}
+	descriptor := descriptorOf(evolutionV2)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, err := Encode(descriptor, value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if allocs != 1 {
+		t.Errorf("expected 1 allocation, got %v", allocs)
+	}
+}
+
+func TestAppendEncode(t *testing.T) {
+	value := &evolvingV2{ID: 7, Name: "seven", Tags: []uint16{1, 2}, Score: -3}
+	descriptor := descriptorOf(evolutionV2)
+
+	expected, err := Encode(descriptor, value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := AppendEncode([]byte("prefix"), descriptor, value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != "prefix"+string(expected) {
+		t.Errorf("expected the payload after the prefix, got %x", buf)
+	}
+
+	// a buffer with enough capacity is reused
+	dst := make([]byte, 0, 256)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, err := AppendEncode(dst, descriptor, value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+
+	// failing leaves dst as it was
+	res, err := AppendEncode(buf[:6], sampleDesc, &evolvingV1{})
+
+	if !errors.Is(err, ErrRequiredNotPresent) || string(res) != "prefix" {
+		t.Errorf("expected ErrRequiredNotPresent and the prefix, got %v %q", err, res)
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	descriptor := descriptorOf(evolutionV1)
+
+	var w Writer
+
+	for _, value := range []evolvingV1{{ID: 1, Name: "first"}, {ID: 2}} {
+		w.Reset()
+
+		err := w.Encode(descriptor, &value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := Encode(descriptor, value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(w.Bytes()) != string(expected) {
+			t.Errorf("expected %x, got %x", expected, w.Bytes())
+		}
+	}
+
+	err := w.Encode(sampleDesc, &evolvingV1{})
+
+	if err == nil || w.Len() == 0 {
+		t.Errorf("expected an error leaving the writer as it was, got %v with %d bytes", err, w.Len())
+	}
+}
+
+var flagsMessage = schema.SchemaMessage{
+	Direction: schema.InboundMessage,
+	Name:      "flags",
+	Fields: []schema.MessageField{
+		{Name: "a", Type: schema.TypeUInt16, Optional: true},
+		{Name: "b", Type: schema.TypeUInt16, Optional: true},
+		{Name: "c", Type: schema.TypeUInt16, Optional: true},
+	},
+}
+
+type flags struct {
+	A uint16 `ipc:"a"`
+	B uint16 `ipc:"b"`
+	C uint16 `ipc:"c"`
+}
+
+// the optional fields are counted against OptionalCount, not against the bytes of flags they take
+func TestEncodeOptionalCount(t *testing.T) {
+	value := flags{A: 1, B: 2, C: 3}
+
+	buf, err := Encode(descriptorOf(flagsMessage), value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(buf, []byte{0x07, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00}) {
+		t.Errorf("unexpected payload: %x", buf)
+	}
+
+	var decoded flags
+
+	reader := NewReader(buf, descriptorOf(flagsMessage))
+	err = reader.Decode(&decoded)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != value {
+		t.Errorf("expected %+v, got %+v", value, decoded)
+	}
+
+	// a descriptor that counts fewer optional fields than the message has is rejected
+	corrupted := descriptorOf(flagsMessage)
+	corrupted.OptionalCount = 2
+
+	_, err = Encode(corrupted, value)
+
+	if !errors.Is(err, ErrOptionalCorrupted) {
+		t.Errorf("expected ErrOptionalCorrupted from Encode, got %v", err)
+	}
+
+	reader = NewReader(buf, corrupted)
+	err = reader.Decode(&decoded)
+
+	if !errors.Is(err, ErrOptionalCorrupted) {
+		t.Errorf("expected ErrOptionalCorrupted from Decode, got %v", err)
+	}
+}
+
+// Encode takes a struct, a pointer to one, or an unaddressable struct value such as one stored in an interface
+func TestEncodeValues(t *testing.T) {
+	value := evolvingV2{ID: 7, Name: "seven", Tags: []uint16{1, 2}, Score: -3}
+	descriptor := descriptorOf(evolutionV2)
+
+	expected, err := Encode(descriptor, &value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, v := range map[string]any{"value": value, "interface": any(value)} {
+		buf, err := Encode(descriptor, v)
+
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if !slices.Equal(buf, expected) {
+			t.Errorf("%s: expected %x, got %x", name, expected, buf)
+		}
+	}
+
+	var nilValue *evolvingV2
+
+	for name, v := range map[string]any{"int": 1, "pointer to int": new(int), "nil pointer": nilValue} {
+		_, err := Encode(descriptor, v)
+
+		if !errors.Is(err, ErrInvalidResultPointer) {
+			t.Errorf("%s: expected ErrInvalidResultPointer, got %v", name, err)
+		}
+	}
+}
+
+// the registry resolves objects to descriptors, arrays of them encode like arrays of plain objects
+func TestEncodeArrayOfDescriptors(t *testing.T) {
+	withMessage := schema.SchemaMessage{
+		Direction: schema.InboundMessage,
+		Name:      "batch",
+		Fields: []schema.MessageField{
+			{Name: "items", Type: schema.TypeArray, Extra: flagsMessage},
+		},
+	}
+
+	withDescriptor := withMessage
+	withDescriptor.Fields = []schema.MessageField{
+		{Name: "items", Type: schema.TypeArray, Extra: descriptorOf(flagsMessage)},
+	}
+
+	type batch struct {
+		Items []flags `ipc:"items"`
+	}
+
+	value := batch{Items: []flags{{A: 1, B: 2, C: 3}, {A: 4, B: 5, C: 6}}}
+
+	expected, err := Encode(descriptorOf(withMessage), value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := Encode(descriptorOf(withDescriptor), value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(buf, expected) {
+		t.Errorf("expected %x, got %x", expected, buf)
+	}
+
+	var decoded batch
+
+	reader := NewReader(buf, descriptorOf(withDescriptor))
+	err = reader.Decode(&decoded)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("expected %+v, got %+v", value, decoded)
+	}
+}
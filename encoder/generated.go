@@ -0,0 +1,314 @@
+package encoder
+
+import (
+	"encoding/binary"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// Marshaler is implemented by messages that encode themselves without reflection, usually generated by
+// schemagen. Encode only uses MarshalIPC when IPCLayout matches the Layout of the descriptor.
+type Marshaler interface {
+	IPCLayout() uint64
+	MarshalIPC(w *Writer) error
+}
+
+// Sizer is implemented by generated messages that compute their encoded size without reflection, Encode and
+// AppendEncode use it to allocate the payload of a Marshaler once
+type Sizer interface {
+	SizeIPC() int
+}
+
+// Unmarshaler is the decoding counterpart of Marshaler, Decode only uses UnmarshalIPC when IPCLayout matches
+// the Layout of the descriptor
+type Unmarshaler interface {
+	IPCLayout() uint64
+	UnmarshalIPC(r *Reader) error
+}
+
+func useGenerated(layout uint64, descriptor schema.MessageDescriptor) bool {
+	return descriptor.Layout != 0 && layout == descriptor.Layout
+}
+
+// MessageStart is where a message or object begins in the buffer of a Writer
+type MessageStart struct {
+	header     uint32
+	optList    uint32
+	extensible bool
+}
+
+// BeginMessage writes the header of a message or object and its zeroed optional flags
+func (w *Writer) BeginMessage(optionalCount uint32, extensible bool) (MessageStart, error) {
+	start := MessageStart{
+		extensible: extensible,
+	}
+
+	optBytes := schema.MessageDescriptor{OptionalCount: optionalCount}.OptFlagLength()
+
+	var err error
+
+	if extensible {
+		if optBytes > 255 {
+			return start, ErrTooManyOptional
+		}
+
+		start.header, err = w.GrowBytes(schema.ExtensibleHeaderSize)
+
+		if err != nil {
+			return start, err
+		}
+
+		w.buffer[start.header+4] = byte(optBytes)
+	}
+
+	start.optList, err = w.GrowBytes(optBytes)
+
+	return start, err
+}
+
+// SetOptional marks the nth optional field of the message as present
+func (w *Writer) SetOptional(start MessageStart, n uint32) {
+	w.SetOpt(n, start.optList)
+}
+
+// EndMessage writes the length of an extensible message once its fields are written
+func (w *Writer) EndMessage(start MessageStart) {
+	if start.extensible {
+		bodyLen := uint32(len(w.buffer)) - start.header - 4
+
+		binary.LittleEndian.PutUint32(w.buffer[start.header:], bodyLen)
+	}
+}
+
+func (w *Writer) WriteUInt64(num uint64) {
+	w.buffer = binary.LittleEndian.AppendUint64(w.buffer, num)
+}
+
+func (w *Writer) WriteInt64(num int64) {
+	w.buffer = binary.LittleEndian.AppendUint64(w.buffer, uint64(num))
+}
+
+func (w *Writer) WriteUInt32(num uint32) {
+	w.buffer = binary.LittleEndian.AppendUint32(w.buffer, num)
+}
+
+func (w *Writer) WriteInt32(num int32) {
+	w.buffer = binary.LittleEndian.AppendUint32(w.buffer, uint32(num))
+}
+
+func (w *Writer) WriteUInt16(num uint16) {
+	w.buffer = binary.LittleEndian.AppendUint16(w.buffer, num)
+}
+
+func (w *Writer) WriteInt16(num int16) {
+	w.buffer = binary.LittleEndian.AppendUint16(w.buffer, uint16(num))
+}
+
+// WriteFixedBinary writes a binary(n) field
+func (w *Writer) WriteFixedBinary(bytes []byte, n int) (err error) {
+	w.buffer, err = appendFixedBinary(w.buffer, bytes, n)
+	return err
+}
+
+// WriteBinary writes a binary field, prefixed by its length as a uint16
+func (w *Writer) WriteBinary(bytes []byte) (err error) {
+	w.buffer, err = appendBinary(w.buffer, bytes)
+	return err
+}
+
+// WriteLongBinary writes a long_binary field, prefixed by its length as a uint32
+func (w *Writer) WriteLongBinary(bytes []byte) (err error) {
+	w.buffer, err = appendLongBinary(w.buffer, bytes)
+	return err
+}
+
+// WriteArrayLen writes the element count of an array field, the elements follow it
+func (w *Writer) WriteArrayLen(n int) (err error) {
+	w.buffer, err = appendArrayLen(w.buffer, n)
+	return err
+}
+
+func appendFixedBinary(buf []byte, bytes []byte, n int) ([]byte, error) {
+	if len(bytes) != n {
+		return buf, ErrWrongLen
+	}
+
+	return append(buf, bytes...), nil
+}
+
+func appendBinary(buf []byte, bytes []byte) ([]byte, error) {
+	if len(bytes) > 65535 {
+		return buf, ErrLenTooBig16
+	}
+
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(bytes)))
+
+	return append(buf, bytes...), nil
+}
+
+func appendLongBinary(buf []byte, bytes []byte) ([]byte, error) {
+	if len(bytes) > 4294967295 {
+		return buf, ErrLenTooBig32
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(bytes)))
+
+	return append(buf, bytes...), nil
+}
+
+func appendArrayLen(buf []byte, n int) ([]byte, error) {
+	if n > 65535 {
+		return buf, ErrArrLenTooBig
+	}
+
+	return binary.LittleEndian.AppendUint16(buf, uint16(n)), nil
+}
+
+// MessageFrame tracks a message or object while its fields are read by generated code
+type MessageFrame struct {
+	optList    []byte
+	optBytes   uint32
+	opt        uint32
+	extensible bool
+	end        uint32
+	outerLen   uint32
+}
+
+// BeginMessage reads the header and optional flags of a message or object
+func (r *Reader) BeginMessage(optionalCount uint32, extensible bool) (MessageFrame, error) {
+	frame := MessageFrame{
+		optBytes:   schema.MessageDescriptor{OptionalCount: optionalCount}.OptFlagLength(),
+		extensible: extensible,
+	}
+
+	if extensible {
+		bodyLen, err := r.ReadUInt32()
+
+		if err != nil {
+			return frame, err
+		}
+
+		if bodyLen > (r.len - r.pos) {
+			return frame, ErrOutOfBounds
+		}
+
+		frame.end = r.pos + bodyLen
+		frame.outerLen = r.len
+		r.len = frame.end
+
+		flagLen, err := r.ReadBytes(1)
+
+		if err != nil {
+			return frame, err
+		}
+
+		frame.optBytes = uint32(flagLen[0])
+	}
+
+	optList, err := r.ReadBytes(frame.optBytes)
+
+	if err != nil {
+		return frame, err
+	}
+
+	frame.optList = optList
+
+	return frame, nil
+}
+
+// Present reports whether the next optional field of the message was sent
+func (f *MessageFrame) Present() bool {
+	opt := f.opt
+	f.opt++
+
+	return opt < f.optBytes*8 && GetOpt(opt, f.optList)
+}
+
+// CheckRequired fails if an extensible message ends before a required field, it was sent by an older peer
+func (r *Reader) CheckRequired(f *MessageFrame) error {
+	if f.extensible && r.pos == f.end {
+		return ErrMissingRequired
+	}
+
+	return nil
+}
+
+// EndMessage skips the trailing fields of an extensible message sent by a peer with a newer schema
+func (r *Reader) EndMessage(f MessageFrame) {
+	if f.extensible {
+		r.pos = f.end
+		r.len = f.outerLen
+	}
+}
+
+// ReadFixedBinary reads a binary(len(dst)) field into dst
+func (r *Reader) ReadFixedBinary(dst []byte) error {
+	bytes, err := r.ReadBytes(uint32(len(dst)))
+
+	if err != nil {
+		return err
+	}
+
+	copy(dst, bytes)
+
+	return nil
+}
+
+// ReadBinary reads a binary field, the result points into the buffer of the reader unless it is in
+// DecodeCopy mode
+func (r *Reader) ReadBinary() ([]byte, error) {
+	bytes, err := r.readBinary()
+
+	return r.own(bytes), err
+}
+
+// ReadLongBinary reads a long_binary field, the result points into the buffer of the reader unless it is in
+// DecodeCopy mode
+func (r *Reader) ReadLongBinary() ([]byte, error) {
+	bytes, err := r.readLongBinary()
+
+	return r.own(bytes), err
+}
+
+func (r *Reader) readBinary() ([]byte, error) {
+	len, err := r.ReadUInt16()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.ReadBytes(uint32(len))
+}
+
+func (r *Reader) readLongBinary() ([]byte, error) {
+	len, err := r.ReadUInt32()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.ReadBytes(len)
+}
+
+// own copies bytes read from the buffer if the reader is in DecodeCopy mode
+func (r *Reader) own(bytes []byte) []byte {
+	if r.mode != DecodeCopy || bytes == nil {
+		return bytes
+	}
+
+	return append([]byte{}, bytes...)
+}
+
+// ReadArrayLen reads the element count of an array field
+func (r *Reader) ReadArrayLen() (int, error) {
+	len, err := r.ReadUInt16()
+
+	return int(len), err
+}
+
+// Deprecated reports a deprecated field that was present to the OnDeprecated callback
+func (r *Reader) Deprecated(name string) {
+	if r.onDeprecated != nil {
+		r.onDeprecated(schema.MessageField{Name: name, Deprecated: true})
+	}
+}
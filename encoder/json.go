@@ -0,0 +1,517 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// The JSON form of a payload is an object with a property per field, in schema order. Absent optional
+// fields are left out, binary fields are base64 strings, 64-bit integers are decimal strings so they survive
+// JavaScript, and all other integers are numbers (strings are accepted for them too).
+
+var ErrUnknownJSONField = errors.New("unknown field in JSON payload")
+var ErrInvalidJSONValue = errors.New("invalid JSON value for field")
+
+// nestedDescriptor returns the descriptor of an object field or an array of objects
+func nestedDescriptor(extra any) schema.MessageDescriptor {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e
+	case schema.SchemaMessage:
+		return schema.MessageDescriptor{
+			Message:       e,
+			OptionalCount: e.CountOptional(),
+		}
+	default:
+		panic("field extra is not an object")
+	}
+}
+
+// ToJSON transcodes a payload encoded with descriptor into its JSON form
+func ToJSON(descriptor schema.MessageDescriptor, payload []byte) ([]byte, error) {
+	r := NewReader(payload, descriptor)
+
+	var buf bytes.Buffer
+
+	err := r.objectToJSON(&buf, descriptor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *Reader) objectToJSON(buf *bytes.Buffer, descriptor schema.MessageDescriptor) error {
+	optBytes := descriptor.OptFlagLength()
+	extensible := descriptor.Message.Extensible
+
+	var end, outerLen uint32
+
+	if extensible {
+		bodyLen, err := r.ReadUInt32()
+
+		if err != nil {
+			return err
+		}
+
+		if bodyLen > (r.len - r.pos) {
+			return ErrOutOfBounds
+		}
+
+		end = r.pos + bodyLen
+		outerLen = r.len
+		r.len = end
+
+		flagLen, err := r.ReadBytes(1)
+
+		if err != nil {
+			return err
+		}
+
+		optBytes = uint32(flagLen[0])
+	}
+
+	optList, err := r.ReadBytes(optBytes)
+
+	if err != nil {
+		return err
+	}
+
+	var optCounter uint32 = 0
+	first := true
+
+	buf.WriteByte('{')
+
+	for _, field := range descriptor.Message.Fields {
+		if field.Optional {
+			opt := optCounter
+			optCounter++
+
+			if opt >= optBytes*8 || !GetOpt(opt, optList) {
+				continue
+			}
+		} else if extensible && r.pos == end {
+			return ErrMissingRequired
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+
+		first = false
+
+		name, _ := json.Marshal(field.Name)
+		buf.Write(name)
+		buf.WriteByte(':')
+
+		err := r.valueToJSON(buf, field)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	if extensible {
+		r.pos = end
+		r.len = outerLen
+	}
+
+	return nil
+}
+
+func (r *Reader) valueToJSON(buf *bytes.Buffer, field schema.MessageField) error {
+	switch field.Type {
+	case schema.TypeFixedBinary, schema.TypeDynamicBinary, schema.TypeLongBinary:
+		{
+			var n uint32
+
+			switch field.Type {
+			case schema.TypeFixedBinary:
+				n = uint32(field.Extra.(int))
+			case schema.TypeDynamicBinary:
+				{
+					len, err := r.ReadUInt16()
+
+					if err != nil {
+						return err
+					}
+
+					n = uint32(len)
+				}
+			default:
+				{
+					len, err := r.ReadUInt32()
+
+					if err != nil {
+						return err
+					}
+
+					n = len
+				}
+			}
+
+			b, err := r.ReadBytes(n)
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteByte('"')
+			buf.WriteString(base64.StdEncoding.EncodeToString(b))
+			buf.WriteByte('"')
+		}
+	case schema.TypeUInt64:
+		{
+			num, err := r.ReadUInt64()
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(strconv.Quote(strconv.FormatUint(num, 10)))
+		}
+	case schema.TypeInt64:
+		{
+			num, err := r.ReadInt64()
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(strconv.Quote(strconv.FormatInt(num, 10)))
+		}
+	case schema.TypeUInt32:
+		{
+			num, err := r.ReadUInt32()
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(strconv.FormatUint(uint64(num), 10))
+		}
+	case schema.TypeInt32:
+		{
+			num, err := r.ReadInt32()
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(strconv.FormatInt(int64(num), 10))
+		}
+	case schema.TypeUInt16:
+		{
+			num, err := r.ReadUInt16()
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(strconv.FormatUint(uint64(num), 10))
+		}
+	case schema.TypeInt16:
+		{
+			num, err := r.ReadInt16()
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(strconv.FormatInt(int64(num), 10))
+		}
+	case schema.TypeObject:
+		return r.objectToJSON(buf, nestedDescriptor(field.Extra))
+	case schema.TypeArray:
+		{
+			arrLen, err := r.ReadUInt16()
+
+			if err != nil {
+				return err
+			}
+
+			buf.WriteByte('[')
+
+			for i := 0; i < int(arrLen); i++ {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+
+				if elem, ok := field.Extra.(schema.MessageField); ok {
+					err = r.valueToJSON(buf, elem)
+				} else {
+					err = r.objectToJSON(buf, nestedDescriptor(field.Extra))
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+
+			buf.WriteByte(']')
+		}
+	default:
+		return ErrTypeCorrupted
+	}
+
+	return nil
+}
+
+// FromJSON encodes the JSON form of a message with descriptor
+func FromJSON(descriptor schema.MessageDescriptor, data []byte) ([]byte, error) {
+	w := Writer{
+		buffer: make([]byte, 0, descriptor.GetFixedSize()),
+	}
+
+	err := w.objectFromJSON(descriptor, json.RawMessage(data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return w.buffer, nil
+}
+
+func unmarshalJSON(data json.RawMessage, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	return d.Decode(v)
+}
+
+func (w *Writer) objectFromJSON(descriptor schema.MessageDescriptor, data json.RawMessage) error {
+	var values map[string]json.RawMessage
+
+	err := unmarshalJSON(data, &values)
+
+	if err != nil || values == nil {
+		return fmt.Errorf("%w %s: expected an object", ErrInvalidJSONValue, descriptor.Message.Name)
+	}
+
+	for name := range values {
+		if !containsField(descriptor.Message.Fields, name) {
+			return fmt.Errorf("%w: %s", ErrUnknownJSONField, name)
+		}
+	}
+
+	optBytes := descriptor.OptFlagLength()
+
+	var headerOffset uint32
+
+	if descriptor.Message.Extensible {
+		if optBytes > 255 {
+			return ErrTooManyOptional
+		}
+
+		headerOffset, err = w.GrowBytes(schema.ExtensibleHeaderSize)
+
+		if err != nil {
+			return err
+		}
+
+		w.buffer[headerOffset+4] = byte(optBytes)
+	}
+
+	optListOffset, err := w.GrowBytes(optBytes)
+
+	if err != nil {
+		return err
+	}
+
+	var optCounter uint32 = 0
+
+	for _, field := range descriptor.Message.Fields {
+		value, exists := values[field.Name]
+
+		if exists && string(value) == "null" {
+			exists = false
+		}
+
+		if field.Optional {
+			opt := optCounter
+			optCounter++
+
+			if !exists {
+				continue
+			}
+
+			w.SetOpt(opt, optListOffset)
+		} else if !exists {
+			return fmt.Errorf("%w: %s", ErrRequiredNotPresent, field.Name)
+		}
+
+		err := w.valueFromJSON(field, value)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if descriptor.Message.Extensible {
+		bodyLen := uint32(len(w.buffer)) - headerOffset - 4
+
+		binary.LittleEndian.PutUint32(w.buffer[headerOffset:], bodyLen)
+	}
+
+	return nil
+}
+
+func containsField(fields []schema.MessageField, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jsonInt parses a JSON number or a string holding a number
+func jsonInt(field schema.MessageField, value json.RawMessage, signed bool, bitSize int) (uint64, error) {
+	var text string
+
+	if len(value) > 0 && value[0] == '"' {
+		err := json.Unmarshal(value, &text)
+
+		if err != nil {
+			return 0, fmt.Errorf("%w %s: %v", ErrInvalidJSONValue, field.Name, err)
+		}
+	} else {
+		text = string(value)
+	}
+
+	if signed {
+		num, err := strconv.ParseInt(text, 10, bitSize)
+
+		if err != nil {
+			return 0, fmt.Errorf("%w %s: %q is not an %s", ErrInvalidJSONValue, field.Name, text, field.Type.ToString())
+		}
+
+		return uint64(num), nil
+	}
+
+	num, err := strconv.ParseUint(text, 10, bitSize)
+
+	if err != nil {
+		return 0, fmt.Errorf("%w %s: %q is not a %s", ErrInvalidJSONValue, field.Name, text, field.Type.ToString())
+	}
+
+	return num, nil
+}
+
+func (w *Writer) valueFromJSON(field schema.MessageField, value json.RawMessage) error {
+	switch field.Type {
+	case schema.TypeFixedBinary, schema.TypeDynamicBinary, schema.TypeLongBinary:
+		{
+			var b []byte
+
+			err := json.Unmarshal(value, &b)
+
+			if err != nil {
+				return fmt.Errorf("%w %s: expected a base64 string", ErrInvalidJSONValue, field.Name)
+			}
+
+			switch field.Type {
+			case schema.TypeFixedBinary:
+				{
+					if len(b) != field.Extra.(int) {
+						return ErrWrongLen
+					}
+				}
+			case schema.TypeDynamicBinary:
+				{
+					if len(b) > 65535 {
+						return ErrLenTooBig16
+					}
+
+					w.buffer = binary.LittleEndian.AppendUint16(w.buffer, uint16(len(b)))
+				}
+			default:
+				{
+					if len(b) > 4294967295 {
+						return ErrLenTooBig32
+					}
+
+					w.buffer = binary.LittleEndian.AppendUint32(w.buffer, uint32(len(b)))
+				}
+			}
+
+			w.buffer = append(w.buffer, b...)
+		}
+	case schema.TypeUInt64, schema.TypeInt64:
+		{
+			num, err := jsonInt(field, value, field.Type == schema.TypeInt64, 64)
+
+			if err != nil {
+				return err
+			}
+
+			w.buffer = binary.LittleEndian.AppendUint64(w.buffer, num)
+		}
+	case schema.TypeUInt32, schema.TypeInt32:
+		{
+			num, err := jsonInt(field, value, field.Type == schema.TypeInt32, 32)
+
+			if err != nil {
+				return err
+			}
+
+			w.buffer = binary.LittleEndian.AppendUint32(w.buffer, uint32(num))
+		}
+	case schema.TypeUInt16, schema.TypeInt16:
+		{
+			num, err := jsonInt(field, value, field.Type == schema.TypeInt16, 16)
+
+			if err != nil {
+				return err
+			}
+
+			w.buffer = binary.LittleEndian.AppendUint16(w.buffer, uint16(num))
+		}
+	case schema.TypeObject:
+		return w.objectFromJSON(nestedDescriptor(field.Extra), value)
+	case schema.TypeArray:
+		{
+			var items []json.RawMessage
+
+			err := unmarshalJSON(value, &items)
+
+			if err != nil {
+				return fmt.Errorf("%w %s: expected an array", ErrInvalidJSONValue, field.Name)
+			}
+
+			if len(items) > 65535 {
+				return ErrArrLenTooBig
+			}
+
+			w.buffer = binary.LittleEndian.AppendUint16(w.buffer, uint16(len(items)))
+
+			for _, item := range items {
+				if elem, ok := field.Extra.(schema.MessageField); ok {
+					elem.Name = field.Name
+					err = w.valueFromJSON(elem, item)
+				} else {
+					err = w.objectFromJSON(nestedDescriptor(field.Extra), item)
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return ErrTypeCorrupted
+	}
+
+	return nil
+}
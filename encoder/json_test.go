@@ -0,0 +1,133 @@
+package encoder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func parseTestDescriptor(t *testing.T, src string, signature string) schema.MessageDescriptor {
+	s, err := schema.Parse([]byte(src))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r.Descriptors[r.UserSignatureMap[signature]]
+}
+
+const jsonSchemaSrc = `
+object Item {
+  binary(2) REQUIRED sku
+  uint32 REQUIRED price
+}
+
+@extensible
+inbound Order {
+  uint64 REQUIRED id
+  int16 OPTIONAL priority
+  array(Item) REQUIRED items
+  array(int32) OPTIONAL tags
+  binary OPTIONAL note
+}
+`
+
+type jsonItem struct {
+	SKU   [2]byte `ipc:"sku"`
+	Price uint32  `ipc:"price"`
+}
+
+type jsonOrder struct {
+	ID    uint64     `ipc:"id"`
+	Items []jsonItem `ipc:"items"`
+	Tags  []int32    `ipc:"tags"`
+	Note  string     `ipc:"note"`
+}
+
+func TestJSONTranscoding(t *testing.T) {
+	descriptor := parseTestDescriptor(t, jsonSchemaSrc, "inbound Order")
+
+	payload, err := Encode(descriptor, jsonOrder{
+		ID:    18446744073709551615,
+		Items: []jsonItem{{SKU: [2]byte{'a', 'b'}, Price: 250}},
+		Tags:  []int32{-1, 7},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ToJSON(descriptor, payload)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `{"id":"18446744073709551615","items":[{"sku":"YWI=","price":250}],"tags":[-1,7]}`
+
+	if string(data) != expected {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	encoded, err := FromJSON(descriptor, data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res jsonOrder
+
+	reader := NewReader(encoded, descriptor)
+	err = reader.Decode(&res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ID != 18446744073709551615 || len(res.Items) != 1 || res.Items[0].Price != 250 || len(res.Tags) != 2 || res.Tags[0] != -1 {
+		t.Errorf("unexpected message: %+v", res)
+	}
+
+	// optional fields can be null and integers can be strings
+	_, err = FromJSON(descriptor, []byte(`{"id":1,"priority":null,"items":[],"tags":["3"]}`))
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJSONTranscodingErrors(t *testing.T) {
+	descriptor := parseTestDescriptor(t, jsonSchemaSrc, "inbound Order")
+
+	cases := map[string]error{
+		`{"items":[]}`:                                  ErrRequiredNotPresent,
+		`{"id":"1","items":[],"extra":1}`:               ErrUnknownJSONField,
+		`{"id":"1","items":[{"sku":"YQ==","price":1}]}`: ErrWrongLen,
+		`{"id":"1","items":[],"priority":40000}`:        ErrInvalidJSONValue,
+		`{"id":"-1","items":[]}`:                        ErrInvalidJSONValue,
+		`{"id":"1","items":{}}`:                         ErrInvalidJSONValue,
+	}
+
+	for src, expected := range cases {
+		_, err := FromJSON(descriptor, []byte(src))
+
+		if !errors.Is(err, expected) {
+			t.Errorf("expected %s to fail with %v, got %v", src, expected, err)
+		}
+	}
+}
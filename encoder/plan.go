@@ -0,0 +1,974 @@
+package encoder
+
+import (
+	"encoding/binary"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// decodePlan is a message descriptor compiled for one Go type, so decoding only follows offsets and typed
+// stores instead of looking up every field with reflection
+type decodePlan struct {
+	optBytes      uint32
+	optionalCount uint32
+	extensible    bool
+	fields        []fieldDecoder
+}
+
+type fieldDecoder struct {
+	optional   bool
+	deprecated bool
+	mapped     bool                 // false if the result has no field for it
+	offset     uintptr              // from the start of the struct, through embedded structs
+	field      *schema.MessageField // reported to OnDeprecated
+	value      valueDecoder
+}
+
+// valueDecoder reads one value and stores it, it is switched on instead of calling closures so the reader
+// doesn't escape to the heap
+type valueDecoder struct {
+	fieldType   schema.FieldType
+	err         error        // returned once the value is present, e.g. if the kind of its field doesn't match
+	n           uint32       // length of a fixed binary, or the fixed size of an array element
+	kind        reflect.Kind // of the value a binary is stored in
+	size        uintptr      // of the value an integer is stored in, or of an array element
+	arrLen      int          // of a [N]byte
+	sliceType   reflect.Type // of an array
+	hasPointers bool         // if the elements of an array do
+	elem        *valueDecoder
+	sub         *decodePlan // of an object, or of the elements of an array
+}
+
+// planCache caches plans by what they are compiled from: the shape of the descriptor and the Go type. Descriptors
+// are usually copies that don't share anything (a peer's registry is built anew on every handshake), so plans
+// are keyed by their contents rather than by address, and editing a descriptor in place gets it a new plan.
+type planCache struct {
+	plans sync.Map // of planKey to plan
+	count atomic.Int64
+}
+
+type planKey struct {
+	shape uint64
+	t     reflect.Type
+}
+
+// maxPlans bounds a cache, it is emptied once it is full so plans of schemas that are no longer used don't pile up
+const maxPlans = 4096
+
+// skipType stands in for the nil type of plans that only skip a message
+var skipType = reflect.TypeFor[struct{}]()
+
+func newPlanKey(descriptor *schema.MessageDescriptor, t reflect.Type) planKey {
+	if t == nil {
+		t = skipType
+	}
+
+	return planKey{
+		shape: messageShape(0, &descriptor.Message, descriptor.OptionalCount),
+		t:     t,
+	}
+}
+
+func mix(h, v uint64) uint64 {
+	return (h ^ v) * 0x100000001b3
+}
+
+// messageShape hashes everything a plan depends on: the layout of the message and the aliases and deprecations
+// of its fields, which schema.SchemaMessage.Layout leaves out
+func messageShape(h uint64, message *schema.SchemaMessage, optionalCount uint32) uint64 {
+	h = mix(h, uint64(optionalCount)<<1|boolBit(message.Extensible))
+	h = mix(h, uint64(len(message.Fields)))
+
+	for i := range message.Fields {
+		h = fieldShape(h, &message.Fields[i])
+	}
+
+	return h
+}
+
+func fieldShape(h uint64, field *schema.MessageField) uint64 {
+	h = mix(h, stringShape(field.Name))
+	h = mix(h, uint64(field.Type)<<3|boolBit(field.Optional)<<2|boolBit(field.Deprecated)<<1|boolBit(field.Embedded))
+	h = mix(h, uint64(len(field.Aliases)))
+
+	for _, alias := range field.Aliases {
+		h = mix(h, stringShape(alias))
+	}
+
+	switch e := field.Extra.(type) {
+	case int:
+		h = mix(h, uint64(e))
+	case schema.MessageField:
+		h = fieldShape(h, &e)
+	case schema.MessageDescriptor:
+		h = messageShape(h, &e.Message, e.OptionalCount)
+	case schema.SchemaMessage:
+		h = messageShape(h, &e, e.CountOptional())
+	}
+
+	return h
+}
+
+func stringShape(s string) uint64 {
+	h := uint64(0xcbf29ce484222325)
+
+	for i := 0; i < len(s); i++ {
+		h = (h ^ uint64(s[i])) * 0x100000001b3
+	}
+
+	return h
+}
+
+func boolBit(b bool) uint64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func (c *planCache) load(key planKey) (any, bool) {
+	return c.plans.Load(key)
+}
+
+func (c *planCache) store(key planKey, plan any) any {
+	cached, loaded := c.plans.LoadOrStore(key, plan)
+
+	if !loaded && c.count.Add(1) > maxPlans {
+		c.plans.Clear()
+		c.count.Store(0)
+	}
+
+	return cached
+}
+
+// len returns the number of cached plans
+func (c *planCache) len() int {
+	return int(c.count.Load())
+}
+
+var decodePlans planCache
+
+func loadDecodePlan(descriptor *schema.MessageDescriptor, t reflect.Type) (*decodePlan, error) {
+	key := newPlanKey(descriptor, t)
+	cached, exists := decodePlans.load(key)
+
+	// a descriptor whose shape collides with another one's has the same key, its plan is compiled every time
+	if exists && cached.(*decodePlan).matches(descriptor) {
+		return cached.(*decodePlan), nil
+	}
+
+	plan, err := compileDecodePlan(*descriptor, t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		return plan, nil
+	}
+
+	return decodePlans.store(key, plan).(*decodePlan), nil
+}
+
+func (plan *decodePlan) matches(descriptor *schema.MessageDescriptor) bool {
+	return len(plan.fields) == len(descriptor.Message.Fields) && plan.extensible == descriptor.Message.Extensible &&
+		plan.optionalCount == descriptor.OptionalCount
+}
+
+// compileDecodePlan compiles a plan for decoding into t, a nil t compiles a plan that only skips the message
+func compileDecodePlan(descriptor schema.MessageDescriptor, t reflect.Type) (*decodePlan, error) {
+	var fMap fieldMap
+
+	if t != nil {
+		var err error
+
+		fMap, err = computeFieldMap(t)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &decodePlan{
+		optBytes:      descriptor.OptFlagLength(),
+		optionalCount: descriptor.OptionalCount,
+		extensible:    descriptor.Message.Extensible,
+		fields:        make([]fieldDecoder, len(descriptor.Message.Fields)),
+	}
+
+	for i := range descriptor.Message.Fields {
+		field := &descriptor.Message.Fields[i]
+		fd := fieldDecoder{optional: field.Optional, deprecated: field.Deprecated, field: field}
+
+		var ft reflect.Type
+		fIdx, exists := fMap.lookup(*field)
+
+		if exists {
+			fd.mapped = true
+			fd.offset, ft = fieldOffset(t, fIdx)
+		}
+
+		value, err := compileValue(*field, ft)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fd.value = value
+		plan.fields[i] = fd
+	}
+
+	return plan, nil
+}
+
+// fieldOffset follows an index path through embedded structs to the offset and type of the field
+func fieldOffset(t reflect.Type, fIdx []int) (uintptr, reflect.Type) {
+	var offset uintptr
+
+	for _, i := range fIdx {
+		field := t.Field(i)
+		offset += field.Offset
+		t = field.Type
+	}
+
+	return offset, t
+}
+
+// compileValue compiles the decoder of a value stored in a value of type ft, or only skipped if ft is nil
+func compileValue(field schema.MessageField, ft reflect.Type) (valueDecoder, error) {
+	vd := valueDecoder{fieldType: field.Type}
+
+	if ft != nil {
+		err := checkKind(field, ft)
+
+		if err != nil {
+			// like reflection used to, the mismatch only fails once the field is present
+			vd.err = err
+			return vd, nil
+		}
+
+		vd.kind = ft.Kind()
+		vd.size = ft.Size()
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		{
+			vd.n = uint32(field.Extra.(int))
+
+			if vd.kind == reflect.Array {
+				vd.arrLen = ft.Len()
+			}
+		}
+	case schema.TypeDynamicBinary, schema.TypeLongBinary:
+		{
+			if vd.kind == reflect.Array {
+				vd.arrLen = ft.Len()
+			}
+		}
+	case schema.TypeUInt64, schema.TypeInt64, schema.TypeUInt32, schema.TypeInt32, schema.TypeUInt16, schema.TypeInt16:
+	case schema.TypeObject:
+		{
+			sub, err := compileDecodePlan(field.Extra.(schema.MessageDescriptor), ft)
+
+			if err != nil {
+				return vd, err
+			}
+
+			vd.sub = sub
+		}
+	case schema.TypeArray:
+		return compileArray(field, ft)
+	default:
+		vd.err = ErrTypeCorrupted
+	}
+
+	return vd, nil
+}
+
+// compileArray compiles the decoder of an array stored in a slice of type ft, or only skipped if ft is nil
+func compileArray(field schema.MessageField, ft reflect.Type) (valueDecoder, error) {
+	vd := valueDecoder{fieldType: field.Type, sliceType: ft}
+
+	var et reflect.Type
+
+	if ft != nil {
+		et = ft.Elem()
+		vd.size = et.Size()
+		vd.hasPointers = hasPointers(et)
+	}
+
+	switch e := field.Extra.(type) {
+	case schema.MessageField:
+		{
+			elem, err := compileValue(e, et)
+
+			if err != nil {
+				return vd, err
+			}
+
+			vd.elem = &elem
+			vd.n = e.Type.GetFixedSize(e.Extra)
+		}
+	case schema.MessageDescriptor:
+		{
+			vd.n = e.GetFixedSize()
+
+			if et != nil && et.Kind() != reflect.Struct {
+				vd.err = ErrInvalidResultPointer
+				break
+			}
+
+			sub, err := compileDecodePlan(e, et)
+
+			if err != nil {
+				return vd, err
+			}
+
+			vd.sub = sub
+		}
+	default:
+		vd.err = ErrTypeCorrupted
+	}
+
+	return vd, nil
+}
+
+// hasPointers reports whether values of t hold any pointer the GC has to know about
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		{
+			for i := 0; i < t.NumField(); i++ {
+				if hasPointers(t.Field(i).Type) {
+					return true
+				}
+			}
+
+			return false
+		}
+	default:
+		return true
+	}
+}
+
+// decodeValue reads one value and stores it at p, p is nil if the result has no field for it and it is only
+// read to skip past it
+func (r *Reader) decodeValue(vd *valueDecoder, p unsafe.Pointer) error {
+	if vd.err != nil {
+		return vd.err
+	}
+
+	switch vd.fieldType {
+	case schema.TypeFixedBinary:
+		{
+			bytes, err := r.ReadBytes(vd.n)
+
+			if err != nil {
+				return err
+			}
+
+			return r.storeBytes(vd, bytes, p)
+		}
+	case schema.TypeDynamicBinary:
+		{
+			bytes, err := r.readBinary()
+
+			if err != nil {
+				return err
+			}
+
+			return r.storeBytes(vd, bytes, p)
+		}
+	case schema.TypeLongBinary:
+		{
+			bytes, err := r.readLongBinary()
+
+			if err != nil {
+				return err
+			}
+
+			return r.storeBytes(vd, bytes, p)
+		}
+	case schema.TypeUInt64, schema.TypeInt64:
+		{
+			bytes, err := r.ReadBytes(8)
+
+			if err != nil {
+				return err
+			}
+
+			storeInt(vd, binary.LittleEndian.Uint64(bytes), p)
+		}
+	case schema.TypeUInt32:
+		{
+			num, err := r.ReadUInt32()
+
+			if err != nil {
+				return err
+			}
+
+			storeInt(vd, uint64(num), p)
+		}
+	case schema.TypeInt32:
+		{
+			num, err := r.ReadInt32()
+
+			if err != nil {
+				return err
+			}
+
+			storeInt(vd, uint64(num), p)
+		}
+	case schema.TypeUInt16:
+		{
+			num, err := r.ReadUInt16()
+
+			if err != nil {
+				return err
+			}
+
+			storeInt(vd, uint64(num), p)
+		}
+	case schema.TypeInt16:
+		{
+			num, err := r.ReadInt16()
+
+			if err != nil {
+				return err
+			}
+
+			storeInt(vd, uint64(num), p)
+		}
+	case schema.TypeObject:
+		return r.decodeMessage(vd.sub, p)
+	case schema.TypeArray:
+		return r.decodeArray(vd, p)
+	}
+
+	return nil
+}
+
+// decodeArray reads an array into a new slice stored at p, or only skips it if p is nil
+func (r *Reader) decodeArray(vd *valueDecoder, p unsafe.Pointer) error {
+	arrLen, err := r.ReadUInt16()
+
+	if err != nil {
+		return err
+	}
+
+	n := int(arrLen)
+
+	if vd.n*uint32(n) > (r.len - r.pos) {
+		return ErrOutOfBounds
+	}
+
+	var base unsafe.Pointer
+
+	if p != nil {
+		base = vd.makeSlice(p, n)
+	}
+
+	for i := 0; i < n; i++ {
+		var ep unsafe.Pointer
+
+		if base != nil {
+			ep = unsafe.Add(base, uintptr(i)*vd.size)
+		}
+
+		if vd.sub != nil {
+			err = r.decodeMessage(vd.sub, ep)
+		} else {
+			err = r.decodeValue(vd.elem, ep)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// makeSlice stores a new slice of n zeroed elements at p and returns its elements
+func (vd *valueDecoder) makeSlice(p unsafe.Pointer, n int) unsafe.Pointer {
+	// elements without pointers are allocated as words, which are aligned for any element, so no type is needed
+	if !vd.hasPointers {
+		base := unsafe.Pointer(unsafe.SliceData(make([]uint64, (uintptr(n)*vd.size+7)/8)))
+		*(*sliceHeader)(p) = sliceHeader{data: base, len: n, cap: n}
+
+		return base
+	}
+
+	// otherwise the slice is grown in place by reflection so the GC knows its type
+	*(*[]byte)(p) = nil
+	slice := reflect.NewAt(vd.sliceType, p).Elem()
+
+	if n == 0 {
+		slice.Set(reflect.MakeSlice(vd.sliceType, 0, 0))
+	} else {
+		slice.Grow(n)
+		slice.SetLen(n)
+	}
+
+	return slice.UnsafePointer()
+}
+
+// sliceHeader is the layout of any slice
+type sliceHeader struct {
+	data unsafe.Pointer
+	len  int
+	cap  int
+}
+
+// storeInt stores an integer in an integer kind of the size of the value, truncating like reflect.Value.SetInt,
+// signed integers are sign extended by the caller so they can be stored in any kind
+func storeInt(vd *valueDecoder, num uint64, p unsafe.Pointer) {
+	if p == nil {
+		return
+	}
+
+	switch vd.size {
+	case 1:
+		*(*uint8)(p) = uint8(num)
+	case 2:
+		*(*uint16)(p) = uint16(num)
+	case 4:
+		*(*uint32)(p) = uint32(num)
+	default:
+		*(*uint64)(p) = num
+	}
+}
+
+// storeBytes stores a binary in a [N]byte, []byte or string kind, only []byte may alias the payload
+func (r *Reader) storeBytes(vd *valueDecoder, bytes []byte, p unsafe.Pointer) error {
+	if p == nil {
+		return nil
+	}
+
+	switch vd.kind {
+	case reflect.Array:
+		{
+			if len(bytes) != vd.arrLen {
+				return ErrWrongLen
+			}
+
+			copy(unsafe.Slice((*byte)(p), vd.arrLen), bytes)
+		}
+	case reflect.Slice:
+		*(*[]byte)(p) = r.own(bytes)
+	default:
+		*(*string)(p) = string(bytes)
+	}
+
+	return nil
+}
+
+// encodeFunc appends one field stored at p to buf
+type encodeFunc func(buf []byte, p unsafe.Pointer) ([]byte, error)
+
+// sizeFunc returns the encoded size of one field stored at p
+type sizeFunc func(p unsafe.Pointer) (uint32, error)
+
+// encodePlan is the encoding counterpart of decodePlan, it also computes the exact size of a message up front
+type encodePlan struct {
+	optBytes      uint32
+	optionalCount uint32
+	extensible    bool
+	fields        []fieldEncoder
+}
+
+type fieldEncoder struct {
+	optional bool
+	mapped   bool    // false if the value has no field for it, which is only allowed for optional fields
+	offset   uintptr // from the start of the struct, through embedded structs
+	err      error   // returned once the field is encoded, e.g. if its kind doesn't match
+	isZero   func(p unsafe.Pointer) bool
+	encode   encodeFunc
+	size     sizeFunc
+}
+
+var encodePlans planCache
+
+func loadEncodePlan(descriptor *schema.MessageDescriptor, t reflect.Type) (*encodePlan, error) {
+	key := newPlanKey(descriptor, t)
+	cached, exists := encodePlans.load(key)
+
+	if exists && cached.(*encodePlan).matches(descriptor) {
+		return cached.(*encodePlan), nil
+	}
+
+	plan, err := compileEncodePlan(*descriptor, t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		return plan, nil
+	}
+
+	return encodePlans.store(key, plan).(*encodePlan), nil
+}
+
+func (plan *encodePlan) matches(descriptor *schema.MessageDescriptor) bool {
+	return len(plan.fields) == len(descriptor.Message.Fields) && plan.extensible == descriptor.Message.Extensible &&
+		plan.optionalCount == descriptor.OptionalCount
+}
+
+func compileEncodePlan(descriptor schema.MessageDescriptor, t reflect.Type) (*encodePlan, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, ErrInvalidResultPointer
+	}
+
+	fMap, err := computeFieldMap(t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &encodePlan{
+		optBytes:      descriptor.OptFlagLength(),
+		optionalCount: descriptor.OptionalCount,
+		extensible:    descriptor.Message.Extensible,
+		fields:        make([]fieldEncoder, len(descriptor.Message.Fields)),
+	}
+
+	for i, field := range descriptor.Message.Fields {
+		fe := fieldEncoder{optional: field.Optional}
+		fIdx, exists := fMap.lookup(field)
+
+		if !exists {
+			// Future Note: use a default value instead of failing
+			fe.err = ErrRequiredNotPresent
+			plan.fields[i] = fe
+			continue
+		}
+
+		var ft reflect.Type
+
+		fe.mapped = true
+		fe.offset, ft = fieldOffset(t, fIdx)
+		fe.isZero = zeroChecker(ft)
+		fe.encode, fe.size, fe.err = compileFieldEncoder(field, ft)
+		plan.fields[i] = fe
+	}
+
+	return plan, nil
+}
+
+// compileFieldEncoder compiles the encoder and sizer of a field stored in a value of type ft, errors that only
+// matter once the field is encoded are returned as the third result
+func compileFieldEncoder(field schema.MessageField, ft reflect.Type) (encodeFunc, sizeFunc, error) {
+	err := checkKind(field, ft)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		{
+			n := field.Extra.(int)
+			get := bytesGetter(ft)
+
+			return func(buf []byte, p unsafe.Pointer) ([]byte, error) {
+					return appendFixedBinary(buf, get(p), n)
+				}, func(p unsafe.Pointer) (uint32, error) {
+					return uint32(n), nil
+				}, nil
+		}
+	case schema.TypeDynamicBinary:
+		{
+			get := bytesGetter(ft)
+
+			return func(buf []byte, p unsafe.Pointer) ([]byte, error) {
+					return appendBinary(buf, get(p))
+				}, func(p unsafe.Pointer) (uint32, error) {
+					return 2 + uint32(len(get(p))), nil
+				}, nil
+		}
+	case schema.TypeLongBinary:
+		{
+			get := bytesGetter(ft)
+
+			return func(buf []byte, p unsafe.Pointer) ([]byte, error) {
+					return appendLongBinary(buf, get(p))
+				}, func(p unsafe.Pointer) (uint32, error) {
+					return 4 + uint32(len(get(p))), nil
+				}, nil
+		}
+	case schema.TypeUInt64, schema.TypeInt64, schema.TypeUInt32, schema.TypeInt32, schema.TypeUInt16, schema.TypeInt16:
+		{
+			get := intGetter(ft)
+			size := field.Type.GetFixedSize(nil)
+			write := intAppender(size)
+
+			return func(buf []byte, p unsafe.Pointer) ([]byte, error) {
+					return write(buf, get(p)), nil
+				}, func(p unsafe.Pointer) (uint32, error) {
+					return size, nil
+				}, nil
+		}
+	case schema.TypeObject:
+		{
+			sub, err := compileEncodePlan(field.Extra.(schema.MessageDescriptor), ft)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return func(buf []byte, p unsafe.Pointer) ([]byte, error) {
+				return appendMessage(buf, sub, p)
+			}, sub.size, nil
+		}
+	case schema.TypeArray:
+		return compileArrayEncoder(field, ft)
+	default:
+		return nil, nil, ErrTypeCorrupted
+	}
+}
+
+func compileArrayEncoder(field schema.MessageField, ft reflect.Type) (encodeFunc, sizeFunc, error) {
+	et := ft.Elem()
+	elemSize := et.Size()
+
+	var elem encodeFunc
+	var elemLen sizeFunc
+
+	switch e := field.Extra.(type) {
+	case schema.MessageField:
+		{
+			var err error
+
+			elem, elemLen, err = compileFieldEncoder(e, et)
+
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	case schema.SchemaMessage, schema.MessageDescriptor:
+		{
+			sub, err := compileEncodePlan(nestedDescriptor(e), et)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			elem = func(buf []byte, p unsafe.Pointer) ([]byte, error) {
+				return appendMessage(buf, sub, p)
+			}
+			elemLen = sub.size
+		}
+	default:
+		return nil, nil, ErrTypeCorrupted
+	}
+
+	encode := func(buf []byte, p unsafe.Pointer) ([]byte, error) {
+		base, n := sliceOf(p)
+
+		buf, err := appendArrayLen(buf, n)
+
+		if err != nil {
+			return buf, err
+		}
+
+		for i := 0; i < n; i++ {
+			buf, err = elem(buf, unsafe.Add(base, uintptr(i)*elemSize))
+
+			if err != nil {
+				return buf, err
+			}
+		}
+
+		return buf, nil
+	}
+
+	size := func(p unsafe.Pointer) (uint32, error) {
+		base, n := sliceOf(p)
+		var total uint32 = 2
+
+		for i := 0; i < n; i++ {
+			itemLen, err := elemLen(unsafe.Add(base, uintptr(i)*elemSize))
+
+			if err != nil {
+				return 0, err
+			}
+
+			total += itemLen
+		}
+
+		return total, nil
+	}
+
+	return encode, size, nil
+}
+
+// size returns the encoded size of the message stored at p, or the error appendMessage would return
+func (plan *encodePlan) size(p unsafe.Pointer) (uint32, error) {
+	total := plan.optBytes
+
+	if plan.extensible {
+		if plan.optBytes > 255 {
+			return 0, ErrTooManyOptional
+		}
+
+		total += schema.ExtensibleHeaderSize
+	}
+
+	var optCounter uint32 = 0
+
+	for i := range plan.fields {
+		fe := &plan.fields[i]
+
+		if fe.optional {
+			if optCounter >= plan.optionalCount {
+				return 0, ErrOptionalCorrupted
+			}
+
+			optCounter++
+
+			if !fe.mapped || fe.isZero(unsafe.Add(p, fe.offset)) {
+				continue
+			}
+		}
+
+		if fe.err != nil {
+			return 0, fe.err
+		}
+
+		fieldLen, err := fe.size(unsafe.Add(p, fe.offset))
+
+		if err != nil {
+			return 0, err
+		}
+
+		total += fieldLen
+	}
+
+	return total, nil
+}
+
+// sliceOf returns the elements and length of the slice stored at p, whatever its element type
+func sliceOf(p unsafe.Pointer) (unsafe.Pointer, int) {
+	s := *(*[]byte)(p)
+
+	return unsafe.Pointer(unsafe.SliceData(s)), len(s)
+}
+
+// zeroChecker returns a check for the zero value of ft, optional fields holding it are left out
+func zeroChecker(ft reflect.Type) func(p unsafe.Pointer) bool {
+	switch ft.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		{
+			get := intGetter(ft)
+
+			return func(p unsafe.Pointer) bool {
+				return get(p) == 0
+			}
+		}
+	case reflect.String:
+		{
+			return func(p unsafe.Pointer) bool {
+				return len(*(*string)(p)) == 0
+			}
+		}
+	case reflect.Slice:
+		{
+			return func(p unsafe.Pointer) bool {
+				return *(*[]byte)(p) == nil
+			}
+		}
+	default:
+		{
+			return func(p unsafe.Pointer) bool {
+				return reflect.NewAt(ft, p).Elem().IsZero()
+			}
+		}
+	}
+}
+
+// intGetter returns a getter of an integer kind, signed kinds are sign extended like reflect.Value.Int
+func intGetter(ft reflect.Type) func(p unsafe.Pointer) uint64 {
+	signed := ft.Kind() >= reflect.Int && ft.Kind() <= reflect.Int64
+
+	switch ft.Size() {
+	case 1:
+		{
+			if signed {
+				return func(p unsafe.Pointer) uint64 { return uint64(*(*int8)(p)) }
+			}
+
+			return func(p unsafe.Pointer) uint64 { return uint64(*(*uint8)(p)) }
+		}
+	case 2:
+		{
+			if signed {
+				return func(p unsafe.Pointer) uint64 { return uint64(*(*int16)(p)) }
+			}
+
+			return func(p unsafe.Pointer) uint64 { return uint64(*(*uint16)(p)) }
+		}
+	case 4:
+		{
+			if signed {
+				return func(p unsafe.Pointer) uint64 { return uint64(*(*int32)(p)) }
+			}
+
+			return func(p unsafe.Pointer) uint64 { return uint64(*(*uint32)(p)) }
+		}
+	default:
+		return func(p unsafe.Pointer) uint64 { return *(*uint64)(p) }
+	}
+}
+
+// intAppender returns an appender of an integer of the given wire size, truncating the value to it
+func intAppender(size uint32) func(buf []byte, num uint64) []byte {
+	switch size {
+	case 2:
+		return func(buf []byte, num uint64) []byte { return binary.LittleEndian.AppendUint16(buf, uint16(num)) }
+	case 4:
+		return func(buf []byte, num uint64) []byte { return binary.LittleEndian.AppendUint32(buf, uint32(num)) }
+	default:
+		return binary.LittleEndian.AppendUint64
+	}
+}
+
+// bytesGetter returns a getter of a [N]byte, []byte or string kind, the result points into the value
+func bytesGetter(ft reflect.Type) func(p unsafe.Pointer) []byte {
+	switch ft.Kind() {
+	case reflect.Array:
+		{
+			arrLen := ft.Len()
+
+			return func(p unsafe.Pointer) []byte {
+				return unsafe.Slice((*byte)(p), arrLen)
+			}
+		}
+	case reflect.Slice:
+		{
+			return func(p unsafe.Pointer) []byte {
+				return *(*[]byte)(p)
+			}
+		}
+	default:
+		{
+			return func(p unsafe.Pointer) []byte {
+				s := *(*string)(p)
+				return unsafe.Slice(unsafe.StringData(s), len(s))
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package shop
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var sampleOrder = PlaceOrder{
+	Header: Header{RequestId: 42, Tenant: []byte("acme")},
+	Items: []Item{
+		{Sku: Sku([]byte("sku-00000001")), Quantity: 2},
+		{Sku: Sku([]byte("sku-00000002")), Quantity: 3},
+		{Sku: Sku([]byte("sku-00000003")), Quantity: 1},
+	},
+	Note: []byte("leave at the door"),
+}
+
+// orderDescriptors returns the descriptor of PlaceOrder, and a copy without a layout that is always
+// encoded and decoded with reflection
+func orderDescriptors(tb testing.TB) (schema.MessageDescriptor, schema.MessageDescriptor) {
+	s, err := schema.ParseFile("shop.schema")
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	generated := r.Descriptors[r.UserSignatureMap[PlaceOrderSignature]]
+	reflected := generated
+	reflected.Layout = 0
+
+	return generated, reflected
+}
+
+func TestCodecMatchesReflection(t *testing.T) {
+	generated, reflected := orderDescriptors(t)
+
+	if sampleOrder.IPCLayout() != generated.Layout {
+		t.Fatalf("expected the generated layout %#x to match the registered layout %#x", sampleOrder.IPCLayout(), generated.Layout)
+	}
+
+	fast, err := encoder.Encode(generated, sampleOrder)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slow, err := encoder.Encode(reflected, sampleOrder)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(fast, slow) {
+		t.Fatalf("generated encoding differs from reflection:\n%x\n%x", fast, slow)
+	}
+
+	var fromFast, fromSlow PlaceOrder
+
+	reader := encoder.NewReader(fast, generated)
+
+	var deprecated []string
+
+	reader.OnDeprecated(func(field schema.MessageField) {
+		deprecated = append(deprecated, field.Name)
+	})
+
+	err = reader.Decode(&fromFast)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader = encoder.NewReader(fast, reflected)
+	err = reader.Decode(&fromSlow)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromFast, sampleOrder) || !reflect.DeepEqual(fromSlow, sampleOrder) {
+		t.Errorf("unexpected decoded orders:\n%+v\n%+v", fromFast, fromSlow)
+	}
+
+	if len(deprecated) != 0 {
+		t.Errorf("expected no deprecated fields, got %v", deprecated)
+	}
+
+	// the deprecated coupon is reported by the generated decoder too
+	withCoupon := sampleOrder
+	withCoupon.Coupon = 5
+
+	payload, err := encoder.Encode(generated, withCoupon)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader = encoder.NewReader(payload, generated)
+	reader.OnDeprecated(func(field schema.MessageField) {
+		deprecated = append(deprecated, field.Name)
+	})
+
+	err = reader.Decode(&fromFast)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fromFast.Coupon != 5 || len(deprecated) != 1 || deprecated[0] != "coupon" {
+		t.Errorf("expected coupon 5 to be reported as deprecated, got %d %v", fromFast.Coupon, deprecated)
+	}
+}
+
+func benchmarkEncode(b *testing.B, descriptor schema.MessageDescriptor) {
+	for i := 0; i < b.N; i++ {
+		_, err := encoder.Encode(descriptor, sampleOrder)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkDecode(b *testing.B, descriptor schema.MessageDescriptor) {
+	payload, err := encoder.Encode(descriptor, sampleOrder)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var res PlaceOrder
+
+		reader := encoder.NewReader(payload, descriptor)
+		err := reader.Decode(&res)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeGenerated(b *testing.B) {
+	generated, _ := orderDescriptors(b)
+	benchmarkEncode(b, generated)
+}
+
+func BenchmarkEncodeReflect(b *testing.B) {
+	_, reflected := orderDescriptors(b)
+	benchmarkEncode(b, reflected)
+}
+
+// compare with BenchmarkDecodeA and BenchmarkDecodeB in the encoder package
+func BenchmarkDecodeGenerated(b *testing.B) {
+	generated, _ := orderDescriptors(b)
+	benchmarkDecode(b, generated)
+}
+
+func BenchmarkDecodeReflect(b *testing.B) {
+	_, reflected := orderDescriptors(b)
+	benchmarkDecode(b, reflected)
+}
@@ -0,0 +1,9 @@
+// Package shop is an example of the code schemagen generates from shop.schema, shop.go for Go, shop.ts
+// for a TypeScript client, shop.py for a Python client, shop.h for a C client and shop.rs for a Rust client
+package shop
+
+//go:generate go run ../../cmd/schemagen -o shop.go shop.schema
+//go:generate go run ../../cmd/schemagen -lang ts -o shop.ts shop.schema
+//go:generate go run ../../cmd/schemagen -lang py -o shop.py shop.schema
+//go:generate go run ../../cmd/schemagen -lang c -o shop.h shop.schema
+//go:generate go run ../../cmd/schemagen -lang rust -o shop.rs shop.schema
@@ -0,0 +1,594 @@
+// Code generated by schemagen from shop.schema. DO NOT EDIT.
+
+package shop
+
+import (
+	schemaipc "github.com/benjamin-larsen/goschemaipc"
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+)
+
+const (
+	SkuLength = 12
+)
+
+type Sku = [12]byte
+
+// Header is the object shop.Header
+type Header struct {
+	RequestId uint32 `ipc:"request_id"`
+	Tenant    []byte `ipc:"tenant,optional"`
+}
+
+// MarshalIPC writes m in the wire format of shop.Header
+func (m Header) MarshalIPC(w *encoder.Writer) error {
+	start1, err := w.BeginMessage(1, false)
+
+	if err != nil {
+		return err
+	}
+
+	w.WriteUInt32(m.RequestId)
+
+	if m.Tenant != nil {
+		w.SetOptional(start1, 0)
+		err = w.WriteBinary(m.Tenant)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	w.EndMessage(start1)
+
+	return nil
+}
+
+// SizeIPC returns the number of bytes MarshalIPC writes for m
+func (m Header) SizeIPC() int {
+	size := 5
+
+	if m.Tenant != nil {
+		size += 2 + len(m.Tenant)
+	}
+
+	return size
+}
+
+// UnmarshalIPC reads m from the wire format of shop.Header
+func (m *Header) UnmarshalIPC(r *encoder.Reader) error {
+	frame1, err := r.BeginMessage(1, false)
+
+	if err != nil {
+		return err
+	}
+
+	m.RequestId, err = r.ReadUInt32()
+
+	if err != nil {
+		return err
+	}
+
+	if frame1.Present() {
+		m.Tenant, err = r.ReadBinary()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	r.EndMessage(frame1)
+
+	return nil
+}
+
+// Item is the object shop.Item
+type Item struct {
+	Sku      Sku    `ipc:"sku"`
+	Quantity uint32 `ipc:"quantity"`
+}
+
+// MarshalIPC writes m in the wire format of shop.Item
+func (m Item) MarshalIPC(w *encoder.Writer) error {
+	start1, err := w.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	err = w.WriteFixedBinary(m.Sku[:], 12)
+
+	if err != nil {
+		return err
+	}
+
+	w.WriteUInt32(m.Quantity)
+
+	w.EndMessage(start1)
+
+	return nil
+}
+
+// SizeIPC returns the number of bytes MarshalIPC writes for m
+func (m Item) SizeIPC() int {
+	size := 16
+
+	return size
+}
+
+// UnmarshalIPC reads m from the wire format of shop.Item
+func (m *Item) UnmarshalIPC(r *encoder.Reader) error {
+	frame1, err := r.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	err = r.ReadFixedBinary(m.Sku[:])
+
+	if err != nil {
+		return err
+	}
+
+	m.Quantity, err = r.ReadUInt32()
+
+	if err != nil {
+		return err
+	}
+
+	r.EndMessage(frame1)
+
+	return nil
+}
+
+// PlaceOrder is the inbound message shop.PlaceOrder
+type PlaceOrder struct {
+	Header
+	Items []Item `ipc:"items"`
+	Note  []byte `ipc:"note,optional,type=long_binary"`
+	// Deprecated: the field is deprecated in the schema.
+	Coupon uint16 `ipc:"coupon,optional"`
+}
+
+// IPCLayout returns the layout hash of shop.PlaceOrder the IPC methods were generated for
+func (m PlaceOrder) IPCLayout() uint64 {
+	return 0x5e7c3c821d6ec57b
+}
+
+// MarshalIPC writes m in the wire format of shop.PlaceOrder
+func (m PlaceOrder) MarshalIPC(w *encoder.Writer) error {
+	start1, err := w.BeginMessage(3, false)
+
+	if err != nil {
+		return err
+	}
+
+	w.WriteUInt32(m.Header.RequestId)
+
+	if m.Header.Tenant != nil {
+		w.SetOptional(start1, 0)
+		err = w.WriteBinary(m.Header.Tenant)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.WriteArrayLen(len(m.Items))
+
+	if err != nil {
+		return err
+	}
+
+	for i2 := range m.Items {
+		err = m.Items[i2].MarshalIPC(w)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.Note != nil {
+		w.SetOptional(start1, 1)
+		err = w.WriteLongBinary(m.Note)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.Coupon != 0 {
+		w.SetOptional(start1, 2)
+		w.WriteUInt16(m.Coupon)
+	}
+
+	w.EndMessage(start1)
+
+	return nil
+}
+
+// SizeIPC returns the number of bytes MarshalIPC writes for m
+func (m PlaceOrder) SizeIPC() int {
+	size := 7
+
+	if m.Header.Tenant != nil {
+		size += 2 + len(m.Header.Tenant)
+	}
+
+	for i1 := range m.Items {
+		size += m.Items[i1].SizeIPC()
+	}
+
+	if m.Note != nil {
+		size += 4 + len(m.Note)
+	}
+
+	if m.Coupon != 0 {
+		size += 2
+	}
+
+	return size
+}
+
+// UnmarshalIPC reads m from the wire format of shop.PlaceOrder
+func (m *PlaceOrder) UnmarshalIPC(r *encoder.Reader) error {
+	frame1, err := r.BeginMessage(3, false)
+
+	if err != nil {
+		return err
+	}
+
+	m.Header.RequestId, err = r.ReadUInt32()
+
+	if err != nil {
+		return err
+	}
+
+	if frame1.Present() {
+		m.Header.Tenant, err = r.ReadBinary()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	n2, err := r.ReadArrayLen()
+
+	if err != nil {
+		return err
+	}
+
+	m.Items = make([]Item, n2)
+
+	for i2 := range m.Items {
+		err = m.Items[i2].UnmarshalIPC(r)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if frame1.Present() {
+		m.Note, err = r.ReadLongBinary()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if frame1.Present() {
+		r.Deprecated("coupon")
+
+		m.Coupon, err = r.ReadUInt16()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	r.EndMessage(frame1)
+
+	return nil
+}
+
+// Receipt is the outbound message shop.Receipt
+type Receipt struct {
+	OrderId uint64 `ipc:"order_id"`
+	Total   int64  `ipc:"total"`
+}
+
+// IPCLayout returns the layout hash of shop.Receipt the IPC methods were generated for
+func (m Receipt) IPCLayout() uint64 {
+	return 0x89b073c31d022b77
+}
+
+// MarshalIPC writes m in the wire format of shop.Receipt
+func (m Receipt) MarshalIPC(w *encoder.Writer) error {
+	start1, err := w.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	w.WriteUInt64(m.OrderId)
+
+	w.WriteInt64(m.Total)
+
+	w.EndMessage(start1)
+
+	return nil
+}
+
+// SizeIPC returns the number of bytes MarshalIPC writes for m
+func (m Receipt) SizeIPC() int {
+	size := 16
+
+	return size
+}
+
+// UnmarshalIPC reads m from the wire format of shop.Receipt
+func (m *Receipt) UnmarshalIPC(r *encoder.Reader) error {
+	frame1, err := r.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	m.OrderId, err = r.ReadUInt64()
+
+	if err != nil {
+		return err
+	}
+
+	m.Total, err = r.ReadInt64()
+
+	if err != nil {
+		return err
+	}
+
+	r.EndMessage(frame1)
+
+	return nil
+}
+
+// WatchOrder is the inbound message shop.WatchOrder
+type WatchOrder struct {
+	OrderId uint64 `ipc:"order_id"`
+}
+
+// IPCLayout returns the layout hash of shop.WatchOrder the IPC methods were generated for
+func (m WatchOrder) IPCLayout() uint64 {
+	return 0x3cd05fa1a3fa48bd
+}
+
+// MarshalIPC writes m in the wire format of shop.WatchOrder
+func (m WatchOrder) MarshalIPC(w *encoder.Writer) error {
+	start1, err := w.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	w.WriteUInt64(m.OrderId)
+
+	w.EndMessage(start1)
+
+	return nil
+}
+
+// SizeIPC returns the number of bytes MarshalIPC writes for m
+func (m WatchOrder) SizeIPC() int {
+	size := 8
+
+	return size
+}
+
+// UnmarshalIPC reads m from the wire format of shop.WatchOrder
+func (m *WatchOrder) UnmarshalIPC(r *encoder.Reader) error {
+	frame1, err := r.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	m.OrderId, err = r.ReadUInt64()
+
+	if err != nil {
+		return err
+	}
+
+	r.EndMessage(frame1)
+
+	return nil
+}
+
+// OrderStatus is the outbound message shop.OrderStatus
+type OrderStatus struct {
+	OrderId uint64 `ipc:"order_id"`
+	Status  []byte `ipc:"status"`
+}
+
+// IPCLayout returns the layout hash of shop.OrderStatus the IPC methods were generated for
+func (m OrderStatus) IPCLayout() uint64 {
+	return 0x5fb9f963f76cf283
+}
+
+// MarshalIPC writes m in the wire format of shop.OrderStatus
+func (m OrderStatus) MarshalIPC(w *encoder.Writer) error {
+	start1, err := w.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	w.WriteUInt64(m.OrderId)
+
+	err = w.WriteBinary(m.Status)
+
+	if err != nil {
+		return err
+	}
+
+	w.EndMessage(start1)
+
+	return nil
+}
+
+// SizeIPC returns the number of bytes MarshalIPC writes for m
+func (m OrderStatus) SizeIPC() int {
+	size := 10 + len(m.Status)
+
+	return size
+}
+
+// UnmarshalIPC reads m from the wire format of shop.OrderStatus
+func (m *OrderStatus) UnmarshalIPC(r *encoder.Reader) error {
+	frame1, err := r.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	m.OrderId, err = r.ReadUInt64()
+
+	if err != nil {
+		return err
+	}
+
+	m.Status, err = r.ReadBinary()
+
+	if err != nil {
+		return err
+	}
+
+	r.EndMessage(frame1)
+
+	return nil
+}
+
+// Ping is the duplex message shop.Ping
+type Ping struct {
+	Seq uint32 `ipc:"seq"`
+}
+
+// IPCLayout returns the layout hash of shop.Ping the IPC methods were generated for
+func (m Ping) IPCLayout() uint64 {
+	return 0x9eb279ceba1aa701
+}
+
+// MarshalIPC writes m in the wire format of shop.Ping
+func (m Ping) MarshalIPC(w *encoder.Writer) error {
+	start1, err := w.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	w.WriteUInt32(m.Seq)
+
+	w.EndMessage(start1)
+
+	return nil
+}
+
+// SizeIPC returns the number of bytes MarshalIPC writes for m
+func (m Ping) SizeIPC() int {
+	size := 4
+
+	return size
+}
+
+// UnmarshalIPC reads m from the wire format of shop.Ping
+func (m *Ping) UnmarshalIPC(r *encoder.Reader) error {
+	frame1, err := r.BeginMessage(0, false)
+
+	if err != nil {
+		return err
+	}
+
+	m.Seq, err = r.ReadUInt32()
+
+	if err != nil {
+		return err
+	}
+
+	r.EndMessage(frame1)
+
+	return nil
+}
+
+const (
+	PlaceOrderSignature   = "inbound shop.PlaceOrder"
+	ReceiptSignature      = "outbound shop.Receipt"
+	WatchOrderSignature   = "inbound shop.WatchOrder"
+	OrderStatusSignature  = "outbound shop.OrderStatus"
+	InboundPingSignature  = "inbound shop.Ping"
+	OutboundPingSignature = "outbound shop.Ping"
+)
+
+// RegisterPlaceOrder registers the handler of inbound shop.PlaceOrder on the server
+func RegisterPlaceOrder(s *schemaipc.Server, handler func(msg *PlaceOrder, c *schemaipc.Conn) error) {
+	schemaipc.RegisterMessage(s, PlaceOrderSignature, handler)
+}
+
+// HandleReceipt registers the handler of outbound shop.Receipt on the client
+func HandleReceipt(c *schemaipc.Client, handler func(msg *Receipt, c *schemaipc.Client) error) {
+	schemaipc.HandleMessage(c, ReceiptSignature, handler)
+}
+
+// RegisterWatchOrder registers the handler of inbound shop.WatchOrder on the server
+func RegisterWatchOrder(s *schemaipc.Server, handler func(msg *WatchOrder, c *schemaipc.Conn) error) {
+	schemaipc.RegisterMessage(s, WatchOrderSignature, handler)
+}
+
+// HandleOrderStatus registers the handler of outbound shop.OrderStatus on the client
+func HandleOrderStatus(c *schemaipc.Client, handler func(msg *OrderStatus, c *schemaipc.Client) error) {
+	schemaipc.HandleMessage(c, OrderStatusSignature, handler)
+}
+
+// RegisterPing registers the handler of inbound shop.Ping on the server
+func RegisterPing(s *schemaipc.Server, handler func(msg *Ping, c *schemaipc.Conn) error) {
+	schemaipc.RegisterMessage(s, InboundPingSignature, handler)
+}
+
+// HandlePing registers the handler of outbound shop.Ping on the client
+func HandlePing(c *schemaipc.Client, handler func(msg *Ping, c *schemaipc.Client) error) {
+	schemaipc.HandleMessage(c, OutboundPingSignature, handler)
+}
+
+const (
+	OrdersPlaceMethod = "shop.Orders.Place"
+	OrdersWatchMethod = "shop.Orders.Watch"
+)
+
+// RegisterOrdersPlace registers the handler of rpc Place(shop.PlaceOrder) returns (shop.Receipt) on the server
+func RegisterOrdersPlace(s *schemaipc.Server, handler func(req *PlaceOrder, c *schemaipc.Conn) (Receipt, error)) {
+	schemaipc.RegisterRPC(s, OrdersPlaceMethod, handler)
+}
+
+// RegisterOrdersWatch registers the handler of rpc Watch(shop.WatchOrder) returns (stream shop.OrderStatus) on the server
+func RegisterOrdersWatch(s *schemaipc.Server, handler func(stream *schemaipc.ServerStream[WatchOrder, OrderStatus]) error) {
+	schemaipc.RegisterStream(s, OrdersWatchMethod, handler)
+}
+
+// OrdersClient calls the methods of shop.Orders
+type OrdersClient struct {
+	Client *schemaipc.Client
+}
+
+// Place calls rpc Place(shop.PlaceOrder) returns (shop.Receipt)
+func (c OrdersClient) Place(req *PlaceOrder) (*Receipt, error) {
+	var res Receipt
+
+	err := c.Client.Call(OrdersPlaceMethod, req, &res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Watch opens a stream of rpc Watch(shop.WatchOrder) returns (stream shop.OrderStatus), requests are sent as WatchOrder and responses received as OrderStatus
+func (c OrdersClient) Watch() (*schemaipc.ClientStream, error) {
+	return c.Client.OpenStream(OrdersWatchMethod)
+}
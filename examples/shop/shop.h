@@ -0,0 +1,798 @@
+/* Code generated by schemagen from shop.schema. DO NOT EDIT. */
+
+#ifndef SHOP_SCHEMA_H
+#define SHOP_SCHEMA_H
+
+#ifndef SCHEMAIPC_RUNTIME_H
+#define SCHEMAIPC_RUNTIME_H
+
+#include <stdbool.h>
+#include <stddef.h>
+#include <stdint.h>
+#include <string.h>
+
+#define IPC_PROTOCOL_VERSION 1
+#define IPC_MIN_PROTOCOL_VERSION 1
+
+/* every frame starts with the payload length and the message ID, both little endian */
+#define IPC_HEADER_SIZE 8
+
+typedef enum ipc_status {
+    IPC_OK = 0,
+    IPC_ERR_OUT_OF_BOUNDS,      /* the payload ended in the middle of the message */
+    IPC_ERR_BUFFER_TOO_SMALL,   /* the encoded message doesn't fit in the buffer */
+    IPC_ERR_ARENA_TOO_SMALL,    /* the arrays of the message don't fit in the arena */
+    IPC_ERR_TOO_LONG,           /* a binary or array is longer than its length prefix allows */
+    IPC_ERR_MISSING_REQUIRED,   /* an extensible message from an older peer lacks a required field */
+    IPC_ERR_TOO_MANY_OPTIONALS  /* an extensible message has more than 2,040 optional fields */
+} ipc_status;
+
+/* a binary field, decoded binaries point into the payload */
+typedef struct ipc_bytes {
+    const uint8_t *data;
+    size_t len;
+} ipc_bytes;
+
+/* memory the arrays of decoded messages are carved from, reset used to reuse it */
+typedef struct ipc_arena {
+    uint8_t *buf;
+    size_t cap;
+    size_t used;
+} ipc_arena;
+
+/* errors are sticky, once err is set the writer and reader ignore further calls */
+typedef struct ipc_writer {
+    uint8_t *buf;
+    size_t cap;
+    size_t len;
+    ipc_status err;
+} ipc_writer;
+
+typedef struct ipc_reader {
+    const uint8_t *buf;
+    size_t pos;
+    size_t len;
+    ipc_arena *arena;
+    ipc_status err;
+} ipc_reader;
+
+typedef struct ipc_message_start {
+    size_t header;
+    size_t opt_list;
+} ipc_message_start;
+
+typedef struct ipc_frame {
+    const uint8_t *opt_list;
+    size_t opt_bytes;
+    size_t opt;
+    size_t end;
+    size_t outer_len;
+} ipc_frame;
+
+static inline ipc_arena ipc_arena_init(void *buf, size_t cap)
+{
+    ipc_arena a = { (uint8_t *)buf, cap, 0 };
+    return a;
+}
+
+static inline ipc_writer ipc_writer_init(uint8_t *buf, size_t cap)
+{
+    ipc_writer w = { buf, cap, 0, IPC_OK };
+    return w;
+}
+
+static inline ipc_reader ipc_reader_init(const uint8_t *buf, size_t len, ipc_arena *arena)
+{
+    ipc_reader r = { buf, 0, len, arena, IPC_OK };
+    return r;
+}
+
+static inline uint8_t *ipc_reserve(ipc_writer *w, size_t n)
+{
+    uint8_t *p;
+
+    if (w->err != IPC_OK) {
+        return NULL;
+    }
+
+    if (n > w->cap - w->len) {
+        w->err = IPC_ERR_BUFFER_TOO_SMALL;
+        return NULL;
+    }
+
+    p = w->buf + w->len;
+    w->len += n;
+
+    return p;
+}
+
+static inline void ipc_store_u16(uint8_t *p, uint16_t v)
+{
+    p[0] = (uint8_t)v;
+    p[1] = (uint8_t)(v >> 8);
+}
+
+static inline void ipc_store_u32(uint8_t *p, uint32_t v)
+{
+    ipc_store_u16(p, (uint16_t)v);
+    ipc_store_u16(p + 2, (uint16_t)(v >> 16));
+}
+
+static inline void ipc_store_u64(uint8_t *p, uint64_t v)
+{
+    ipc_store_u32(p, (uint32_t)v);
+    ipc_store_u32(p + 4, (uint32_t)(v >> 32));
+}
+
+static inline uint16_t ipc_load_u16(const uint8_t *p)
+{
+    return (uint16_t)(p[0] | (uint16_t)p[1] << 8);
+}
+
+static inline uint32_t ipc_load_u32(const uint8_t *p)
+{
+    return ipc_load_u16(p) | (uint32_t)ipc_load_u16(p + 2) << 16;
+}
+
+static inline uint64_t ipc_load_u64(const uint8_t *p)
+{
+    return ipc_load_u32(p) | (uint64_t)ipc_load_u32(p + 4) << 32;
+}
+
+static inline void ipc_put_u8(ipc_writer *w, uint8_t v)
+{
+    uint8_t *p = ipc_reserve(w, 1);
+
+    if (p != NULL) {
+        *p = v;
+    }
+}
+
+static inline void ipc_put_u16(ipc_writer *w, uint16_t v)
+{
+    uint8_t *p = ipc_reserve(w, 2);
+
+    if (p != NULL) {
+        ipc_store_u16(p, v);
+    }
+}
+
+static inline void ipc_put_u32(ipc_writer *w, uint32_t v)
+{
+    uint8_t *p = ipc_reserve(w, 4);
+
+    if (p != NULL) {
+        ipc_store_u32(p, v);
+    }
+}
+
+static inline void ipc_put_u64(ipc_writer *w, uint64_t v)
+{
+    uint8_t *p = ipc_reserve(w, 8);
+
+    if (p != NULL) {
+        ipc_store_u64(p, v);
+    }
+}
+
+static inline void ipc_put_raw(ipc_writer *w, const uint8_t *data, size_t n)
+{
+    uint8_t *p = ipc_reserve(w, n);
+
+    if (p != NULL && n > 0) {
+        memcpy(p, data, n);
+    }
+}
+
+static inline void ipc_put_binary(ipc_writer *w, ipc_bytes b)
+{
+    if (b.len > 0xFFFF) {
+        if (w->err == IPC_OK) {
+            w->err = IPC_ERR_TOO_LONG;
+        }
+
+        return;
+    }
+
+    ipc_put_u16(w, (uint16_t)b.len);
+    ipc_put_raw(w, b.data, b.len);
+}
+
+static inline void ipc_put_long_binary(ipc_writer *w, ipc_bytes b)
+{
+    if ((uint64_t)b.len > 0xFFFFFFFF) {
+        if (w->err == IPC_OK) {
+            w->err = IPC_ERR_TOO_LONG;
+        }
+
+        return;
+    }
+
+    ipc_put_u32(w, (uint32_t)b.len);
+    ipc_put_raw(w, b.data, b.len);
+}
+
+static inline void ipc_put_array_len(ipc_writer *w, size_t n)
+{
+    if (n > 0xFFFF) {
+        if (w->err == IPC_OK) {
+            w->err = IPC_ERR_TOO_LONG;
+        }
+
+        return;
+    }
+
+    ipc_put_u16(w, (uint16_t)n);
+}
+
+static inline ipc_message_start ipc_begin_message(ipc_writer *w, size_t optional_count, bool extensible)
+{
+    ipc_message_start start;
+    size_t opt_bytes = (optional_count + 7) / 8;
+    uint8_t *p;
+
+    start.header = w->len;
+
+    if (extensible) {
+        if (opt_bytes > 255) {
+            if (w->err == IPC_OK) {
+                w->err = IPC_ERR_TOO_MANY_OPTIONALS;
+            }
+        }
+
+        ipc_put_u32(w, 0);
+        ipc_put_u8(w, (uint8_t)opt_bytes);
+    }
+
+    start.opt_list = w->len;
+    p = ipc_reserve(w, opt_bytes);
+
+    if (p != NULL && opt_bytes > 0) {
+        memset(p, 0, opt_bytes);
+    }
+
+    return start;
+}
+
+static inline void ipc_set_optional(ipc_writer *w, ipc_message_start start, size_t n)
+{
+    if (w->err == IPC_OK) {
+        w->buf[start.opt_list + (n >> 3)] |= (uint8_t)(1u << (n & 7));
+    }
+}
+
+static inline void ipc_end_message(ipc_writer *w, ipc_message_start start, bool extensible)
+{
+    if (extensible && w->err == IPC_OK) {
+        ipc_store_u32(w->buf + start.header, (uint32_t)(w->len - start.header - 4));
+    }
+}
+
+static inline const uint8_t *ipc_take(ipc_reader *r, size_t n)
+{
+    const uint8_t *p;
+
+    if (r->err != IPC_OK) {
+        return NULL;
+    }
+
+    if (n > r->len - r->pos) {
+        r->err = IPC_ERR_OUT_OF_BOUNDS;
+        return NULL;
+    }
+
+    p = r->buf + r->pos;
+    r->pos += n;
+
+    return p;
+}
+
+static inline uint8_t ipc_get_u8(ipc_reader *r)
+{
+    const uint8_t *p = ipc_take(r, 1);
+    return p != NULL ? *p : 0;
+}
+
+static inline uint16_t ipc_get_u16(ipc_reader *r)
+{
+    const uint8_t *p = ipc_take(r, 2);
+    return p != NULL ? ipc_load_u16(p) : 0;
+}
+
+static inline uint32_t ipc_get_u32(ipc_reader *r)
+{
+    const uint8_t *p = ipc_take(r, 4);
+    return p != NULL ? ipc_load_u32(p) : 0;
+}
+
+static inline uint64_t ipc_get_u64(ipc_reader *r)
+{
+    const uint8_t *p = ipc_take(r, 8);
+    return p != NULL ? ipc_load_u64(p) : 0;
+}
+
+static inline void ipc_get_fixed(ipc_reader *r, uint8_t *dst, size_t n)
+{
+    const uint8_t *p = ipc_take(r, n);
+
+    if (p != NULL && n > 0) {
+        memcpy(dst, p, n);
+    }
+}
+
+static inline ipc_bytes ipc_get_view(ipc_reader *r, size_t n)
+{
+    ipc_bytes b = { NULL, 0 };
+    const uint8_t *p = ipc_take(r, n);
+
+    if (p != NULL) {
+        b.data = p;
+        b.len = n;
+    }
+
+    return b;
+}
+
+static inline ipc_bytes ipc_get_binary(ipc_reader *r)
+{
+    return ipc_get_view(r, ipc_get_u16(r));
+}
+
+static inline ipc_bytes ipc_get_long_binary(ipc_reader *r)
+{
+    return ipc_get_view(r, ipc_get_u32(r));
+}
+
+static inline size_t ipc_get_array_len(ipc_reader *r)
+{
+    return ipc_get_u16(r);
+}
+
+/* ipc_alloc returns zeroed memory for n elements of size bytes from the arena of the reader */
+static inline void *ipc_alloc(ipc_reader *r, size_t n, size_t size)
+{
+    ipc_arena *a = r->arena;
+    size_t start;
+    void *p;
+
+    if (r->err != IPC_OK || n == 0) {
+        return NULL;
+    }
+
+    if (a == NULL) {
+        r->err = IPC_ERR_ARENA_TOO_SMALL;
+        return NULL;
+    }
+
+    /* 8 bytes is enough for every type the generated structs hold */
+    start = (a->used + 7) & ~(size_t)7;
+
+    if (start > a->cap || n > (a->cap - start) / size) {
+        r->err = IPC_ERR_ARENA_TOO_SMALL;
+        return NULL;
+    }
+
+    p = a->buf + start;
+    a->used = start + n * size;
+    memset(p, 0, n * size);
+
+    return p;
+}
+
+static inline ipc_frame ipc_begin_frame(ipc_reader *r, size_t optional_count, bool extensible)
+{
+    ipc_frame frame = { NULL, (optional_count + 7) / 8, 0, 0, 0 };
+
+    if (extensible) {
+        size_t body_len = ipc_get_u32(r);
+
+        if (r->err == IPC_OK && body_len > r->len - r->pos) {
+            r->err = IPC_ERR_OUT_OF_BOUNDS;
+        }
+
+        if (r->err != IPC_OK) {
+            return frame;
+        }
+
+        frame.end = r->pos + body_len;
+        frame.outer_len = r->len;
+        r->len = frame.end;
+
+        /* the sender might know about more or less optional fields than we do */
+        frame.opt_bytes = ipc_get_u8(r);
+    }
+
+    frame.opt_list = ipc_take(r, frame.opt_bytes);
+
+    return frame;
+}
+
+static inline bool ipc_present(ipc_reader *r, ipc_frame *frame)
+{
+    size_t opt = frame->opt++;
+
+    if (r->err != IPC_OK || opt >= frame->opt_bytes * 8) {
+        return false;
+    }
+
+    return (frame->opt_list[opt >> 3] & (1u << (opt & 7))) != 0;
+}
+
+static inline void ipc_check_required(ipc_reader *r, const ipc_frame *frame)
+{
+    if (r->err == IPC_OK && r->pos == frame->end) {
+        r->err = IPC_ERR_MISSING_REQUIRED;
+    }
+}
+
+static inline void ipc_end_frame(ipc_reader *r, const ipc_frame *frame, bool extensible)
+{
+    /* skip trailing fields sent by a peer with a newer schema */
+    if (extensible && r->err == IPC_OK) {
+        r->pos = frame->end;
+        r->len = frame->outer_len;
+    }
+}
+
+/* ipc_put_header writes the frame header of a payload of len bytes */
+static inline void ipc_put_header(uint8_t header[IPC_HEADER_SIZE], uint32_t len, uint32_t id)
+{
+    ipc_store_u32(header, len);
+    ipc_store_u32(header + 4, id);
+}
+
+static inline void ipc_get_header(const uint8_t header[IPC_HEADER_SIZE], uint32_t *len, uint32_t *id)
+{
+    *len = ipc_load_u32(header);
+    *id = ipc_load_u32(header + 4);
+}
+
+#endif
+
+/* SHOP_FINGERPRINT initializes the uint8_t[32] sent in Hello, the server rejects other schemas */
+#define SHOP_FINGERPRINT { 0x6a, 0x2a, 0x0f, 0x43, 0x22, 0x5f, 0x20, 0xb1, 0x51, 0x4e, 0x59, 0xda, 0xe8, 0x8d, 0x4f, 0xbd, 0x52, 0xe2, 0x67, 0xd1, 0x14, 0x1d, 0x0b, 0xbf, 0xbb, 0x66, 0x34, 0x12, 0x71, 0xff, 0xf9, 0xce }
+
+#define SHOP_PLACE_ORDER_ID 12u
+#define SHOP_RECEIPT_ID 13u
+#define SHOP_WATCH_ORDER_ID 14u
+#define SHOP_ORDER_STATUS_ID 15u
+#define SHOP_PING_ID 16u
+
+#define SHOP_SKU_LENGTH 12u
+
+typedef uint8_t shop_sku[12];
+
+/* shop_header is the object shop.Header */
+typedef struct shop_header {
+    uint32_t request_id;
+    bool has_tenant;
+    ipc_bytes tenant;
+} shop_header;
+
+static inline void shop_header_write(ipc_writer *w, const shop_header *m)
+{
+    ipc_message_start start = ipc_begin_message(w, 1, false);
+
+    ipc_put_u32(w, m->request_id);
+    if (m->has_tenant) {
+        ipc_set_optional(w, start, 0);
+        ipc_put_binary(w, m->tenant);
+    }
+
+    ipc_end_message(w, start, false);
+}
+
+static inline void shop_header_read(ipc_reader *r, shop_header *m)
+{
+    ipc_frame frame = ipc_begin_frame(r, 1, false);
+
+    memset(m, 0, sizeof *m);
+    m->request_id = ipc_get_u32(r);
+    m->has_tenant = ipc_present(r, &frame);
+    if (m->has_tenant) {
+        m->tenant = ipc_get_binary(r);
+    }
+
+    ipc_end_frame(r, &frame, false);
+}
+
+/* shop_item is the object shop.Item */
+typedef struct shop_item {
+    shop_sku sku;
+    uint32_t quantity;
+} shop_item;
+
+static inline void shop_item_write(ipc_writer *w, const shop_item *m)
+{
+    ipc_message_start start = ipc_begin_message(w, 0, false);
+
+    ipc_put_raw(w, m->sku, 12);
+    ipc_put_u32(w, m->quantity);
+
+    ipc_end_message(w, start, false);
+}
+
+static inline void shop_item_read(ipc_reader *r, shop_item *m)
+{
+    ipc_frame frame = ipc_begin_frame(r, 0, false);
+
+    memset(m, 0, sizeof *m);
+    ipc_get_fixed(r, m->sku, 12);
+    m->quantity = ipc_get_u32(r);
+
+    ipc_end_frame(r, &frame, false);
+}
+
+/* shop_place_order is the inbound message shop.PlaceOrder */
+typedef struct shop_place_order {
+    uint32_t request_id;
+    bool has_tenant;
+    ipc_bytes tenant;
+    shop_item *items;
+    size_t items_len;
+    bool has_note;
+    ipc_bytes note;
+    bool has_coupon;
+    uint16_t coupon; /* deprecated */
+} shop_place_order;
+
+static inline void shop_place_order_write(ipc_writer *w, const shop_place_order *m)
+{
+    ipc_message_start start = ipc_begin_message(w, 3, false);
+
+    ipc_put_u32(w, m->request_id);
+    if (m->has_tenant) {
+        ipc_set_optional(w, start, 0);
+        ipc_put_binary(w, m->tenant);
+    }
+    ipc_put_array_len(w, m->items_len);
+    for (size_t i = 0; i < m->items_len; i++) {
+        shop_item_write(w, &m->items[i]);
+    }
+    if (m->has_note) {
+        ipc_set_optional(w, start, 1);
+        ipc_put_long_binary(w, m->note);
+    }
+    if (m->has_coupon) {
+        ipc_set_optional(w, start, 2);
+        ipc_put_u16(w, m->coupon);
+    }
+
+    ipc_end_message(w, start, false);
+}
+
+static inline void shop_place_order_read(ipc_reader *r, shop_place_order *m)
+{
+    ipc_frame frame = ipc_begin_frame(r, 3, false);
+
+    memset(m, 0, sizeof *m);
+    m->request_id = ipc_get_u32(r);
+    m->has_tenant = ipc_present(r, &frame);
+    if (m->has_tenant) {
+        m->tenant = ipc_get_binary(r);
+    }
+    m->items_len = ipc_get_array_len(r);
+    m->items = ipc_alloc(r, m->items_len, sizeof *m->items);
+    for (size_t i = 0; i < m->items_len && r->err == IPC_OK; i++) {
+        shop_item_read(r, &m->items[i]);
+    }
+    m->has_note = ipc_present(r, &frame);
+    if (m->has_note) {
+        m->note = ipc_get_long_binary(r);
+    }
+    m->has_coupon = ipc_present(r, &frame);
+    if (m->has_coupon) {
+        m->coupon = ipc_get_u16(r);
+    }
+
+    ipc_end_frame(r, &frame, false);
+}
+
+/* shop_place_order_encode writes m to buf, len is set to the length of the payload */
+static inline ipc_status shop_place_order_encode(const shop_place_order *m, uint8_t *buf, size_t cap, size_t *len)
+{
+    ipc_writer w = ipc_writer_init(buf, cap);
+
+    shop_place_order_write(&w, m);
+    *len = w.len;
+
+    return w.err;
+}
+
+/* shop_place_order_decode reads m from payload, its binaries point into payload and its arrays into arena */
+static inline ipc_status shop_place_order_decode(shop_place_order *m, const uint8_t *payload, size_t len, ipc_arena *arena)
+{
+    ipc_reader r = ipc_reader_init(payload, len, arena);
+
+    shop_place_order_read(&r, m);
+
+    return r.err;
+}
+
+/* shop_receipt is the outbound message shop.Receipt */
+typedef struct shop_receipt {
+    uint64_t order_id;
+    int64_t total;
+} shop_receipt;
+
+static inline void shop_receipt_write(ipc_writer *w, const shop_receipt *m)
+{
+    ipc_message_start start = ipc_begin_message(w, 0, false);
+
+    ipc_put_u64(w, m->order_id);
+    ipc_put_u64(w, (uint64_t)m->total);
+
+    ipc_end_message(w, start, false);
+}
+
+static inline void shop_receipt_read(ipc_reader *r, shop_receipt *m)
+{
+    ipc_frame frame = ipc_begin_frame(r, 0, false);
+
+    memset(m, 0, sizeof *m);
+    m->order_id = ipc_get_u64(r);
+    m->total = (int64_t)ipc_get_u64(r);
+
+    ipc_end_frame(r, &frame, false);
+}
+
+/* shop_receipt_encode writes m to buf, len is set to the length of the payload */
+static inline ipc_status shop_receipt_encode(const shop_receipt *m, uint8_t *buf, size_t cap, size_t *len)
+{
+    ipc_writer w = ipc_writer_init(buf, cap);
+
+    shop_receipt_write(&w, m);
+    *len = w.len;
+
+    return w.err;
+}
+
+/* shop_receipt_decode reads m from payload, its binaries point into payload and its arrays into arena */
+static inline ipc_status shop_receipt_decode(shop_receipt *m, const uint8_t *payload, size_t len, ipc_arena *arena)
+{
+    ipc_reader r = ipc_reader_init(payload, len, arena);
+
+    shop_receipt_read(&r, m);
+
+    return r.err;
+}
+
+/* shop_watch_order is the inbound message shop.WatchOrder */
+typedef struct shop_watch_order {
+    uint64_t order_id;
+} shop_watch_order;
+
+static inline void shop_watch_order_write(ipc_writer *w, const shop_watch_order *m)
+{
+    ipc_message_start start = ipc_begin_message(w, 0, false);
+
+    ipc_put_u64(w, m->order_id);
+
+    ipc_end_message(w, start, false);
+}
+
+static inline void shop_watch_order_read(ipc_reader *r, shop_watch_order *m)
+{
+    ipc_frame frame = ipc_begin_frame(r, 0, false);
+
+    memset(m, 0, sizeof *m);
+    m->order_id = ipc_get_u64(r);
+
+    ipc_end_frame(r, &frame, false);
+}
+
+/* shop_watch_order_encode writes m to buf, len is set to the length of the payload */
+static inline ipc_status shop_watch_order_encode(const shop_watch_order *m, uint8_t *buf, size_t cap, size_t *len)
+{
+    ipc_writer w = ipc_writer_init(buf, cap);
+
+    shop_watch_order_write(&w, m);
+    *len = w.len;
+
+    return w.err;
+}
+
+/* shop_watch_order_decode reads m from payload, its binaries point into payload and its arrays into arena */
+static inline ipc_status shop_watch_order_decode(shop_watch_order *m, const uint8_t *payload, size_t len, ipc_arena *arena)
+{
+    ipc_reader r = ipc_reader_init(payload, len, arena);
+
+    shop_watch_order_read(&r, m);
+
+    return r.err;
+}
+
+/* shop_order_status is the outbound message shop.OrderStatus */
+typedef struct shop_order_status {
+    uint64_t order_id;
+    ipc_bytes status;
+} shop_order_status;
+
+static inline void shop_order_status_write(ipc_writer *w, const shop_order_status *m)
+{
+    ipc_message_start start = ipc_begin_message(w, 0, false);
+
+    ipc_put_u64(w, m->order_id);
+    ipc_put_binary(w, m->status);
+
+    ipc_end_message(w, start, false);
+}
+
+static inline void shop_order_status_read(ipc_reader *r, shop_order_status *m)
+{
+    ipc_frame frame = ipc_begin_frame(r, 0, false);
+
+    memset(m, 0, sizeof *m);
+    m->order_id = ipc_get_u64(r);
+    m->status = ipc_get_binary(r);
+
+    ipc_end_frame(r, &frame, false);
+}
+
+/* shop_order_status_encode writes m to buf, len is set to the length of the payload */
+static inline ipc_status shop_order_status_encode(const shop_order_status *m, uint8_t *buf, size_t cap, size_t *len)
+{
+    ipc_writer w = ipc_writer_init(buf, cap);
+
+    shop_order_status_write(&w, m);
+    *len = w.len;
+
+    return w.err;
+}
+
+/* shop_order_status_decode reads m from payload, its binaries point into payload and its arrays into arena */
+static inline ipc_status shop_order_status_decode(shop_order_status *m, const uint8_t *payload, size_t len, ipc_arena *arena)
+{
+    ipc_reader r = ipc_reader_init(payload, len, arena);
+
+    shop_order_status_read(&r, m);
+
+    return r.err;
+}
+
+/* shop_ping is the duplex message shop.Ping */
+typedef struct shop_ping {
+    uint32_t seq;
+} shop_ping;
+
+static inline void shop_ping_write(ipc_writer *w, const shop_ping *m)
+{
+    ipc_message_start start = ipc_begin_message(w, 0, false);
+
+    ipc_put_u32(w, m->seq);
+
+    ipc_end_message(w, start, false);
+}
+
+static inline void shop_ping_read(ipc_reader *r, shop_ping *m)
+{
+    ipc_frame frame = ipc_begin_frame(r, 0, false);
+
+    memset(m, 0, sizeof *m);
+    m->seq = ipc_get_u32(r);
+
+    ipc_end_frame(r, &frame, false);
+}
+
+/* shop_ping_encode writes m to buf, len is set to the length of the payload */
+static inline ipc_status shop_ping_encode(const shop_ping *m, uint8_t *buf, size_t cap, size_t *len)
+{
+    ipc_writer w = ipc_writer_init(buf, cap);
+
+    shop_ping_write(&w, m);
+    *len = w.len;
+
+    return w.err;
+}
+
+/* shop_ping_decode reads m from payload, its binaries point into payload and its arrays into arena */
+static inline ipc_status shop_ping_decode(shop_ping *m, const uint8_t *payload, size_t len, ipc_arena *arena)
+{
+    ipc_reader r = ipc_reader_init(payload, len, arena);
+
+    shop_ping_read(&r, m);
+
+    return r.err;
+}
+
+#endif
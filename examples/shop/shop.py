@@ -0,0 +1,827 @@
+# Code generated by schemagen from shop.schema. DO NOT EDIT.
+
+from __future__ import annotations
+
+import socket
+import struct
+import zlib
+from dataclasses import dataclass
+from typing import Any, Callable, Optional
+
+
+class IPCError(Exception):
+    pass
+
+
+class RemoteError(IPCError):
+    """A protocol error sent by the server, the connection is closed after it."""
+
+
+class RPCError(IPCError):
+    """An error returned by a method handler on the server, the connection stays usable."""
+
+    def __init__(self, method: str, message: str) -> None:
+        super().__init__(f"rpc {method}: {message}")
+        self.method = method
+
+
+_HEADER = struct.Struct("<II")
+_U8 = struct.Struct("<B")
+_U16 = struct.Struct("<H")
+_U32 = struct.Struct("<I")
+
+
+class _Writer:
+    __slots__ = ("buf",)
+
+    def __init__(self) -> None:
+        self.buf = bytearray()
+
+    def pack(self, s: struct.Struct, *values: Any) -> None:
+        try:
+            self.buf += s.pack(*values)
+        except struct.error as e:
+            raise IPCError(f"integer field: {e}") from None
+
+    def fixed_binary(self, b: bytes, n: int) -> None:
+        if len(b) != n:
+            raise IPCError("fixed binary field: wrong length")
+
+        self.buf += b
+
+    def binary(self, b: bytes) -> None:
+        if len(b) > 0xFFFF:
+            raise IPCError("binary field: length too long (must not be more than 65,535 bytes)")
+
+        self.buf += _U16.pack(len(b))
+        self.buf += b
+
+    def long_binary(self, b: bytes) -> None:
+        self.buf += _U32.pack(len(b))
+        self.buf += b
+
+    def array_len(self, n: int) -> None:
+        if n > 0xFFFF:
+            raise IPCError("array field: length too long (must not be more than 65,535 elements)")
+
+        self.buf += _U16.pack(n)
+
+    def pack_array(self, code: str, values: list[int]) -> None:
+        self.array_len(len(values))
+        self.pack(struct.Struct(f"<{len(values)}{code}"), *values)
+
+    def begin_message(self, optional_count: int, extensible: bool) -> tuple[int, int]:
+        opt_bytes = (optional_count + 7) // 8
+        header = len(self.buf)
+
+        if extensible:
+            if opt_bytes > 255:
+                raise IPCError("extensible message: too many optional fields (must not be more than 2,040)")
+
+            self.buf += _U32.pack(0)
+            self.buf += _U8.pack(opt_bytes)
+
+        opt_list = len(self.buf)
+        self.buf += bytes(opt_bytes)
+
+        return header, opt_list
+
+    def set_optional(self, start: tuple[int, int], n: int) -> None:
+        self.buf[start[1] + (n >> 3)] |= 1 << (n & 7)
+
+    def end_message(self, start: tuple[int, int], extensible: bool) -> None:
+        if extensible:
+            _U32.pack_into(self.buf, start[0], len(self.buf) - start[0] - 4)
+
+
+class _Frame:
+    __slots__ = ("opt_list", "opt", "end", "outer_len")
+
+    def __init__(self, opt_list: bytes, end: int, outer_len: int) -> None:
+        self.opt_list = opt_list
+        self.opt = 0
+        self.end = end
+        self.outer_len = outer_len
+
+
+class _Reader:
+    __slots__ = ("buf", "pos", "len")
+
+    def __init__(self, buf: bytes) -> None:
+        self.buf = buf
+        self.pos = 0
+        self.len = len(buf)
+
+    def take(self, n: int) -> int:
+        if n > self.len - self.pos:
+            raise IPCError("out of bounds")
+
+        pos = self.pos
+        self.pos += n
+
+        return pos
+
+    def unpack(self, s: struct.Struct) -> tuple:
+        return s.unpack_from(self.buf, self.take(s.size))
+
+    def fixed_binary(self, n: int) -> bytes:
+        pos = self.take(n)
+        return bytes(self.buf[pos:pos + n])
+
+    def binary(self) -> bytes:
+        return self.fixed_binary(self.unpack(_U16)[0])
+
+    def long_binary(self) -> bytes:
+        return self.fixed_binary(self.unpack(_U32)[0])
+
+    def array_len(self) -> int:
+        return self.unpack(_U16)[0]
+
+    def unpack_array(self, code: str) -> list[int]:
+        return list(self.unpack(struct.Struct(f"<{self.array_len()}{code}")))
+
+    def begin_message(self, optional_count: int, extensible: bool) -> _Frame:
+        opt_bytes = (optional_count + 7) // 8
+        end = outer_len = 0
+
+        if extensible:
+            body_len = self.unpack(_U32)[0]
+
+            if body_len > self.len - self.pos:
+                raise IPCError("out of bounds")
+
+            end = self.pos + body_len
+            outer_len = self.len
+            self.len = end
+
+            # the sender might know about more or less optional fields than we do
+            opt_bytes = self.unpack(_U8)[0]
+
+        return _Frame(self.fixed_binary(opt_bytes), end, outer_len)
+
+    def present(self, frame: _Frame) -> bool:
+        opt = frame.opt
+        frame.opt += 1
+
+        return opt < len(frame.opt_list) * 8 and frame.opt_list[opt >> 3] & (1 << (opt & 7)) != 0
+
+    def check_required(self, frame: _Frame) -> None:
+        if self.pos == frame.end:
+            raise IPCError("required field is missing from message")
+
+    def end_message(self, frame: _Frame, extensible: bool) -> None:
+        if extensible:
+            # skip trailing fields sent by a peer with a newer schema
+            self.pos = frame.end
+            self.len = frame.outer_len
+
+
+# compressed messages are raw deflate streams
+def _deflate(data: bytes) -> bytes:
+    c = zlib.compressobj(wbits=-15)
+    return c.compress(data) + c.flush()
+
+
+def _inflate(data: bytes, limit: int) -> bytes:
+    d = zlib.decompressobj(wbits=-15)
+    res = d.decompress(data, limit + 1 if limit else 0)
+
+    if limit and len(res) > limit:
+        raise IPCError("message too large")
+
+    return res
+
+
+@dataclass
+class _Handler:
+    decode: Callable[[bytes], Any]
+    handle: Callable[[Any], None]
+    compress: bool
+
+
+class BaseClient:
+    """A blocking client over a stream socket. Messages sent by the server are dispatched to their handlers
+    while a call waits for its response, or by poll."""
+
+    def __init__(self, sock: socket.socket, max_message_size: int = 0) -> None:
+        self.sock = sock
+        self.max_message_size = max_message_size
+        self._handlers: dict[int, _Handler] = {}
+        self._responses: dict[int, tuple[bool, bytes]] = {}
+        self._last_call_id = 0
+
+    @classmethod
+    def connect(cls, address: tuple[str, int], max_message_size: int = 0):
+        client = cls(socket.create_connection(address), max_message_size)
+        client.handshake()
+
+        return client
+
+    def close(self) -> None:
+        self.sock.close()
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc: Any) -> None:
+        self.close()
+
+    def _recv_exact(self, n: int) -> bytes:
+        buf = bytearray()
+
+        while len(buf) < n:
+            chunk = self.sock.recv(n - len(buf))
+
+            if not chunk:
+                raise IPCError("connection closed")
+
+            buf += chunk
+
+        return bytes(buf)
+
+    def _read_frame(self) -> tuple[int, bytes]:
+        length, msg_id = _HEADER.unpack(self._recv_exact(_HEADER.size))
+
+        if self.max_message_size and length > self.max_message_size:
+            raise IPCError("message too large")
+
+        return msg_id, self._recv_exact(length)
+
+    def _write_frame(self, msg_id: int, payload: bytes) -> None:
+        self.sock.sendall(_HEADER.pack(len(payload), msg_id) + payload)
+
+    def handshake(self) -> None:
+        """Sends Hello and checks that the server has the schema the client was generated from."""
+        self._write_frame(_InternalId.INBOUND_HELLO, _encode(_write_internal_inbound_hello, _InternalInboundHello(
+            minVersion=MIN_PROTOCOL_VERSION,
+            currVersion=PROTOCOL_VERSION,
+            fingerprint=FINGERPRINT,
+        )))
+
+        msg_id, payload = self._read_frame()
+
+        if msg_id == _InternalId.OUTBOUND_PROTOCOL_ERROR:
+            raise RemoteError(f"protocol error: {self._protocol_error(payload)}")
+
+        if msg_id != _InternalId.OUTBOUND_HELLO:
+            raise IPCError("server sent an unexpected message")
+
+        hello = _read_internal_outbound_hello(_Reader(payload))
+
+        if hello.currVersion < MIN_PROTOCOL_VERSION or hello.minVersion > PROTOCOL_VERSION:
+            raise IPCError("peer protocol version is not supported")
+
+        if hello.fingerprint != FINGERPRINT:
+            raise IPCError("server schema doesn't match the client schema")
+
+    def _protocol_error(self, payload: bytes) -> str:
+        return _read_internal_outbound_protocol_error(_Reader(payload)).message.decode(errors="replace")
+
+    def poll(self) -> None:
+        """Reads one message from the server and dispatches it."""
+        msg_id, payload = self._read_frame()
+
+        if msg_id == _InternalId.OUTBOUND_PROTOCOL_ERROR:
+            raise RemoteError(f"protocol error: {self._protocol_error(payload)}")
+
+        if msg_id == _InternalId.OUTBOUND_RPC_RESPONSE:
+            res = _read_internal_outbound_rpc_response(_Reader(payload))
+            self._responses[res.callId] = (True, res.payload)
+            return
+
+        if msg_id == _InternalId.OUTBOUND_RPC_ERROR:
+            res = _read_internal_outbound_rpc_error(_Reader(payload))
+            self._responses[res.callId] = (False, res.message)
+            return
+
+        handler = self._handlers.get(msg_id)
+
+        if handler is None:
+            return
+
+        if handler.compress:
+            payload = _inflate(payload, self.max_message_size)
+
+        handler.handle(handler.decode(payload))
+
+    def _send(self, msg_id: int, payload: bytes, compress: bool) -> None:
+        self._write_frame(msg_id, _deflate(payload) if compress else payload)
+
+    def _handle(self, msg_id: int, compress: bool, decode: Callable[[bytes], Any], handler: Callable[[Any], None]) -> None:
+        self._handlers[msg_id] = _Handler(decode, handler, compress)
+
+    def _call(self, method: str, payload: bytes, request_compress: bool, response_compress: bool) -> bytes:
+        self._last_call_id = (self._last_call_id + 1) & 0xFFFFFFFF
+        call_id = self._last_call_id
+
+        if request_compress:
+            payload = _deflate(payload)
+
+        self._write_frame(_InternalId.INBOUND_RPC_REQUEST, _encode(_write_internal_inbound_rpc_request, _InternalInboundRpcRequest(
+            callId=call_id,
+            method=method.encode(),
+            payload=payload,
+        )))
+
+        while call_id not in self._responses:
+            self.poll()
+
+        ok, res = self._responses.pop(call_id)
+
+        if not ok:
+            raise RPCError(method, res.decode(errors="replace"))
+
+        return _inflate(res, self.max_message_size) if response_compress else res
+
+
+def _encode(write: Callable[[_Writer, Any], None], m: Any) -> bytes:
+    w = _Writer()
+    write(w, m)
+
+    return bytes(w.buf)
+
+
+PROTOCOL_VERSION = 1
+MIN_PROTOCOL_VERSION = 1
+
+# FINGERPRINT identifies the schema the client was generated from, handshake fails if the server has another one
+FINGERPRINT = bytes.fromhex("6a2a0f43225f20b1514e59dae88d4fbd52e267d1141d0bbfbb66341271fff9ce")
+
+
+class _InternalId:
+    INBOUND_HELLO = 0
+    OUTBOUND_HELLO = 1
+    OUTBOUND_PROTOCOL_ERROR = 2
+    INBOUND_RPC_REQUEST = 4
+    OUTBOUND_RPC_RESPONSE = 5
+    OUTBOUND_RPC_ERROR = 6
+
+
+class MessageId:
+    """The descriptor ID of every message, the same as the server assigns."""
+
+    PLACE_ORDER = 12
+    RECEIPT = 13
+    WATCH_ORDER = 14
+    ORDER_STATUS = 15
+    PING = 16
+
+
+SKU_LENGTH = 12
+
+Sku = bytes
+
+_S_H = struct.Struct("<H")
+_S_I = struct.Struct("<I")
+_S_IHHHI = struct.Struct("<IHHHI")
+_S_II = struct.Struct("<II")
+_S_Q = struct.Struct("<Q")
+_S_Qq = struct.Struct("<Qq")
+_S_ii = struct.Struct("<ii")
+
+
+@dataclass(kw_only=True)
+class Header:
+    """The object shop.Header."""
+
+    request_id: int
+    tenant: Optional[bytes] = None
+
+
+def _write_header(w: _Writer, m: Header) -> None:
+    start = w.begin_message(1, False)
+    w.pack(_S_I, m.request_id)
+    if m.tenant is not None:
+        w.set_optional(start, 0)
+        w.binary(m.tenant)
+    w.end_message(start, False)
+
+
+def _read_header(r: _Reader) -> Header:
+    frame = r.begin_message(1, False)
+    request_id = r.unpack(_S_I)[0]
+    tenant = r.binary() if r.present(frame) else None
+    r.end_message(frame, False)
+    return Header(request_id=request_id, tenant=tenant)
+
+
+@dataclass(kw_only=True)
+class Item:
+    """The object shop.Item."""
+
+    sku: Sku
+    quantity: int
+
+
+def _write_item(w: _Writer, m: Item) -> None:
+    start = w.begin_message(0, False)
+    w.fixed_binary(m.sku, 12)
+    w.pack(_S_I, m.quantity)
+    w.end_message(start, False)
+
+
+def _read_item(r: _Reader) -> Item:
+    frame = r.begin_message(0, False)
+    sku = r.fixed_binary(12)
+    quantity = r.unpack(_S_I)[0]
+    r.end_message(frame, False)
+    return Item(sku=sku, quantity=quantity)
+
+
+@dataclass(kw_only=True)
+class PlaceOrder(Header):
+    """The inbound message shop.PlaceOrder."""
+
+    items: list[Item]
+    note: Optional[bytes] = None
+    # Deprecated: the field is deprecated in the schema.
+    coupon: Optional[int] = None
+
+
+def _write_place_order(w: _Writer, m: PlaceOrder) -> None:
+    start = w.begin_message(3, False)
+    w.pack(_S_I, m.request_id)
+    if m.tenant is not None:
+        w.set_optional(start, 0)
+        w.binary(m.tenant)
+    w.array_len(len(m.items))
+    for item in m.items:
+        _write_item(w, item)
+    if m.note is not None:
+        w.set_optional(start, 1)
+        w.long_binary(m.note)
+    if m.coupon is not None:
+        w.set_optional(start, 2)
+        w.pack(_S_H, m.coupon)
+    w.end_message(start, False)
+
+
+def _read_place_order(r: _Reader) -> PlaceOrder:
+    frame = r.begin_message(3, False)
+    request_id = r.unpack(_S_I)[0]
+    tenant = r.binary() if r.present(frame) else None
+    items = [_read_item(r) for _ in range(r.array_len())]
+    note = r.long_binary() if r.present(frame) else None
+    coupon = r.unpack(_S_H)[0] if r.present(frame) else None
+    r.end_message(frame, False)
+    return PlaceOrder(request_id=request_id, tenant=tenant, items=items, note=note, coupon=coupon)
+
+
+def encode_place_order(m: PlaceOrder) -> bytes:
+    return _encode(_write_place_order, m)
+
+
+def decode_place_order(payload: bytes) -> PlaceOrder:
+    return _read_place_order(_Reader(payload))
+
+
+@dataclass(kw_only=True)
+class Receipt:
+    """The outbound message shop.Receipt."""
+
+    order_id: int
+    total: int
+
+
+def _write_receipt(w: _Writer, m: Receipt) -> None:
+    start = w.begin_message(0, False)
+    w.pack(_S_Qq, m.order_id, m.total)
+    w.end_message(start, False)
+
+
+def _read_receipt(r: _Reader) -> Receipt:
+    frame = r.begin_message(0, False)
+    order_id, total = r.unpack(_S_Qq)
+    r.end_message(frame, False)
+    return Receipt(order_id=order_id, total=total)
+
+
+def encode_receipt(m: Receipt) -> bytes:
+    return _encode(_write_receipt, m)
+
+
+def decode_receipt(payload: bytes) -> Receipt:
+    return _read_receipt(_Reader(payload))
+
+
+@dataclass(kw_only=True)
+class WatchOrder:
+    """The inbound message shop.WatchOrder."""
+
+    order_id: int
+
+
+def _write_watch_order(w: _Writer, m: WatchOrder) -> None:
+    start = w.begin_message(0, False)
+    w.pack(_S_Q, m.order_id)
+    w.end_message(start, False)
+
+
+def _read_watch_order(r: _Reader) -> WatchOrder:
+    frame = r.begin_message(0, False)
+    order_id = r.unpack(_S_Q)[0]
+    r.end_message(frame, False)
+    return WatchOrder(order_id=order_id)
+
+
+def encode_watch_order(m: WatchOrder) -> bytes:
+    return _encode(_write_watch_order, m)
+
+
+def decode_watch_order(payload: bytes) -> WatchOrder:
+    return _read_watch_order(_Reader(payload))
+
+
+@dataclass(kw_only=True)
+class OrderStatus:
+    """The outbound message shop.OrderStatus."""
+
+    order_id: int
+    status: bytes
+
+
+def _write_order_status(w: _Writer, m: OrderStatus) -> None:
+    start = w.begin_message(0, False)
+    w.pack(_S_Q, m.order_id)
+    w.binary(m.status)
+    w.end_message(start, False)
+
+
+def _read_order_status(r: _Reader) -> OrderStatus:
+    frame = r.begin_message(0, False)
+    order_id = r.unpack(_S_Q)[0]
+    status = r.binary()
+    r.end_message(frame, False)
+    return OrderStatus(order_id=order_id, status=status)
+
+
+def encode_order_status(m: OrderStatus) -> bytes:
+    return _encode(_write_order_status, m)
+
+
+def decode_order_status(payload: bytes) -> OrderStatus:
+    return _read_order_status(_Reader(payload))
+
+
+@dataclass(kw_only=True)
+class Ping:
+    """The duplex message shop.Ping."""
+
+    seq: int
+
+
+def _write_ping(w: _Writer, m: Ping) -> None:
+    start = w.begin_message(0, False)
+    w.pack(_S_I, m.seq)
+    w.end_message(start, False)
+
+
+def _read_ping(r: _Reader) -> Ping:
+    frame = r.begin_message(0, False)
+    seq = r.unpack(_S_I)[0]
+    r.end_message(frame, False)
+    return Ping(seq=seq)
+
+
+def encode_ping(m: Ping) -> bytes:
+    return _encode(_write_ping, m)
+
+
+def decode_ping(payload: bytes) -> Ping:
+    return _read_ping(_Reader(payload))
+
+
+@dataclass(kw_only=True)
+class _InternalInboundHello:
+    """The internal message inbound Hello."""
+
+    minVersion: int
+    currVersion: int
+    fingerprint: Optional[bytes] = None
+
+
+def _write_internal_inbound_hello(w: _Writer, m: _InternalInboundHello) -> None:
+    start = w.begin_message(1, False)
+    w.pack(_S_ii, m.minVersion, m.currVersion)
+    if m.fingerprint is not None:
+        w.set_optional(start, 0)
+        w.fixed_binary(m.fingerprint, 32)
+    w.end_message(start, False)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundHelloSchemaFields:
+    """An object of the field fields of _InternalOutboundHelloSchema."""
+
+    name: bytes
+    type: int
+    extra: bytes
+    optional: int
+    aliases: list[bytes]
+
+
+def _read_internal_outbound_hello_schema_fields(r: _Reader) -> _InternalOutboundHelloSchemaFields:
+    frame = r.begin_message(0, False)
+    name = r.binary()
+    type = r.unpack(_S_H)[0]
+    extra = r.long_binary()
+    optional = r.unpack(_S_H)[0]
+    aliases = [r.binary() for _ in range(r.array_len())]
+    r.end_message(frame, False)
+    return _InternalOutboundHelloSchemaFields(name=name, type=type, extra=extra, optional=optional, aliases=aliases)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundHelloSchema:
+    """An object of the field schema of _InternalOutboundHello."""
+
+    id: int
+    internal: int
+    direction: int
+    flags: int
+    maxSize: int
+    name: bytes
+    fields: list[_InternalOutboundHelloSchemaFields]
+
+
+def _read_internal_outbound_hello_schema(r: _Reader) -> _InternalOutboundHelloSchema:
+    frame = r.begin_message(0, False)
+    id, internal, direction, flags, maxSize = r.unpack(_S_IHHHI)
+    name = r.binary()
+    fields = [_read_internal_outbound_hello_schema_fields(r) for _ in range(r.array_len())]
+    r.end_message(frame, False)
+    return _InternalOutboundHelloSchema(id=id, internal=internal, direction=direction, flags=flags, maxSize=maxSize, name=name, fields=fields)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundHelloMethods:
+    """An object of the field methods of _InternalOutboundHello."""
+
+    name: bytes
+    request: int
+    response: int
+
+
+def _read_internal_outbound_hello_methods(r: _Reader) -> _InternalOutboundHelloMethods:
+    frame = r.begin_message(0, False)
+    name = r.binary()
+    request, response = r.unpack(_S_II)
+    r.end_message(frame, False)
+    return _InternalOutboundHelloMethods(name=name, request=request, response=response)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundHelloTypes:
+    """An object of the field types of _InternalOutboundHello."""
+
+    name: bytes
+    type: int
+    extra: bytes
+
+
+def _read_internal_outbound_hello_types(r: _Reader) -> _InternalOutboundHelloTypes:
+    frame = r.begin_message(0, False)
+    name = r.binary()
+    type = r.unpack(_S_H)[0]
+    extra = r.long_binary()
+    r.end_message(frame, False)
+    return _InternalOutboundHelloTypes(name=name, type=type, extra=extra)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundHelloConstants:
+    """An object of the field constants of _InternalOutboundHello."""
+
+    name: bytes
+    value: int
+
+
+def _read_internal_outbound_hello_constants(r: _Reader) -> _InternalOutboundHelloConstants:
+    frame = r.begin_message(0, False)
+    name = r.binary()
+    value = r.unpack(_S_Q)[0]
+    r.end_message(frame, False)
+    return _InternalOutboundHelloConstants(name=name, value=value)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundHello:
+    """The internal message outbound Hello."""
+
+    minVersion: int
+    currVersion: int
+    fingerprint: bytes
+    schema: list[_InternalOutboundHelloSchema]
+    methods: list[_InternalOutboundHelloMethods]
+    types: Optional[list[_InternalOutboundHelloTypes]] = None
+    constants: Optional[list[_InternalOutboundHelloConstants]] = None
+
+
+def _read_internal_outbound_hello(r: _Reader) -> _InternalOutboundHello:
+    frame = r.begin_message(2, False)
+    minVersion, currVersion = r.unpack(_S_ii)
+    fingerprint = r.fixed_binary(32)
+    schema = [_read_internal_outbound_hello_schema(r) for _ in range(r.array_len())]
+    methods = [_read_internal_outbound_hello_methods(r) for _ in range(r.array_len())]
+    types = [_read_internal_outbound_hello_types(r) for _ in range(r.array_len())] if r.present(frame) else None
+    constants = [_read_internal_outbound_hello_constants(r) for _ in range(r.array_len())] if r.present(frame) else None
+    r.end_message(frame, False)
+    return _InternalOutboundHello(minVersion=minVersion, currVersion=currVersion, fingerprint=fingerprint, schema=schema, methods=methods, types=types, constants=constants)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundProtocolError:
+    """The internal message outbound ProtocolError."""
+
+    message: bytes
+
+
+def _read_internal_outbound_protocol_error(r: _Reader) -> _InternalOutboundProtocolError:
+    frame = r.begin_message(0, False)
+    message = r.binary()
+    r.end_message(frame, False)
+    return _InternalOutboundProtocolError(message=message)
+
+
+@dataclass(kw_only=True)
+class _InternalInboundRpcRequest:
+    """The internal message inbound RpcRequest."""
+
+    callId: int
+    method: bytes
+    payload: bytes
+
+
+def _write_internal_inbound_rpc_request(w: _Writer, m: _InternalInboundRpcRequest) -> None:
+    start = w.begin_message(0, False)
+    w.pack(_S_I, m.callId)
+    w.binary(m.method)
+    w.long_binary(m.payload)
+    w.end_message(start, False)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundRpcResponse:
+    """The internal message outbound RpcResponse."""
+
+    callId: int
+    payload: bytes
+
+
+def _read_internal_outbound_rpc_response(r: _Reader) -> _InternalOutboundRpcResponse:
+    frame = r.begin_message(0, False)
+    callId = r.unpack(_S_I)[0]
+    payload = r.long_binary()
+    r.end_message(frame, False)
+    return _InternalOutboundRpcResponse(callId=callId, payload=payload)
+
+
+@dataclass(kw_only=True)
+class _InternalOutboundRpcError:
+    """The internal message outbound RpcError."""
+
+    callId: int
+    message: bytes
+
+
+def _read_internal_outbound_rpc_error(r: _Reader) -> _InternalOutboundRpcError:
+    frame = r.begin_message(0, False)
+    callId = r.unpack(_S_I)[0]
+    message = r.binary()
+    r.end_message(frame, False)
+    return _InternalOutboundRpcError(callId=callId, message=message)
+
+
+class Client(BaseClient):
+    """Sends and receives the messages of shop.schema, handshake must succeed before anything else."""
+
+    def send_place_order(self, m: PlaceOrder) -> None:
+        """Sends inbound shop.PlaceOrder to the server."""
+        self._send(MessageId.PLACE_ORDER, encode_place_order(m), False)
+
+    def on_receipt(self, handler: Callable[[Receipt], None]) -> None:
+        """Sets the handler of outbound shop.Receipt."""
+        self._handle(MessageId.RECEIPT, False, decode_receipt, handler)
+
+    def send_watch_order(self, m: WatchOrder) -> None:
+        """Sends inbound shop.WatchOrder to the server."""
+        self._send(MessageId.WATCH_ORDER, encode_watch_order(m), False)
+
+    def on_order_status(self, handler: Callable[[OrderStatus], None]) -> None:
+        """Sets the handler of outbound shop.OrderStatus."""
+        self._handle(MessageId.ORDER_STATUS, False, decode_order_status, handler)
+
+    def send_ping(self, m: Ping) -> None:
+        """Sends inbound shop.Ping to the server."""
+        self._send(MessageId.PING, encode_ping(m), False)
+
+    def on_ping(self, handler: Callable[[Ping], None]) -> None:
+        """Sets the handler of outbound shop.Ping."""
+        self._handle(MessageId.PING, False, decode_ping, handler)
+
+    def orders_place(self, req: PlaceOrder) -> Receipt:
+        """Calls rpc Place(shop.PlaceOrder) returns (shop.Receipt)."""
+        res = self._call("shop.Orders.Place", encode_place_order(req), False, False)
+        return decode_receipt(res)
+
+    # shop.Orders.Watch is a streaming method, which the Python client doesn't support yet
@@ -0,0 +1,137 @@
+package shop
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	schemaipc "github.com/benjamin-larsen/goschemaipc"
+)
+
+func TestGeneratedCode(t *testing.T) {
+	s := &schemaipc.Server{
+		SchemaPath:     "shop.schema",
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	RegisterOrdersPlace(s, func(req *PlaceOrder, c *schemaipc.Conn) (Receipt, error) {
+		var total int64
+
+		for _, item := range req.Items {
+			total += int64(item.Quantity)
+		}
+
+		return Receipt{OrderId: uint64(req.RequestId), Total: total}, nil
+	})
+
+	RegisterOrdersWatch(s, func(stream *schemaipc.ServerStream[WatchOrder, OrderStatus]) error {
+		req, err := stream.Recv()
+
+		if err != nil {
+			return err
+		}
+
+		for _, status := range []string{"packed", "shipped"} {
+			err = stream.Send(OrderStatus{OrderId: req.OrderId, Status: []byte(status)})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	RegisterPing(s, func(msg *Ping, c *schemaipc.Conn) error {
+		return c.Send(OutboundPingSignature, Ping{Seq: msg.Seq + 1})
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := &schemaipc.Client{
+		SchemaPath: "shop.schema",
+	}
+
+	pong := make(chan uint32, 1)
+
+	HandlePing(c, func(msg *Ping, c *schemaipc.Client) error {
+		pong <- msg.Seq
+		return nil
+	})
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go c.Run()
+
+	orders := OrdersClient{Client: c}
+
+	receipt, err := orders.Place(&PlaceOrder{
+		Header: Header{RequestId: 7},
+		Items: []Item{
+			{Sku: Sku([]byte("sku-00000001")), Quantity: 2},
+			{Sku: Sku([]byte("sku-00000002")), Quantity: 3},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.OrderId != 7 || receipt.Total != 5 {
+		t.Errorf("unexpected receipt: %+v", receipt)
+	}
+
+	stream, err := orders.Watch()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stream.Send(&WatchOrder{OrderId: 7})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []string
+
+	for {
+		var status OrderStatus
+
+		err = stream.Recv(&status)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statuses = append(statuses, string(status.Status))
+	}
+
+	if fmt.Sprint(statuses) != "[packed shipped]" {
+		t.Errorf("unexpected statuses: %v", statuses)
+	}
+
+	err = c.Send(InboundPingSignature, Ping{Seq: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seq := <-pong; seq != 2 {
+		t.Errorf("expected pong 2, got %d", seq)
+	}
+}
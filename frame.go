@@ -0,0 +1,281 @@
+package schemaipc
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var nullHeader = ProtocolHeader{}
+
+func readHeader(r io.Reader) (ProtocolHeader, error) {
+	var rawHeader [8]byte
+
+	bytesRead, err := io.ReadFull(r, rawHeader[:])
+
+	if err != nil {
+		return nullHeader, err
+	}
+
+	if bytesRead != 8 {
+		return nullHeader, ErrHeaderLength
+	}
+
+	return ProtocolHeader{
+		PacketLength: binary.LittleEndian.Uint32(rawHeader[:4]),
+		MessageType:  binary.LittleEndian.Uint32(rawHeader[4:]),
+	}, nil
+}
+
+// readPayload allocates every payload, so []byte fields decoded with encoder.DecodeZeroCopy can alias it.
+// Connections that decode with encoder.DecodeCopy read the payloads of user messages with reusePayload instead.
+func readPayload(r io.Reader, len uint32) ([]byte, error) {
+	payload := make([]byte, len)
+
+	bytesRead, err := io.ReadFull(r, payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if bytesRead != int(len) {
+		return nil, ErrMsgReadLength
+	}
+
+	return payload, nil
+}
+
+// reusePayload reads a payload into buf, which is grown as needed and overwritten by the next payload
+func reusePayload(r io.Reader, buf *[]byte, len uint32) ([]byte, error) {
+	if uint32(cap(*buf)) < len {
+		*buf = make([]byte, len)
+	}
+
+	payload := (*buf)[:len]
+
+	_, err := io.ReadFull(r, payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// messageLimit returns the largest payload accepted for a message, falling back to the connection's limit
+func messageLimit(descriptor schema.MessageDescriptor, fallback uint32) uint32 {
+	if descriptor.Message.Options.MaxSize != 0 {
+		return descriptor.Message.Options.MaxSize
+	}
+
+	return fallback
+}
+
+// envelopeLimit returns the largest RpcRequest, RpcResponse or StreamData frame accepted. Their payloads are
+// limited by the request (or response) message of their method, which is only known once the frame is decoded,
+// so the frame may be as large as the largest of those limits plus the envelope around it. A fallback of 0 means
+// unlimited, the frame is then only limited if every method sets its own limit.
+func envelopeLimit(envelope schema.MessageDescriptor, registry *schema.MessageDescriptorRegistry, requests bool, fallback uint32) uint32 {
+	limit := fallback
+	var nameLen int
+
+	for name, m := range registry.Methods {
+		id := m.Response
+
+		if requests {
+			id = m.Request
+		}
+
+		methodLimit := messageLimit(registry.Descriptors[id], fallback)
+
+		if methodLimit == 0 {
+			return 0
+		}
+
+		limit = max(limit, methodLimit)
+		nameLen = max(nameLen, len(name))
+	}
+
+	if limit == 0 {
+		return 0
+	}
+
+	return limit + envelope.GetFixedSize() + uint32(nameLen)
+}
+
+// isEnvelope reports whether descriptor is an internal message carrying the payload of an RPC method
+func isEnvelope(descriptor schema.MessageDescriptor) bool {
+	if !descriptor.Internal {
+		return false
+	}
+
+	switch descriptor.Message.Name {
+	case "RpcRequest", "RpcResponse", "StreamData":
+		return true
+	}
+
+	return false
+}
+
+// carrying returns an envelope that is written at the priority of the message whose payload it carries
+func carrying(envelope, message schema.MessageDescriptor) schema.MessageDescriptor {
+	envelope.Message.Options.Priority = message.Message.Options.Priority
+
+	return envelope
+}
+
+// encodePayload encodes v for the wire, rejecting it if it exceeds the size limit of the message
+// and compressing it if the message is compressed
+func encodePayload(descriptor schema.MessageDescriptor, v any) ([]byte, error) {
+	payload, err := encoder.Encode(descriptor, v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	options := descriptor.Message.Options
+
+	if options.MaxSize != 0 && uint32(len(payload)) > options.MaxSize {
+		return nil, ErrMsgLength
+	}
+
+	if !options.Compress {
+		return payload, nil
+	}
+
+	var buf bytes.Buffer
+
+	err = compress(&buf, payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func compress(dst io.Writer, payload []byte) error {
+	w, err := flate.NewWriter(dst, flate.DefaultCompression)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(payload)
+
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+// frames larger than this aren't pooled, so one large message doesn't keep its buffer alive
+const maxPooledFrame = 64 << 10
+
+var framePool = sync.Pool{
+	New: func() any {
+		return new(encoder.Writer)
+	},
+}
+
+// encodeFrame encodes v behind its header into a pooled writer, so the frame is written in one go without
+// allocating, the writer must be given back with releaseFrame once the frame is written
+func encodeFrame(descriptor schema.MessageDescriptor, v any) (*encoder.Writer, error) {
+	w := framePool.Get().(*encoder.Writer)
+	w.Reset()
+
+	// the header is filled in once the length of the payload is known
+	_, err := w.GrowBytes(8)
+
+	if err == nil {
+		err = encodeFramePayload(w, descriptor, v)
+	}
+
+	if err != nil {
+		releaseFrame(w)
+		return nil, err
+	}
+
+	frame := w.Bytes()
+
+	binary.LittleEndian.PutUint32(frame[:4], uint32(len(frame)-8))
+	binary.LittleEndian.PutUint32(frame[4:], descriptor.ID)
+
+	return w, nil
+}
+
+// encodeFramePayload writes the payload of a frame like encodePayload, compressed payloads are still encoded
+// into their own buffer first
+func encodeFramePayload(w *encoder.Writer, descriptor schema.MessageDescriptor, v any) error {
+	options := descriptor.Message.Options
+
+	if options.Compress {
+		payload, err := encoder.Encode(descriptor, v)
+
+		if err != nil {
+			return err
+		}
+
+		if options.MaxSize != 0 && uint32(len(payload)) > options.MaxSize {
+			return ErrMsgLength
+		}
+
+		return compress(w, payload)
+	}
+
+	err := w.Encode(descriptor, v)
+
+	if err != nil {
+		return err
+	}
+
+	if options.MaxSize != 0 && uint32(w.Len()-8) > options.MaxSize {
+		return ErrMsgLength
+	}
+
+	return nil
+}
+
+func releaseFrame(w *encoder.Writer) {
+	if cap(w.Bytes()) > maxPooledFrame {
+		return
+	}
+
+	framePool.Put(w)
+}
+
+// decodePayload decompresses a payload read off the wire if the message is compressed,
+// payloads of (or decompressing to) more than limit bytes are rejected. A limit of 0 means unlimited.
+func decodePayload(descriptor schema.MessageDescriptor, payload []byte, limit uint32) ([]byte, error) {
+	if !descriptor.Message.Options.Compress {
+		if limit != 0 && uint32(len(payload)) > limit {
+			return nil, ErrMsgLength
+		}
+
+		return payload, nil
+	}
+
+	var r io.Reader = flate.NewReader(bytes.NewReader(payload))
+
+	if limit != 0 {
+		r = io.LimitReader(r, int64(limit)+1)
+	}
+
+	res, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limit != 0 && uint32(len(res)) > limit {
+		return nil, ErrMsgLength
+	}
+
+	return res, nil
+}
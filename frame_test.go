@@ -0,0 +1,106 @@
+package schemaipc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestEncodeFrame(t *testing.T) {
+	type echo struct {
+		Data []byte `ipc:"data"`
+	}
+
+	for _, compress := range []bool{false, true} {
+		descriptor := schema.MessageDescriptor{
+			ID: 9,
+			Message: schema.SchemaMessage{
+				Direction: schema.OutboundMessage,
+				Name:      "Echo",
+				Fields:    []schema.MessageField{{Name: "data", Type: schema.TypeDynamicBinary}},
+				Options:   schema.MessageOptions{MaxSize: 64, Compress: compress},
+			},
+		}
+
+		value := echo{Data: bytes.Repeat([]byte("echo"), 8)}
+
+		frame, err := encodeFrame(descriptor, value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := frame.Bytes()
+
+		if binary.LittleEndian.Uint32(data[:4]) != uint32(len(data)-8) || binary.LittleEndian.Uint32(data[4:8]) != 9 {
+			t.Errorf("unexpected header: %x", data[:8])
+		}
+
+		payload, err := decodePayload(descriptor, data[8:], 0)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res echo
+
+		reader := encoder.NewReader(payload, descriptor)
+		err = reader.Decode(&res)
+
+		if err != nil || !bytes.Equal(res.Data, value.Data) {
+			t.Errorf("unexpected result: %q, %v", res.Data, err)
+		}
+
+		releaseFrame(frame)
+
+		// the size limit applies to the payload before it is compressed
+		_, err = encodeFrame(descriptor, echo{Data: bytes.Repeat([]byte("echo"), 16)})
+
+		if !errors.Is(err, ErrMsgLength) {
+			t.Errorf("expected ErrMsgLength, got %v", err)
+		}
+	}
+}
+
+func TestEnvelopeLimit(t *testing.T) {
+	r := schema.MessageDescriptorRegistry{}
+
+	err := r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.RegisterSchema(parseTestSchema(t, uploadSchema))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope := r.Descriptors[r.InternalSignatureMap["inbound RpcRequest"]]
+	overhead := envelope.GetFixedSize() + uint32(len("Files.Archive"))
+
+	tests := []struct {
+		requests bool
+		fallback uint32
+		expected uint32
+	}{
+		// the largest request of a method is larger than the connection's limit
+		{true, 1024, 8192 + overhead},
+		// every request sets its own limit, which applies on an unlimited connection too
+		{true, 0, 8192 + overhead},
+		{false, 1024, 1024 + overhead},
+		// a response without a limit of its own on an unlimited connection
+		{false, 0, 0},
+	}
+
+	for _, test := range tests {
+		if limit := envelopeLimit(envelope, &r, test.requests, test.fallback); limit != test.expected {
+			t.Errorf("requests: %t, fallback: %d: expected %d, got %d", test.requests, test.fallback, test.expected, limit)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package gogen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+const encoderImport = "github.com/benjamin-larsen/goschemaipc/encoder"
+
+// The generated MarshalIPC and UnmarshalIPC methods write and read the wire format directly, in the same way
+// as the reflection based encoder. Nested objects are encoded by their own methods, or inline if anonymous.
+
+const checkErr = "\nif err != nil {\nreturn err\n}\n\n"
+
+// goField is a field of a message with embedded objects flattened, expr is how the Go code reaches it
+type goField struct {
+	field schema.MessageField
+	expr  string
+}
+
+func (g *generator) flatten(fields []schema.MessageField, prefix string) []goField {
+	var res []goField
+
+	for _, field := range fields {
+		if !field.Embedded {
+			res = append(res, goField{field: field, expr: prefix + "." + schema.PascalName(field.Name)})
+			continue
+		}
+
+		embedded := nestedMessage(field.Extra)
+		path := prefix + "." + schema.PascalName(field.Name)
+
+		if embedded.Name != "" {
+			path = prefix + "." + g.goName(embedded.Name)
+		}
+
+		res = append(res, g.flatten(embedded.Fields, path)...)
+	}
+
+	return res
+}
+
+func countOptional(fields []goField) uint32 {
+	var count uint32
+
+	for _, f := range fields {
+		if f.field.Optional {
+			count++
+		}
+	}
+
+	return count
+}
+
+// codec writes the IPC methods of a generated struct, only messages get IPCLayout so objects are never
+// used on their own
+func (g *generator) codec(message schema.SchemaMessage) {
+	name := g.goName(message.Name)
+
+	g.usesEncoder = true
+
+	if message.Direction != schema.ObjectDef {
+		fmt.Fprintf(&g.buf, "// IPCLayout returns the layout hash of %s the IPC methods were generated for\n", message.Name)
+		fmt.Fprintf(&g.buf, "func (m %s) IPCLayout() uint64 {\nreturn %#x\n}\n\n", name, message.Layout())
+	}
+
+	var b strings.Builder
+
+	g.tmp = 0
+	g.encodeObject(&b, "m", message)
+
+	fmt.Fprintf(&g.buf, "// MarshalIPC writes m in the wire format of %s\n", message.Name)
+	fmt.Fprintf(&g.buf, "func (m %s) MarshalIPC(w *encoder.Writer) error {\n%sreturn nil\n}\n\n", name, trimBlocks(b.String()))
+
+	b.Reset()
+
+	g.tmp = 0
+	fixed, terms := g.sizeObject(&b, "m", message)
+
+	fmt.Fprintf(&g.buf, "// SizeIPC returns the number of bytes MarshalIPC writes for m\n")
+	fmt.Fprintf(&g.buf, "func (m %s) SizeIPC() int {\nsize := %s\n\n%sreturn size\n}\n\n", name, sumSize(fixed, terms), trimBlocks(b.String()))
+
+	b.Reset()
+
+	g.tmp = 0
+	g.decodeObject(&b, "m", message)
+
+	fmt.Fprintf(&g.buf, "// UnmarshalIPC reads m from the wire format of %s\n", message.Name)
+	fmt.Fprintf(&g.buf, "func (m *%s) UnmarshalIPC(r *encoder.Reader) error {\n%sreturn nil\n}\n\n", name, trimBlocks(b.String()))
+}
+
+// trimBlocks removes the blank line every statement is followed by at the end of blocks
+func trimBlocks(code string) string {
+	return strings.ReplaceAll(code, "\n\n}", "\n}")
+}
+
+func (g *generator) nextTmp() int {
+	g.tmp++
+	return g.tmp
+}
+
+func (g *generator) encodeObject(b *strings.Builder, expr string, message schema.SchemaMessage) {
+	fields := g.flatten(message.Fields, expr)
+	start := fmt.Sprintf("start%d", g.nextTmp())
+
+	fmt.Fprintf(b, "%s, err := w.BeginMessage(%d, %t)\n%s", start, countOptional(fields), message.Extensible, checkErr)
+
+	var opt uint32
+
+	for _, f := range fields {
+		if !f.field.Optional {
+			g.encodeValue(b, f.expr, f.field)
+			continue
+		}
+
+		fmt.Fprintf(b, "if %s {\nw.SetOptional(%s, %d)\n", g.present(f.expr, f.field), start, opt)
+		g.encodeValue(b, f.expr, f.field)
+		b.WriteString("}\n\n")
+
+		opt++
+	}
+
+	fmt.Fprintf(b, "w.EndMessage(%s)\n\n", start)
+}
+
+func (g *generator) encodeValue(b *strings.Builder, expr string, field schema.MessageField) {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		fmt.Fprintf(b, "err = w.WriteFixedBinary(%s[:], %d)\n%s", expr, field.Extra.(int), checkErr)
+	case schema.TypeDynamicBinary:
+		fmt.Fprintf(b, "err = w.WriteBinary(%s)\n%s", expr, checkErr)
+	case schema.TypeLongBinary:
+		fmt.Fprintf(b, "err = w.WriteLongBinary(%s)\n%s", expr, checkErr)
+	case schema.TypeUInt64:
+		fmt.Fprintf(b, "w.WriteUInt64(%s)\n\n", expr)
+	case schema.TypeInt64:
+		fmt.Fprintf(b, "w.WriteInt64(%s)\n\n", expr)
+	case schema.TypeUInt32:
+		fmt.Fprintf(b, "w.WriteUInt32(%s)\n\n", expr)
+	case schema.TypeInt32:
+		fmt.Fprintf(b, "w.WriteInt32(%s)\n\n", expr)
+	case schema.TypeUInt16:
+		fmt.Fprintf(b, "w.WriteUInt16(%s)\n\n", expr)
+	case schema.TypeInt16:
+		fmt.Fprintf(b, "w.WriteInt16(%s)\n\n", expr)
+	case schema.TypeObject:
+		g.encodeNested(b, expr, nestedMessage(field.Extra))
+	case schema.TypeArray:
+		{
+			i := fmt.Sprintf("i%d", g.nextTmp())
+
+			fmt.Fprintf(b, "err = w.WriteArrayLen(len(%s))\n%s", expr, checkErr)
+			fmt.Fprintf(b, "for %s := range %s {\n", i, expr)
+
+			item := fmt.Sprintf("%s[%s]", expr, i)
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				g.encodeValue(b, item, elem)
+			} else {
+				g.encodeNested(b, item, nestedMessage(field.Extra))
+			}
+
+			b.WriteString("}\n\n")
+		}
+	}
+}
+
+func (g *generator) encodeNested(b *strings.Builder, expr string, message schema.SchemaMessage) {
+	if message.Name != "" {
+		fmt.Fprintf(b, "err = %s.MarshalIPC(w)\n%s", expr, checkErr)
+		return
+	}
+
+	b.WriteString("{\n")
+	g.encodeObject(b, expr, message)
+	b.WriteString("}\n\n")
+}
+
+// present is the condition under which an optional field is sent, the same as reflect.Value.IsZero being false
+func (g *generator) present(expr string, field schema.MessageField) string {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		return fmt.Sprintf("%s != [%d]byte{}", expr, field.Extra.(int))
+	case schema.TypeDynamicBinary, schema.TypeLongBinary, schema.TypeArray:
+		return expr + " != nil"
+	case schema.TypeObject:
+		{
+			var conds []string
+
+			for _, f := range g.flatten(nestedMessage(field.Extra).Fields, expr) {
+				conds = append(conds, g.present(f.expr, f.field))
+			}
+
+			if len(conds) == 0 {
+				return "false"
+			}
+
+			return "(" + strings.Join(conds, " || ") + ")"
+		}
+	default:
+		return expr + " != 0"
+	}
+}
+
+// sizeObject writes the loops adding the size of an object to size, and returns its fixed size and the terms
+// of its variable size outside of loops
+func (g *generator) sizeObject(b *strings.Builder, expr string, message schema.SchemaMessage) (int, []string) {
+	fields := g.flatten(message.Fields, expr)
+	fixed := int(schema.MessageDescriptor{OptionalCount: countOptional(fields)}.OptFlagLength())
+
+	if message.Extensible {
+		fixed += schema.ExtensibleHeaderSize
+	}
+
+	var terms []string
+
+	for _, f := range fields {
+		if !f.field.Optional {
+			n, fieldTerms := g.sizeValue(b, f.expr, f.field)
+			fixed += n
+			terms = append(terms, fieldTerms...)
+
+			continue
+		}
+
+		var loops strings.Builder
+
+		n, fieldTerms := g.sizeValue(&loops, f.expr, f.field)
+
+		fmt.Fprintf(b, "if %s {\n%s%s}\n\n", g.present(f.expr, f.field), addSize(n, fieldTerms), loops.String())
+	}
+
+	return fixed, terms
+}
+
+// sizeValue is sizeObject for a single value
+func (g *generator) sizeValue(b *strings.Builder, expr string, field schema.MessageField) (int, []string) {
+	switch field.Type {
+	case schema.TypeDynamicBinary:
+		return 2, []string{"len(" + expr + ")"}
+	case schema.TypeLongBinary:
+		return 4, []string{"len(" + expr + ")"}
+	case schema.TypeObject:
+		return g.sizeNested(b, expr, nestedMessage(field.Extra))
+	case schema.TypeArray:
+		{
+			var loops strings.Builder
+
+			i := fmt.Sprintf("i%d", g.nextTmp())
+			item := fmt.Sprintf("%s[%s]", expr, i)
+
+			var n int
+			var terms []string
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				n, terms = g.sizeValue(&loops, item, elem)
+			} else {
+				n, terms = g.sizeNested(&loops, item, nestedMessage(field.Extra))
+			}
+
+			// elements of a fixed size don't need a loop
+			if len(terms) == 0 && loops.Len() == 0 {
+				if n == 0 {
+					return 2, nil
+				}
+
+				return 2, []string{fmt.Sprintf("len(%s)*%d", expr, n)}
+			}
+
+			fmt.Fprintf(b, "for %s := range %s {\n%s%s}\n\n", i, expr, addSize(n, terms), loops.String())
+
+			return 2, nil
+		}
+	default:
+		return int(field.Type.GetFixedSize(field.Extra)), nil
+	}
+}
+
+func (g *generator) sizeNested(b *strings.Builder, expr string, message schema.SchemaMessage) (int, []string) {
+	if message.Name != "" {
+		return 0, []string{expr + ".SizeIPC()"}
+	}
+
+	return g.sizeObject(b, expr, message)
+}
+
+// sumSize is the expression adding up a fixed size and variable terms
+func sumSize(n int, terms []string) string {
+	if n == 0 && len(terms) > 0 {
+		return strings.Join(terms, " + ")
+	}
+
+	return strings.Join(append([]string{fmt.Sprint(n)}, terms...), " + ")
+}
+
+// addSize is the statement adding a fixed size and variable terms to size, if there is anything to add
+func addSize(n int, terms []string) string {
+	if n == 0 && len(terms) == 0 {
+		return ""
+	}
+
+	return "size += " + sumSize(n, terms) + "\n\n"
+}
+
+func (g *generator) decodeObject(b *strings.Builder, expr string, message schema.SchemaMessage) {
+	fields := g.flatten(message.Fields, expr)
+	frame := fmt.Sprintf("frame%d", g.nextTmp())
+
+	fmt.Fprintf(b, "%s, err := r.BeginMessage(%d, %t)\n%s", frame, countOptional(fields), message.Extensible, checkErr)
+
+	for _, f := range fields {
+		if f.field.Optional {
+			fmt.Fprintf(b, "if %s.Present() {\n", frame)
+		} else if message.Extensible {
+			fmt.Fprintf(b, "err = r.CheckRequired(&%s)\n%s", frame, checkErr)
+		}
+
+		if f.field.Deprecated {
+			fmt.Fprintf(b, "r.Deprecated(%q)\n\n", f.field.Name)
+		}
+
+		g.decodeValue(b, f.expr, f.field)
+
+		if f.field.Optional {
+			b.WriteString("}\n\n")
+		}
+	}
+
+	fmt.Fprintf(b, "r.EndMessage(%s)\n\n", frame)
+}
+
+func (g *generator) decodeValue(b *strings.Builder, expr string, field schema.MessageField) {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		fmt.Fprintf(b, "err = r.ReadFixedBinary(%s[:])\n%s", expr, checkErr)
+	case schema.TypeDynamicBinary:
+		fmt.Fprintf(b, "%s, err = r.ReadBinary()\n%s", expr, checkErr)
+	case schema.TypeLongBinary:
+		fmt.Fprintf(b, "%s, err = r.ReadLongBinary()\n%s", expr, checkErr)
+	case schema.TypeUInt64:
+		fmt.Fprintf(b, "%s, err = r.ReadUInt64()\n%s", expr, checkErr)
+	case schema.TypeInt64:
+		fmt.Fprintf(b, "%s, err = r.ReadInt64()\n%s", expr, checkErr)
+	case schema.TypeUInt32:
+		fmt.Fprintf(b, "%s, err = r.ReadUInt32()\n%s", expr, checkErr)
+	case schema.TypeInt32:
+		fmt.Fprintf(b, "%s, err = r.ReadInt32()\n%s", expr, checkErr)
+	case schema.TypeUInt16:
+		fmt.Fprintf(b, "%s, err = r.ReadUInt16()\n%s", expr, checkErr)
+	case schema.TypeInt16:
+		fmt.Fprintf(b, "%s, err = r.ReadInt16()\n%s", expr, checkErr)
+	case schema.TypeObject:
+		g.decodeNested(b, expr, nestedMessage(field.Extra))
+	case schema.TypeArray:
+		{
+			tmp := g.nextTmp()
+			n := fmt.Sprintf("n%d", tmp)
+			i := fmt.Sprintf("i%d", tmp)
+
+			fmt.Fprintf(b, "%s, err := r.ReadArrayLen()\n%s", n, checkErr)
+			fmt.Fprintf(b, "%s = make(%s, %s)\n\n", expr, g.goType(field), n)
+			fmt.Fprintf(b, "for %s := range %s {\n", i, expr)
+
+			item := fmt.Sprintf("%s[%s]", expr, i)
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				g.decodeValue(b, item, elem)
+			} else {
+				g.decodeNested(b, item, nestedMessage(field.Extra))
+			}
+
+			b.WriteString("}\n\n")
+		}
+	}
+}
+
+func (g *generator) decodeNested(b *strings.Builder, expr string, message schema.SchemaMessage) {
+	if message.Name != "" {
+		fmt.Fprintf(b, "err = %s.UnmarshalIPC(r)\n%s", expr, checkErr)
+		return
+	}
+
+	b.WriteString("{\n")
+	g.decodeObject(b, expr, message)
+	b.WriteString("}\n\n")
+}
@@ -0,0 +1,401 @@
+// Package gogen generates Go code from a schema: a struct with ipc tags for every message and object, with
+// methods that encode and decode it without reflection, the type aliases and constants of the schema,
+// signature and method name constants, and typed helpers to register handlers and call methods, so handlers
+// never spell out signatures by hand.
+package gogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+const ipcImport = "github.com/benjamin-larsen/goschemaipc"
+
+type Options struct {
+	Package string // name of the generated package
+	Source  string // file the schema was read from, named in the header of the generated file
+}
+
+type generator struct {
+	pkg     string            // package of the schema, stripped from the names it declares
+	names   map[string]string // Go identifier to the schema name it was derived from
+	buf     bytes.Buffer
+	usesIPC bool
+	// the generated code imports the encoder package
+	usesEncoder bool
+	tmp         int // counter for the names of temporary variables
+}
+
+// Generate returns the formatted Go source for the schema and everything it imports
+func Generate(s schema.Schema, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		return nil, fmt.Errorf("package name is required")
+	}
+
+	g := generator{
+		pkg:   s.Package,
+		names: make(map[string]string),
+	}
+
+	schemas := s.Files()
+
+	err := g.checkNames(schemas)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range schemas {
+		g.constants(file.Constants)
+		g.typeAliases(file.Types)
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			g.message(message)
+			g.codec(message)
+		}
+	}
+
+	for _, file := range schemas {
+		g.signatures(file.Messages)
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			g.handlers(message)
+		}
+	}
+
+	for _, file := range schemas {
+		for _, service := range file.Services {
+			g.service(service)
+		}
+	}
+
+	var out bytes.Buffer
+
+	out.WriteString("// Code generated by schemagen")
+
+	if opts.Source != "" {
+		fmt.Fprintf(&out, " from %s", opts.Source)
+	}
+
+	fmt.Fprintf(&out, ". DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+
+	out.WriteString("import (\n")
+
+	if g.usesIPC {
+		fmt.Fprintf(&out, "schemaipc %q\n", ipcImport)
+	}
+
+	if g.usesEncoder {
+		fmt.Fprintf(&out, "%q\n", encoderImport)
+	}
+
+	out.WriteString(")\n\n")
+
+	out.Write(g.buf.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+// goName returns the Go identifier of a declared name, names from other packages keep their package as a prefix
+func (g *generator) goName(name string) string {
+	if g.pkg != "" {
+		name = strings.TrimPrefix(name, g.pkg+".")
+	}
+
+	return schema.PascalName(name)
+}
+
+func (g *generator) declare(ident, name string) error {
+	if existing, exists := g.names[ident]; exists && existing != name {
+		return fmt.Errorf("%s and %s both generate the Go identifier %s", existing, name, ident)
+	}
+
+	g.names[ident] = name
+
+	return nil
+}
+
+// checkNames reports declarations that would generate the same Go identifier
+func (g *generator) checkNames(schemas []schema.Schema) error {
+	for _, file := range schemas {
+		for _, constant := range file.Constants {
+			err := g.declare(g.goName(constant.Name), constant.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, alias := range file.Types {
+			err := g.declare(g.goName(alias.Name), alias.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, message := range file.Messages {
+			err := g.declare(g.goName(message.Name), message.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) constants(constants []schema.Constant) {
+	if len(constants) == 0 {
+		return
+	}
+
+	g.buf.WriteString("const (\n")
+
+	for _, constant := range constants {
+		fmt.Fprintf(&g.buf, "%s = %d\n", g.goName(constant.Name), constant.Value)
+	}
+
+	g.buf.WriteString(")\n\n")
+}
+
+func (g *generator) typeAliases(aliases []schema.TypeAlias) {
+	for _, alias := range aliases {
+		fmt.Fprintf(&g.buf, "type %s = %s\n\n", g.goName(alias.Name), g.goType(alias.Field))
+	}
+}
+
+func nestedMessage(extra any) schema.SchemaMessage {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e.Message
+	case schema.SchemaMessage:
+		return e
+	default:
+		return schema.SchemaMessage{}
+	}
+}
+
+// goType returns the Go type of a field, named objects and type aliases are referred to by name
+func (g *generator) goType(field schema.MessageField) string {
+	if field.TypeAlias != "" {
+		return g.goName(field.TypeAlias)
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		return fmt.Sprintf("[%d]byte", field.Extra.(int))
+	case schema.TypeDynamicBinary, schema.TypeLongBinary:
+		return "[]byte"
+	case schema.TypeObject:
+		return g.objectType(nestedMessage(field.Extra))
+	case schema.TypeArray:
+		{
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				return "[]" + g.goType(elem)
+			}
+
+			return "[]" + g.objectType(nestedMessage(field.Extra))
+		}
+	default:
+		return field.Type.ToString()
+	}
+}
+
+func (g *generator) objectType(message schema.SchemaMessage) string {
+	if message.Name != "" {
+		return g.goName(message.Name)
+	}
+
+	var b strings.Builder
+
+	b.WriteString("struct {\n")
+	g.writeFields(&b, message.Fields)
+	b.WriteString("}")
+
+	return b.String()
+}
+
+// wireType is the type= tag option of fields whose wire type isn't the one derived from their Go type
+func wireType(field schema.MessageField) string {
+	switch field.Type {
+	case schema.TypeLongBinary:
+		return "long_binary"
+	case schema.TypeArray:
+		{
+			if elem, ok := field.Extra.(schema.MessageField); ok && elem.Type == schema.TypeLongBinary {
+				return "array(long_binary)"
+			}
+		}
+	}
+
+	return ""
+}
+
+func (g *generator) writeFields(b *strings.Builder, fields []schema.MessageField) {
+	for _, field := range fields {
+		if field.Deprecated {
+			b.WriteString("// Deprecated: the field is deprecated in the schema.\n")
+		}
+
+		if field.Embedded && field.ObjectName() != "" {
+			fmt.Fprintf(b, "%s\n", g.goType(field))
+			continue
+		}
+
+		tag := field.Name
+
+		if field.Optional {
+			tag += ",optional"
+		}
+
+		if typ := wireType(field); typ != "" {
+			tag += ",type=" + typ
+		}
+
+		fmt.Fprintf(b, "%s %s `ipc:\"%s\"`\n", schema.PascalName(field.Name), g.goType(field), tag)
+	}
+}
+
+func (g *generator) message(message schema.SchemaMessage) {
+	name := g.goName(message.Name)
+
+	if message.Direction == schema.ObjectDef {
+		fmt.Fprintf(&g.buf, "// %s is the object %s\n", name, message.Name)
+	} else {
+		fmt.Fprintf(&g.buf, "// %s is the %s message %s\n", name, message.Direction.ToString(), message.Name)
+	}
+
+	if message.Deprecated {
+		g.buf.WriteString("//\n// Deprecated: the message is deprecated in the schema.\n")
+	}
+
+	var b strings.Builder
+
+	g.writeFields(&b, message.Fields)
+
+	fmt.Fprintf(&g.buf, "type %s struct {\n%s}\n\n", name, b.String())
+}
+
+// signatureConst is the name of the constant holding the signature of a message in a direction
+func (g *generator) signatureConst(message schema.SchemaMessage, direction schema.MessageDirection) string {
+	name := g.goName(message.Name) + "Signature"
+
+	if message.Direction == schema.DuplexMessage {
+		return schema.PascalName(direction.ToString()) + name
+	}
+
+	return name
+}
+
+// directions returns the directions a message is registered under
+func directions(message schema.SchemaMessage) []schema.MessageDirection {
+	switch message.Direction {
+	case schema.InboundMessage, schema.OutboundMessage:
+		return []schema.MessageDirection{message.Direction}
+	case schema.DuplexMessage:
+		return []schema.MessageDirection{schema.InboundMessage, schema.OutboundMessage}
+	default:
+		return nil
+	}
+}
+
+func (g *generator) signatures(messages []schema.SchemaMessage) {
+	var b strings.Builder
+
+	for _, message := range messages {
+		for _, direction := range directions(message) {
+			fmt.Fprintf(&b, "%s = %q\n", g.signatureConst(message, direction), schema.Signature(direction, message.Name))
+		}
+	}
+
+	if b.Len() > 0 {
+		fmt.Fprintf(&g.buf, "const (\n%s)\n\n", b.String())
+	}
+}
+
+func (g *generator) handlers(message schema.SchemaMessage) {
+	name := g.goName(message.Name)
+
+	for _, direction := range directions(message) {
+		g.usesIPC = true
+
+		if direction == schema.InboundMessage {
+			fmt.Fprintf(&g.buf, "// Register%s registers the handler of %s on the server\n", name, schema.Signature(direction, message.Name))
+			fmt.Fprintf(&g.buf, "func Register%s(s *schemaipc.Server, handler func(msg *%s, c *schemaipc.Conn) error) {\n", name, name)
+			fmt.Fprintf(&g.buf, "schemaipc.RegisterMessage(s, %s, handler)\n}\n\n", g.signatureConst(message, direction))
+		} else {
+			fmt.Fprintf(&g.buf, "// Handle%s registers the handler of %s on the client\n", name, schema.Signature(direction, message.Name))
+			fmt.Fprintf(&g.buf, "func Handle%s(c *schemaipc.Client, handler func(msg *%s, c *schemaipc.Client) error) {\n", name, name)
+			fmt.Fprintf(&g.buf, "schemaipc.HandleMessage(c, %s, handler)\n}\n\n", g.signatureConst(message, direction))
+		}
+	}
+}
+
+func (g *generator) service(service schema.Service) {
+	if len(service.Methods) == 0 {
+		return
+	}
+
+	g.usesIPC = true
+
+	name := g.goName(service.Name)
+	client := name + "Client"
+
+	g.buf.WriteString("const (\n")
+
+	for _, method := range service.Methods {
+		fmt.Fprintf(&g.buf, "%s%sMethod = %q\n", name, schema.PascalName(method.Name), service.MethodName(method))
+	}
+
+	g.buf.WriteString(")\n\n")
+
+	for _, method := range service.Methods {
+		methodName := schema.PascalName(method.Name)
+		constName := name + methodName + "Method"
+		request := g.goName(method.Request)
+		response := g.goName(method.Response)
+
+		fmt.Fprintf(&g.buf, "// Register%s%s registers the handler of %s on the server\n", name, methodName, method.ToString())
+
+		if method.ClientStream || method.ServerStream {
+			fmt.Fprintf(&g.buf, "func Register%s%s(s *schemaipc.Server, handler func(stream *schemaipc.ServerStream[%s, %s]) error) {\n", name, methodName, request, response)
+			fmt.Fprintf(&g.buf, "schemaipc.RegisterStream(s, %s, handler)\n}\n\n", constName)
+		} else {
+			fmt.Fprintf(&g.buf, "func Register%s%s(s *schemaipc.Server, handler func(req *%s, c *schemaipc.Conn) (%s, error)) {\n", name, methodName, request, response)
+			fmt.Fprintf(&g.buf, "schemaipc.RegisterRPC(s, %s, handler)\n}\n\n", constName)
+		}
+	}
+
+	fmt.Fprintf(&g.buf, "// %s calls the methods of %s\n", client, service.Name)
+	fmt.Fprintf(&g.buf, "type %s struct {\nClient *schemaipc.Client\n}\n\n", client)
+
+	for _, method := range service.Methods {
+		methodName := schema.PascalName(method.Name)
+		constName := name + methodName + "Method"
+		request := g.goName(method.Request)
+		response := g.goName(method.Response)
+
+		if method.ClientStream || method.ServerStream {
+			fmt.Fprintf(&g.buf, "// %s opens a stream of %s, requests are sent as %s and responses received as %s\n", methodName, method.ToString(), request, response)
+			fmt.Fprintf(&g.buf, "func (c %s) %s() (*schemaipc.ClientStream, error) {\n", client, methodName)
+			fmt.Fprintf(&g.buf, "return c.Client.OpenStream(%s)\n}\n\n", constName)
+
+			continue
+		}
+
+		fmt.Fprintf(&g.buf, "// %s calls %s\n", methodName, method.ToString())
+		fmt.Fprintf(&g.buf, "func (c %s) %s(req *%s) (*%s, error) {\n", client, methodName, request, response)
+		fmt.Fprintf(&g.buf, "var res %s\n\nerr := c.Client.Call(%s, req, &res)\n\n", response, constName)
+		g.buf.WriteString("if err != nil {\nreturn nil, err\n}\n\nreturn &res, nil\n}\n\n")
+	}
+}
@@ -0,0 +1,55 @@
+package gogen
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestGenerateIsUpToDate(t *testing.T) {
+	s, err := schema.ParseFile("../examples/shop/shop.schema")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{Package: "shop", Source: "shop.schema"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile("../examples/shop/shop.go")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(code, expected) {
+		t.Errorf("examples/shop/shop.go is out of date, run go generate ./examples/shop")
+	}
+}
+
+func TestGenerateNameCollision(t *testing.T) {
+	s, err := schema.Parse([]byte(`
+inbound user_name {
+  uint32 REQUIRED id
+}
+
+outbound UserName {
+  uint32 REQUIRED id
+}
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Generate(s, Options{Package: "users"})
+
+	if err == nil {
+		t.Error("expected user_name and UserName to collide")
+	}
+}
@@ -0,0 +1,75 @@
+/* Checks the generated C header against the golden vectors, run in this directory with
+ *
+ *     cc -std=c99 -Wall -Wextra -Wpedantic -o test_golden test_golden.c && ./test_golden
+ */
+
+#include <stdio.h>
+
+#include "golden.h"
+
+static uint8_t buf[4096];
+static uint8_t arena_buf[4096];
+
+static const char *status_name(ipc_status status)
+{
+    switch (status) {
+    case IPC_OK:
+        return "ok";
+    case IPC_ERR_OUT_OF_BOUNDS:
+        return "out of bounds";
+    case IPC_ERR_BUFFER_TOO_SMALL:
+        return "buffer too small";
+    case IPC_ERR_ARENA_TOO_SMALL:
+        return "arena too small";
+    case IPC_ERR_TOO_LONG:
+        return "too long";
+    case IPC_ERR_MISSING_REQUIRED:
+        return "missing required field";
+    case IPC_ERR_TOO_MANY_OPTIONALS:
+        return "too many optional fields";
+    }
+
+    return "unknown";
+}
+
+static int expect_status(const char *vector, const char *step, ipc_status got, ipc_status want)
+{
+    if (got != want) {
+        printf("%s: %s: expected %s, got %s\n", vector, step, status_name(want), status_name(got));
+        return 1;
+    }
+
+    return 0;
+}
+
+static int expect_payload(const char *vector, const char *step, ipc_status status, size_t len, const uint8_t *want, size_t want_len)
+{
+    if (expect_status(vector, step, status, IPC_OK)) {
+        return 1;
+    }
+
+    if (len != want_len || (len > 0 && memcmp(buf, want, len) != 0)) {
+        printf("%s: %s: payload differs from the vector\n", vector, step);
+        return 1;
+    }
+
+    return 0;
+}
+
+#include "vectors.h"
+
+int main(void)
+{
+    size_t i;
+    int failed = 0;
+
+    for (i = 0; i < sizeof vectors / sizeof vectors[0]; i++) {
+        if (vectors[i].run() != 0) {
+            failed = 1;
+        } else {
+            printf("ok %s\n", vectors[i].name);
+        }
+    }
+
+    return failed;
+}
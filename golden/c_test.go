@@ -0,0 +1,32 @@
+package golden
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestC(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+
+	if err != nil {
+		t.Skip("cc is not installed")
+	}
+
+	bin := filepath.Join(t.TempDir(), "test_golden")
+
+	cmd := exec.Command(cc, "-std=c99", "-Wall", "-Wextra", "-Wpedantic", "-Werror", "-o", bin, "test_golden.c")
+	cmd.Dir = "c"
+
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+
+	out, err = exec.Command(bin).CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
@@ -0,0 +1,13 @@
+// Package golden holds the golden vectors the code generators of every language are tested against: values
+// of the messages in golden.schema in the JSON form of encoder.ToJSON, and their payloads as encoded by the
+// Go encoder. Generated code is correct if it encodes every value to its payload and decodes every payload
+// back to its value. For C and Rust the vectors are compiled into the test sources c/vectors.h and
+// rust/tests/vectors.rs.
+package golden
+
+//go:generate go run ../cmd/schemagen -o golden.go golden.schema
+//go:generate go run ../cmd/schemagen -lang ts -o ts/golden.ts golden.schema
+//go:generate go run ../cmd/schemagen -lang py -o python/golden.py golden.schema
+//go:generate go run ../cmd/schemagen -lang c -o c/golden.h golden.schema
+//go:generate go run ../cmd/schemagen -lang rust -o rust/src/golden.rs golden.schema
+//go:generate go run ./gen -o vectors.json -c c/vectors.h -rust rust/tests/vectors.rs
@@ -0,0 +1,63 @@
+// Command gen writes the golden vectors encoded by the Go encoder to a file, and the sources that check the
+// generated C and Rust code against them
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/benjamin-larsen/goschemaipc/golden"
+)
+
+func main() {
+	out := flag.String("o", "vectors.json", "output file")
+	cOut := flag.String("c", "", "C vectors file, not written if empty")
+	rustOut := flag.String("rust", "", "Rust vectors file, not written if empty")
+
+	flag.Parse()
+
+	err := run(*out, *cOut, *rustOut)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(out, cOut, rustOut string) error {
+	vectors, err := golden.Build()
+
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		path     string
+		generate func([]golden.Vector) ([]byte, error)
+	}{
+		{out, golden.Marshal},
+		{cOut, golden.CVectors},
+		{rustOut, golden.RustVectors},
+	}
+
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+
+		data, err := file.generate(vectors)
+
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(file.path, data, 0644)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
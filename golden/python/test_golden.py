@@ -0,0 +1,134 @@
+"""Tests the generated Python code against the golden vectors, run with python3 -m unittest in this directory.
+
+The client tests need a golden server, the Go tests of the golden package start one and set GOLDEN_ADDR.
+"""
+
+import base64
+import dataclasses
+import json
+import os
+import pathlib
+import re
+import typing
+import unittest
+
+import golden
+
+VECTORS = json.loads((pathlib.Path(__file__).parent.parent / "vectors.json").read_text())
+
+
+def from_json(hint, value):
+    """Converts a value in the JSON form of encoder.ToJSON to the type hint of a field."""
+    if typing.get_origin(hint) is typing.Union:
+        hint = next(arg for arg in typing.get_args(hint) if arg is not type(None))
+
+    if typing.get_origin(hint) is list:
+        (elem,) = typing.get_args(hint)
+        return [from_json(elem, v) for v in value]
+
+    if dataclasses.is_dataclass(hint):
+        hints = typing.get_type_hints(hint, vars(golden))
+        return hint(**{f.name: from_json(hints[f.name], value[f.name]) for f in dataclasses.fields(hint) if f.name in value})
+
+    if hint is bytes:
+        return base64.b64decode(value)
+
+    # 64-bit integers are strings
+    return int(value)
+
+
+def message_class(signature):
+    return getattr(golden, signature.split(".")[-1])
+
+
+def codec(signature):
+    snake = re.sub(r"(?<!^)(?=[A-Z])", "_", signature.split(".")[-1]).lower()
+
+    return getattr(golden, "encode_" + snake), getattr(golden, "decode_" + snake)
+
+
+class TestVectors(unittest.TestCase):
+    def test_encode(self):
+        for vector in VECTORS:
+            if vector.get("decodeOnly"):
+                continue
+
+            with self.subTest(vector["name"]):
+                encode, _ = codec(vector["message"])
+                value = from_json(message_class(vector["message"]), vector["value"])
+
+                self.assertEqual(encode(value).hex(), vector["payload"])
+
+    def test_decode(self):
+        for vector in VECTORS:
+            with self.subTest(vector["name"]):
+                _, decode = codec(vector["message"])
+                value = from_json(message_class(vector["message"]), vector["value"])
+
+                self.assertEqual(decode(bytes.fromhex(vector["payload"])), value)
+
+    def test_truncated(self):
+        for vector in VECTORS:
+            payload = bytes.fromhex(vector["payload"])
+
+            if not payload:
+                continue
+
+            with self.subTest(vector["name"]):
+                _, decode = codec(vector["message"])
+
+                with self.assertRaises(golden.IPCError):
+                    decode(payload[:-1])
+
+    def test_out_of_range(self):
+        value = from_json(golden.Record, {"id": 1 << 32, "stamp": "0"})
+
+        with self.assertRaises(golden.IPCError):
+            golden.encode_record(value)
+
+
+@unittest.skipUnless(os.environ.get("GOLDEN_ADDR"), "GOLDEN_ADDR is not set")
+class TestClient(unittest.TestCase):
+    def setUp(self):
+        host, port = os.environ["GOLDEN_ADDR"].rsplit(":", 1)
+        self.client = golden.Client.connect((host, int(port)))
+
+    def tearDown(self):
+        self.client.close()
+
+    def test_rpc(self):
+        for vector in VECTORS:
+            # the Go server drops optional fields set to their zero value
+            if vector["message"] != "inbound golden.Everything" or vector["name"] == "everything-zero-optionals":
+                continue
+
+            with self.subTest(vector["name"]):
+                value = from_json(golden.Everything, vector["value"])
+                self.assertEqual(self.client.golden_echo(value), value)
+
+    def test_rpc_extensible(self):
+        res = self.client.golden_touch(golden.Record(id=1, stamp=-5))
+        self.assertEqual(res, golden.Record(id=1, note=b"touched", stamp=-4))
+
+    def test_rpc_error(self):
+        with self.assertRaises(golden.RPCError):
+            self.client.golden_touch(golden.Record(id=0, stamp=0))
+
+        # the connection is still usable after an error
+        self.assertEqual(self.client.golden_touch(golden.Record(id=2, stamp=0)).stamp, 1)
+
+    def test_messages(self):
+        received = []
+        self.client.on_everything(received.append)
+
+        value = from_json(golden.Everything, VECTORS[1]["value"])
+        self.client.send_everything(value)
+
+        while not received:
+            self.client.poll()
+
+        self.assertEqual(received, [value])
+
+
+if __name__ == "__main__":
+    unittest.main()
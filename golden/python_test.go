@@ -0,0 +1,86 @@
+package golden
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+
+	schemaipc "github.com/benjamin-larsen/goschemaipc"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// startServer serves golden.schema on a local port, Echo returns its request, Touch bumps the stamp and
+// inbound Everything is sent back
+func startServer(t *testing.T) string {
+	parsed, err := schema.Parse(Schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &schemaipc.Server{
+		Schema:            parsed,
+		MaxMessageSize:    1 << 20,
+		ExportDefinitions: true,
+	}
+
+	s.Init()
+
+	RegisterGoldenEcho(s, func(req *Everything, c *schemaipc.Conn) (Everything, error) {
+		return *req, nil
+	})
+
+	RegisterGoldenTouch(s, func(req *Record, c *schemaipc.Conn) (Record, error) {
+		if req.Id == 0 {
+			return Record{}, errors.New("id must not be 0")
+		}
+
+		return Record{Id: req.Id, Note: []byte("touched"), Stamp: req.Stamp + 1}, nil
+	})
+
+	RegisterEverything(s, func(msg *Everything, c *schemaipc.Conn) error {
+		return c.Send(OutboundEverythingSignature, *msg)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			go s.HandleConnection(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestPython(t *testing.T) {
+	python, err := exec.LookPath("python3")
+
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+
+	cmd := exec.Command(python, "-m", "unittest", "-v")
+	cmd.Dir = "python"
+	cmd.Env = append(os.Environ(), "GOLDEN_ADDR="+startServer(t))
+
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
@@ -0,0 +1,5 @@
+//! Checks the generated Rust module against the golden vectors, run with cargo test in this directory.
+
+mod golden;
+
+pub use golden::*;
@@ -0,0 +1,25 @@
+package golden
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestRust(t *testing.T) {
+	cargo, err := exec.LookPath("cargo")
+
+	if err != nil {
+		t.Skip("cargo is not installed")
+	}
+
+	cmd := exec.Command(cargo, "test", "--offline", "--quiet")
+	cmd.Dir = "rust"
+	cmd.Env = append(os.Environ(), "CARGO_TARGET_DIR="+t.TempDir())
+
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
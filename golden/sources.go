@@ -0,0 +1,502 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/benjamin-larsen/goschemaipc/cgen"
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/rustgen"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// a vector with its value parsed and the messages it is checked against
+type sourceVector struct {
+	Vector
+	fields    []schema.MessageField
+	name      string         // name of the message
+	value     map[string]any // the value with numbers kept as json.Number
+	payload   []byte
+	canonical []byte // the payload as the current schema encodes the value
+}
+
+func nestedMessage(extra any) schema.SchemaMessage {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e.Message
+	case schema.SchemaMessage:
+		return e
+	default:
+		return schema.SchemaMessage{}
+	}
+}
+
+// flatten returns the fields of a message with the fields of included objects spliced in, the same as the
+// generated structs hold them
+func flatten(fields []schema.MessageField) []schema.MessageField {
+	var res []schema.MessageField
+
+	for _, field := range fields {
+		if field.Embedded {
+			res = append(res, flatten(nestedMessage(field.Extra).Fields)...)
+		} else {
+			res = append(res, field)
+		}
+	}
+
+	return res
+}
+
+func parseVectors(vectors []Vector) ([]sourceVector, error) {
+	r, err := Registry()
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]sourceVector, len(vectors))
+
+	for i, vector := range vectors {
+		d, err := descriptor(r, vector.Message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(vector.Value))
+		dec.UseNumber()
+
+		var value map[string]any
+
+		err = dec.Decode(&value)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vector.Name, err)
+		}
+
+		payload, err := hex.DecodeString(vector.Payload)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vector.Name, err)
+		}
+
+		canonical, err := encoder.FromJSON(d, vector.Value)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vector.Name, err)
+		}
+
+		res[i] = sourceVector{
+			Vector:    vector,
+			fields:    flatten(d.Message.Fields),
+			name:      d.Message.Name,
+			value:     value,
+			payload:   payload,
+			canonical: canonical,
+		}
+	}
+
+	return res, nil
+}
+
+// number returns the digits of an integer in the JSON form, 64-bit integers are strings
+func number(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return v.(json.Number).String()
+}
+
+func binary(v any) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(v.(string))
+}
+
+// hexList formats bytes as a list of hex literals
+func hexList(b []byte) string {
+	items := make([]string, len(b))
+
+	for i, c := range b {
+		items[i] = fmt.Sprintf("0x%02x", c)
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// wrap breaks a list of hex literals into lines of 16 bytes
+func wrap(b []byte, indent string) string {
+	var lines []string
+
+	for len(b) > 16 {
+		lines = append(lines, indent+hexList(b[:16])+",")
+		b = b[16:]
+	}
+
+	lines = append(lines, indent+hexList(b))
+
+	return strings.Join(lines, "\n")
+}
+
+// cBytes returns the initializer of a byte array, which can't be empty in C
+func cBytes(b []byte) string {
+	if len(b) == 0 {
+		return "{ 0 }"
+	}
+
+	return "{\n" + wrap(b, "        ") + "\n    }"
+}
+
+// cSource builds the initializers of a vector, arrays are declared as static arrays before the value
+type cSource struct {
+	statics []string
+}
+
+// CVectors returns the contents of c/vectors.h, which checks the generated C header against the vectors
+func CVectors(vectors []Vector) ([]byte, error) {
+	parsed, err := parseVectors(vectors)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+
+	b.WriteString("/* Code generated by golden/gen from vectors.json. DO NOT EDIT. */\n\n")
+	b.WriteString("/* included by test_golden.c, which declares buf, arena_buf, expect_payload and expect_status */\n\n")
+
+	for i, vector := range parsed {
+		var c cSource
+
+		name := cgen.Name(vector.name)
+		value, err := c.object(vector.fields, vector.value)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vector.Name, err)
+		}
+
+		fmt.Fprintf(&b, "/* %s */\n", vector.Name)
+		fmt.Fprintf(&b, "static int vector_%d(void)\n{\n", i)
+		fmt.Fprintf(&b, "    static const uint8_t payload[%d] = %s;\n", max(len(vector.payload), 1), cBytes(vector.payload))
+
+		// a payload from a newer peer carries fields the value drops, the value encodes to its canonical form
+		expected := "payload"
+
+		if vector.DecodeOnly {
+			expected = "canonical"
+			fmt.Fprintf(&b, "    static const uint8_t canonical[%d] = %s;\n", max(len(vector.canonical), 1), cBytes(vector.canonical))
+		}
+
+		for _, static := range c.statics {
+			fmt.Fprintf(&b, "    %s\n", static)
+		}
+
+		fmt.Fprintf(&b, "    %s value = %s;\n", name, value)
+		fmt.Fprintf(&b, "    %s decoded;\n", name)
+		b.WriteString("    ipc_arena arena = ipc_arena_init(arena_buf, sizeof arena_buf);\n")
+		b.WriteString("    size_t len = 0;\n")
+		b.WriteString("    ipc_status status;\n")
+		b.WriteString("    int failed = 0;\n\n")
+
+		n, m := len(vector.payload), len(vector.canonical)
+		label := fmt.Sprintf("%q", vector.Name)
+
+		// the status is stored first, the order arguments are evaluated in is unspecified
+		fmt.Fprintf(&b, "    status = %s_encode(&value, buf, sizeof buf, &len);\n", name)
+		fmt.Fprintf(&b, "    failed |= expect_payload(%s, \"encode\", status, len, %s, %d);\n", label, expected, m)
+
+		if m > 0 {
+			fmt.Fprintf(&b, "    failed |= expect_status(%s, \"encode into a short buffer\", %s_encode(&value, buf, %d, &len), IPC_ERR_BUFFER_TOO_SMALL);\n", label, name, m-1)
+		}
+
+		fmt.Fprintf(&b, "    failed |= expect_status(%s, \"decode\", %s_decode(&decoded, payload, %d, &arena), IPC_OK);\n", label, name, n)
+		fmt.Fprintf(&b, "    status = %s_encode(&decoded, buf, sizeof buf, &len);\n", name)
+		fmt.Fprintf(&b, "    failed |= expect_payload(%s, \"encode the decoded value\", status, len, %s, %d);\n", label, expected, m)
+
+		if n > 0 {
+			fmt.Fprintf(&b, "    failed |= expect_status(%s, \"decode a truncated payload\", %s_decode(&decoded, payload, %d, &arena), IPC_ERR_OUT_OF_BOUNDS);\n", label, name, n-1)
+		}
+
+		b.WriteString("\n    return failed;\n}\n\n")
+	}
+
+	b.WriteString("static const struct {\n    const char *name;\n    int (*run)(void);\n} vectors[] = {\n")
+
+	for i, vector := range parsed {
+		fmt.Fprintf(&b, "    { %q, vector_%d },\n", vector.Name, i)
+	}
+
+	b.WriteString("};\n")
+
+	return []byte(b.String()), nil
+}
+
+func (c *cSource) object(fields []schema.MessageField, value map[string]any) (string, error) {
+	var parts []string
+
+	for _, field := range flatten(fields) {
+		v, ok := value[field.Name]
+
+		if !ok {
+			continue
+		}
+
+		if field.Optional {
+			parts = append(parts, ".has_"+field.Name+" = true")
+		}
+
+		init, err := c.value(field, v)
+
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, "."+cgen.FieldName(field)+" = "+init)
+
+		if field.Type == schema.TypeArray {
+			parts = append(parts, fmt.Sprintf(".%s_len = %d", field.Name, len(v.([]any))))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "{ 0 }", nil
+	}
+
+	return "{ " + strings.Join(parts, ", ") + " }", nil
+}
+
+var cIntTypes = map[schema.FieldType]string{
+	schema.TypeUInt64: "uint64_t",
+	schema.TypeInt64:  "int64_t",
+	schema.TypeUInt32: "uint32_t",
+	schema.TypeInt32:  "int32_t",
+	schema.TypeUInt16: "uint16_t",
+	schema.TypeInt16:  "int16_t",
+}
+
+// cInt returns an integer literal, the minimum of a signed type is written as an expression as its digits
+// don't fit the type
+func cInt(t schema.FieldType, digits string) string {
+	switch {
+	case t == schema.TypeInt64 && digits == "-9223372036854775808":
+		return "(-9223372036854775807LL - 1)"
+	case t == schema.TypeInt32 && digits == "-2147483648":
+		return "(-2147483647 - 1)"
+	case t == schema.TypeUInt64 || t == schema.TypeUInt32 || t == schema.TypeUInt16:
+		return digits + "u"
+	case t == schema.TypeInt64:
+		return digits + "LL"
+	default:
+		return digits
+	}
+}
+
+func (c *cSource) value(field schema.MessageField, v any) (string, error) {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		{
+			b, err := binary(v)
+
+			if err != nil {
+				return "", err
+			}
+
+			return "{ " + hexList(b) + " }", nil
+		}
+	case schema.TypeDynamicBinary, schema.TypeLongBinary:
+		{
+			b, err := binary(v)
+
+			if err != nil {
+				return "", err
+			}
+
+			var s strings.Builder
+
+			for _, c := range b {
+				fmt.Fprintf(&s, "\\x%02x", c)
+			}
+
+			return fmt.Sprintf("{ (const uint8_t *)\"%s\", %d }", s.String(), len(b)), nil
+		}
+	case schema.TypeObject:
+		return c.object(nestedMessage(field.Extra).Fields, v.(map[string]any))
+	case schema.TypeArray:
+		{
+			items := v.([]any)
+
+			if len(items) == 0 {
+				return "NULL", nil
+			}
+
+			elem, isField := field.Extra.(schema.MessageField)
+
+			if !isField {
+				elem = schema.MessageField{Type: schema.TypeObject, Extra: field.Extra}
+			}
+
+			inits := make([]string, len(items))
+
+			for i, item := range items {
+				init, err := c.value(elem, item)
+
+				if err != nil {
+					return "", err
+				}
+
+				inits[i] = init
+			}
+
+			name := fmt.Sprintf("array%d", len(c.statics))
+
+			var decl string
+
+			switch elem.Type {
+			case schema.TypeFixedBinary:
+				decl = fmt.Sprintf("uint8_t %s[][%d]", name, elem.Extra.(int))
+			case schema.TypeDynamicBinary, schema.TypeLongBinary:
+				decl = "ipc_bytes " + name + "[]"
+			case schema.TypeObject:
+				decl = cgen.Name(elem.ObjectName()) + " " + name + "[]"
+			default:
+				decl = cIntTypes[elem.Type] + " " + name + "[]"
+			}
+
+			c.statics = append(c.statics, fmt.Sprintf("static %s = { %s };", decl, strings.Join(inits, ", ")))
+
+			return name, nil
+		}
+	default:
+		return cInt(field.Type, number(v)), nil
+	}
+}
+
+// RustVectors returns the contents of rust/tests/vectors.rs, which checks the generated Rust module against
+// the vectors
+func RustVectors(vectors []Vector) ([]byte, error) {
+	parsed, err := parseVectors(vectors)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+
+	b.WriteString("// Code generated by golden/gen from vectors.json. DO NOT EDIT.\n\n")
+	b.WriteString("use golden::*;\n")
+
+	for _, vector := range parsed {
+		name := rustgen.TypeName("golden", vector.name)
+		value, err := rustObject(name, vector.fields, vector.value, "    ")
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vector.Name, err)
+		}
+
+		fmt.Fprintf(&b, "\n#[test]\nfn %s() {\n", strings.ReplaceAll(vector.Name, "-", "_"))
+		fmt.Fprintf(&b, "    let payload: &[u8] = &[\n%s\n    ];\n", wrap(vector.payload, "        "))
+		fmt.Fprintf(&b, "    let value = %s;\n\n", value)
+
+		// a payload from a newer peer carries fields the value drops
+		if !vector.DecodeOnly {
+			b.WriteString("    assert_eq!(value.encode().unwrap(), payload);\n")
+		}
+
+		fmt.Fprintf(&b, "    assert_eq!(%s::decode(payload).unwrap(), value);\n", name)
+
+		if len(vector.payload) > 0 {
+			fmt.Fprintf(&b, "    assert_eq!(%s::decode(&payload[..payload.len() - 1]), Err(Error::OutOfBounds));\n", name)
+		}
+
+		b.WriteString("}\n")
+	}
+
+	return []byte(b.String()), nil
+}
+
+// rustObject returns a struct expression, optional fields missing from the value are None
+func rustObject(name string, fields []schema.MessageField, value map[string]any, indent string) (string, error) {
+	if len(fields) == 0 {
+		return name + " {}", nil
+	}
+
+	lines := []string{name + " {"}
+
+	for _, field := range flatten(fields) {
+		v, ok := value[field.Name]
+
+		expr := "None"
+
+		if ok {
+			var err error
+
+			expr, err = rustValue(field, v, indent+"    ")
+
+			if err != nil {
+				return "", err
+			}
+
+			if field.Optional {
+				expr = "Some(" + expr + ")"
+			}
+		}
+
+		lines = append(lines, fmt.Sprintf("%s    %s: %s,", indent, rustgen.FieldName(field), expr))
+	}
+
+	return strings.Join(append(lines, indent+"}"), "\n"), nil
+}
+
+func rustValue(field schema.MessageField, v any, indent string) (string, error) {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		{
+			b, err := binary(v)
+
+			if err != nil {
+				return "", err
+			}
+
+			return "[" + hexList(b) + "]", nil
+		}
+	case schema.TypeDynamicBinary, schema.TypeLongBinary:
+		{
+			b, err := binary(v)
+
+			if err != nil {
+				return "", err
+			}
+
+			return "vec![" + hexList(b) + "]", nil
+		}
+	case schema.TypeObject:
+		return rustObject(rustgen.TypeName("golden", field.ObjectName()), nestedMessage(field.Extra).Fields, v.(map[string]any), indent)
+	case schema.TypeArray:
+		{
+			elem, isField := field.Extra.(schema.MessageField)
+
+			if !isField {
+				elem = schema.MessageField{Type: schema.TypeObject, Extra: field.Extra}
+			}
+
+			var items []string
+
+			for _, item := range v.([]any) {
+				expr, err := rustValue(elem, item, indent)
+
+				if err != nil {
+					return "", err
+				}
+
+				items = append(items, expr)
+			}
+
+			return "vec![" + strings.Join(items, ", ") + "]", nil
+		}
+	default:
+		return number(v), nil
+	}
+}
@@ -0,0 +1,1016 @@
+// Code generated by schemagen from golden.schema. DO NOT EDIT.
+
+export const PROTOCOL_VERSION = 1;
+export const MIN_PROTOCOL_VERSION = 1;
+
+// FINGERPRINT identifies the schema the client was generated from, handshake fails if the server has another one
+export const FINGERPRINT = new Uint8Array([134, 109, 178, 150, 175, 222, 218, 83, 192, 76, 54, 194, 230, 35, 109, 145, 156, 205, 21, 145, 73, 81, 121, 65, 225, 40, 22, 56, 17, 47, 250, 69]);
+
+const InternalId = {
+  InboundHello: 0,
+  OutboundHello: 1,
+  OutboundProtocolError: 2,
+  InboundRpcRequest: 4,
+  OutboundRpcResponse: 5,
+  OutboundRpcError: 6,
+} as const;
+
+// MessageId holds the descriptor ID of every message, the same as the server assigns
+export const MessageId = {
+  Everything: 12,
+  Record: 13,
+  Empty: 14,
+} as const;
+
+export class IPCError extends Error {}
+
+// RemoteError is a protocol error sent by the server
+export class RemoteError extends Error {}
+
+// RPCError is returned by a method handler on the server, the connection stays usable
+export class RPCError extends Error {
+  constructor(readonly method: string, message: string) {
+    super(`rpc ${method}: ${message}`);
+  }
+}
+
+interface MessageStart {
+  header: number;
+  optList: number;
+  extensible: boolean;
+}
+
+export class Writer {
+  private buf = new Uint8Array(64);
+  private view = new DataView(this.buf.buffer);
+  private length = 0;
+
+  // grow reserves n zeroed bytes and returns their offset, buf and view must only be read after it
+  private grow(n: number): number {
+    const pos = this.length;
+
+    if (pos + n > this.buf.length) {
+      let size = this.buf.length * 2;
+
+      while (size < pos + n) {
+        size *= 2;
+      }
+
+      const next = new Uint8Array(size);
+      next.set(this.buf.subarray(0, pos));
+
+      this.buf = next;
+      this.view = new DataView(next.buffer);
+    }
+
+    this.length = pos + n;
+    this.buf.fill(0, pos, pos + n);
+
+    return pos;
+  }
+
+  bytes(): Uint8Array {
+    return this.buf.slice(0, this.length);
+  }
+
+  uint64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigUint64(pos, v, true);
+  }
+
+  int64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigInt64(pos, v, true);
+  }
+
+  uint32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setUint32(pos, v, true);
+  }
+
+  int32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setInt32(pos, v, true);
+  }
+
+  uint16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setUint16(pos, v, true);
+  }
+
+  int16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setInt16(pos, v, true);
+  }
+
+  private raw(b: Uint8Array): void {
+    const pos = this.grow(b.length);
+    this.buf.set(b, pos);
+  }
+
+  fixedBinary(b: Uint8Array, n: number): void {
+    if (b.length !== n) {
+      throw new IPCError("fixed binary field: wrong length");
+    }
+
+    this.raw(b);
+  }
+
+  binary(b: Uint8Array): void {
+    if (b.length > 0xffff) {
+      throw new IPCError("binary field: length too long (must not be more than 65,535 bytes)");
+    }
+
+    this.uint16(b.length);
+    this.raw(b);
+  }
+
+  longBinary(b: Uint8Array): void {
+    this.uint32(b.length);
+    this.raw(b);
+  }
+
+  arrayLen(n: number): void {
+    if (n > 0xffff) {
+      throw new IPCError("array field: length too long (must not be more than 65,535 elements)");
+    }
+
+    this.uint16(n);
+  }
+
+  beginMessage(optionalCount: number, extensible: boolean): MessageStart {
+    const optBytes = Math.ceil(optionalCount / 8);
+    let header = 0;
+
+    if (extensible) {
+      if (optBytes > 255) {
+        throw new IPCError("extensible message: too many optional fields (must not be more than 2,040)");
+      }
+
+      header = this.grow(5);
+      this.buf[header + 4] = optBytes;
+    }
+
+    return { header, optList: this.grow(optBytes), extensible };
+  }
+
+  setOptional(start: MessageStart, n: number): void {
+    this.buf[start.optList + (n >> 3)] |= 1 << (n & 7);
+  }
+
+  endMessage(start: MessageStart): void {
+    if (start.extensible) {
+      this.view.setUint32(start.header, this.length - start.header - 4, true);
+    }
+  }
+}
+
+interface MessageFrame {
+  optList: Uint8Array;
+  optBytes: number;
+  opt: number;
+  extensible: boolean;
+  end: number;
+  outerLen: number;
+}
+
+export class Reader {
+  private readonly view: DataView;
+  private pos = 0;
+  private len: number;
+
+  constructor(private readonly buf: Uint8Array) {
+    this.view = new DataView(buf.buffer, buf.byteOffset, buf.byteLength);
+    this.len = buf.length;
+  }
+
+  private take(n: number): number {
+    if (n > this.len - this.pos) {
+      throw new IPCError("out of bounds");
+    }
+
+    const pos = this.pos;
+    this.pos += n;
+
+    return pos;
+  }
+
+  uint64(): bigint {
+    return this.view.getBigUint64(this.take(8), true);
+  }
+
+  int64(): bigint {
+    return this.view.getBigInt64(this.take(8), true);
+  }
+
+  uint32(): number {
+    return this.view.getUint32(this.take(4), true);
+  }
+
+  int32(): number {
+    return this.view.getInt32(this.take(4), true);
+  }
+
+  uint16(): number {
+    return this.view.getUint16(this.take(2), true);
+  }
+
+  int16(): number {
+    return this.view.getInt16(this.take(2), true);
+  }
+
+  // binary fields are copied, so they stay valid after the payload is released
+  fixedBinary(n: number): Uint8Array {
+    const pos = this.take(n);
+    return this.buf.slice(pos, pos + n);
+  }
+
+  binary(): Uint8Array {
+    return this.fixedBinary(this.uint16());
+  }
+
+  longBinary(): Uint8Array {
+    return this.fixedBinary(this.uint32());
+  }
+
+  arrayLen(): number {
+    return this.uint16();
+  }
+
+  beginMessage(optionalCount: number, extensible: boolean): MessageFrame {
+    const frame: MessageFrame = {
+      optList: new Uint8Array(0),
+      optBytes: Math.ceil(optionalCount / 8),
+      opt: 0,
+      extensible,
+      end: 0,
+      outerLen: 0,
+    };
+
+    if (extensible) {
+      const bodyLen = this.uint32();
+
+      if (bodyLen > this.len - this.pos) {
+        throw new IPCError("out of bounds");
+      }
+
+      frame.end = this.pos + bodyLen;
+      frame.outerLen = this.len;
+      this.len = frame.end;
+
+      // the sender might know about more or less optional fields than we do
+      frame.optBytes = this.buf[this.take(1)];
+    }
+
+    frame.optList = this.fixedBinary(frame.optBytes);
+
+    return frame;
+  }
+
+  present(frame: MessageFrame): boolean {
+    const opt = frame.opt++;
+
+    return opt < frame.optBytes * 8 && (frame.optList[opt >> 3] & (1 << (opt & 7))) !== 0;
+  }
+
+  checkRequired(frame: MessageFrame): void {
+    if (frame.extensible && this.pos === frame.end) {
+      throw new IPCError("required field is missing from message");
+    }
+  }
+
+  endMessage(frame: MessageFrame): void {
+    if (frame.extensible) {
+      // skip trailing fields sent by a peer with a newer schema
+      this.pos = frame.end;
+      this.len = frame.outerLen;
+    }
+  }
+}
+
+// compressed messages are raw deflate streams
+async function transform(data: Uint8Array, stream: GenericTransformStream): Promise<Uint8Array> {
+  const piped = new Blob([data]).stream().pipeThrough(stream);
+  return new Uint8Array(await new Response(piped).arrayBuffer());
+}
+
+function deflate(data: Uint8Array): Promise<Uint8Array> {
+  return transform(data, new CompressionStream("deflate-raw"));
+}
+
+function inflate(data: Uint8Array): Promise<Uint8Array> {
+  return transform(data, new DecompressionStream("deflate-raw"));
+}
+
+const textEncoder = new TextEncoder();
+const textDecoder = new TextDecoder();
+
+function sameBytes(a: Uint8Array, b: Uint8Array): boolean {
+  return a.length === b.length && a.every((v, i) => v === b[i]);
+}
+
+// Transport carries the frames of a connection, e.g. a socket or a WebSocket
+export interface Transport {
+  write(data: Uint8Array): void;
+  close(): void;
+}
+
+interface PendingCall {
+  method: string;
+  resolve(payload: Uint8Array): void;
+  reject(err: Error): void;
+}
+
+export class BaseClient {
+  private pending = new Uint8Array(0);
+  private queue: Promise<void> = Promise.resolve();
+  private readonly handlers = new Map<number, (payload: Uint8Array) => Promise<void> | void>();
+  private readonly calls = new Map<number, PendingCall>();
+  private lastCallId = 0;
+  private hello?: { resolve(): void; reject(err: Error): void };
+
+  // called with errors that can't be returned to a caller, such as a failing handler
+  onError: (err: Error) => void = (err) => console.error(err);
+
+  constructor(protected readonly transport: Transport) {}
+
+  // receive is fed the bytes read from the transport, frames are dispatched in order
+  receive(chunk: Uint8Array): void {
+    const buf = new Uint8Array(this.pending.length + chunk.length);
+    buf.set(this.pending);
+    buf.set(chunk, this.pending.length);
+
+    let off = 0;
+
+    while (buf.length - off >= 8) {
+      const header = new DataView(buf.buffer, off, 8);
+      const length = header.getUint32(0, true);
+      const id = header.getUint32(4, true);
+
+      if (buf.length - off - 8 < length) {
+        break;
+      }
+
+      const payload = buf.slice(off + 8, off + 8 + length);
+      off += 8 + length;
+
+      this.queue = this.queue.then(() => this.dispatch(id, payload)).catch((err) => this.onError(err));
+    }
+
+    this.pending = buf.slice(off);
+  }
+
+  // handshake sends Hello and resolves once the server confirmed that it has the same schema
+  handshake(): Promise<void> {
+    return new Promise((resolve, reject) => {
+      this.hello = { resolve, reject };
+
+      const w = new Writer();
+
+      writeInternalInboundHello(w, {
+        minVersion: MIN_PROTOCOL_VERSION,
+        currVersion: PROTOCOL_VERSION,
+        fingerprint: FINGERPRINT,
+      });
+
+      this.writeFrame(InternalId.InboundHello, w.bytes());
+    });
+  }
+
+  close(): void {
+    for (const call of this.calls.values()) {
+      call.reject(new IPCError("call aborted: connection closed"));
+    }
+
+    this.calls.clear();
+    this.transport.close();
+  }
+
+  private writeFrame(id: number, payload: Uint8Array): void {
+    const frame = new Uint8Array(8 + payload.length);
+    const header = new DataView(frame.buffer);
+
+    header.setUint32(0, payload.length, true);
+    header.setUint32(4, id, true);
+    frame.set(payload, 8);
+
+    this.transport.write(frame);
+  }
+
+  private async dispatch(id: number, payload: Uint8Array): Promise<void> {
+    switch (id) {
+      case InternalId.OutboundHello:
+        return this.handleHello(readInternalOutboundHello(new Reader(payload)));
+      case InternalId.OutboundProtocolError: {
+        const err = new RemoteError(`protocol error: ${textDecoder.decode(readInternalOutboundProtocolError(new Reader(payload)).message)}`);
+
+        if (this.hello) {
+          this.hello.reject(err);
+          this.hello = undefined;
+          return;
+        }
+
+        throw err;
+      }
+      case InternalId.OutboundRpcResponse: {
+        const res = readInternalOutboundRpcResponse(new Reader(payload));
+        this.takeCall(res.callId)?.resolve(res.payload);
+        return;
+      }
+      case InternalId.OutboundRpcError: {
+        const res = readInternalOutboundRpcError(new Reader(payload));
+        const call = this.takeCall(res.callId);
+        call?.reject(new RPCError(call.method, textDecoder.decode(res.message)));
+        return;
+      }
+    }
+
+    const handler = this.handlers.get(id);
+
+    if (handler) {
+      await handler(payload);
+    }
+  }
+
+  private handleHello(hello: InternalOutboundHello): void {
+    const pending = this.hello;
+    this.hello = undefined;
+
+    if (!pending) {
+      throw new IPCError("server sent an unexpected message");
+    }
+
+    if (hello.currVersion < MIN_PROTOCOL_VERSION || hello.minVersion > PROTOCOL_VERSION) {
+      pending.reject(new IPCError("peer protocol version is not supported"));
+    } else if (!sameBytes(hello.fingerprint, FINGERPRINT)) {
+      pending.reject(new IPCError("server schema doesn't match the client schema"));
+    } else {
+      pending.resolve();
+    }
+  }
+
+  private takeCall(callId: number): PendingCall | undefined {
+    const call = this.calls.get(callId);
+    this.calls.delete(callId);
+
+    return call;
+  }
+
+  protected async sendMessage(id: number, payload: Uint8Array, compress: boolean): Promise<void> {
+    this.writeFrame(id, compress ? await deflate(payload) : payload);
+  }
+
+  protected handle(id: number, compress: boolean, handler: (payload: Uint8Array) => Promise<void> | void): void {
+    this.handlers.set(id, async (payload) => handler(compress ? await inflate(payload) : payload));
+  }
+
+  protected async call(method: string, payload: Uint8Array, requestCompress: boolean, responseCompress: boolean): Promise<Uint8Array> {
+    this.lastCallId = (this.lastCallId + 1) >>> 0;
+
+    const callId = this.lastCallId;
+
+    if (requestCompress) {
+      payload = await deflate(payload);
+    }
+
+    const result = new Promise<Uint8Array>((resolve, reject) => {
+      this.calls.set(callId, { method, resolve, reject });
+    });
+
+    const w = new Writer();
+
+    writeInternalInboundRpcRequest(w, { callId, method: textEncoder.encode(method), payload });
+    this.writeFrame(InternalId.InboundRpcRequest, w.bytes());
+
+    const res = await result;
+
+    return responseCompress ? inflate(res) : res;
+  }
+}
+
+export const TagLength = 4;
+
+export type Tag = Uint8Array;
+
+// Point is the object golden.Point
+export interface Point {
+  x: number;
+  y?: number;
+}
+
+function writePoint(w: Writer, m: Point): void {
+  const start1 = w.beginMessage(1, false);
+  w.int16(m.x);
+  if (m.y !== undefined) {
+    w.setOptional(start1, 0);
+    w.int16(m.y);
+  }
+  w.endMessage(start1);
+}
+
+function readPoint(r: Reader): Point {
+  const m = {} as Point;
+  const frame1 = r.beginMessage(1, false);
+  m.x = r.int16();
+  if (r.present(frame1)) {
+    m.y = r.int16();
+  }
+  r.endMessage(frame1);
+  return m;
+}
+
+// Meta is the object golden.Meta
+export interface Meta {
+  revision: number;
+  author?: Uint8Array;
+}
+
+function writeMeta(w: Writer, m: Meta): void {
+  const start1 = w.beginMessage(1, false);
+  w.uint32(m.revision);
+  if (m.author !== undefined) {
+    w.setOptional(start1, 0);
+    w.binary(m.author);
+  }
+  w.endMessage(start1);
+}
+
+function readMeta(r: Reader): Meta {
+  const m = {} as Meta;
+  const frame1 = r.beginMessage(1, false);
+  m.revision = r.uint32();
+  if (r.present(frame1)) {
+    m.author = r.binary();
+  }
+  r.endMessage(frame1);
+  return m;
+}
+
+// Everything is the duplex message golden.Everything
+export interface Everything extends Meta {
+  u64: bigint;
+  i64: bigint;
+  u32: number;
+  i32: number;
+  u16: number;
+  i16: number;
+  tag: Tag;
+  name: Uint8Array;
+  blob: Uint8Array;
+  origin: Point;
+  path: Point[];
+  samples: number[];
+  labels: Uint8Array[];
+  chunks?: Uint8Array[];
+  opt_u64?: bigint;
+  opt_i64?: bigint;
+  opt_u32?: number;
+  opt_i32?: number;
+  opt_u16?: number;
+  opt_i16?: number;
+  opt_fixed?: Uint8Array;
+  opt_point?: Point;
+}
+
+function writeEverything(w: Writer, m: Everything): void {
+  const start1 = w.beginMessage(10, false);
+  w.uint32(m.revision);
+  if (m.author !== undefined) {
+    w.setOptional(start1, 0);
+    w.binary(m.author);
+  }
+  w.uint64(m.u64);
+  w.int64(m.i64);
+  w.uint32(m.u32);
+  w.int32(m.i32);
+  w.uint16(m.u16);
+  w.int16(m.i16);
+  w.fixedBinary(m.tag, 4);
+  w.binary(m.name);
+  w.longBinary(m.blob);
+  writePoint(w, m.origin);
+  w.arrayLen(m.path.length);
+  for (const item2 of m.path) {
+    writePoint(w, item2);
+  }
+  w.arrayLen(m.samples.length);
+  for (const item3 of m.samples) {
+    w.int32(item3);
+  }
+  w.arrayLen(m.labels.length);
+  for (const item4 of m.labels) {
+    w.binary(item4);
+  }
+  if (m.chunks !== undefined) {
+    w.setOptional(start1, 1);
+    w.arrayLen(m.chunks.length);
+    for (const item5 of m.chunks) {
+      w.longBinary(item5);
+    }
+  }
+  if (m.opt_u64 !== undefined) {
+    w.setOptional(start1, 2);
+    w.uint64(m.opt_u64);
+  }
+  if (m.opt_i64 !== undefined) {
+    w.setOptional(start1, 3);
+    w.int64(m.opt_i64);
+  }
+  if (m.opt_u32 !== undefined) {
+    w.setOptional(start1, 4);
+    w.uint32(m.opt_u32);
+  }
+  if (m.opt_i32 !== undefined) {
+    w.setOptional(start1, 5);
+    w.int32(m.opt_i32);
+  }
+  if (m.opt_u16 !== undefined) {
+    w.setOptional(start1, 6);
+    w.uint16(m.opt_u16);
+  }
+  if (m.opt_i16 !== undefined) {
+    w.setOptional(start1, 7);
+    w.int16(m.opt_i16);
+  }
+  if (m.opt_fixed !== undefined) {
+    w.setOptional(start1, 8);
+    w.fixedBinary(m.opt_fixed, 2);
+  }
+  if (m.opt_point !== undefined) {
+    w.setOptional(start1, 9);
+    writePoint(w, m.opt_point);
+  }
+  w.endMessage(start1);
+}
+
+function readEverything(r: Reader): Everything {
+  const m = {} as Everything;
+  const frame1 = r.beginMessage(10, false);
+  m.revision = r.uint32();
+  if (r.present(frame1)) {
+    m.author = r.binary();
+  }
+  m.u64 = r.uint64();
+  m.i64 = r.int64();
+  m.u32 = r.uint32();
+  m.i32 = r.int32();
+  m.u16 = r.uint16();
+  m.i16 = r.int16();
+  m.tag = r.fixedBinary(4);
+  m.name = r.binary();
+  m.blob = r.longBinary();
+  m.origin = readPoint(r);
+  const n2 = r.arrayLen();
+  const arr2 = new Array<Point>(n2);
+  for (let i2 = 0; i2 < n2; i2++) {
+    arr2[i2] = readPoint(r);
+  }
+  m.path = arr2;
+  const n3 = r.arrayLen();
+  const arr3 = new Array<number>(n3);
+  for (let i3 = 0; i3 < n3; i3++) {
+    arr3[i3] = r.int32();
+  }
+  m.samples = arr3;
+  const n4 = r.arrayLen();
+  const arr4 = new Array<Uint8Array>(n4);
+  for (let i4 = 0; i4 < n4; i4++) {
+    arr4[i4] = r.binary();
+  }
+  m.labels = arr4;
+  if (r.present(frame1)) {
+    const n5 = r.arrayLen();
+    const arr5 = new Array<Uint8Array>(n5);
+    for (let i5 = 0; i5 < n5; i5++) {
+      arr5[i5] = r.longBinary();
+    }
+    m.chunks = arr5;
+  }
+  if (r.present(frame1)) {
+    m.opt_u64 = r.uint64();
+  }
+  if (r.present(frame1)) {
+    m.opt_i64 = r.int64();
+  }
+  if (r.present(frame1)) {
+    m.opt_u32 = r.uint32();
+  }
+  if (r.present(frame1)) {
+    m.opt_i32 = r.int32();
+  }
+  if (r.present(frame1)) {
+    m.opt_u16 = r.uint16();
+  }
+  if (r.present(frame1)) {
+    m.opt_i16 = r.int16();
+  }
+  if (r.present(frame1)) {
+    m.opt_fixed = r.fixedBinary(2);
+  }
+  if (r.present(frame1)) {
+    m.opt_point = readPoint(r);
+  }
+  r.endMessage(frame1);
+  return m;
+}
+
+export function encodeEverything(m: Everything): Uint8Array {
+  const w = new Writer();
+  writeEverything(w, m);
+  return w.bytes();
+}
+
+export function decodeEverything(payload: Uint8Array): Everything {
+  return readEverything(new Reader(payload));
+}
+
+// Record is the duplex message golden.Record
+export interface Record {
+  id: number;
+  note?: Uint8Array;
+  at?: Point;
+  stamp: bigint;
+}
+
+function writeRecord(w: Writer, m: Record): void {
+  const start1 = w.beginMessage(2, true);
+  w.uint32(m.id);
+  if (m.note !== undefined) {
+    w.setOptional(start1, 0);
+    w.binary(m.note);
+  }
+  if (m.at !== undefined) {
+    w.setOptional(start1, 1);
+    writePoint(w, m.at);
+  }
+  w.int64(m.stamp);
+  w.endMessage(start1);
+}
+
+function readRecord(r: Reader): Record {
+  const m = {} as Record;
+  const frame1 = r.beginMessage(2, true);
+  r.checkRequired(frame1);
+  m.id = r.uint32();
+  if (r.present(frame1)) {
+    m.note = r.binary();
+  }
+  if (r.present(frame1)) {
+    m.at = readPoint(r);
+  }
+  r.checkRequired(frame1);
+  m.stamp = r.int64();
+  r.endMessage(frame1);
+  return m;
+}
+
+export function encodeRecord(m: Record): Uint8Array {
+  const w = new Writer();
+  writeRecord(w, m);
+  return w.bytes();
+}
+
+export function decodeRecord(payload: Uint8Array): Record {
+  return readRecord(new Reader(payload));
+}
+
+// Empty is the duplex message golden.Empty
+export interface Empty {
+}
+
+function writeEmpty(w: Writer, m: Empty): void {
+  const start1 = w.beginMessage(0, false);
+  w.endMessage(start1);
+}
+
+function readEmpty(r: Reader): Empty {
+  const m = {} as Empty;
+  const frame1 = r.beginMessage(0, false);
+  r.endMessage(frame1);
+  return m;
+}
+
+export function encodeEmpty(m: Empty): Uint8Array {
+  const w = new Writer();
+  writeEmpty(w, m);
+  return w.bytes();
+}
+
+export function decodeEmpty(payload: Uint8Array): Empty {
+  return readEmpty(new Reader(payload));
+}
+
+// InternalInboundHello is the internal message inbound Hello
+interface InternalInboundHello {
+  minVersion: number;
+  currVersion: number;
+  fingerprint?: Uint8Array;
+}
+
+function writeInternalInboundHello(w: Writer, m: InternalInboundHello): void {
+  const start1 = w.beginMessage(1, false);
+  w.int32(m.minVersion);
+  w.int32(m.currVersion);
+  if (m.fingerprint !== undefined) {
+    w.setOptional(start1, 0);
+    w.fixedBinary(m.fingerprint, 32);
+  }
+  w.endMessage(start1);
+}
+
+// InternalOutboundHello is the internal message outbound Hello
+interface InternalOutboundHello {
+  minVersion: number;
+  currVersion: number;
+  fingerprint: Uint8Array;
+  schema: Array<{ id: number; internal: number; direction: number; flags: number; maxSize: number; name: Uint8Array; fields: Array<{ name: Uint8Array; type: number; extra: Uint8Array; optional: number; aliases: Uint8Array[] }> }>;
+  methods: Array<{ name: Uint8Array; request: number; response: number }>;
+  types?: Array<{ name: Uint8Array; type: number; extra: Uint8Array }>;
+  constants?: Array<{ name: Uint8Array; value: bigint }>;
+}
+
+function readInternalOutboundHello(r: Reader): InternalOutboundHello {
+  const m = {} as InternalOutboundHello;
+  const frame1 = r.beginMessage(2, false);
+  m.minVersion = r.int32();
+  m.currVersion = r.int32();
+  m.fingerprint = r.fixedBinary(32);
+  const n2 = r.arrayLen();
+  const arr2 = new Array<{ id: number; internal: number; direction: number; flags: number; maxSize: number; name: Uint8Array; fields: Array<{ name: Uint8Array; type: number; extra: Uint8Array; optional: number; aliases: Uint8Array[] }> }>(n2);
+  for (let i2 = 0; i2 < n2; i2++) {
+    const obj3 = {} as { id: number; internal: number; direction: number; flags: number; maxSize: number; name: Uint8Array; fields: Array<{ name: Uint8Array; type: number; extra: Uint8Array; optional: number; aliases: Uint8Array[] }> };
+    const frame4 = r.beginMessage(0, false);
+    obj3.id = r.uint32();
+    obj3.internal = r.uint16();
+    obj3.direction = r.uint16();
+    obj3.flags = r.uint16();
+    obj3.maxSize = r.uint32();
+    obj3.name = r.binary();
+    const n5 = r.arrayLen();
+    const arr5 = new Array<{ name: Uint8Array; type: number; extra: Uint8Array; optional: number; aliases: Uint8Array[] }>(n5);
+    for (let i5 = 0; i5 < n5; i5++) {
+      const obj6 = {} as { name: Uint8Array; type: number; extra: Uint8Array; optional: number; aliases: Uint8Array[] };
+      const frame7 = r.beginMessage(0, false);
+      obj6.name = r.binary();
+      obj6.type = r.uint16();
+      obj6.extra = r.longBinary();
+      obj6.optional = r.uint16();
+      const n8 = r.arrayLen();
+      const arr8 = new Array<Uint8Array>(n8);
+      for (let i8 = 0; i8 < n8; i8++) {
+        arr8[i8] = r.binary();
+      }
+      obj6.aliases = arr8;
+      r.endMessage(frame7);
+      arr5[i5] = obj6;
+    }
+    obj3.fields = arr5;
+    r.endMessage(frame4);
+    arr2[i2] = obj3;
+  }
+  m.schema = arr2;
+  const n9 = r.arrayLen();
+  const arr9 = new Array<{ name: Uint8Array; request: number; response: number }>(n9);
+  for (let i9 = 0; i9 < n9; i9++) {
+    const obj10 = {} as { name: Uint8Array; request: number; response: number };
+    const frame11 = r.beginMessage(0, false);
+    obj10.name = r.binary();
+    obj10.request = r.uint32();
+    obj10.response = r.uint32();
+    r.endMessage(frame11);
+    arr9[i9] = obj10;
+  }
+  m.methods = arr9;
+  if (r.present(frame1)) {
+    const n12 = r.arrayLen();
+    const arr12 = new Array<{ name: Uint8Array; type: number; extra: Uint8Array }>(n12);
+    for (let i12 = 0; i12 < n12; i12++) {
+      const obj13 = {} as { name: Uint8Array; type: number; extra: Uint8Array };
+      const frame14 = r.beginMessage(0, false);
+      obj13.name = r.binary();
+      obj13.type = r.uint16();
+      obj13.extra = r.longBinary();
+      r.endMessage(frame14);
+      arr12[i12] = obj13;
+    }
+    m.types = arr12;
+  }
+  if (r.present(frame1)) {
+    const n15 = r.arrayLen();
+    const arr15 = new Array<{ name: Uint8Array; value: bigint }>(n15);
+    for (let i15 = 0; i15 < n15; i15++) {
+      const obj16 = {} as { name: Uint8Array; value: bigint };
+      const frame17 = r.beginMessage(0, false);
+      obj16.name = r.binary();
+      obj16.value = r.uint64();
+      r.endMessage(frame17);
+      arr15[i15] = obj16;
+    }
+    m.constants = arr15;
+  }
+  r.endMessage(frame1);
+  return m;
+}
+
+// InternalOutboundProtocolError is the internal message outbound ProtocolError
+interface InternalOutboundProtocolError {
+  message: Uint8Array;
+}
+
+function readInternalOutboundProtocolError(r: Reader): InternalOutboundProtocolError {
+  const m = {} as InternalOutboundProtocolError;
+  const frame1 = r.beginMessage(0, false);
+  m.message = r.binary();
+  r.endMessage(frame1);
+  return m;
+}
+
+// InternalInboundRpcRequest is the internal message inbound RpcRequest
+interface InternalInboundRpcRequest {
+  callId: number;
+  method: Uint8Array;
+  payload: Uint8Array;
+}
+
+function writeInternalInboundRpcRequest(w: Writer, m: InternalInboundRpcRequest): void {
+  const start1 = w.beginMessage(0, false);
+  w.uint32(m.callId);
+  w.binary(m.method);
+  w.longBinary(m.payload);
+  w.endMessage(start1);
+}
+
+// InternalOutboundRpcResponse is the internal message outbound RpcResponse
+interface InternalOutboundRpcResponse {
+  callId: number;
+  payload: Uint8Array;
+}
+
+function readInternalOutboundRpcResponse(r: Reader): InternalOutboundRpcResponse {
+  const m = {} as InternalOutboundRpcResponse;
+  const frame1 = r.beginMessage(0, false);
+  m.callId = r.uint32();
+  m.payload = r.longBinary();
+  r.endMessage(frame1);
+  return m;
+}
+
+// InternalOutboundRpcError is the internal message outbound RpcError
+interface InternalOutboundRpcError {
+  callId: number;
+  message: Uint8Array;
+}
+
+function readInternalOutboundRpcError(r: Reader): InternalOutboundRpcError {
+  const m = {} as InternalOutboundRpcError;
+  const frame1 = r.beginMessage(0, false);
+  m.callId = r.uint32();
+  m.message = r.binary();
+  r.endMessage(frame1);
+  return m;
+}
+
+// Client sends and receives the messages of the schema, call handshake before anything else
+export class Client extends BaseClient {
+  // sendEverything sends inbound golden.Everything to the server
+  sendEverything(m: Everything): Promise<void> {
+    return this.sendMessage(MessageId.Everything, encodeEverything(m), false);
+  }
+
+  // onEverything sets the handler of outbound golden.Everything, messages are handled one at a time in the order they arrive
+  onEverything(handler: (m: Everything) => Promise<void> | void): void {
+    this.handle(MessageId.Everything, false, (payload) => handler(decodeEverything(payload)));
+  }
+
+  // sendRecord sends inbound golden.Record to the server
+  sendRecord(m: Record): Promise<void> {
+    return this.sendMessage(MessageId.Record, encodeRecord(m), false);
+  }
+
+  // onRecord sets the handler of outbound golden.Record, messages are handled one at a time in the order they arrive
+  onRecord(handler: (m: Record) => Promise<void> | void): void {
+    this.handle(MessageId.Record, false, (payload) => handler(decodeRecord(payload)));
+  }
+
+  // sendEmpty sends inbound golden.Empty to the server
+  sendEmpty(m: Empty): Promise<void> {
+    return this.sendMessage(MessageId.Empty, encodeEmpty(m), false);
+  }
+
+  // onEmpty sets the handler of outbound golden.Empty, messages are handled one at a time in the order they arrive
+  onEmpty(handler: (m: Empty) => Promise<void> | void): void {
+    this.handle(MessageId.Empty, false, (payload) => handler(decodeEmpty(payload)));
+  }
+
+  // goldenEcho calls rpc Echo(golden.Everything) returns (golden.Everything)
+  async goldenEcho(req: Everything): Promise<Everything> {
+    const res = await this.call("golden.Golden.Echo", encodeEverything(req), false, false);
+    return decodeEverything(res);
+  }
+
+  // goldenTouch calls rpc Touch(golden.Record) returns (golden.Record)
+  async goldenTouch(req: Record): Promise<Record> {
+    const res = await this.call("golden.Golden.Touch", encodeRecord(req), false, false);
+    return decodeRecord(res);
+  }
+}
@@ -0,0 +1,122 @@
+// Tests the generated TypeScript code against the golden vectors. The Go tests of the golden package compile it
+// with tsc and run it with node, passing the path of vectors.json.
+
+import * as golden from "./golden";
+
+// the Node.js type definitions aren't installed, only what the test uses is declared
+declare function require(module: "fs"): { readFileSync(path: string, encoding: "utf8"): string };
+declare const process: { argv: string[]; exitCode?: number };
+
+interface Vector {
+  name: string;
+  message: string;
+  value: unknown;
+  payload: string;
+  decodeOnly?: boolean;
+}
+
+type Codec = [encode: (m: any) => Uint8Array, decode: (payload: Uint8Array) => unknown];
+
+const codecs: { [name: string]: Codec } = {
+  Everything: [golden.encodeEverything, golden.decodeEverything],
+  Record: [golden.encodeRecord, golden.decodeRecord],
+  Empty: [golden.encodeEmpty, golden.decodeEmpty],
+};
+
+function fromHex(s: string): Uint8Array {
+  const b = new Uint8Array(s.length / 2);
+
+  for (let i = 0; i < b.length; i++) {
+    b[i] = parseInt(s.slice(i * 2, i * 2 + 2), 16);
+  }
+
+  return b;
+}
+
+function toHex(b: Uint8Array): string {
+  return Array.from(b, (c) => c.toString(16).padStart(2, "0")).join("");
+}
+
+// toJSON converts a decoded value to the JSON form of encoder.ToJSON
+function toJSON(v: unknown): unknown {
+  if (v instanceof Uint8Array) {
+    return btoa(String.fromCharCode(...v));
+  }
+
+  // 64-bit integers are strings
+  if (typeof v === "bigint") {
+    return v.toString();
+  }
+
+  if (Array.isArray(v)) {
+    return v.map(toJSON);
+  }
+
+  if (typeof v === "object" && v !== null) {
+    return Object.fromEntries(Object.entries(v).map(([key, value]) => [key, toJSON(value)]));
+  }
+
+  return v;
+}
+
+// sorted returns a JSON value as a string with the keys of objects sorted, so values compare as strings
+function sorted(v: unknown): string {
+  return JSON.stringify(v, (_, value) => {
+    if (typeof value !== "object" || value === null || Array.isArray(value)) {
+      return value;
+    }
+
+    return Object.fromEntries(Object.keys(value).sort().map((key) => [key, value[key]]));
+  });
+}
+
+function run(vector: Vector): string[] {
+  const [encode, decode] = codecs[vector.message.split(".").pop()!];
+  const payload = fromHex(vector.payload);
+  const failures: string[] = [];
+
+  const decoded = decode(payload);
+
+  if (sorted(toJSON(decoded)) !== sorted(vector.value)) {
+    failures.push(`decode: got ${sorted(toJSON(decoded))}, expected ${sorted(vector.value)}`);
+  }
+
+  // a payload from a newer peer carries fields the value drops
+  if (!vector.decodeOnly) {
+    const encoded = toHex(encode(decoded));
+
+    if (encoded !== vector.payload) {
+      failures.push(`encode: got ${encoded}, expected ${vector.payload}`);
+    }
+  }
+
+  if (payload.length > 0) {
+    try {
+      decode(payload.subarray(0, payload.length - 1));
+      failures.push("decode a truncated payload: expected an error");
+    } catch (err) {
+      if (!(err instanceof golden.IPCError)) {
+        throw err;
+      }
+    }
+  }
+
+  return failures;
+}
+
+const vectors: Vector[] = JSON.parse(require("fs").readFileSync(process.argv[2], "utf8"));
+
+for (const vector of vectors) {
+  let failures: string[];
+
+  try {
+    failures = run(vector);
+  } catch (err) {
+    failures = [String(err)];
+  }
+
+  for (const failure of failures) {
+    console.log(`${vector.name}: ${failure}`);
+    process.exitCode = 1;
+  }
+}
@@ -0,0 +1,38 @@
+package golden
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeScript(t *testing.T) {
+	tsc, err := exec.LookPath("tsc")
+
+	if err != nil {
+		t.Skip("tsc is not installed")
+	}
+
+	node, err := exec.LookPath("node")
+
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	dir := t.TempDir()
+
+	cmd := exec.Command(tsc, "-p", ".", "--outDir", dir)
+	cmd.Dir = "ts"
+
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+
+	out, err = exec.Command(node, filepath.Join(dir, "test_golden.js"), "vectors.json").CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
@@ -0,0 +1,245 @@
+package golden
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+//go:embed golden.schema
+var Schema []byte
+
+type Vector struct {
+	Name    string          `json:"name"`
+	Message string          `json:"message"` // signature of the message, e.g. inbound golden.Record
+	Value   json.RawMessage `json:"value"`
+	Payload string          `json:"payload"` // hex encoded
+	// the payload was sent by a peer with a newer schema, it decodes to the value but the value doesn't
+	// encode to it
+	DecodeOnly bool `json:"decodeOnly,omitempty"`
+}
+
+type testCase struct {
+	name    string
+	message string
+	value   string
+	newer   bool // encoded with newerRecord
+}
+
+var blob = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("0123456789abcdef"), 20))
+
+var cases = []testCase{
+	{
+		name:    "everything-required",
+		message: "inbound golden.Everything",
+		value: `{"revision": 3, "u64": "1", "i64": "-2", "u32": 3, "i32": -4, "u16": 5, "i16": -6, "tag": "dGFncw==",
+			"name": "bmFtZQ==", "blob": "YmxvYg==", "origin": {"x": 1}, "path": [], "samples": [], "labels": []}`,
+	},
+	{
+		name:    "everything-extremes",
+		message: "inbound golden.Everything",
+		value: `{"revision": 4294967295, "author": "", "u64": "18446744073709551615", "i64": "-9223372036854775808",
+			"u32": 4294967295, "i32": -2147483648, "u16": 65535, "i16": -32768, "tag": "/////w==", "name": "",
+			"blob": "` + blob + `", "origin": {"x": -32768, "y": 32767},
+			"path": [{"x": 1}, {"x": 2, "y": -2}, {"x": 3}], "samples": [2147483647, 0, -1], "labels": ["YQ==", ""],
+			"chunks": ["", "Y2h1bms="], "opt_u64": "9223372036854775808", "opt_i64": "9223372036854775807",
+			"opt_u32": 1, "opt_i32": -1, "opt_u16": 2, "opt_i16": -2, "opt_fixed": "AAE=", "opt_point": {"x": 0, "y": -1}}`,
+	},
+	{
+		// optional fields that are present with a zero value are different from absent ones
+		name:    "everything-zero-optionals",
+		message: "inbound golden.Everything",
+		value: `{"revision": 0, "u64": "0", "i64": "0", "u32": 0, "i32": 0, "u16": 0, "i16": 0, "tag": "AAAAAA==",
+			"name": "", "blob": "", "origin": {"x": 0}, "path": [], "samples": [], "labels": [], "opt_i16": 0,
+			"opt_point": {"x": 0}}`,
+	},
+	{
+		name:    "record-minimal",
+		message: "inbound golden.Record",
+		value:   `{"id": 1, "stamp": "1700000000000"}`,
+	},
+	{
+		name:    "record-full",
+		message: "outbound golden.Record",
+		value:   `{"id": 2, "note": "bm90ZQ==", "at": {"x": -5, "y": 5}, "stamp": "-1"}`,
+	},
+	{
+		name:    "empty",
+		message: "inbound golden.Empty",
+		value:   `{}`,
+	},
+	{
+		name:    "record-from-newer-peer",
+		message: "inbound golden.Record",
+		value:   `{"id": 3, "note": "bmV3ZXI=", "stamp": "7", "a6": "c2tpcHBlZA==", "added": 9}`,
+		newer:   true,
+	},
+}
+
+// newerRecord is Record as a later version of the schema declares it, with fields appended
+const newerRecord = `
+package golden
+
+object Point {
+  int16 REQUIRED x
+  int16 OPTIONAL y
+}
+
+@extensible
+duplex Record {
+  uint32 REQUIRED id
+  binary OPTIONAL note
+  Point OPTIONAL at
+  int64 REQUIRED stamp
+  binary OPTIONAL a1
+  binary OPTIONAL a2
+  binary OPTIONAL a3
+  binary OPTIONAL a4
+  binary OPTIONAL a5
+  binary OPTIONAL a6
+  binary OPTIONAL a7
+  uint32 REQUIRED added
+}
+`
+
+// Registry returns a registry with golden.schema registered
+func Registry() (schema.MessageDescriptorRegistry, error) {
+	return registry(Schema)
+}
+
+func registry(source []byte) (schema.MessageDescriptorRegistry, error) {
+	r := schema.MessageDescriptorRegistry{}
+
+	s, err := schema.Parse(source)
+
+	if err != nil {
+		return r, err
+	}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		return r, err
+	}
+
+	err = r.RegisterSchema(s)
+
+	return r, err
+}
+
+func descriptor(r schema.MessageDescriptorRegistry, signature string) (schema.MessageDescriptor, error) {
+	id, exists := r.UserSignatureMap[signature]
+
+	if !exists {
+		return schema.MessageDescriptor{}, fmt.Errorf("unknown message: %s", signature)
+	}
+
+	return r.Descriptors[id], nil
+}
+
+// canonical returns the JSON form of a payload, which is what the value of a vector holds
+func canonical(descriptor schema.MessageDescriptor, payload []byte) (json.RawMessage, error) {
+	data, err := encoder.ToJSON(descriptor, payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = json.Indent(&buf, data, "    ", "  ")
+
+	return buf.Bytes(), err
+}
+
+// Build encodes the golden vectors with the Go encoder
+func Build() ([]Vector, error) {
+	current, err := Registry()
+
+	if err != nil {
+		return nil, err
+	}
+
+	newer, err := registry([]byte(newerRecord))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors []Vector
+
+	for _, c := range cases {
+		encodeWith := current
+
+		if c.newer {
+			encodeWith = newer
+		}
+
+		sender, err := descriptor(encodeWith, c.message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := encoder.FromJSON(sender, []byte(c.value))
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.name, err)
+		}
+
+		receiver, err := descriptor(current, c.message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := canonical(receiver, payload)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.name, err)
+		}
+
+		vectors = append(vectors, Vector{
+			Name:       c.name,
+			Message:    c.message,
+			Value:      value,
+			Payload:    hex.EncodeToString(payload),
+			DecodeOnly: c.newer,
+		})
+	}
+
+	return vectors, nil
+}
+
+// Marshal returns the contents of vectors.json
+func Marshal(vectors []Vector) ([]byte, error) {
+	data, err := json.MarshalIndent(vectors, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// Load reads golden vectors from a file written by Marshal
+func Load(path string) ([]Vector, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors []Vector
+
+	err = json.Unmarshal(data, &vectors)
+
+	return vectors, err
+}
@@ -0,0 +1,137 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+)
+
+func TestVectorsAreUpToDate(t *testing.T) {
+	vectors, err := Build()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []struct {
+		path     string
+		generate func([]Vector) ([]byte, error)
+	}{
+		{"vectors.json", Marshal},
+		{"c/vectors.h", CVectors},
+		{"rust/tests/vectors.rs", RustVectors},
+	}
+
+	for _, file := range files {
+		data, err := file.generate(vectors)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := os.ReadFile(file.path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(data, expected) {
+			t.Errorf("golden/%s is out of date, run go generate ./golden", file.path)
+		}
+	}
+}
+
+// newMessage returns a pointer to the generated type of a message
+func newMessage(t *testing.T, signature string) any {
+	switch signature {
+	case InboundEverythingSignature, OutboundEverythingSignature:
+		return &Everything{}
+	case InboundRecordSignature, OutboundRecordSignature:
+		return &Record{}
+	case InboundEmptySignature, OutboundEmptySignature:
+		return &Empty{}
+	default:
+		t.Fatalf("unknown message: %s", signature)
+		return nil
+	}
+}
+
+// the Go types can't tell an optional field set to its zero value from a missing one, so they are compared with
+// reflection rather than with the vectors
+func TestGeneratedGoMatchesReflection(t *testing.T) {
+	vectors, err := Load("vectors.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Registry()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vector := range vectors {
+		payload, err := hex.DecodeString(vector.Payload)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		generated, err := descriptor(r, vector.Message)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reflected := generated
+		reflected.Layout = 0
+
+		fast, slow := newMessage(t, vector.Message), newMessage(t, vector.Message)
+
+		reader := encoder.NewReader(payload, generated)
+		err = reader.Decode(fast)
+
+		if err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+			continue
+		}
+
+		reader = encoder.NewReader(payload, reflected)
+		err = reader.Decode(slow)
+
+		if err != nil {
+			t.Errorf("%s: %v", vector.Name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(fast, slow) {
+			t.Errorf("%s: generated decoding differs from reflection:\n%+v\n%+v", vector.Name, fast, slow)
+		}
+
+		fastPayload, err := encoder.Encode(generated, fast)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slowPayload, err := encoder.Encode(reflected, slow)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(fastPayload, slowPayload) {
+			t.Errorf("%s: generated encoding differs from reflection:\n%x\n%x", vector.Name, fastPayload, slowPayload)
+		}
+
+		// the payload is allocated with the generated size, which has to be exact
+		if size := fast.(encoder.Sizer).SizeIPC(); size != len(fastPayload) || cap(fastPayload) != size {
+			t.Errorf("%s: generated size %d, encoded %d bytes with a capacity of %d", vector.Name, size, len(fastPayload), cap(fastPayload))
+		}
+	}
+}
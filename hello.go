@@ -0,0 +1,558 @@
+package schemaipc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+const ProtocolVersion = 1
+const MinProtocolVersion = 1
+
+var ErrUnsupportedVersion = errors.New("peer protocol version is not supported")
+var ErrDuplicateHello = errors.New("client sent hello on an established connection")
+var ErrInvalidHelloSchema = errors.New("server sent an invalid schema in hello")
+
+type helloInbound struct {
+	MinVersion  int32  `ipc:"minVersion"`
+	CurrVersion int32  `ipc:"currVersion"`
+	Fingerprint []byte `ipc:"fingerprint"`
+}
+
+type helloField struct {
+	Name     []byte   `ipc:"name"`
+	Type     uint16   `ipc:"type"`
+	Extra    []byte   `ipc:"extra"`
+	Optional uint16   `ipc:"optional"`
+	Aliases  [][]byte `ipc:"aliases"`
+}
+
+const (
+	helloFlagExtensible uint16 = 1 << iota
+	helloFlagCompress
+)
+
+// the priority of a message is stored in bits 2-3 of its flags
+const helloPriorityShift = 2
+const helloPriorityMask uint16 = 3 << helloPriorityShift
+
+type helloDescriptor struct {
+	ID        uint32       `ipc:"id"`
+	Internal  uint16       `ipc:"internal"`
+	Direction uint16       `ipc:"direction"`
+	Flags     uint16       `ipc:"flags"`
+	MaxSize   uint32       `ipc:"maxSize"`
+	Name      []byte       `ipc:"name"`
+	Fields    []helloField `ipc:"fields"`
+}
+
+type helloTypeAlias struct {
+	Name  []byte `ipc:"name"`
+	Type  uint16 `ipc:"type"`
+	Extra []byte `ipc:"extra"`
+}
+
+const (
+	helloMethodClientStream uint16 = 1 << iota
+	helloMethodServerStream
+)
+
+type helloMethod struct {
+	Name     []byte `ipc:"name"`
+	Request  uint32 `ipc:"request"`
+	Response uint32 `ipc:"response"`
+	Flags    uint16 `ipc:"flags"`
+}
+
+type helloConstant struct {
+	Name  []byte `ipc:"name"`
+	Value uint64 `ipc:"value"`
+}
+
+type helloOutbound struct {
+	MinVersion  int32             `ipc:"minVersion"`
+	CurrVersion int32             `ipc:"currVersion"`
+	Fingerprint [32]byte          `ipc:"fingerprint"`
+	Schema      []helloDescriptor `ipc:"schema"`
+	Methods     []helloMethod     `ipc:"methods"`
+	Types       []helloTypeAlias  `ipc:"types"`
+	Constants   []helloConstant   `ipc:"constants"`
+}
+
+type protocolError struct {
+	Message []byte `ipc:"message"`
+}
+
+func boolToUint16(b bool) uint16 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// helloEncoding holds the descriptors of the objects nested in the outbound Hello,
+// which are also used to encode the extra of object and array fields
+type helloEncoding struct {
+	descriptor schema.MessageDescriptor
+	field      schema.MessageDescriptor
+}
+
+func newHelloEncoding(r *schema.MessageDescriptorRegistry) helloEncoding {
+	hello := r.Descriptors[r.InternalSignatureMap["outbound Hello"]]
+
+	var e helloEncoding
+
+	for _, field := range hello.Message.Fields {
+		if field.Name == "schema" {
+			e.descriptor = field.Extra.(schema.MessageDescriptor)
+		}
+	}
+
+	for _, field := range e.descriptor.Message.Fields {
+		if field.Name == "fields" {
+			e.field = field.Extra.(schema.MessageDescriptor)
+		}
+	}
+
+	return e
+}
+
+// The extra of a field is encoded as follows:
+//   - binary(N): N as uint32
+//   - object: the object as messageDescriptor
+//   - array: the element as messageField, which is an object field for arrays of objects
+func (e helloEncoding) encodeExtra(field schema.MessageField) ([]byte, error) {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		return binary.LittleEndian.AppendUint32(nil, uint32(field.Extra.(int))), nil
+	case schema.TypeObject:
+		{
+			object, err := e.encodeDescriptor(field.Extra.(schema.MessageDescriptor))
+
+			if err != nil {
+				return nil, err
+			}
+
+			return encoder.Encode(e.descriptor, object)
+		}
+	case schema.TypeArray:
+		{
+			elemField, ok := field.Extra.(schema.MessageField)
+
+			if !ok {
+				elemField = schema.MessageField{
+					Type:  schema.TypeObject,
+					Extra: field.Extra,
+				}
+			}
+
+			elem, err := e.encodeField(elemField)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return encoder.Encode(e.field, elem)
+		}
+	default:
+		return nil, nil
+	}
+}
+
+func (e helloEncoding) decodeExtra(fType schema.FieldType, extra []byte) (any, error) {
+	switch fType {
+	case schema.TypeFixedBinary:
+		{
+			if len(extra) != 4 {
+				return nil, ErrInvalidHelloSchema
+			}
+
+			return int(binary.LittleEndian.Uint32(extra)), nil
+		}
+	case schema.TypeObject:
+		{
+			var object helloDescriptor
+
+			reader := encoder.NewReader(extra, e.descriptor)
+			err := reader.Decode(&object)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return e.decodeDescriptor(object)
+		}
+	case schema.TypeArray:
+		{
+			var elem helloField
+
+			reader := encoder.NewReader(extra, e.field)
+			err := reader.Decode(&elem)
+
+			if err != nil {
+				return nil, err
+			}
+
+			elemField, err := e.decodeField(elem)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if elemField.Type == schema.TypeObject {
+				return elemField.Extra, nil
+			}
+
+			if elemField.Type == schema.TypeArray {
+				return nil, ErrInvalidHelloSchema
+			}
+
+			return elemField, nil
+		}
+	default:
+		return nil, nil
+	}
+}
+
+func (e helloEncoding) decodeField(f helloField) (schema.MessageField, error) {
+	fType := schema.FieldType(f.Type)
+
+	if fType < schema.TypeFixedBinary || fType > schema.TypeArray {
+		return schema.MessageField{}, ErrInvalidHelloSchema
+	}
+
+	extra, err := e.decodeExtra(fType, f.Extra)
+
+	if err != nil {
+		return schema.MessageField{}, err
+	}
+
+	field := schema.MessageField{
+		Name:     string(f.Name),
+		Type:     fType,
+		Extra:    extra,
+		Optional: f.Optional != 0,
+	}
+
+	for _, alias := range f.Aliases {
+		field.Aliases = append(field.Aliases, string(alias))
+	}
+
+	return field, nil
+}
+
+func (e helloEncoding) decodeDescriptor(d helloDescriptor) (schema.MessageDescriptor, error) {
+	direction := schema.MessageDirection(d.Direction)
+
+	if direction < schema.InboundMessage || direction > schema.ObjectDef {
+		return schema.MessageDescriptor{}, ErrInvalidHelloSchema
+	}
+
+	priority := schema.Priority((d.Flags & helloPriorityMask) >> helloPriorityShift)
+
+	if priority > schema.PriorityHigh {
+		return schema.MessageDescriptor{}, ErrInvalidHelloSchema
+	}
+
+	message := schema.SchemaMessage{
+		Direction:  direction,
+		Name:       string(d.Name),
+		Fields:     make([]schema.MessageField, 0, len(d.Fields)),
+		Extensible: d.Flags&helloFlagExtensible != 0,
+		Options: schema.MessageOptions{
+			MaxSize:  d.MaxSize,
+			Priority: priority,
+			Compress: d.Flags&helloFlagCompress != 0,
+		},
+	}
+
+	for _, f := range d.Fields {
+		field, err := e.decodeField(f)
+
+		if err != nil {
+			return schema.MessageDescriptor{}, err
+		}
+
+		message.Fields = append(message.Fields, field)
+	}
+
+	return schema.MessageDescriptor{
+		ID:            d.ID,
+		Message:       message,
+		OptionalCount: message.CountOptional(),
+		Internal:      d.Internal != 0,
+	}, nil
+}
+
+// decodeHelloSchema turns the schema advertised by the server back into descriptors
+func decodeHelloSchema(r *schema.MessageDescriptorRegistry, descriptors []helloDescriptor) ([]schema.MessageDescriptor, error) {
+	e := newHelloEncoding(r)
+	res := make([]schema.MessageDescriptor, 0, len(descriptors))
+
+	for _, d := range descriptors {
+		descriptor, err := e.decodeDescriptor(d)
+
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, descriptor)
+	}
+
+	return res, nil
+}
+
+func (e helloEncoding) encodeField(field schema.MessageField) (helloField, error) {
+	extra, err := e.encodeExtra(field)
+
+	if err != nil {
+		return helloField{}, err
+	}
+
+	f := helloField{
+		Name:     []byte(field.Name),
+		Type:     uint16(field.Type),
+		Extra:    extra,
+		Optional: boolToUint16(field.Optional),
+		Aliases:  make([][]byte, 0, len(field.Aliases)),
+	}
+
+	for _, alias := range field.Aliases {
+		f.Aliases = append(f.Aliases, []byte(alias))
+	}
+
+	return f, nil
+}
+
+func (e helloEncoding) encodeDescriptor(descriptor schema.MessageDescriptor) (helloDescriptor, error) {
+	d := helloDescriptor{
+		ID:        descriptor.ID,
+		Internal:  boolToUint16(descriptor.Internal),
+		Direction: uint16(descriptor.Message.Direction),
+		MaxSize:   descriptor.Message.Options.MaxSize,
+		Name:      []byte(descriptor.Message.Name),
+		Fields:    make([]helloField, 0, len(descriptor.Message.Fields)),
+	}
+
+	if descriptor.Message.Extensible {
+		d.Flags |= helloFlagExtensible
+	}
+
+	if descriptor.Message.Options.Compress {
+		d.Flags |= helloFlagCompress
+	}
+
+	d.Flags |= uint16(descriptor.Message.Options.Priority) << helloPriorityShift
+
+	for _, field := range descriptor.Message.Fields {
+		f, err := e.encodeField(field)
+
+		if err != nil {
+			return d, err
+		}
+
+		d.Fields = append(d.Fields, f)
+	}
+
+	return d, nil
+}
+
+// buildHelloSchema encodes every descriptor except the inbound and outbound Hello, sorted by ID
+func buildHelloSchema(r *schema.MessageDescriptorRegistry) ([]helloDescriptor, error) {
+	e := newHelloEncoding(r)
+
+	ids := make([]uint32, 0, len(r.Descriptors))
+
+	for id := range r.Descriptors {
+		if id > 1 {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	descriptors := make([]helloDescriptor, 0, len(ids))
+
+	for _, id := range ids {
+		d, err := e.encodeDescriptor(r.Descriptors[id])
+
+		if err != nil {
+			return nil, err
+		}
+
+		descriptors = append(descriptors, d)
+	}
+
+	return descriptors, nil
+}
+
+// buildHelloMethods lists the RPC methods of the registry, sorted by name
+func buildHelloMethods(r *schema.MessageDescriptorRegistry) []helloMethod {
+	names := make([]string, 0, len(r.Methods))
+
+	for name := range r.Methods {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	methods := make([]helloMethod, 0, len(names))
+
+	for _, name := range names {
+		method := r.Methods[name]
+
+		m := helloMethod{
+			Name:     []byte(method.Name),
+			Request:  method.Request,
+			Response: method.Response,
+		}
+
+		if method.ClientStream {
+			m.Flags |= helloMethodClientStream
+		}
+
+		if method.ServerStream {
+			m.Flags |= helloMethodServerStream
+		}
+
+		methods = append(methods, m)
+	}
+
+	return methods
+}
+
+func decodeHelloMethods(methods []helloMethod) []schema.RPCMethod {
+	res := make([]schema.RPCMethod, 0, len(methods))
+
+	for _, method := range methods {
+		res = append(res, schema.RPCMethod{
+			Name:         string(method.Name),
+			Request:      method.Request,
+			Response:     method.Response,
+			ClientStream: method.Flags&helloMethodClientStream != 0,
+			ServerStream: method.Flags&helloMethodServerStream != 0,
+		})
+	}
+
+	return res
+}
+
+// helloDefinitions encodes the type aliases and constants of the registered schema
+func helloDefinitions(r *schema.MessageDescriptorRegistry) ([]helloTypeAlias, []helloConstant, error) {
+	e := newHelloEncoding(r)
+
+	types := make([]helloTypeAlias, 0, len(r.Types))
+
+	for _, alias := range r.Types {
+		extra, err := e.encodeExtra(alias.Field)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		types = append(types, helloTypeAlias{
+			Name:  []byte(alias.Name),
+			Type:  uint16(alias.Field.Type),
+			Extra: extra,
+		})
+	}
+
+	constants := make([]helloConstant, 0, len(r.Constants))
+
+	for _, constant := range r.Constants {
+		constants = append(constants, helloConstant{
+			Name:  []byte(constant.Name),
+			Value: constant.Value,
+		})
+	}
+
+	return types, constants, nil
+}
+
+// decodeHelloDefinitions turns the type aliases and constants advertised by the server back into schema definitions
+func decodeHelloDefinitions(r *schema.MessageDescriptorRegistry, hello helloOutbound) ([]schema.TypeAlias, []schema.Constant, error) {
+	e := newHelloEncoding(r)
+
+	types := make([]schema.TypeAlias, 0, len(hello.Types))
+
+	for _, alias := range hello.Types {
+		field, err := e.decodeField(helloField{
+			Type:  alias.Type,
+			Extra: alias.Extra,
+		})
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		types = append(types, schema.TypeAlias{
+			Name:  string(alias.Name),
+			Field: field,
+		})
+	}
+
+	constants := make([]schema.Constant, 0, len(hello.Constants))
+
+	for _, constant := range hello.Constants {
+		constants = append(constants, schema.Constant{
+			Name:  string(constant.Name),
+			Value: constant.Value,
+		})
+	}
+
+	return types, constants, nil
+}
+
+func (s *Server) handleHello(r schema.Reader, conn schema.Conn) error {
+	c := conn.(*Conn)
+
+	if c.state != ConnWaitHello {
+		return ErrDuplicateHello
+	}
+
+	var hello helloInbound
+
+	err := r.Decode(&hello)
+
+	if err != nil {
+		return err
+	}
+
+	if hello.CurrVersion < MinProtocolVersion || hello.MinVersion > ProtocolVersion {
+		c.sendProtocolError("unsupported protocol version")
+		return ErrUnsupportedVersion
+	}
+
+	fingerprint := s.Registry.Fingerprint()
+
+	res := helloOutbound{
+		MinVersion:  MinProtocolVersion,
+		CurrVersion: ProtocolVersion,
+		Fingerprint: fingerprint,
+		Schema:      s.helloSchema,
+		Methods:     s.helloMethods,
+		Types:       s.helloTypes,
+		Constants:   s.helloConstants,
+	}
+
+	if bytes.Equal(hello.Fingerprint, fingerprint[:]) {
+		// the client already has this exact schema
+		res.Schema = nil
+		res.Methods = nil
+	}
+
+	err = c.sendInternal("outbound Hello", res)
+
+	if err != nil {
+		return err
+	}
+
+	c.state = ConnEstablished
+
+	return nil
+}
@@ -0,0 +1,259 @@
+// Package jsonschema generates JSON Schema (draft 2020-12) documents describing the JSON form of messages,
+// as produced by encoder.ToJSON and accepted by encoder.FromJSON.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type property struct {
+	name   string
+	schema *node
+}
+
+// properties keeps the schema order of the fields, a map would sort them
+type properties []property
+
+func (p properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(prop.name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(prop.schema)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+type node struct {
+	Schema               string           `json:"$schema,omitempty"`
+	Ref                  string           `json:"$ref,omitempty"`
+	Title                string           `json:"title,omitempty"`
+	Description          string           `json:"description,omitempty"`
+	Type                 string           `json:"type,omitempty"`
+	Properties           properties       `json:"properties,omitempty"`
+	Required             []string         `json:"required,omitempty"`
+	AdditionalProperties *bool            `json:"additionalProperties,omitempty"`
+	Items                *node            `json:"items,omitempty"`
+	MaxItems             *int             `json:"maxItems,omitempty"`
+	Minimum              *int64           `json:"minimum,omitempty"`
+	Maximum              *uint64          `json:"maximum,omitempty"`
+	Pattern              string           `json:"pattern,omitempty"`
+	ContentEncoding      string           `json:"contentEncoding,omitempty"`
+	MinLength            *int             `json:"minLength,omitempty"`
+	MaxLength            *int             `json:"maxLength,omitempty"`
+	Deprecated           bool             `json:"deprecated,omitempty"`
+	Defs                 map[string]*node `json:"$defs,omitempty"`
+}
+
+// base64Length is the length of n bytes encoded as padded base64
+func base64Length(n int) int {
+	return (n + 2) / 3 * 4
+}
+
+func integer(min int64, max uint64) *node {
+	return &node{
+		Type:    "integer",
+		Minimum: &min,
+		Maximum: &max,
+	}
+}
+
+type generator struct {
+	defs map[string]*node
+}
+
+func (g *generator) field(field schema.MessageField) *node {
+	var n *node
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		{
+			length := base64Length(field.Extra.(int))
+
+			n = &node{
+				Type:            "string",
+				ContentEncoding: "base64",
+				MinLength:       &length,
+				MaxLength:       &length,
+			}
+		}
+	case schema.TypeDynamicBinary:
+		{
+			length := base64Length(math.MaxUint16)
+
+			n = &node{
+				Type:            "string",
+				ContentEncoding: "base64",
+				MaxLength:       &length,
+			}
+		}
+	case schema.TypeLongBinary:
+		n = &node{
+			Type:            "string",
+			ContentEncoding: "base64",
+		}
+	case schema.TypeUInt64:
+		n = &node{
+			Type:        "string",
+			Pattern:     "^[0-9]+$",
+			Description: "uint64 as a decimal string",
+		}
+	case schema.TypeInt64:
+		n = &node{
+			Type:        "string",
+			Pattern:     "^-?[0-9]+$",
+			Description: "int64 as a decimal string",
+		}
+	case schema.TypeUInt32:
+		n = integer(0, math.MaxUint32)
+	case schema.TypeInt32:
+		n = integer(math.MinInt32, math.MaxInt32)
+	case schema.TypeUInt16:
+		n = integer(0, math.MaxUint16)
+	case schema.TypeInt16:
+		n = integer(math.MinInt16, math.MaxInt16)
+	case schema.TypeObject:
+		n = g.object(field)
+	case schema.TypeArray:
+		{
+			maxItems := math.MaxUint16
+
+			n = &node{
+				Type:     "array",
+				MaxItems: &maxItems,
+			}
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				n.Items = g.field(elem)
+			} else {
+				n.Items = g.object(field)
+			}
+		}
+	default:
+		n = &node{}
+	}
+
+	n.Deprecated = field.Deprecated
+
+	return n
+}
+
+// object returns a reference to a named object, which is defined once under $defs, or an anonymous object inline
+func (g *generator) object(field schema.MessageField) *node {
+	name := field.ObjectName()
+	message := messageOf(field.Extra)
+
+	if name == "" {
+		return g.message(message)
+	}
+
+	if _, exists := g.defs[name]; !exists {
+		g.defs[name] = g.message(message)
+	}
+
+	return &node{Ref: "#/$defs/" + name}
+}
+
+func messageOf(extra any) schema.SchemaMessage {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e.Message
+	case schema.SchemaMessage:
+		return e
+	default:
+		return schema.SchemaMessage{}
+	}
+}
+
+func (g *generator) message(message schema.SchemaMessage) *node {
+	noAdditional := false
+
+	n := &node{
+		Type:                 "object",
+		Properties:           make(properties, 0, len(message.Fields)),
+		AdditionalProperties: &noAdditional,
+	}
+
+	for _, field := range message.Fields {
+		n.Properties = append(n.Properties, property{
+			name:   field.Name,
+			schema: g.field(field),
+		})
+
+		if !field.Optional {
+			n.Required = append(n.Required, field.Name)
+		}
+	}
+
+	return n
+}
+
+// Generate returns the JSON Schema document of a message
+func Generate(message schema.SchemaMessage) ([]byte, error) {
+	g := generator{
+		defs: make(map[string]*node),
+	}
+
+	n := g.message(message)
+	n.Schema = Draft
+	n.Title = message.Name
+	n.Deprecated = message.Deprecated
+
+	if len(g.defs) > 0 {
+		n.Defs = g.defs
+	}
+
+	return json.MarshalIndent(n, "", "  ")
+}
+
+// GenerateRegistry returns the JSON Schema documents of the user-defined messages of a registry, by signature.
+// Duplex messages are listed under both their inbound and outbound signature.
+func GenerateRegistry(r *schema.MessageDescriptorRegistry) (map[string][]byte, error) {
+	res := make(map[string][]byte, len(r.UserSignatureMap))
+
+	for signature, id := range r.UserSignatureMap {
+		descriptor := r.Descriptors[id]
+
+		if descriptor.Message.Direction == schema.ObjectDef {
+			continue
+		}
+
+		doc, err := Generate(descriptor.Message)
+
+		if err != nil {
+			return nil, err
+		}
+
+		res[signature] = doc
+	}
+
+	return res, nil
+}
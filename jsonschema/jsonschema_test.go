@@ -0,0 +1,83 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	s, err := schema.Parse([]byte(`
+object Item {
+  binary(4) REQUIRED sku
+  uint16 REQUIRED count
+}
+
+duplex Order {
+  uint64 REQUIRED id
+  array(Item) REQUIRED items
+  Item OPTIONAL gift
+  @deprecated
+  array(int32) OPTIONAL tags
+}
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := GenerateRegistry(&r)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(docs) != 2 || string(docs["inbound Order"]) != string(docs["outbound Order"]) {
+		t.Fatalf("expected a document for both directions of Order, got %d", len(docs))
+	}
+
+	doc := docs["inbound Order"]
+
+	var parsed map[string]any
+
+	err = json.Unmarshal(doc, &parsed)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compact := strings.Join(strings.Fields(string(doc)), "")
+
+	expected := []string{
+		`"$schema":"https://json-schema.org/draft/2020-12/schema"`,
+		`"type":"string","pattern":"^[0-9]+$"`,
+		`"items":{"type":"array","items":{"$ref":"#/$defs/Item"},"maxItems":65535}`,
+		`"gift":{"$ref":"#/$defs/Item"}`,
+		`"required":["id","items"]`,
+		`"sku":{"type":"string","contentEncoding":"base64","minLength":8,"maxLength":8}`,
+		`"count":{"type":"integer","minimum":0,"maximum":65535}`,
+		`"deprecated":true`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(compact, e) {
+			t.Errorf("expected the document to contain %s:\n%s", e, doc)
+		}
+	}
+}
@@ -0,0 +1,867 @@
+// Package pygen generates a Python client from a schema: a dataclass for every message and object, functions
+// that encode and decode them with the struct module in the wire format, and a blocking socket Client that
+// performs the Hello handshake, dispatches received messages to handlers and calls the unary methods of the
+// services. The generated module only uses the standard library and needs Python 3.10 or later.
+package pygen
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// runtime.py holds the reader, writer and BaseClient classes the generated code is built on
+//
+//go:embed runtime.py
+var runtime string
+
+// the protocol versions the runtime implements, see ProtocolVersion and MinProtocolVersion in schemaipc
+const (
+	protocolVersion    = 1
+	minProtocolVersion = 1
+)
+
+// the internal messages used by the runtime
+var internalSignatures = []string{
+	"inbound Hello",
+	"outbound Hello",
+	"outbound ProtocolError",
+	"inbound RpcRequest",
+	"outbound RpcResponse",
+	"outbound RpcError",
+}
+
+// names declared or imported by the runtime, schema names must not generate them
+var reserved = []string{
+	"IPCError", "RemoteError", "RPCError", "BaseClient", "Client", "MessageId", "PROTOCOL_VERSION",
+	"MIN_PROTOCOL_VERSION", "FINGERPRINT", "Any", "Callable", "Optional", "annotations",
+}
+
+// keywords of Python, fields named after them get an underscore appended
+var keywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true,
+	"await": true, "break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// names used by the generated read functions, locals named after fields must not shadow them
+var readLocals = map[string]bool{
+	"r": true, "frame": true, "range": true, "_": true,
+}
+
+type Options struct {
+	Source string // file the schema was read from, named in the header of the generated file
+}
+
+type generator struct {
+	pkg     string            // package of the schema, stripped from the names it declares
+	names   map[string]string // Python identifier to the schema name it was derived from
+	buf     strings.Builder
+	depth   int             // indentation of the next line
+	structs map[string]bool // formats of the struct.Struct constants the generated code uses
+}
+
+// Generate returns the Python source for the schema and everything it imports
+func Generate(s schema.Schema, opts Options) ([]byte, error) {
+	g := generator{
+		pkg:     s.Package,
+		names:   make(map[string]string),
+		structs: make(map[string]bool),
+	}
+
+	for _, ident := range reserved {
+		g.names[ident] = ident
+	}
+
+	schemas := s.Files()
+
+	err := g.checkNames(schemas)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			g.message(message, g.className(message.Name), messageDoc(message), false)
+		}
+	}
+
+	for _, message := range schema.InternalSchema.Messages {
+		for _, signature := range internalSignatures {
+			if message.Signature() == signature {
+				g.message(message, internalName(message), fmt.Sprintf("The internal message %s.", message.Signature()), true)
+			}
+		}
+	}
+
+	g.client(schemas, opts.Source)
+
+	body := g.buf.String()
+
+	g.buf.Reset()
+
+	g.buf.WriteString("# Code generated by schemagen")
+
+	if opts.Source != "" {
+		fmt.Fprintf(&g.buf, " from %s", opts.Source)
+	}
+
+	g.buf.WriteString(". DO NOT EDIT.\n\n")
+	g.buf.WriteString(runtime)
+	g.line("")
+	g.line("")
+
+	g.line("PROTOCOL_VERSION = %d", protocolVersion)
+	g.line("MIN_PROTOCOL_VERSION = %d", minProtocolVersion)
+	g.line("")
+
+	fingerprint := r.Fingerprint()
+
+	g.line("# FINGERPRINT identifies the schema the client was generated from, handshake fails if the server has another one")
+	g.line("FINGERPRINT = bytes.fromhex(%q)", hex.EncodeToString(fingerprint[:]))
+	g.line("")
+	g.line("")
+
+	g.messageIDs(schemas, &r)
+
+	for _, file := range schemas {
+		g.constants(file.Constants)
+		g.typeAliases(file.Types)
+	}
+
+	for _, format := range sortedKeys(g.structs) {
+		g.line("_S_%s = struct.Struct(%q)", format, "<"+format)
+	}
+
+	g.buf.WriteString("\n\n")
+	g.buf.WriteString(strings.TrimRight(body, "\n"))
+	g.buf.WriteString("\n")
+
+	return []byte(g.buf.String()), nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// snakeName turns a class name into a function name, e.g. PlaceOrder becomes place_order
+func snakeName(name string) string {
+	var b strings.Builder
+
+	runes := []rune(name)
+
+	for i, c := range runes {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(c))
+	}
+
+	return b.String()
+}
+
+// constName turns a class name into the name of a constant, e.g. PlaceOrder becomes PLACE_ORDER
+func constName(name string) string {
+	return strings.ToUpper(snakeName(name))
+}
+
+// className returns the class name of a declared name, names from other packages keep their package as a prefix
+func (g *generator) className(name string) string {
+	if g.pkg != "" {
+		name = strings.TrimPrefix(name, g.pkg+".")
+	}
+
+	return schema.PascalName(name)
+}
+
+// internalName is the class name of an internal message, Hello exists in both directions
+func internalName(message schema.SchemaMessage) string {
+	return "_Internal" + schema.PascalName(message.Direction.ToString()) + message.Name
+}
+
+// fieldName returns the attribute name of a field
+func fieldName(field schema.MessageField) string {
+	if keywords[field.Name] {
+		return field.Name + "_"
+	}
+
+	return field.Name
+}
+
+// localName returns the name of the local variable a read function reads a field into
+func localName(field schema.MessageField) string {
+	name := fieldName(field)
+
+	if readLocals[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+func (g *generator) declare(ident, name string) error {
+	if existing, exists := g.names[ident]; exists && existing != name {
+		if existing == ident && name != ident {
+			return fmt.Errorf("%s generates the Python identifier %s, which is used by the runtime", name, ident)
+		}
+
+		return fmt.Errorf("%s and %s both generate the Python identifier %s", existing, name, ident)
+	}
+
+	g.names[ident] = name
+
+	return nil
+}
+
+// checkNames reports declarations that would generate the same Python identifier
+func (g *generator) checkNames(schemas []schema.Schema) error {
+	for _, file := range schemas {
+		for _, constant := range file.Constants {
+			err := g.declare(constName(g.className(constant.Name)), constant.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, alias := range file.Types {
+			err := g.declare(g.className(alias.Name), alias.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// messages are declared by signature, an inbound and an outbound message can't share a name
+		for _, message := range file.Messages {
+			err := g.declare(g.className(message.Name), message.Signature())
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// line writes a line of code at the current indentation, a line ending with : opens a block
+func (g *generator) line(format string, args ...any) {
+	code := fmt.Sprintf(format, args...)
+
+	if code != "" {
+		g.buf.WriteString(strings.Repeat("    ", g.depth))
+	}
+
+	g.buf.WriteString(code)
+	g.buf.WriteString("\n")
+
+	if strings.HasSuffix(code, ":") {
+		g.depth++
+	}
+}
+
+// dedent closes the block opened by the last line ending with :
+func (g *generator) dedent() {
+	g.depth--
+}
+
+// directions returns the directions a message is registered under
+func directions(message schema.SchemaMessage) []schema.MessageDirection {
+	switch message.Direction {
+	case schema.InboundMessage, schema.OutboundMessage:
+		return []schema.MessageDirection{message.Direction}
+	case schema.DuplexMessage:
+		return []schema.MessageDirection{schema.InboundMessage, schema.OutboundMessage}
+	default:
+		return nil
+	}
+}
+
+func (g *generator) messageIDs(schemas []schema.Schema, r *schema.MessageDescriptorRegistry) {
+	g.line("class _InternalId:")
+
+	for _, signature := range internalSignatures {
+		id := r.InternalSignatureMap[signature]
+		message := r.Descriptors[id].Message
+
+		g.line("%s = %d", constName(schema.PascalName(strings.Fields(signature)[0])+message.Name), id)
+	}
+
+	g.dedent()
+	g.line("")
+	g.line("")
+
+	g.line("class MessageId:")
+	g.line(`"""The descriptor ID of every message, the same as the server assigns."""`)
+	g.line("")
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			dirs := directions(message)
+
+			if len(dirs) == 0 {
+				continue
+			}
+
+			g.line("%s = %d", constName(g.className(message.Name)), r.UserSignatureMap[schema.Signature(dirs[0], message.Name)])
+		}
+	}
+
+	g.dedent()
+	g.line("")
+	g.line("")
+}
+
+func (g *generator) constants(constants []schema.Constant) {
+	for _, constant := range constants {
+		g.line("%s = %d", constName(g.className(constant.Name)), constant.Value)
+	}
+
+	if len(constants) > 0 {
+		g.line("")
+	}
+}
+
+func (g *generator) typeAliases(aliases []schema.TypeAlias) {
+	for _, alias := range aliases {
+		g.line("%s = %s", g.className(alias.Name), g.pyType(alias.Field, ""))
+	}
+
+	if len(aliases) > 0 {
+		g.line("")
+	}
+}
+
+func nestedMessage(extra any) schema.SchemaMessage {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e.Message
+	case schema.SchemaMessage:
+		return e
+	default:
+		return schema.SchemaMessage{}
+	}
+}
+
+// flatten returns the fields of a message with the fields of embedded objects spliced in, the same as they
+// appear on the wire
+func flatten(fields []schema.MessageField) []schema.MessageField {
+	var res []schema.MessageField
+
+	for _, field := range fields {
+		if field.Embedded {
+			res = append(res, flatten(nestedMessage(field.Extra).Fields)...)
+		} else {
+			res = append(res, field)
+		}
+	}
+
+	return res
+}
+
+// countOptional counts the optional fields of a message, including those of included objects
+func countOptional(fields []schema.MessageField) int {
+	var count int
+
+	for _, field := range flatten(fields) {
+		if field.Optional {
+			count++
+		}
+	}
+
+	return count
+}
+
+// objectClass returns the class of an object field or an array of objects, anonymous objects get a class
+// named after the class and field they are declared in
+func (g *generator) objectClass(field schema.MessageField, parent string) string {
+	if name := field.ObjectName(); name != "" {
+		return g.className(name)
+	}
+
+	return parent + schema.PascalName(field.Name)
+}
+
+// arrayObject returns the object an array holds, ok is false for arrays of other types
+func arrayObject(field schema.MessageField) (schema.SchemaMessage, bool) {
+	if _, isField := field.Extra.(schema.MessageField); isField {
+		return schema.SchemaMessage{}, false
+	}
+
+	return nestedMessage(field.Extra), true
+}
+
+// pyType returns the type hint of a field, parent is the class the field is declared in
+func (g *generator) pyType(field schema.MessageField, parent string) string {
+	if field.TypeAlias != "" {
+		return g.className(field.TypeAlias)
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary, schema.TypeDynamicBinary, schema.TypeLongBinary:
+		return "bytes"
+	case schema.TypeObject:
+		return g.objectClass(field, parent)
+	case schema.TypeArray:
+		{
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				return "list[" + g.pyType(elem, parent) + "]"
+			}
+
+			return "list[" + g.objectClass(field, parent) + "]"
+		}
+	default:
+		return "int"
+	}
+}
+
+// structCode is the format character of an integer type in the struct module
+func structCode(t schema.FieldType) (string, bool) {
+	switch t {
+	case schema.TypeUInt64:
+		return "Q", true
+	case schema.TypeInt64:
+		return "q", true
+	case schema.TypeUInt32:
+		return "I", true
+	case schema.TypeInt32:
+		return "i", true
+	case schema.TypeUInt16:
+		return "H", true
+	case schema.TypeInt16:
+		return "h", true
+	default:
+		return "", false
+	}
+}
+
+// anonymousObjects returns the fields of a message that declare anonymous objects
+func anonymousObjects(fields []schema.MessageField) []schema.MessageField {
+	var res []schema.MessageField
+
+	for _, field := range flatten(fields) {
+		switch field.Type {
+		case schema.TypeObject:
+			{
+				if nestedMessage(field.Extra).Name == "" {
+					res = append(res, field)
+				}
+			}
+		case schema.TypeArray:
+			{
+				if object, ok := arrayObject(field); ok && object.Name == "" {
+					res = append(res, field)
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// message writes the dataclass of a message or object and the functions that write and read it, user
+// messages also get public encode and decode functions
+func (g *generator) message(message schema.SchemaMessage, name, doc string, internal bool) {
+	for _, field := range anonymousObjects(message.Fields) {
+		object, ok := arrayObject(field)
+
+		if !ok {
+			object = nestedMessage(field.Extra)
+		}
+
+		// objects of internal messages are only written or read along with the message
+		if internal {
+			object.Direction = message.Direction
+		} else {
+			object.Direction = schema.ObjectDef
+		}
+
+		g.message(object, g.objectClass(field, name), fmt.Sprintf("An object of the field %s of %s.", field.Name, name), internal)
+	}
+
+	var bases []string
+	var fields []schema.MessageField
+
+	// included objects are inherited from, their fields are spliced into the message on the wire
+	for _, field := range message.Fields {
+		if field.Embedded && field.ObjectName() != "" {
+			bases = append(bases, g.pyType(field, name))
+		} else if field.Embedded {
+			fields = append(fields, flatten(nestedMessage(field.Extra).Fields)...)
+		} else {
+			fields = append(fields, field)
+		}
+	}
+
+	g.line("@dataclass(kw_only=True)")
+
+	if len(bases) > 0 {
+		g.line("class %s(%s):", name, strings.Join(bases, ", "))
+	} else {
+		g.line("class %s:", name)
+	}
+
+	g.line(`"""%s"""`, doc)
+
+	if message.Deprecated {
+		g.line("")
+		g.line("# Deprecated: the message is deprecated in the schema.")
+	}
+
+	if len(fields) > 0 {
+		g.line("")
+	}
+
+	for _, field := range fields {
+		if field.Deprecated {
+			g.line("# Deprecated: the field is deprecated in the schema.")
+		}
+
+		if field.Optional {
+			g.line("%s: Optional[%s] = None", fieldName(field), g.pyType(field, name))
+		} else {
+			g.line("%s: %s", fieldName(field), g.pyType(field, name))
+		}
+	}
+
+	g.dedent()
+	g.line("")
+	g.line("")
+
+	function := snakeName(strings.TrimPrefix(name, "_"))
+
+	// the client only writes inbound internal messages and reads outbound ones
+	if !internal || message.Direction != schema.OutboundMessage {
+		g.writeFunction(message, name, function)
+	}
+
+	if !internal || message.Direction != schema.InboundMessage {
+		g.readFunction(message, name, function)
+	}
+
+	if internal || message.Direction == schema.ObjectDef || message.Name == "" {
+		return
+	}
+
+	g.line("def encode_%s(m: %s) -> bytes:", function, name)
+	g.line("return _encode(_write_%s, m)", function)
+	g.dedent()
+	g.line("")
+	g.line("")
+
+	g.line("def decode_%s(payload: bytes) -> %s:", function, name)
+	g.line("return _read_%s(_Reader(payload))", function)
+	g.dedent()
+	g.line("")
+	g.line("")
+}
+
+func messageDoc(message schema.SchemaMessage) string {
+	if message.Direction == schema.ObjectDef {
+		return fmt.Sprintf("The object %s.", message.Name)
+	}
+
+	return fmt.Sprintf("The %s message %s.", message.Direction.ToString(), message.Name)
+}
+
+// intRun returns the number of consecutive required integer fields at the start of fields, they are packed
+// with a single struct
+func intRun(fields []schema.MessageField) (int, string) {
+	var format string
+
+	for i, field := range fields {
+		code, ok := structCode(field.Type)
+
+		if !ok || field.Optional {
+			return i, format
+		}
+
+		format += code
+	}
+
+	return len(fields), format
+}
+
+func (g *generator) structName(format string) string {
+	g.structs[format] = true
+	return "_S_" + format
+}
+
+func (g *generator) writeFunction(message schema.SchemaMessage, name, function string) {
+	fields := flatten(message.Fields)
+	extensible := pyBool(message.Extensible)
+
+	g.line("def _write_%s(w: _Writer, m: %s) -> None:", function, name)
+	g.line("start = w.begin_message(%d, %s)", countOptional(message.Fields), extensible)
+
+	var opt int
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		value := "m." + fieldName(field)
+
+		if n, format := intRun(fields[i:]); n > 0 {
+			values := make([]string, n)
+
+			for j := range values {
+				values[j] = "m." + fieldName(fields[i+j])
+			}
+
+			g.line("w.pack(%s, %s)", g.structName(format), strings.Join(values, ", "))
+
+			i += n - 1
+			continue
+		}
+
+		if !field.Optional {
+			g.writeValue(value, field, name)
+			continue
+		}
+
+		g.line("if %s is not None:", value)
+		g.line("w.set_optional(start, %d)", opt)
+		g.writeValue(value, field, name)
+		g.dedent()
+
+		opt++
+	}
+
+	g.line("w.end_message(start, %s)", extensible)
+	g.dedent()
+	g.line("")
+	g.line("")
+}
+
+func (g *generator) writeValue(expr string, field schema.MessageField, parent string) {
+	if code, ok := structCode(field.Type); ok {
+		g.line("w.pack(%s, %s)", g.structName(code), expr)
+		return
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		g.line("w.fixed_binary(%s, %d)", expr, field.Extra.(int))
+	case schema.TypeDynamicBinary:
+		g.line("w.binary(%s)", expr)
+	case schema.TypeLongBinary:
+		g.line("w.long_binary(%s)", expr)
+	case schema.TypeObject:
+		g.line("_write_%s(w, %s)", snakeName(strings.TrimPrefix(g.objectClass(field, parent), "_")), expr)
+	case schema.TypeArray:
+		{
+			elem, isField := field.Extra.(schema.MessageField)
+
+			// arrays of integers are packed at once
+			if code, ok := structCode(elem.Type); isField && ok {
+				g.line("w.pack_array(%q, %s)", code, expr)
+				return
+			}
+
+			g.line("w.array_len(len(%s))", expr)
+			g.line("for item in %s:", expr)
+
+			if isField {
+				g.writeValue("item", elem, parent)
+			} else {
+				g.line("_write_%s(w, item)", snakeName(strings.TrimPrefix(g.objectClass(field, parent), "_")))
+			}
+
+			g.dedent()
+		}
+	}
+}
+
+func (g *generator) readFunction(message schema.SchemaMessage, name, function string) {
+	fields := flatten(message.Fields)
+	extensible := pyBool(message.Extensible)
+
+	g.line("def _read_%s(r: _Reader) -> %s:", function, name)
+	g.line("frame = r.begin_message(%d, %s)", countOptional(message.Fields), extensible)
+
+	args := make([]string, len(fields))
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+
+		args[i] = fmt.Sprintf("%s=%s", fieldName(field), localName(field))
+
+		// a required field sent by an older peer is missing from an extensible message
+		if message.Extensible && !field.Optional {
+			g.line("r.check_required(frame)")
+			g.line("%s = %s", localName(field), g.readValue(field, name))
+			continue
+		}
+
+		if n, format := intRun(fields[i:]); n > 1 {
+			locals := make([]string, n)
+
+			for j := range locals {
+				locals[j] = localName(fields[i+j])
+				args[i+j] = fmt.Sprintf("%s=%s", fieldName(fields[i+j]), locals[j])
+			}
+
+			g.line("%s = r.unpack(%s)", strings.Join(locals, ", "), g.structName(format))
+
+			i += n - 1
+			continue
+		}
+
+		if field.Optional {
+			g.line("%s = %s if r.present(frame) else None", localName(field), g.readValue(field, name))
+		} else {
+			g.line("%s = %s", localName(field), g.readValue(field, name))
+		}
+	}
+
+	g.line("r.end_message(frame, %s)", extensible)
+	g.line("return %s(%s)", name, strings.Join(args, ", "))
+	g.dedent()
+	g.line("")
+	g.line("")
+}
+
+// readValue returns the expression that reads a field
+func (g *generator) readValue(field schema.MessageField, parent string) string {
+	if code, ok := structCode(field.Type); ok {
+		return fmt.Sprintf("r.unpack(%s)[0]", g.structName(code))
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		return fmt.Sprintf("r.fixed_binary(%d)", field.Extra.(int))
+	case schema.TypeDynamicBinary:
+		return "r.binary()"
+	case schema.TypeLongBinary:
+		return "r.long_binary()"
+	case schema.TypeObject:
+		return fmt.Sprintf("_read_%s(r)", snakeName(strings.TrimPrefix(g.objectClass(field, parent), "_")))
+	case schema.TypeArray:
+		{
+			elem, isField := field.Extra.(schema.MessageField)
+
+			if code, ok := structCode(elem.Type); isField && ok {
+				return fmt.Sprintf("r.unpack_array(%q)", code)
+			}
+
+			item := fmt.Sprintf("_read_%s(r)", snakeName(strings.TrimPrefix(g.objectClass(field, parent), "_")))
+
+			if isField {
+				item = g.readValue(elem, parent)
+			}
+
+			return fmt.Sprintf("[%s for _ in range(r.array_len())]", item)
+		}
+	default:
+		return "None"
+	}
+}
+
+func pyBool(b bool) string {
+	if b {
+		return "True"
+	}
+
+	return "False"
+}
+
+func (g *generator) client(schemas []schema.Schema, source string) {
+	messages := make(map[string]schema.SchemaMessage)
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			messages[message.Name] = message
+		}
+	}
+
+	g.line("class Client(BaseClient):")
+
+	if source != "" {
+		g.line(`"""Sends and receives the messages of %s, handshake must succeed before anything else."""`, source)
+	} else {
+		g.line(`"""Sends and receives the messages of the schema, handshake must succeed before anything else."""`)
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			name := g.className(message.Name)
+			function := snakeName(name)
+
+			for _, direction := range directions(message) {
+				g.line("")
+
+				if direction == schema.InboundMessage {
+					g.line("def send_%s(self, m: %s) -> None:", function, name)
+					g.line(`"""Sends %s to the server."""`, schema.Signature(direction, message.Name))
+					g.line("self._send(MessageId.%s, encode_%s(m), %s)", constName(name), function, pyBool(message.Options.Compress))
+				} else {
+					g.line("def on_%s(self, handler: Callable[[%s], None]) -> None:", function, name)
+					g.line(`"""Sets the handler of %s."""`, schema.Signature(direction, message.Name))
+					g.line("self._handle(MessageId.%s, %s, decode_%s, handler)", constName(name), pyBool(message.Options.Compress), function)
+				}
+
+				g.dedent()
+			}
+		}
+	}
+
+	for _, file := range schemas {
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				g.line("")
+
+				if method.ClientStream || method.ServerStream {
+					g.line("# %s is a streaming method, which the Python client doesn't support yet", service.MethodName(method))
+					continue
+				}
+
+				request := messages[method.Request]
+				response := messages[method.Response]
+
+				g.line("def %s_%s(self, req: %s) -> %s:", snakeName(g.className(service.Name)), snakeName(schema.PascalName(method.Name)), g.className(method.Request), g.className(method.Response))
+				g.line(`"""Calls %s."""`, method.ToString())
+				g.line("res = self._call(%q, encode_%s(req), %s, %s)", service.MethodName(method), snakeName(g.className(method.Request)), pyBool(request.Options.Compress), pyBool(response.Options.Compress))
+				g.line("return decode_%s(res)", snakeName(g.className(method.Response)))
+				g.dedent()
+			}
+		}
+	}
+
+	g.dedent()
+}
@@ -0,0 +1,157 @@
+package pygen
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestGenerateIsUpToDate(t *testing.T) {
+	files := map[string]string{
+		"../examples/shop/shop.schema": "../examples/shop/shop.py",
+		"../golden/golden.schema":      "../golden/python/golden.py",
+	}
+
+	for source, generated := range files {
+		s, err := schema.ParseFile(source)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, err := Generate(s, Options{Source: source[strings.LastIndex(source, "/")+1:]})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := os.ReadFile(generated)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(code, expected) {
+			t.Errorf("%s is out of date, run go generate ./examples/shop ./golden", strings.TrimPrefix(generated, "../"))
+		}
+	}
+}
+
+func TestGenerateMatchesRegistry(t *testing.T) {
+	s, err := schema.ParseFile("../examples/shop/shop.schema")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := r.Fingerprint()
+
+	expected := []string{
+		fmt.Sprintf("FINGERPRINT = bytes.fromhex(%q)", hex.EncodeToString(fingerprint[:])),
+		fmt.Sprintf("    PLACE_ORDER = %d", r.UserSignatureMap["inbound shop.PlaceOrder"]),
+		fmt.Sprintf("    PING = %d", r.UserSignatureMap["inbound shop.Ping"]),
+		fmt.Sprintf("    OUTBOUND_HELLO = %d", r.InternalSignatureMap["outbound Hello"]),
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(code), line+"\n") {
+			t.Errorf("expected the generated code to contain %q", line)
+		}
+	}
+}
+
+func TestGenerateNameCollision(t *testing.T) {
+	tests := map[string]string{
+		"inbound user_name {\n  uint32 REQUIRED id\n}\n\noutbound UserName {\n  uint32 REQUIRED id\n}": "inbound user_name and outbound UserName",
+		"const max_size = 1\n\nconst MaxSize = 2":                                                      "max_size and MaxSize",
+		"outbound Client {\n  uint32 REQUIRED id\n}":                                                   "used by the runtime",
+	}
+
+	for source, message := range tests {
+		s, err := schema.Parse([]byte(source))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = Generate(s, Options{})
+
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q, got %v", message, err)
+		}
+	}
+}
+
+func TestGenerateNames(t *testing.T) {
+	s, err := schema.Parse([]byte(`
+object Flags {
+  uint16 OPTIONAL a
+  uint16 OPTIONAL b
+  uint16 OPTIONAL c
+  uint16 OPTIONAL d
+  uint16 OPTIONAL e
+  uint16 OPTIONAL f
+  uint16 OPTIONAL g
+  uint16 OPTIONAL h
+}
+
+inbound HTTPRequest {
+  include Flags
+  uint16 OPTIONAL i
+  uint32 REQUIRED from
+  uint32 REQUIRED frame
+}
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// i is the ninth optional field, keywords and names used by the read functions are renamed
+	expected := []string{
+		"def encode_http_request(m: HTTPRequest) -> bytes:",
+		"    start = w.begin_message(9, False)",
+		"        w.set_optional(start, 8)",
+		"    from_: int",
+		"    w.pack(_S_II, m.from_, m.frame)",
+		"    from_, frame_ = r.unpack(_S_II)",
+		"    return HTTPRequest(a=a, b=b, c=c, d=d, e=e, f=f, g=g, h=h, i=i, from_=from_, frame=frame_)",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(code), line+"\n") {
+			t.Errorf("expected the generated code to contain %q", line)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+from __future__ import annotations
+
+import socket
+import struct
+import zlib
+from dataclasses import dataclass
+from typing import Any, Callable, Optional
+
+
+class IPCError(Exception):
+    pass
+
+
+class RemoteError(IPCError):
+    """A protocol error sent by the server, the connection is closed after it."""
+
+
+class RPCError(IPCError):
+    """An error returned by a method handler on the server, the connection stays usable."""
+
+    def __init__(self, method: str, message: str) -> None:
+        super().__init__(f"rpc {method}: {message}")
+        self.method = method
+
+
+_HEADER = struct.Struct("<II")
+_U8 = struct.Struct("<B")
+_U16 = struct.Struct("<H")
+_U32 = struct.Struct("<I")
+
+
+class _Writer:
+    __slots__ = ("buf",)
+
+    def __init__(self) -> None:
+        self.buf = bytearray()
+
+    def pack(self, s: struct.Struct, *values: Any) -> None:
+        try:
+            self.buf += s.pack(*values)
+        except struct.error as e:
+            raise IPCError(f"integer field: {e}") from None
+
+    def fixed_binary(self, b: bytes, n: int) -> None:
+        if len(b) != n:
+            raise IPCError("fixed binary field: wrong length")
+
+        self.buf += b
+
+    def binary(self, b: bytes) -> None:
+        if len(b) > 0xFFFF:
+            raise IPCError("binary field: length too long (must not be more than 65,535 bytes)")
+
+        self.buf += _U16.pack(len(b))
+        self.buf += b
+
+    def long_binary(self, b: bytes) -> None:
+        self.buf += _U32.pack(len(b))
+        self.buf += b
+
+    def array_len(self, n: int) -> None:
+        if n > 0xFFFF:
+            raise IPCError("array field: length too long (must not be more than 65,535 elements)")
+
+        self.buf += _U16.pack(n)
+
+    def pack_array(self, code: str, values: list[int]) -> None:
+        self.array_len(len(values))
+        self.pack(struct.Struct(f"<{len(values)}{code}"), *values)
+
+    def begin_message(self, optional_count: int, extensible: bool) -> tuple[int, int]:
+        opt_bytes = (optional_count + 7) // 8
+        header = len(self.buf)
+
+        if extensible:
+            if opt_bytes > 255:
+                raise IPCError("extensible message: too many optional fields (must not be more than 2,040)")
+
+            self.buf += _U32.pack(0)
+            self.buf += _U8.pack(opt_bytes)
+
+        opt_list = len(self.buf)
+        self.buf += bytes(opt_bytes)
+
+        return header, opt_list
+
+    def set_optional(self, start: tuple[int, int], n: int) -> None:
+        self.buf[start[1] + (n >> 3)] |= 1 << (n & 7)
+
+    def end_message(self, start: tuple[int, int], extensible: bool) -> None:
+        if extensible:
+            _U32.pack_into(self.buf, start[0], len(self.buf) - start[0] - 4)
+
+
+class _Frame:
+    __slots__ = ("opt_list", "opt", "end", "outer_len")
+
+    def __init__(self, opt_list: bytes, end: int, outer_len: int) -> None:
+        self.opt_list = opt_list
+        self.opt = 0
+        self.end = end
+        self.outer_len = outer_len
+
+
+class _Reader:
+    __slots__ = ("buf", "pos", "len")
+
+    def __init__(self, buf: bytes) -> None:
+        self.buf = buf
+        self.pos = 0
+        self.len = len(buf)
+
+    def take(self, n: int) -> int:
+        if n > self.len - self.pos:
+            raise IPCError("out of bounds")
+
+        pos = self.pos
+        self.pos += n
+
+        return pos
+
+    def unpack(self, s: struct.Struct) -> tuple:
+        return s.unpack_from(self.buf, self.take(s.size))
+
+    def fixed_binary(self, n: int) -> bytes:
+        pos = self.take(n)
+        return bytes(self.buf[pos:pos + n])
+
+    def binary(self) -> bytes:
+        return self.fixed_binary(self.unpack(_U16)[0])
+
+    def long_binary(self) -> bytes:
+        return self.fixed_binary(self.unpack(_U32)[0])
+
+    def array_len(self) -> int:
+        return self.unpack(_U16)[0]
+
+    def unpack_array(self, code: str) -> list[int]:
+        return list(self.unpack(struct.Struct(f"<{self.array_len()}{code}")))
+
+    def begin_message(self, optional_count: int, extensible: bool) -> _Frame:
+        opt_bytes = (optional_count + 7) // 8
+        end = outer_len = 0
+
+        if extensible:
+            body_len = self.unpack(_U32)[0]
+
+            if body_len > self.len - self.pos:
+                raise IPCError("out of bounds")
+
+            end = self.pos + body_len
+            outer_len = self.len
+            self.len = end
+
+            # the sender might know about more or less optional fields than we do
+            opt_bytes = self.unpack(_U8)[0]
+
+        return _Frame(self.fixed_binary(opt_bytes), end, outer_len)
+
+    def present(self, frame: _Frame) -> bool:
+        opt = frame.opt
+        frame.opt += 1
+
+        return opt < len(frame.opt_list) * 8 and frame.opt_list[opt >> 3] & (1 << (opt & 7)) != 0
+
+    def check_required(self, frame: _Frame) -> None:
+        if self.pos == frame.end:
+            raise IPCError("required field is missing from message")
+
+    def end_message(self, frame: _Frame, extensible: bool) -> None:
+        if extensible:
+            # skip trailing fields sent by a peer with a newer schema
+            self.pos = frame.end
+            self.len = frame.outer_len
+
+
+# compressed messages are raw deflate streams
+def _deflate(data: bytes) -> bytes:
+    c = zlib.compressobj(wbits=-15)
+    return c.compress(data) + c.flush()
+
+
+def _inflate(data: bytes, limit: int) -> bytes:
+    d = zlib.decompressobj(wbits=-15)
+    res = d.decompress(data, limit + 1 if limit else 0)
+
+    if limit and len(res) > limit:
+        raise IPCError("message too large")
+
+    return res
+
+
+@dataclass
+class _Handler:
+    decode: Callable[[bytes], Any]
+    handle: Callable[[Any], None]
+    compress: bool
+
+
+class BaseClient:
+    """A blocking client over a stream socket. Messages sent by the server are dispatched to their handlers
+    while a call waits for its response, or by poll."""
+
+    def __init__(self, sock: socket.socket, max_message_size: int = 0) -> None:
+        self.sock = sock
+        self.max_message_size = max_message_size
+        self._handlers: dict[int, _Handler] = {}
+        self._responses: dict[int, tuple[bool, bytes]] = {}
+        self._last_call_id = 0
+
+    @classmethod
+    def connect(cls, address: tuple[str, int], max_message_size: int = 0):
+        client = cls(socket.create_connection(address), max_message_size)
+        client.handshake()
+
+        return client
+
+    def close(self) -> None:
+        self.sock.close()
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc: Any) -> None:
+        self.close()
+
+    def _recv_exact(self, n: int) -> bytes:
+        buf = bytearray()
+
+        while len(buf) < n:
+            chunk = self.sock.recv(n - len(buf))
+
+            if not chunk:
+                raise IPCError("connection closed")
+
+            buf += chunk
+
+        return bytes(buf)
+
+    def _read_frame(self) -> tuple[int, bytes]:
+        length, msg_id = _HEADER.unpack(self._recv_exact(_HEADER.size))
+
+        if self.max_message_size and length > self.max_message_size:
+            raise IPCError("message too large")
+
+        return msg_id, self._recv_exact(length)
+
+    def _write_frame(self, msg_id: int, payload: bytes) -> None:
+        self.sock.sendall(_HEADER.pack(len(payload), msg_id) + payload)
+
+    def handshake(self) -> None:
+        """Sends Hello and checks that the server has the schema the client was generated from."""
+        self._write_frame(_InternalId.INBOUND_HELLO, _encode(_write_internal_inbound_hello, _InternalInboundHello(
+            minVersion=MIN_PROTOCOL_VERSION,
+            currVersion=PROTOCOL_VERSION,
+            fingerprint=FINGERPRINT,
+        )))
+
+        msg_id, payload = self._read_frame()
+
+        if msg_id == _InternalId.OUTBOUND_PROTOCOL_ERROR:
+            raise RemoteError(f"protocol error: {self._protocol_error(payload)}")
+
+        if msg_id != _InternalId.OUTBOUND_HELLO:
+            raise IPCError("server sent an unexpected message")
+
+        hello = _read_internal_outbound_hello(_Reader(payload))
+
+        if hello.currVersion < MIN_PROTOCOL_VERSION or hello.minVersion > PROTOCOL_VERSION:
+            raise IPCError("peer protocol version is not supported")
+
+        if hello.fingerprint != FINGERPRINT:
+            raise IPCError("server schema doesn't match the client schema")
+
+    def _protocol_error(self, payload: bytes) -> str:
+        return _read_internal_outbound_protocol_error(_Reader(payload)).message.decode(errors="replace")
+
+    def poll(self) -> None:
+        """Reads one message from the server and dispatches it."""
+        msg_id, payload = self._read_frame()
+
+        if msg_id == _InternalId.OUTBOUND_PROTOCOL_ERROR:
+            raise RemoteError(f"protocol error: {self._protocol_error(payload)}")
+
+        if msg_id == _InternalId.OUTBOUND_RPC_RESPONSE:
+            res = _read_internal_outbound_rpc_response(_Reader(payload))
+            self._responses[res.callId] = (True, res.payload)
+            return
+
+        if msg_id == _InternalId.OUTBOUND_RPC_ERROR:
+            res = _read_internal_outbound_rpc_error(_Reader(payload))
+            self._responses[res.callId] = (False, res.message)
+            return
+
+        handler = self._handlers.get(msg_id)
+
+        if handler is None:
+            return
+
+        if handler.compress:
+            payload = _inflate(payload, self.max_message_size)
+
+        handler.handle(handler.decode(payload))
+
+    def _send(self, msg_id: int, payload: bytes, compress: bool) -> None:
+        self._write_frame(msg_id, _deflate(payload) if compress else payload)
+
+    def _handle(self, msg_id: int, compress: bool, decode: Callable[[bytes], Any], handler: Callable[[Any], None]) -> None:
+        self._handlers[msg_id] = _Handler(decode, handler, compress)
+
+    def _call(self, method: str, payload: bytes, request_compress: bool, response_compress: bool) -> bytes:
+        self._last_call_id = (self._last_call_id + 1) & 0xFFFFFFFF
+        call_id = self._last_call_id
+
+        if request_compress:
+            payload = _deflate(payload)
+
+        self._write_frame(_InternalId.INBOUND_RPC_REQUEST, _encode(_write_internal_inbound_rpc_request, _InternalInboundRpcRequest(
+            callId=call_id,
+            method=method.encode(),
+            payload=payload,
+        )))
+
+        while call_id not in self._responses:
+            self.poll()
+
+        ok, res = self._responses.pop(call_id)
+
+        if not ok:
+            raise RPCError(method, res.decode(errors="replace"))
+
+        return _inflate(res, self.max_message_size) if response_compress else res
+
+
+def _encode(write: Callable[[_Writer, Any], None], m: Any) -> bytes:
+    w = _Writer()
+    write(w, m)
+
+    return bytes(w.buf)
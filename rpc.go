@@ -0,0 +1,329 @@
+package schemaipc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var ErrUnknownMethod = errors.New("unknown RPC method")
+var ErrCallAborted = errors.New("connection closed before the call was answered")
+
+type RPCError struct {
+	Method  string
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc %s: %s", e.Method, e.Message)
+}
+
+type rpcRequest struct {
+	CallID  uint32 `ipc:"callId"`
+	Method  []byte `ipc:"method"`
+	Payload []byte `ipc:"payload"`
+}
+
+type rpcResponse struct {
+	CallID  uint32 `ipc:"callId"`
+	Payload []byte `ipc:"payload"`
+}
+
+type rpcError struct {
+	CallID  uint32 `ipc:"callId"`
+	Message []byte `ipc:"message"`
+}
+
+type rpcHandler func(callID uint32, payload []byte, c *Conn) error
+
+// RegisterRPC registers the handler of a service method (Service.Method), it panics if Req or Res
+// can't be decoded from the request or encoded as the response of the method
+func RegisterRPC[Req, Res any](s *Server, method string, handler func(req *Req, c *Conn) (Res, error)) {
+	m, request, response := s.checkRPCHandler(method, reflect.TypeFor[Req](), reflect.TypeFor[Res]())
+
+	if m.Streaming() {
+		s := fmt.Sprintf("method (%s) is streaming, use RegisterStream", method)
+		panic(s)
+	}
+
+	s.rpcHandlers[method] = func(callID uint32, payload []byte, c *Conn) error {
+		var req Req
+
+		payload, err := decodePayload(request, payload, messageLimit(request, c.server.MaxMessageSize))
+
+		if err != nil {
+			return c.sendRPCError(callID, err.Error())
+		}
+
+		reader := encoder.NewReader(payload, request)
+		reader.SetMode(c.server.DecodeMode)
+		err = reader.Decode(&req)
+
+		if err != nil {
+			return err
+		}
+
+		res, err := handler(&req, c)
+
+		if err != nil {
+			// handler errors are returned to the caller, the connection stays usable
+			return c.sendRPCError(callID, err.Error())
+		}
+
+		payload, err = encodePayload(response, res)
+
+		if err != nil {
+			return err
+		}
+
+		return c.sendEnvelope("outbound RpcResponse", response, rpcResponse{
+			CallID:  callID,
+			Payload: payload,
+		})
+	}
+}
+
+// checkRPCHandler looks up a method a handler is being registered for and checks the handler's types against it
+func (s *Server) checkRPCHandler(method string, req, res reflect.Type) (schema.RPCMethod, schema.MessageDescriptor, schema.MessageDescriptor) {
+	if !s.Registry.RegisteredUser {
+		panic("schema is not registered")
+	}
+
+	m, exists := s.Registry.Methods[method]
+
+	if !exists {
+		s := fmt.Sprintf("method (%s) doesn't exist", method)
+		panic(s)
+	}
+
+	if s.rpcHandlers[method] != nil {
+		s := fmt.Sprintf("method (%s) is already registered", method)
+		panic(s)
+	}
+
+	request := s.Registry.Descriptors[m.Request]
+	response := s.Registry.Descriptors[m.Response]
+
+	err := encoder.CheckType(request, req)
+
+	if err != nil {
+		s := fmt.Sprintf("request of method (%s): %v", method, err)
+		panic(s)
+	}
+
+	err = encoder.CheckType(response, res)
+
+	if err != nil {
+		s := fmt.Sprintf("response of method (%s): %v", method, err)
+		panic(s)
+	}
+
+	if s.rpcHandlers == nil {
+		s.rpcHandlers = make(map[string]rpcHandler)
+	}
+
+	return m, request, response
+}
+
+func (c *Conn) sendRPCError(callID uint32, message string) error {
+	return c.sendInternal("outbound RpcError", rpcError{
+		CallID:  callID,
+		Message: []byte(message),
+	})
+}
+
+func (s *Server) handleRPCRequest(r schema.Reader, conn schema.Conn) error {
+	c := conn.(*Conn)
+
+	if c.state != ConnEstablished {
+		return ErrInvalidDescriptor
+	}
+
+	var req rpcRequest
+
+	err := r.Decode(&req)
+
+	if err != nil {
+		return err
+	}
+
+	method := string(req.Method)
+	handler := s.rpcHandlers[method]
+
+	if handler == nil {
+		return c.sendRPCError(req.CallID, fmt.Sprintf("%v: %s", ErrUnknownMethod, method))
+	}
+
+	return handler(req.CallID, req.Payload, c)
+}
+
+type rpcResult struct {
+	payload []byte
+	err     error
+}
+
+// Call invokes a service method (Service.Method) and decodes its response into res.
+// Responses are received by Run, which must be running for the call to complete.
+func (c *Client) Call(method string, req any, res any) error {
+	if c.peer == nil {
+		return ErrNotEstablished
+	}
+
+	m, exists := c.peer.Methods[method]
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownMethod, method)
+	}
+
+	if m.Streaming() {
+		return fmt.Errorf("%w: %s", ErrStreamingMethod, method)
+	}
+
+	request := c.peer.Descriptors[m.Request]
+
+	payload, err := encodePayload(request, req)
+
+	if err != nil {
+		return err
+	}
+
+	result := make(chan rpcResult, 1)
+
+	callID, err := c.addCall(result, nil)
+
+	if err != nil {
+		return err
+	}
+
+	err = c.writeMessage(carrying(c.internalDescriptor("inbound RpcRequest"), request), rpcRequest{
+		CallID:  callID,
+		Method:  []byte(method),
+		Payload: payload,
+	})
+
+	if err != nil {
+		c.takeCall(callID)
+		return err
+	}
+
+	r := <-result
+
+	if r.err != nil {
+		if rpcErr, ok := r.err.(*RPCError); ok {
+			rpcErr.Method = method
+		}
+
+		return r.err
+	}
+
+	response := c.peer.Descriptors[m.Response]
+
+	payload, err = decodePayload(response, r.payload, messageLimit(response, c.MaxMessageSize))
+
+	if err != nil {
+		return err
+	}
+
+	reader := encoder.NewReader(payload, response)
+	reader.SetMode(c.DecodeMode)
+
+	return reader.Decode(res)
+}
+
+// addCall registers a pending call, or a stream if result is nil, under the next call ID
+func (c *Client) addCall(result chan rpcResult, stream *streamQueue) (uint32, error) {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+
+	if c.callsAborted {
+		return 0, ErrCallAborted
+	}
+
+	c.lastCallID++
+	callID := c.lastCallID
+
+	if result == nil {
+		if c.streams == nil {
+			c.streams = make(map[uint32]*streamQueue)
+		}
+
+		c.streams[callID] = stream
+		return callID, nil
+	}
+
+	if c.calls == nil {
+		c.calls = make(map[uint32]chan rpcResult)
+	}
+
+	c.calls[callID] = result
+
+	return callID, nil
+}
+
+func (c *Client) takeCall(callID uint32) chan rpcResult {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+
+	result := c.calls[callID]
+	delete(c.calls, callID)
+
+	return result
+}
+
+func (c *Client) handleRPCResult(descriptor schema.MessageDescriptor, payload []byte) error {
+	reader := encoder.NewReader(payload, descriptor)
+
+	if descriptor.Message.Name == "RpcError" {
+		var res rpcError
+
+		err := reader.Decode(&res)
+
+		if err != nil {
+			return err
+		}
+
+		if result := c.takeCall(res.CallID); result != nil {
+			result <- rpcResult{err: &RPCError{Message: string(res.Message)}}
+		} else if stream := c.takeStream(res.CallID); stream != nil {
+			// streams are answered with an RpcError if the server couldn't start the call
+			stream.end(&RPCError{Method: stream.method, Message: string(res.Message)})
+		}
+
+		return nil
+	}
+
+	var res rpcResponse
+
+	err := reader.Decode(&res)
+
+	if err != nil {
+		return err
+	}
+
+	if result := c.takeCall(res.CallID); result != nil {
+		result <- rpcResult{payload: res.Payload}
+	}
+
+	return nil
+}
+
+// abortCalls fails the calls that are still waiting for a response once the connection is gone
+func (c *Client) abortCalls() {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+
+	c.callsAborted = true
+
+	for callID, result := range c.calls {
+		result <- rpcResult{err: ErrCallAborted}
+		delete(c.calls, callID)
+	}
+
+	for callID, stream := range c.streams {
+		stream.end(ErrCallAborted)
+		delete(c.streams, callID)
+	}
+}
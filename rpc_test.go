@@ -0,0 +1,621 @@
+package schemaipc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+const rpcSchema = `
+inbound GetUserReq {
+  uint64 REQUIRED id
+}
+
+outbound User {
+  uint64 REQUIRED id
+  binary REQUIRED name
+}
+
+service Accounts {
+  rpc GetUser(GetUserReq) returns (User)
+}
+`
+
+type getUserReq struct {
+	ID uint64 `ipc:"id"`
+}
+
+type user struct {
+	ID   uint64 `ipc:"id"`
+	Name string `ipc:"name"`
+}
+
+func TestRPC(t *testing.T) {
+	s := &Server{
+		Schema:         parseTestSchema(t, rpcSchema),
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	RegisterRPC(s, "Accounts.GetUser", func(req *getUserReq, c *Conn) (user, error) {
+		if req.ID == 0 {
+			return user{}, errors.New("no such user")
+		}
+
+		return user{ID: req.ID, Name: "alice"}, nil
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema: s.Schema,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.Run()
+	}()
+
+	var res user
+
+	err = c.Call("Accounts.GetUser", getUserReq{ID: 7}, &res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ID != 7 || res.Name != "alice" {
+		t.Errorf("unexpected response: %+v", res)
+	}
+
+	err = c.Call("Accounts.GetUser", getUserReq{}, &res)
+
+	var rpcErr *RPCError
+
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "no such user" {
+		t.Errorf("expected an RPC error, got %v", err)
+	}
+
+	err = c.Call("Accounts.Missing", getUserReq{}, &res)
+
+	if !errors.Is(err, ErrUnknownMethod) {
+		t.Errorf("expected an unknown method, got %v", err)
+	}
+
+	c.Close()
+
+	err = <-done
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a MaxMessageSize of 0 means unlimited
+func TestRPCUnlimited(t *testing.T) {
+	s := &Server{
+		Schema: parseTestSchema(t, uploadSchema),
+	}
+
+	s.Init()
+
+	RegisterRPC(s, "Files.Upload", func(req *uploadReq, c *Conn) (uploadRes, error) {
+		return uploadRes{Size: uint32(len(req.Data))}, nil
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema: s.Schema,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go c.Run()
+
+	var res uploadRes
+
+	err = c.Call("Files.Upload", uploadReq{Data: make([]byte, 3000)}, &res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Size != 3000 {
+		t.Errorf("unexpected size: %d", res.Size)
+	}
+}
+
+func TestRegisterRPCChecksTypes(t *testing.T) {
+	s := &Server{
+		Schema:         parseTestSchema(t, rpcSchema),
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a response without a name to be rejected")
+		}
+	}()
+
+	RegisterRPC(s, "Accounts.GetUser", func(req *getUserReq, c *Conn) (getUserReq, error) {
+		return *req, nil
+	})
+}
+
+const streamSchema = `
+inbound TailReq {
+  uint32 REQUIRED lines
+}
+
+duplex LogLine {
+  binary REQUIRED text
+}
+
+outbound Summary {
+  uint32 REQUIRED count
+}
+
+service Logs {
+  rpc Tail(TailReq) returns (stream LogLine)
+  rpc Upload(stream LogLine) returns (Summary)
+  rpc Echo(stream LogLine) returns (stream LogLine)
+}
+`
+
+type tailReq struct {
+	Lines uint32 `ipc:"lines"`
+}
+
+type logLine struct {
+	Text string `ipc:"text"`
+}
+
+type summary struct {
+	Count uint32 `ipc:"count"`
+}
+
+func recvLines(t *testing.T, stream *ClientStream) []string {
+	var lines []string
+
+	for {
+		var line logLine
+
+		err := stream.Recv(&line)
+
+		if errors.Is(err, io.EOF) {
+			return lines
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines = append(lines, line.Text)
+	}
+}
+
+func TestRPCStreams(t *testing.T) {
+	s := &Server{
+		Schema:         parseTestSchema(t, streamSchema),
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	RegisterStream(s, "Logs.Tail", func(stream *ServerStream[tailReq, logLine]) error {
+		req, err := stream.Recv()
+
+		if err != nil {
+			return err
+		}
+
+		if req.Lines > 3 {
+			return errors.New("too many lines")
+		}
+
+		for i := range req.Lines {
+			err = stream.Send(logLine{Text: fmt.Sprintf("line %d", i)})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	RegisterStream(s, "Logs.Upload", func(stream *ServerStream[logLine, summary]) error {
+		var count uint32
+
+		for {
+			_, err := stream.Recv()
+
+			if errors.Is(err, io.EOF) {
+				return stream.Send(summary{Count: count})
+			}
+
+			if err != nil {
+				return err
+			}
+
+			count++
+		}
+	})
+
+	RegisterStream(s, "Logs.Echo", func(stream *ServerStream[logLine, logLine]) error {
+		for {
+			line, err := stream.Recv()
+
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			err = stream.Send(*line)
+
+			if err != nil {
+				return err
+			}
+		}
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema: s.Schema,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.Run()
+	}()
+
+	tail, err := c.OpenStream("Logs.Tail")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tail.Send(tailReq{Lines: 3})
+
+	if err := tail.Send(tailReq{Lines: 3}); !errors.Is(err, ErrNotStreaming) {
+		t.Errorf("expected a second request to be rejected, got %v", err)
+	}
+
+	tail.CloseSend()
+
+	if lines := recvLines(t, tail); !slices.Equal(lines, []string{"line 0", "line 1", "line 2"}) {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+
+	tail, err = c.OpenStream("Logs.Tail")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tail.Send(tailReq{Lines: 10})
+	tail.CloseSend()
+
+	var rpcErr *RPCError
+
+	if err := tail.Recv(&logLine{}); !errors.As(err, &rpcErr) || rpcErr.Method != "Logs.Tail" || rpcErr.Message != "too many lines" {
+		t.Errorf("expected the stream to end with an error, got %v", err)
+	}
+
+	upload, err := c.OpenStream("Logs.Upload")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		upload.Send(logLine{Text: "chunk"})
+	}
+
+	upload.CloseSend()
+
+	var res summary
+
+	err = upload.Recv(&res)
+
+	if err != nil || res.Count != 5 {
+		t.Errorf("unexpected summary: %+v, %v", res, err)
+	}
+
+	if err := upload.Recv(&res); !errors.Is(err, io.EOF) {
+		t.Errorf("expected the stream to end, got %v", err)
+	}
+
+	echo, err := c.OpenStream("Logs.Echo")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{"a", "b", "c"} {
+		echo.Send(logLine{Text: text})
+
+		var line logLine
+
+		err = echo.Recv(&line)
+
+		if err != nil || line.Text != text {
+			t.Errorf("unexpected echo: %+v, %v", line, err)
+		}
+	}
+
+	echo.CloseSend()
+
+	if lines := recvLines(t, echo); len(lines) != 0 {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+
+	if err := c.Call("Logs.Tail", tailReq{}, &res); !errors.Is(err, ErrStreamingMethod) {
+		t.Errorf("expected a streaming method to be rejected by Call, got %v", err)
+	}
+
+	c.Close()
+
+	err = <-done
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientStreamClose(t *testing.T) {
+	s := &Server{
+		Schema:         parseTestSchema(t, streamSchema),
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	closed := make(chan error, 1)
+
+	RegisterStream(s, "Logs.Echo", func(stream *ServerStream[logLine, logLine]) error {
+		for {
+			line, err := stream.Recv()
+
+			if err != nil {
+				closed <- err
+				return err
+			}
+
+			err = stream.Send(*line)
+
+			if err != nil {
+				return err
+			}
+		}
+	})
+
+	RegisterStream(s, "Logs.Tail", func(stream *ServerStream[tailReq, logLine]) error {
+		_, err := stream.Recv()
+
+		if err != nil {
+			return err
+		}
+
+		return stream.Send(logLine{Text: "tail"})
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema: s.Schema,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go c.Run()
+
+	echo, err := c.OpenStream("Logs.Echo")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// more responses than are buffered, so Run waits for them to be received
+	for i := 0; i < streamBufferSize+4; i++ {
+		err = echo.Send(logLine{Text: "unread"})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = echo.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rpcErr *RPCError
+
+	if err := <-closed; !errors.As(err, &rpcErr) || rpcErr.Message != ErrStreamClosed.Error() {
+		t.Errorf("expected the server to see the stream closed, got %v", err)
+	}
+
+	if err := echo.Recv(&logLine{}); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("expected Recv to fail once closed, got %v", err)
+	}
+
+	if err := echo.Send(logLine{}); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("expected Send to fail once closed, got %v", err)
+	}
+
+	// Run is no longer waiting on the closed stream
+	tail, err := c.OpenStream("Logs.Tail")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tail.Send(tailReq{})
+
+	if lines := recvLines(t, tail); !slices.Equal(lines, []string{"tail"}) {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+}
+
+const uploadSchema = `
+@maxSize(4KiB)
+inbound UploadReq {
+  long_binary REQUIRED data
+}
+
+@maxSize(8KiB)
+inbound ArchiveReq {
+  long_binary REQUIRED data
+}
+
+@priority(high)
+outbound UploadRes {
+  uint32 REQUIRED size
+}
+
+service Files {
+  rpc Upload(UploadReq) returns (UploadRes)
+  rpc Archive(ArchiveReq) returns (UploadRes)
+}
+`
+
+type uploadReq struct {
+	Data []byte `ipc:"data"`
+}
+
+type uploadRes struct {
+	Size uint32 `ipc:"size"`
+}
+
+func TestRPCMaxSize(t *testing.T) {
+	s := &Server{
+		Schema:         parseTestSchema(t, uploadSchema),
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	RegisterRPC(s, "Files.Upload", func(req *uploadReq, c *Conn) (uploadRes, error) {
+		return uploadRes{Size: uint32(len(req.Data))}, nil
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	c := Client{
+		Schema: s.Schema,
+	}
+
+	err := c.Handshake(client)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go c.Run()
+
+	// larger than the server's MaxMessageSize, but within the @maxSize of the request
+	var res uploadRes
+
+	err = c.Call("Files.Upload", uploadReq{Data: make([]byte, 3000)}, &res)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Size != 3000 {
+		t.Errorf("unexpected size: %d", res.Size)
+	}
+
+	// Call refuses to encode requests over @maxSize, so one is written by hand. RpcRequest frames are limited
+	// by the largest request of any method, so the frame is read and the request is rejected by its own limit.
+	request := c.peer.Descriptors[c.peer.Methods["Files.Upload"].Request]
+	request.Message.Options.MaxSize = 0
+
+	payload, err := encodePayload(request, uploadReq{Data: make([]byte, 5000)})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(chan rpcResult, 1)
+	callID, err := c.addCall(result, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.writeMessage(c.internalDescriptor("inbound RpcRequest"), rpcRequest{
+		CallID:  callID,
+		Method:  []byte("Files.Upload"),
+		Payload: payload,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rpcErr *RPCError
+
+	if r := <-result; !errors.As(r.err, &rpcErr) || rpcErr.Message != ErrMsgLength.Error() {
+		t.Errorf("expected a request over @maxSize to be rejected, got %v", r.err)
+	}
+
+	// the response is written at the priority of its message
+	envelope := carrying(c.internalDescriptor("outbound RpcResponse"), c.peer.Descriptors[c.peer.Methods["Files.Upload"].Response])
+
+	if envelope.Message.Options.Priority != schema.PriorityHigh || envelope.Message.Name != "RpcResponse" {
+		t.Errorf("unexpected envelope: %+v", envelope.Message)
+	}
+}
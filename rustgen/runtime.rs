@@ -0,0 +1,309 @@
+#![allow(dead_code)]
+
+use std::fmt;
+
+pub const PROTOCOL_VERSION: i32 = 1;
+pub const MIN_PROTOCOL_VERSION: i32 = 1;
+
+/// Every frame starts with the payload length and the message ID, both little endian.
+pub const HEADER_SIZE: usize = 8;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Error {
+    /// The payload ended in the middle of the message.
+    OutOfBounds,
+    /// A binary or array is longer than its length prefix allows.
+    TooLong,
+    /// An extensible message from an older peer lacks a required field.
+    MissingRequired,
+    /// An extensible message has more than 2,040 optional fields.
+    TooManyOptionals,
+}
+
+impl fmt::Display for Error {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(match self {
+            Error::OutOfBounds => "out of bounds",
+            Error::TooLong => "binary or array field is too long",
+            Error::MissingRequired => "required field is missing from message",
+            Error::TooManyOptionals => "extensible message has too many optional fields",
+        })
+    }
+}
+
+impl std::error::Error for Error {}
+
+/// A message or object of the schema.
+pub trait Message: Sized {
+    fn write(&self, w: &mut Writer) -> Result<(), Error>;
+    fn read(r: &mut Reader<'_>) -> Result<Self, Error>;
+
+    /// Returns the payload of the message.
+    fn encode(&self) -> Result<Vec<u8>, Error> {
+        let mut w = Writer::new();
+        self.write(&mut w)?;
+
+        Ok(w.into_bytes())
+    }
+
+    /// Reads the message from a payload.
+    fn decode(payload: &[u8]) -> Result<Self, Error> {
+        Self::read(&mut Reader::new(payload))
+    }
+}
+
+/// Writes the frame header of a payload of len bytes.
+pub fn header(len: u32, id: u32) -> [u8; HEADER_SIZE] {
+    let mut header = [0; HEADER_SIZE];
+    header[..4].copy_from_slice(&len.to_le_bytes());
+    header[4..].copy_from_slice(&id.to_le_bytes());
+
+    header
+}
+
+/// Reads the payload length and message ID from a frame header.
+pub fn parse_header(header: [u8; HEADER_SIZE]) -> (u32, u32) {
+    let len = u32::from_le_bytes([header[0], header[1], header[2], header[3]]);
+    let id = u32::from_le_bytes([header[4], header[5], header[6], header[7]]);
+
+    (len, id)
+}
+
+#[derive(Default)]
+pub struct Writer {
+    buf: Vec<u8>,
+}
+
+pub struct Start {
+    header: usize,
+    opt_list: usize,
+}
+
+impl Writer {
+    pub fn new() -> Self {
+        Writer { buf: Vec::new() }
+    }
+
+    pub fn into_bytes(self) -> Vec<u8> {
+        self.buf
+    }
+
+    pub fn u16(&mut self, v: u16) {
+        self.buf.extend_from_slice(&v.to_le_bytes());
+    }
+
+    pub fn i16(&mut self, v: i16) {
+        self.buf.extend_from_slice(&v.to_le_bytes());
+    }
+
+    pub fn u32(&mut self, v: u32) {
+        self.buf.extend_from_slice(&v.to_le_bytes());
+    }
+
+    pub fn i32(&mut self, v: i32) {
+        self.buf.extend_from_slice(&v.to_le_bytes());
+    }
+
+    pub fn u64(&mut self, v: u64) {
+        self.buf.extend_from_slice(&v.to_le_bytes());
+    }
+
+    pub fn i64(&mut self, v: i64) {
+        self.buf.extend_from_slice(&v.to_le_bytes());
+    }
+
+    pub fn bytes(&mut self, b: &[u8]) {
+        self.buf.extend_from_slice(b);
+    }
+
+    pub fn binary(&mut self, b: &[u8]) -> Result<(), Error> {
+        let len = u16::try_from(b.len()).map_err(|_| Error::TooLong)?;
+        self.u16(len);
+        self.bytes(b);
+
+        Ok(())
+    }
+
+    pub fn long_binary(&mut self, b: &[u8]) -> Result<(), Error> {
+        let len = u32::try_from(b.len()).map_err(|_| Error::TooLong)?;
+        self.u32(len);
+        self.bytes(b);
+
+        Ok(())
+    }
+
+    pub fn array_len(&mut self, n: usize) -> Result<(), Error> {
+        let n = u16::try_from(n).map_err(|_| Error::TooLong)?;
+        self.u16(n);
+
+        Ok(())
+    }
+
+    pub fn begin_message(&mut self, optional_count: usize, extensible: bool) -> Result<Start, Error> {
+        let opt_bytes = (optional_count + 7) / 8;
+        let header = self.buf.len();
+
+        if extensible {
+            let opt_bytes = u8::try_from(opt_bytes).map_err(|_| Error::TooManyOptionals)?;
+            self.u32(0);
+            self.buf.push(opt_bytes);
+        }
+
+        let opt_list = self.buf.len();
+        self.buf.resize(opt_list + opt_bytes, 0);
+
+        Ok(Start { header, opt_list })
+    }
+
+    pub fn set_optional(&mut self, start: &Start, n: usize) {
+        self.buf[start.opt_list + (n >> 3)] |= 1 << (n & 7);
+    }
+
+    pub fn end_message(&mut self, start: Start, extensible: bool) {
+        if extensible {
+            let len = (self.buf.len() - start.header - 4) as u32;
+            self.buf[start.header..start.header + 4].copy_from_slice(&len.to_le_bytes());
+        }
+    }
+}
+
+pub struct Reader<'a> {
+    buf: &'a [u8],
+    pos: usize,
+    len: usize,
+}
+
+pub struct Frame<'a> {
+    opt_list: &'a [u8],
+    opt: usize,
+    end: usize,
+    outer_len: usize,
+}
+
+impl<'a> Reader<'a> {
+    pub fn new(buf: &'a [u8]) -> Self {
+        Reader { buf, pos: 0, len: buf.len() }
+    }
+
+    pub fn take(&mut self, n: usize) -> Result<&'a [u8], Error> {
+        if n > self.len - self.pos {
+            return Err(Error::OutOfBounds);
+        }
+
+        let b = &self.buf[self.pos..self.pos + n];
+        self.pos += n;
+
+        Ok(b)
+    }
+
+    pub fn fixed<const N: usize>(&mut self) -> Result<[u8; N], Error> {
+        let mut b = [0; N];
+        b.copy_from_slice(self.take(N)?);
+
+        Ok(b)
+    }
+
+    pub fn u8(&mut self) -> Result<u8, Error> {
+        Ok(self.take(1)?[0])
+    }
+
+    pub fn u16(&mut self) -> Result<u16, Error> {
+        self.fixed().map(u16::from_le_bytes)
+    }
+
+    pub fn i16(&mut self) -> Result<i16, Error> {
+        self.fixed().map(i16::from_le_bytes)
+    }
+
+    pub fn u32(&mut self) -> Result<u32, Error> {
+        self.fixed().map(u32::from_le_bytes)
+    }
+
+    pub fn i32(&mut self) -> Result<i32, Error> {
+        self.fixed().map(i32::from_le_bytes)
+    }
+
+    pub fn u64(&mut self) -> Result<u64, Error> {
+        self.fixed().map(u64::from_le_bytes)
+    }
+
+    pub fn i64(&mut self) -> Result<i64, Error> {
+        self.fixed().map(i64::from_le_bytes)
+    }
+
+    pub fn binary(&mut self) -> Result<Vec<u8>, Error> {
+        let n = self.u16()? as usize;
+
+        Ok(self.take(n)?.to_vec())
+    }
+
+    pub fn long_binary(&mut self) -> Result<Vec<u8>, Error> {
+        let n = self.u32()? as usize;
+
+        Ok(self.take(n)?.to_vec())
+    }
+
+    pub fn array_len(&mut self) -> Result<usize, Error> {
+        Ok(self.u16()? as usize)
+    }
+
+    /// Reads an array with the function that reads its elements.
+    pub fn array<T>(&mut self, mut read: impl FnMut(&mut Self) -> Result<T, Error>) -> Result<Vec<T>, Error> {
+        let n = self.array_len()?;
+
+        // every element takes at least a byte, don't let the length alone allocate more than the payload
+        let mut res = Vec::with_capacity(n.min(self.len - self.pos));
+
+        for _ in 0..n {
+            res.push(read(self)?);
+        }
+
+        Ok(res)
+    }
+
+    pub fn begin_message(&mut self, optional_count: usize, extensible: bool) -> Result<Frame<'a>, Error> {
+        let mut opt_bytes = (optional_count + 7) / 8;
+        let mut end = 0;
+        let mut outer_len = 0;
+
+        if extensible {
+            let body_len = self.u32()? as usize;
+
+            if body_len > self.len - self.pos {
+                return Err(Error::OutOfBounds);
+            }
+
+            end = self.pos + body_len;
+            outer_len = self.len;
+            self.len = end;
+
+            // the sender might know about more or less optional fields than we do
+            opt_bytes = self.u8()? as usize;
+        }
+
+        Ok(Frame { opt_list: self.take(opt_bytes)?, opt: 0, end, outer_len })
+    }
+
+    pub fn present(&mut self, frame: &mut Frame<'a>) -> bool {
+        let opt = frame.opt;
+        frame.opt += 1;
+
+        opt < frame.opt_list.len() * 8 && frame.opt_list[opt >> 3] & (1 << (opt & 7)) != 0
+    }
+
+    pub fn check_required(&self, frame: &Frame<'a>) -> Result<(), Error> {
+        if self.pos == frame.end {
+            return Err(Error::MissingRequired);
+        }
+
+        Ok(())
+    }
+
+    pub fn end_message(&mut self, frame: Frame<'a>, extensible: bool) {
+        // skip trailing fields sent by a peer with a newer schema
+        if extensible {
+            self.pos = frame.end;
+            self.len = frame.outer_len;
+        }
+    }
+}
@@ -0,0 +1,568 @@
+// Package rustgen generates a Rust module from a schema: a struct for every message and object that
+// implements Message, which encodes and decodes it in the wire format. The generated module only uses the
+// standard library.
+package rustgen
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// runtime.rs holds the reader, writer and Message trait the generated code is built on
+//
+//go:embed runtime.rs
+var runtime string
+
+// names declared by the runtime or the prelude, schema names must not generate them
+var reserved = []string{
+	"Error", "Message", "Writer", "Reader", "Start", "Frame", "PROTOCOL_VERSION", "MIN_PROTOCOL_VERSION",
+	"HEADER_SIZE", "FINGERPRINT", "Option", "Some", "None", "Result", "Ok", "Err", "Vec", "String", "Box",
+}
+
+// keywords of Rust, fields named after them are written as raw identifiers
+var keywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true, "crate": true,
+	"dyn": true, "else": true, "enum": true, "extern": true, "false": true, "fn": true, "for": true,
+	"if": true, "impl": true, "in": true, "let": true, "loop": true, "match": true, "mod": true,
+	"move": true, "mut": true, "pub": true, "ref": true, "return": true, "static": true, "struct": true,
+	"trait": true, "true": true, "type": true, "unsafe": true, "use": true, "where": true, "while": true,
+	"abstract": true, "become": true, "box": true, "do": true, "final": true, "macro": true,
+	"override": true, "priv": true, "typeof": true, "unsized": true, "virtual": true, "yield": true,
+	"try": true,
+}
+
+type Options struct {
+	Source string // file the schema was read from, named in the header of the generated file
+}
+
+type generator struct {
+	pkg   string            // package of the schema, stripped from the names it declares
+	names map[string]string // Rust identifier to the schema name it was derived from
+	buf   strings.Builder
+	depth int // indentation of the next line
+}
+
+// Generate returns the Rust module for the schema and everything it imports
+func Generate(s schema.Schema, opts Options) ([]byte, error) {
+	g := generator{
+		pkg:   s.Package,
+		names: make(map[string]string),
+	}
+
+	for _, ident := range reserved {
+		g.names[ident] = ident
+	}
+
+	schemas := s.Files()
+
+	err := g.checkNames(schemas)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	g.buf.WriteString("// Code generated by schemagen")
+
+	if opts.Source != "" {
+		fmt.Fprintf(&g.buf, " from %s", opts.Source)
+	}
+
+	g.buf.WriteString(". DO NOT EDIT.\n\n")
+	g.buf.WriteString(runtime)
+	g.line("")
+
+	fingerprint := r.Fingerprint()
+	bytes := make([]string, len(fingerprint))
+
+	for i, b := range fingerprint {
+		bytes[i] = fmt.Sprintf("0x%02x", b)
+	}
+
+	g.line("/// Identifies the schema the module was generated from, the server rejects other schemas in Hello.")
+	g.line("pub const FINGERPRINT: [u8; 32] = [%s];", strings.Join(bytes, ", "))
+	g.line("")
+
+	g.messageIDs(schemas, &r)
+
+	for _, file := range schemas {
+		g.constants(file.Constants)
+		g.typeAliases(file.Types)
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			g.message(message)
+		}
+	}
+
+	return []byte(strings.TrimRight(g.buf.String(), "\n") + "\n"), nil
+}
+
+// constName turns a type name into the name of a constant, e.g. PlaceOrder becomes PLACE_ORDER
+func constName(name string) string {
+	var b strings.Builder
+
+	runes := []rune(name)
+
+	for i, c := range runes {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToUpper(c))
+	}
+
+	return b.String()
+}
+
+// typeName returns the Rust name of a declared name, names from other packages keep their package as a prefix
+func (g *generator) typeName(name string) string {
+	if g.pkg != "" {
+		name = strings.TrimPrefix(name, g.pkg+".")
+	}
+
+	return schema.PascalName(name)
+}
+
+// TypeName returns the Rust name of a name declared by a schema of the package, e.g. PlaceOrder for
+// shop.PlaceOrder in package shop
+func TypeName(pkg, name string) string {
+	g := generator{pkg: pkg}
+	return g.typeName(name)
+}
+
+// FieldName returns the struct field of a field
+func FieldName(field schema.MessageField) string {
+	return fieldName(field)
+}
+
+// fieldName returns the struct field of a field
+func fieldName(field schema.MessageField) string {
+	if keywords[field.Name] {
+		return "r#" + field.Name
+	}
+
+	return field.Name
+}
+
+func (g *generator) declare(ident, name string) error {
+	if existing, exists := g.names[ident]; exists && existing != name {
+		if existing == ident && name != ident {
+			return fmt.Errorf("%s generates the Rust identifier %s, which is used by the runtime", name, ident)
+		}
+
+		return fmt.Errorf("%s and %s both generate the Rust identifier %s", existing, name, ident)
+	}
+
+	g.names[ident] = name
+
+	return nil
+}
+
+// checkNames reports declarations that would generate the same Rust identifier
+func (g *generator) checkNames(schemas []schema.Schema) error {
+	for _, file := range schemas {
+		for _, constant := range file.Constants {
+			err := g.declare(constName(g.typeName(constant.Name)), constant.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, alias := range file.Types {
+			err := g.declare(g.typeName(alias.Name), alias.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// messages are declared by signature, an inbound and an outbound message can't share a name
+		for _, message := range file.Messages {
+			err := g.declare(g.typeName(message.Name), message.Signature())
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// line writes a line of code at the current indentation, a line ending with { opens a block and a line
+// starting with } closes one
+func (g *generator) line(format string, args ...any) {
+	code := fmt.Sprintf(format, args...)
+
+	if strings.HasPrefix(code, "}") {
+		g.depth--
+	}
+
+	if code != "" {
+		g.buf.WriteString(strings.Repeat("    ", g.depth))
+	}
+
+	g.buf.WriteString(code)
+	g.buf.WriteString("\n")
+
+	if strings.HasSuffix(code, "{") {
+		g.depth++
+	}
+}
+
+// directions returns the directions a message is registered under
+func directions(message schema.SchemaMessage) []schema.MessageDirection {
+	switch message.Direction {
+	case schema.InboundMessage, schema.OutboundMessage:
+		return []schema.MessageDirection{message.Direction}
+	case schema.DuplexMessage:
+		return []schema.MessageDirection{schema.InboundMessage, schema.OutboundMessage}
+	default:
+		return nil
+	}
+}
+
+func (g *generator) messageIDs(schemas []schema.Schema, r *schema.MessageDescriptorRegistry) {
+	g.line("/// The descriptor ID of every message, the same as the server assigns.")
+	g.line("pub mod message_id {")
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			dirs := directions(message)
+
+			if len(dirs) == 0 {
+				continue
+			}
+
+			// compressed payloads are raw deflate streams, which the caller inflates before decoding
+			if message.Options.Compress {
+				g.line("/// Compressed with raw deflate.")
+			}
+
+			g.line("pub const %s: u32 = %d;", constName(g.typeName(message.Name)), r.UserSignatureMap[schema.Signature(dirs[0], message.Name)])
+		}
+	}
+
+	g.line("}")
+	g.line("")
+}
+
+func (g *generator) constants(constants []schema.Constant) {
+	for _, constant := range constants {
+		g.line("pub const %s: u64 = %d;", constName(g.typeName(constant.Name)), constant.Value)
+	}
+
+	if len(constants) > 0 {
+		g.line("")
+	}
+}
+
+func (g *generator) typeAliases(aliases []schema.TypeAlias) {
+	for _, alias := range aliases {
+		g.line("pub type %s = %s;", g.typeName(alias.Name), g.rustType(alias.Field))
+	}
+
+	if len(aliases) > 0 {
+		g.line("")
+	}
+}
+
+func nestedMessage(extra any) schema.SchemaMessage {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e.Message
+	case schema.SchemaMessage:
+		return e
+	default:
+		return schema.SchemaMessage{}
+	}
+}
+
+// flatten returns the fields of a message with the fields of embedded objects spliced in, the same as they
+// appear on the wire
+func flatten(fields []schema.MessageField) []schema.MessageField {
+	var res []schema.MessageField
+
+	for _, field := range fields {
+		if field.Embedded {
+			res = append(res, flatten(nestedMessage(field.Extra).Fields)...)
+		} else {
+			res = append(res, field)
+		}
+	}
+
+	return res
+}
+
+// countOptional counts the optional fields of a message, including those of included objects
+func countOptional(fields []schema.MessageField) int {
+	var count int
+
+	for _, field := range flatten(fields) {
+		if field.Optional {
+			count++
+		}
+	}
+
+	return count
+}
+
+// intType returns the Rust type of an integer type, which is also the name of its reader and writer methods
+func intType(t schema.FieldType) (string, bool) {
+	switch t {
+	case schema.TypeUInt64:
+		return "u64", true
+	case schema.TypeInt64:
+		return "i64", true
+	case schema.TypeUInt32:
+		return "u32", true
+	case schema.TypeInt32:
+		return "i32", true
+	case schema.TypeUInt16:
+		return "u16", true
+	case schema.TypeInt16:
+		return "i16", true
+	default:
+		return "", false
+	}
+}
+
+func (g *generator) rustType(field schema.MessageField) string {
+	if field.TypeAlias != "" {
+		return g.typeName(field.TypeAlias)
+	}
+
+	if t, ok := intType(field.Type); ok {
+		return t
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		return fmt.Sprintf("[u8; %d]", field.Extra.(int))
+	case schema.TypeDynamicBinary, schema.TypeLongBinary:
+		return "Vec<u8>"
+	case schema.TypeObject:
+		return g.typeName(field.ObjectName())
+	case schema.TypeArray:
+		{
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				return "Vec<" + g.rustType(elem) + ">"
+			}
+
+			return "Vec<" + g.typeName(field.ObjectName()) + ">"
+		}
+	default:
+		return "()"
+	}
+}
+
+// message writes the struct of a message or object and its Message implementation
+func (g *generator) message(message schema.SchemaMessage) {
+	name := g.typeName(message.Name)
+	fields := flatten(message.Fields)
+	extensible := fmt.Sprint(message.Extensible)
+
+	if message.Direction == schema.ObjectDef {
+		g.line("/// The object %s.", message.Name)
+	} else {
+		g.line("/// The %s message %s.", message.Direction.ToString(), message.Name)
+	}
+
+	if message.Deprecated {
+		g.line("///")
+		g.line("/// Deprecated: the message is deprecated in the schema.")
+	}
+
+	g.line("#[derive(Debug, Clone, PartialEq, Eq)]")
+
+	if len(fields) == 0 {
+		g.line("pub struct %s {}", name)
+	} else {
+		g.line("pub struct %s {", name)
+
+		for _, field := range fields {
+			if field.Deprecated {
+				g.line("/// Deprecated: the field is deprecated in the schema.")
+			}
+
+			if field.Optional {
+				g.line("pub %s: Option<%s>,", fieldName(field), g.rustType(field))
+			} else {
+				g.line("pub %s: %s,", fieldName(field), g.rustType(field))
+			}
+		}
+
+		g.line("}")
+	}
+
+	g.line("")
+	g.line("impl Message for %s {", name)
+	g.line("fn write(&self, w: &mut Writer) -> Result<(), Error> {")
+	g.line("let start = w.begin_message(%d, %s)?;", countOptional(message.Fields), extensible)
+
+	var opt int
+
+	for _, field := range fields {
+		if !field.Optional {
+			g.writeValue("self."+fieldName(field), false, field)
+			continue
+		}
+
+		g.line("if let Some(v) = &self.%s {", fieldName(field))
+		g.line("w.set_optional(&start, %d);", opt)
+		g.writeValue("v", true, field)
+		g.line("}")
+
+		opt++
+	}
+
+	g.line("w.end_message(start, %s);", extensible)
+	g.line("")
+	g.line("Ok(())")
+	g.line("}")
+	g.line("")
+
+	g.line("fn read(r: &mut Reader<'_>) -> Result<Self, Error> {")
+
+	if countOptional(message.Fields) > 0 {
+		g.line("let mut frame = r.begin_message(%d, %s)?;", countOptional(message.Fields), extensible)
+	} else {
+		g.line("let frame = r.begin_message(0, %s)?;", extensible)
+	}
+
+	// struct expressions evaluate their fields in the order they are written
+	if len(fields) == 0 {
+		g.line("let m = %s {};", name)
+	} else {
+		g.line("let m = %s {", name)
+
+		for _, field := range fields {
+			value := g.readValue(field)
+
+			switch {
+			case field.Optional:
+				value = fmt.Sprintf("if r.present(&mut frame) { Some(%s) } else { None }", value)
+			case message.Extensible:
+				// a required field sent by an older peer is missing from an extensible message
+				value = fmt.Sprintf("{ r.check_required(&frame)?; %s }", value)
+			}
+
+			g.line("%s: %s,", fieldName(field), value)
+		}
+
+		g.line("};")
+	}
+
+	g.line("r.end_message(frame, %s);", extensible)
+	g.line("")
+	g.line("Ok(m)")
+	g.line("}")
+	g.line("}")
+	g.line("")
+}
+
+// writeValue writes a field, expr is a reference to the value if ref is set
+func (g *generator) writeValue(expr string, ref bool, field schema.MessageField) {
+	value, borrowed := expr, expr
+
+	if ref {
+		value = "*" + expr
+	} else {
+		borrowed = "&" + expr
+	}
+
+	if t, ok := intType(field.Type); ok {
+		g.line("w.%s(%s);", t, value)
+		return
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		g.line("w.bytes(%s);", borrowed)
+	case schema.TypeDynamicBinary:
+		g.line("w.binary(%s)?;", borrowed)
+	case schema.TypeLongBinary:
+		g.line("w.long_binary(%s)?;", borrowed)
+	case schema.TypeObject:
+		g.line("%s.write(w)?;", expr)
+	case schema.TypeArray:
+		{
+			g.line("w.array_len(%s.len())?;", expr)
+			g.line("for item in %s {", borrowed)
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				g.writeValue("item", true, elem)
+			} else {
+				g.line("item.write(w)?;")
+			}
+
+			g.line("}")
+		}
+	}
+}
+
+// readValue returns the expression that reads a field
+func (g *generator) readValue(field schema.MessageField) string {
+	if t, ok := intType(field.Type); ok {
+		return fmt.Sprintf("r.%s()?", t)
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		return "r.fixed()?"
+	case schema.TypeDynamicBinary:
+		return "r.binary()?"
+	case schema.TypeLongBinary:
+		return "r.long_binary()?"
+	case schema.TypeObject:
+		return g.typeName(field.ObjectName()) + "::read(r)?"
+	case schema.TypeArray:
+		{
+			elem, ok := field.Extra.(schema.MessageField)
+
+			if !ok {
+				return fmt.Sprintf("r.array(%s::read)?", g.typeName(field.ObjectName()))
+			}
+
+			if t, isInt := intType(elem.Type); isInt {
+				return fmt.Sprintf("r.array(Reader::%s)?", t)
+			}
+
+			switch elem.Type {
+			case schema.TypeFixedBinary:
+				return "r.array(Reader::fixed)?"
+			case schema.TypeLongBinary:
+				return "r.array(Reader::long_binary)?"
+			default:
+				return "r.array(Reader::binary)?"
+			}
+		}
+	default:
+		return "()"
+	}
+}
@@ -0,0 +1,125 @@
+package rustgen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestGenerateIsUpToDate(t *testing.T) {
+	files := map[string]string{
+		"../examples/shop/shop.schema": "../examples/shop/shop.rs",
+		"../golden/golden.schema":      "../golden/rust/src/golden.rs",
+	}
+
+	for source, generated := range files {
+		s, err := schema.ParseFile(source)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, err := Generate(s, Options{Source: source[strings.LastIndex(source, "/")+1:]})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := os.ReadFile(generated)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(code, expected) {
+			t.Errorf("%s is out of date, run go generate ./examples/shop ./golden", strings.TrimPrefix(generated, "../"))
+		}
+	}
+}
+
+func TestGenerateMatchesRegistry(t *testing.T) {
+	s, err := schema.ParseFile("../examples/shop/shop.schema")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("    pub const PLACE_ORDER: u32 = %d;", r.UserSignatureMap["inbound shop.PlaceOrder"]),
+		fmt.Sprintf("    pub const PING: u32 = %d;", r.UserSignatureMap["inbound shop.Ping"]),
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(code), line+"\n") {
+			t.Errorf("expected the generated code to contain %q", line)
+		}
+	}
+}
+
+func TestGenerateNameCollision(t *testing.T) {
+	tests := map[string]string{
+		"inbound user_name {\n  uint32 REQUIRED id\n}\n\noutbound UserName {\n  uint32 REQUIRED id\n}": "inbound user_name and outbound UserName",
+		"const max_size = 1\n\nconst MaxSize = 2":                                                      "max_size and MaxSize",
+		"outbound Writer {\n  uint32 REQUIRED id\n}":                                                   "used by the runtime",
+	}
+
+	for source, message := range tests {
+		s, err := schema.Parse([]byte(source))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = Generate(s, Options{})
+
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q, got %v", message, err)
+		}
+	}
+}
+
+func TestGenerateRawIdentifiers(t *testing.T) {
+	s, err := schema.Parse([]byte("inbound Move {\n  uint32 REQUIRED type\n  uint32 OPTIONAL match\n}"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"    pub r#type: u32,", "    pub r#match: Option<u32>,"}
+
+	for _, line := range expected {
+		if !strings.Contains(string(code), line+"\n") {
+			t.Errorf("expected the generated code to contain %q", line)
+		}
+	}
+}
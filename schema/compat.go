@@ -0,0 +1,661 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+)
+
+type ChangeKind int
+
+const (
+	MessageAdded ChangeKind = iota
+	MessageRemoved
+	DirectionChanged
+	IDChanged
+	FieldAdded
+	FieldRemoved
+	FieldRenamed
+	FieldTypeChanged
+	FieldOptionalityChanged
+	FieldsReordered
+	ExtensibilityChanged
+	MethodAdded
+	MethodRemoved
+	MethodChanged
+	CompressionChanged
+	MaxSizeChanged
+	PriorityChanged
+)
+
+func (k ChangeKind) ToString() string {
+	switch k {
+	case MessageAdded:
+		return "message added"
+	case MessageRemoved:
+		return "message removed"
+	case DirectionChanged:
+		return "direction changed"
+	case IDChanged:
+		return "ID changed"
+	case FieldAdded:
+		return "field added"
+	case FieldRemoved:
+		return "field removed"
+	case FieldRenamed:
+		return "field renamed"
+	case FieldTypeChanged:
+		return "type changed"
+	case FieldOptionalityChanged:
+		return "optionality changed"
+	case FieldsReordered:
+		return "fields reordered"
+	case ExtensibilityChanged:
+		return "extensibility changed"
+	case MethodAdded:
+		return "method added"
+	case MethodRemoved:
+		return "method removed"
+	case MethodChanged:
+		return "method changed"
+	case CompressionChanged:
+		return "compression changed"
+	case MaxSizeChanged:
+		return "max size changed"
+	case PriorityChanged:
+		return "priority changed"
+	default:
+		return ""
+	}
+}
+
+type Compatibility int
+
+const (
+	Safe Compatibility = iota
+	Breaking
+)
+
+func (c Compatibility) ToString() string {
+	if c == Breaking {
+		return "breaking"
+	}
+
+	return "safe"
+}
+
+type Change struct {
+	Kind        ChangeKind
+	Signature   string // signature of the message in the new schema (or the old one if it was removed)
+	Field       string // dotted path of the field, empty for message-level changes
+	Description string
+	Readers     Compatibility // can peers on the old schema read messages written with the new schema
+	Writers     Compatibility // can messages written by peers on the old schema be read with the new schema
+}
+
+func (c Change) Breaking() bool {
+	return c.Readers == Breaking || c.Writers == Breaking
+}
+
+func (c Change) String() string {
+	subject := c.Signature
+
+	if c.Field != "" {
+		subject += "." + c.Field
+	}
+
+	return fmt.Sprintf("%s: %s (readers: %s, writers: %s)", subject, c.Description, c.Readers.ToString(), c.Writers.ToString())
+}
+
+func HasBreakingChanges(changes []Change) bool {
+	return slices.ContainsFunc(changes, Change.Breaking)
+}
+
+// assignIDs returns the descriptor ID every message signature would be registered with
+func assignIDs(s Schema) (map[string]uint32, error) {
+	r := MessageDescriptorRegistry{}
+
+	err := r.RegisterInternal()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uint32, len(r.Descriptors))
+
+	for id, descriptor := range r.Descriptors {
+		if !descriptor.Internal {
+			ids[descriptor.Message.Signature()] = id
+		}
+	}
+
+	return ids, nil
+}
+
+// CheckCompatibility lists every difference between two versions of a schema and classifies whether
+// it breaks peers that are still on the old version. Object definitions are compared where they are used.
+// It returns an error if either version can't be registered, as the IDs of its messages aren't known.
+func CheckCompatibility(old, new Schema) ([]Change, error) {
+	var changes []Change
+
+	oldIDs, err := assignIDs(old)
+
+	if err != nil {
+		return nil, fmt.Errorf("old schema: %w", err)
+	}
+
+	newIDs, err := assignIDs(new)
+
+	if err != nil {
+		return nil, fmt.Errorf("new schema: %w", err)
+	}
+
+	oldMessages := make(map[string]SchemaMessage)
+	oldByName := make(map[string][]string)
+	matched := make(map[string]bool)
+
+	for _, message := range old.AllMessages() {
+		if message.Direction == ObjectDef {
+			continue
+		}
+
+		oldMessages[message.Signature()] = message
+		oldByName[message.Name] = append(oldByName[message.Name], message.Signature())
+	}
+
+	newSignatures := make(map[string]bool)
+
+	for _, message := range new.AllMessages() {
+		if message.Direction != ObjectDef {
+			newSignatures[message.Signature()] = true
+		}
+	}
+
+	for _, message := range new.AllMessages() {
+		if message.Direction == ObjectDef {
+			continue
+		}
+
+		signature := message.Signature()
+		oldMessage, exists := oldMessages[signature]
+
+		if !exists {
+			// a message with the same name that disappeared from the new schema changed its direction
+			for _, oldSignature := range oldByName[message.Name] {
+				if !matched[oldSignature] && !newSignatures[oldSignature] {
+					oldMessage = oldMessages[oldSignature]
+					exists = true
+					break
+				}
+			}
+
+			if exists {
+				changes = append(changes, Change{
+					Kind:        DirectionChanged,
+					Signature:   signature,
+					Description: fmt.Sprintf("direction changed from %s to %s", oldMessage.Direction.ToString(), message.Direction.ToString()),
+					Readers:     Breaking,
+					Writers:     Breaking,
+				})
+			}
+		}
+
+		if !exists {
+			changes = append(changes, Change{
+				Kind:        MessageAdded,
+				Signature:   signature,
+				Description: "message added",
+				Readers:     Safe,
+				Writers:     Safe,
+			})
+
+			continue
+		}
+
+		oldSignature := oldMessage.Signature()
+		matched[oldSignature] = true
+
+		oldID, oldExists := oldIDs[oldSignature]
+		newID, newExists := newIDs[signature]
+
+		if oldExists && newExists && oldID != newID {
+			changes = append(changes, Change{
+				Kind:        IDChanged,
+				Signature:   signature,
+				Description: fmt.Sprintf("ID changed from %d to %d", oldID, newID),
+				Readers:     Breaking,
+				Writers:     Breaking,
+			})
+		}
+
+		changes = append(changes, compareOptions(signature, oldMessage.Options, message.Options)...)
+		changes = append(changes, compareFields(signature, "", oldMessage, message)...)
+	}
+
+	for _, message := range old.AllMessages() {
+		if message.Direction == ObjectDef || matched[message.Signature()] {
+			continue
+		}
+
+		// peers on the old schema can still send the message, but nothing is going to accept it
+		changes = append(changes, Change{
+			Kind:        MessageRemoved,
+			Signature:   message.Signature(),
+			Description: "message removed",
+			Readers:     Safe,
+			Writers:     Breaking,
+		})
+	}
+
+	return append(changes, compareMethods(old, new)...), nil
+}
+
+func maxSizeString(size uint32) string {
+	if size == 0 {
+		return "the connection's limit"
+	}
+
+	return fmt.Sprint(size)
+}
+
+// compareOptions compares the options of two versions of a message. The receiver of a message enforces its
+// maxSize, and both sides have to agree on whether it is compressed.
+func compareOptions(signature string, old, new MessageOptions) []Change {
+	var changes []Change
+
+	if old.Compress != new.Compress {
+		changes = append(changes, Change{
+			Kind:        CompressionChanged,
+			Signature:   signature,
+			Description: fmt.Sprintf("compress changed from %t to %t", old.Compress, new.Compress),
+			Readers:     Breaking,
+			Writers:     Breaking,
+		})
+	}
+
+	if old.MaxSize != new.MaxSize {
+		change := Change{
+			Kind:        MaxSizeChanged,
+			Signature:   signature,
+			Description: fmt.Sprintf("maxSize changed from %s to %s", maxSizeString(old.MaxSize), maxSizeString(new.MaxSize)),
+			Readers:     Safe,
+			Writers:     Safe,
+		}
+
+		// 0 leaves the size to the connection, which can't be compared, so it counts as larger than any maxSize
+		if new.MaxSize != 0 && (old.MaxSize == 0 || new.MaxSize < old.MaxSize) {
+			// peers on the old schema can send messages that are now too large
+			change.Writers = Breaking
+		} else {
+			// peers on the old schema reject the larger messages that can now be sent to them
+			change.Readers = Breaking
+		}
+
+		changes = append(changes, change)
+	}
+
+	// the priority only orders the writes of the sender
+	if old.Priority != new.Priority {
+		changes = append(changes, Change{
+			Kind:        PriorityChanged,
+			Signature:   signature,
+			Description: fmt.Sprintf("priority changed from %s to %s", old.Priority.ToString(), new.Priority.ToString()),
+			Readers:     Safe,
+			Writers:     Safe,
+		})
+	}
+
+	return changes
+}
+
+func methodsByName(s Schema) map[string]Method {
+	methods := make(map[string]Method)
+
+	for _, service := range s.AllServices() {
+		for _, method := range service.Methods {
+			methods[service.MethodName(method)] = method
+		}
+	}
+
+	return methods
+}
+
+func compareMethods(old, new Schema) []Change {
+	var changes []Change
+
+	oldMethods := methodsByName(old)
+	newMethods := methodsByName(new)
+
+	names := make([]string, 0, len(oldMethods)+len(newMethods))
+
+	for name := range oldMethods {
+		names = append(names, name)
+	}
+
+	for name := range newMethods {
+		if _, exists := oldMethods[name]; !exists {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		oldMethod, oldExists := oldMethods[name]
+		newMethod, newExists := newMethods[name]
+
+		change := Change{
+			Signature: "rpc " + name,
+		}
+
+		switch {
+		case !oldExists:
+			change.Kind = MethodAdded
+			change.Description = "method added"
+		case !newExists:
+			// peers on the old schema can still call the method, but nothing is going to answer it
+			change.Kind = MethodRemoved
+			change.Description = "method removed"
+			change.Writers = Breaking
+		case oldMethod != newMethod:
+			change.Kind = MethodChanged
+			change.Description = fmt.Sprintf("changed from %s to %s", oldMethod.ToString(), newMethod.ToString())
+			change.Readers = Breaking
+			change.Writers = Breaking
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// CheckFieldTypes returns an error if a field that both versions of a message share has a different type,
+// fields that only exist in one version are fine when decoding by name
+func CheckFieldTypes(local, peer SchemaMessage) error {
+	for _, change := range compareFields(local.Signature(), "", local, peer) {
+		if change.Kind == FieldTypeChanged {
+			return fmt.Errorf("%s.%s: %s", change.Signature, change.Field, change.Description)
+		}
+	}
+
+	return nil
+}
+
+// MapFieldNames returns the peer's version of a message with the names of the matching local fields added
+// as aliases, so values decoded or encoded by name with the peer descriptor find the fields of local structs
+func MapFieldNames(local, peer SchemaMessage) SchemaMessage {
+	newToOld, _ := matchFields(local.Fields, peer.Fields)
+
+	peer.Fields = slices.Clone(peer.Fields)
+
+	for j, field := range peer.Fields {
+		i, exists := newToOld[j]
+
+		if !exists {
+			continue
+		}
+
+		localField := local.Fields[i]
+
+		for _, name := range append([]string{localField.Name}, localField.Aliases...) {
+			if !field.HasName(name) {
+				field.Aliases = append(slices.Clip(field.Aliases), name)
+			}
+		}
+
+		localNested, localIsObject := nestedMessage(localField.Extra)
+
+		switch e := field.Extra.(type) {
+		case SchemaMessage:
+			if localIsObject {
+				field.Extra = MapFieldNames(localNested, e)
+			}
+		case MessageDescriptor:
+			if localIsObject {
+				e.Message = MapFieldNames(localNested, e.Message)
+				field.Extra = e
+			}
+		}
+
+		peer.Fields[j] = field
+	}
+
+	return peer
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
+
+func nestedMessage(extra any) (SchemaMessage, bool) {
+	switch e := extra.(type) {
+	case SchemaMessage:
+		return e, true
+	case MessageDescriptor:
+		return e.Message, true
+	default:
+		return SchemaMessage{}, false
+	}
+}
+
+func requiredString(optional bool) string {
+	if optional {
+		return "optional"
+	}
+
+	return "required"
+}
+
+// compareFields compares two versions of a message field by field. Fields are encoded positionally,
+// so any structural change shifts the layout for the peer that doesn't know about it, unless the
+// message is extensible and fields are only appended to (or dropped from) the end.
+func compareFields(signature, prefix string, old, new SchemaMessage) []Change {
+	var changes []Change
+
+	if old.Extensible != new.Extensible {
+		changes = append(changes, Change{
+			Kind:        ExtensibilityChanged,
+			Signature:   signature,
+			Field:       prefix,
+			Description: fmt.Sprintf("extensible changed from %t to %t", old.Extensible, new.Extensible),
+			Readers:     Breaking,
+			Writers:     Breaking,
+		})
+	}
+
+	extensible := old.Extensible && new.Extensible
+
+	// compare the fields as they are encoded, with embedded objects spliced in
+	if fields, err := flattenFields(old); err == nil {
+		old.Fields = fields
+	}
+
+	if fields, err := flattenFields(new); err == nil {
+		new.Fields = fields
+	}
+
+	newToOld, oldToNew := matchFields(old.Fields, new.Fields)
+
+	// fields after the last field both versions share are trailing fields
+	lastCommonOld, lastCommonNew := -1, -1
+
+	for i := range old.Fields {
+		if _, exists := oldToNew[i]; exists {
+			lastCommonOld = i
+		}
+	}
+
+	for i := range new.Fields {
+		if _, exists := newToOld[i]; exists {
+			lastCommonNew = i
+		}
+	}
+
+	// a trailing field that replaces a removed one would be read in its place
+	replaced := lastCommonOld < len(old.Fields)-1 && lastCommonNew < len(new.Fields)-1
+
+	var oldOrder, newOrder []int
+
+	for i, field := range old.Fields {
+		if _, exists := oldToNew[i]; exists {
+			oldOrder = append(oldOrder, i)
+			continue
+		}
+
+		change := Change{
+			Kind:        FieldRemoved,
+			Signature:   signature,
+			Field:       joinPath(prefix, field.Name),
+			Description: fmt.Sprintf("%s field removed", requiredString(field.Optional)),
+			Readers:     Breaking,
+			Writers:     Breaking,
+		}
+
+		if extensible && !replaced && i > lastCommonOld {
+			// newer readers skip the trailing data, older readers only tolerate it missing if it's optional
+			change.Writers = Safe
+
+			if field.Optional {
+				change.Readers = Safe
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	for i, field := range new.Fields {
+		oldIdx, exists := newToOld[i]
+
+		if !exists {
+			change := Change{
+				Kind:        FieldAdded,
+				Signature:   signature,
+				Field:       joinPath(prefix, field.Name),
+				Description: fmt.Sprintf("%s field added", requiredString(field.Optional)),
+				Readers:     Breaking,
+				Writers:     Breaking,
+			}
+
+			if extensible && !replaced && i > lastCommonNew {
+				// older readers skip the trailing data, newer readers only tolerate it missing if it's optional
+				change.Readers = Safe
+
+				if field.Optional {
+					change.Writers = Safe
+				}
+			}
+
+			changes = append(changes, change)
+
+			continue
+		}
+
+		oldField := old.Fields[oldIdx]
+
+		if oldField.Name != field.Name {
+			// the wire format is positional, peers matching by name find the field through its alias
+			changes = append(changes, Change{
+				Kind:        FieldRenamed,
+				Signature:   signature,
+				Field:       joinPath(prefix, field.Name),
+				Description: fmt.Sprintf("renamed from %s to %s", oldField.Name, field.Name),
+				Readers:     Safe,
+				Writers:     Safe,
+			})
+		}
+
+		newOrder = append(newOrder, oldIdx)
+		changes = append(changes, compareField(signature, joinPath(prefix, field.Name), oldField, field)...)
+	}
+
+	if !slices.Equal(oldOrder, newOrder) {
+		changes = append(changes, Change{
+			Kind:        FieldsReordered,
+			Signature:   signature,
+			Field:       prefix,
+			Description: "fields reordered",
+			Readers:     Breaking,
+			Writers:     Breaking,
+		})
+	}
+
+	return changes
+}
+
+// matchFields pairs up the fields of two versions of a message, first by name and then through their aliases
+// so a renamed field isn't reported as removed and added
+func matchFields(old, new []MessageField) (newToOld, oldToNew map[int]int) {
+	newToOld = make(map[int]int, len(new))
+	oldToNew = make(map[int]int, len(old))
+
+	pair := func(match func(o, n MessageField) bool) {
+		for j, n := range new {
+			if _, exists := newToOld[j]; exists {
+				continue
+			}
+
+			for i, o := range old {
+				if _, exists := oldToNew[i]; exists || !match(o, n) {
+					continue
+				}
+
+				newToOld[j] = i
+				oldToNew[i] = j
+
+				break
+			}
+		}
+	}
+
+	pair(func(o, n MessageField) bool { return o.Name == n.Name })
+	pair(func(o, n MessageField) bool { return n.HasName(o.Name) || o.HasName(n.Name) })
+
+	return newToOld, oldToNew
+}
+
+func compareField(signature, path string, old, new MessageField) []Change {
+	var changes []Change
+
+	if old.Optional != new.Optional {
+		changes = append(changes, Change{
+			Kind:        FieldOptionalityChanged,
+			Signature:   signature,
+			Field:       path,
+			Description: fmt.Sprintf("changed from %s to %s", requiredString(old.Optional), requiredString(new.Optional)),
+			Readers:     Breaking,
+			Writers:     Breaking,
+		})
+	}
+
+	oldNested, oldIsObject := nestedMessage(old.Extra)
+	newNested, newIsObject := nestedMessage(new.Extra)
+
+	if old.Type == new.Type && oldIsObject && newIsObject {
+		return append(changes, compareFields(signature, path, oldNested, newNested)...)
+	}
+
+	if old.Type == new.Type && old.TypeString() == new.TypeString() {
+		return changes
+	}
+
+	return append(changes, Change{
+		Kind:        FieldTypeChanged,
+		Signature:   signature,
+		Field:       path,
+		Description: fmt.Sprintf("type changed from %s to %s", old.TypeString(), new.TypeString()),
+		Readers:     Breaking,
+		Writers:     Breaking,
+	})
+}
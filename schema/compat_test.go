@@ -0,0 +1,283 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, src string) Schema {
+	s, err := Parse([]byte(src))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func checkCompatibility(t *testing.T, old, new Schema) []Change {
+	changes, err := CheckCompatibility(old, new)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return changes
+}
+
+func findChange(changes []Change, kind ChangeKind, field string) (Change, bool) {
+	for _, change := range changes {
+		if change.Kind == kind && change.Field == field {
+			return change, true
+		}
+	}
+
+	return Change{}, false
+}
+
+func TestCompatibilityIdentical(t *testing.T) {
+	s := mustParse(t, testSchema)
+
+	changes := checkCompatibility(t, s, s)
+
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestCompatibilityUnregisteredSchema(t *testing.T) {
+	s := mustParse(t, "inbound A {\n  uint32 REQUIRED x\n}")
+
+	// the ID of the internal Hello message
+	invalid := mustParse(t, "reserved 1\n\ninbound A {\n  uint32 REQUIRED x\n}")
+
+	for _, schemas := range [][2]Schema{{invalid, s}, {s, invalid}} {
+		_, err := CheckCompatibility(schemas[0], schemas[1])
+
+		if err == nil || !strings.Contains(err.Error(), "reserved message ID") {
+			t.Errorf("expected the reserved ID to be reported, got %v", err)
+		}
+	}
+}
+
+func TestCompatibilityChanges(t *testing.T) {
+	old := mustParse(t, `
+object Header {
+  uint32 REQUIRED requestId
+}
+
+inbound A {
+  Header REQUIRED header
+  int32 REQUIRED x
+  int32 REQUIRED y
+}
+
+inbound B {
+  binary REQUIRED data
+}
+
+outbound C {
+  int64 REQUIRED at
+}
+`)
+
+	new := mustParse(t, `
+object Header {
+  uint64 REQUIRED requestId
+}
+
+inbound A {
+  Header REQUIRED header
+  int32 REQUIRED y
+  int32 REQUIRED x
+  binary OPTIONAL note
+}
+
+duplex C {
+  int64 REQUIRED at
+}
+
+inbound D {}
+`)
+
+	changes := checkCompatibility(t, old, new)
+
+	expected := []struct {
+		kind  ChangeKind
+		field string
+	}{
+		{FieldTypeChanged, "header.requestId"},
+		{FieldsReordered, ""},
+		{FieldAdded, "note"},
+		{DirectionChanged, ""},
+		{MessageAdded, ""},
+		{MessageRemoved, ""},
+	}
+
+	for _, e := range expected {
+		change, exists := findChange(changes, e.kind, e.field)
+
+		if !exists {
+			t.Errorf("expected %s on %q, got %v", e.kind.ToString(), e.field, changes)
+		}
+
+		if e.kind == MessageRemoved && (change.Signature != "inbound B" || change.Readers != Safe || change.Writers != Breaking) {
+			t.Errorf("unexpected removal: %v", change)
+		}
+	}
+
+	if !HasBreakingChanges(changes) {
+		t.Error("expected breaking changes")
+	}
+}
+
+func TestCompatibilityExtensible(t *testing.T) {
+	old := mustParse(t, `
+@extensible
+inbound A {
+  int32 REQUIRED x
+  binary OPTIONAL legacy
+}
+`)
+
+	appended := mustParse(t, `
+@extensible
+inbound A {
+  int32 REQUIRED x
+  binary OPTIONAL legacy
+  int64 OPTIONAL y
+  int64 REQUIRED z
+}
+`)
+
+	changes := checkCompatibility(t, old, appended)
+
+	if y, _ := findChange(changes, FieldAdded, "y"); y.Breaking() {
+		t.Errorf("appending an optional field should be safe: %v", y)
+	}
+
+	if z, _ := findChange(changes, FieldAdded, "z"); z.Readers != Safe || z.Writers != Breaking {
+		t.Errorf("appending a required field should only break writers: %v", z)
+	}
+
+	dropped := mustParse(t, `
+@extensible
+inbound A {
+  int32 REQUIRED x
+}
+`)
+
+	if HasBreakingChanges(checkCompatibility(t, old, dropped)) {
+		t.Errorf("dropping a trailing optional field should be safe: %v", checkCompatibility(t, old, dropped))
+	}
+
+	replaced := mustParse(t, `
+@extensible
+inbound A {
+  int32 REQUIRED x
+  int64 OPTIONAL y
+}
+`)
+
+	if !HasBreakingChanges(checkCompatibility(t, old, replaced)) {
+		t.Error("replacing a trailing field should be breaking")
+	}
+}
+
+func TestCompatibilityRename(t *testing.T) {
+	old := mustParse(t, "inbound A {\n  int32 REQUIRED uid\n  binary REQUIRED name\n}")
+	renamed := mustParse(t, "inbound A {\n  int32 REQUIRED userId aka uid\n  binary REQUIRED name\n}")
+
+	changes := checkCompatibility(t, old, renamed)
+
+	if HasBreakingChanges(changes) {
+		t.Errorf("expected an aliased rename to be safe, got %v", changes)
+	}
+
+	if _, ok := findChange(changes, FieldRenamed, "userId"); !ok {
+		t.Errorf("expected userId to be reported as renamed, got %v", changes)
+	}
+
+	// dropping the alias later is still safe for peers that know the new name
+	if changes := checkCompatibility(t, renamed, mustParse(t, "inbound A {\n  int32 REQUIRED userId\n  binary REQUIRED name\n}")); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	// without an alias the rename is a removal and an addition
+	changes = checkCompatibility(t, old, mustParse(t, "inbound A {\n  int32 REQUIRED userId\n  binary REQUIRED name\n}"))
+
+	if _, ok := findChange(changes, FieldRemoved, "uid"); !ok {
+		t.Errorf("expected uid to be removed, got %v", changes)
+	}
+}
+
+func TestCompatibilityMethods(t *testing.T) {
+	const messages = "inbound Req {\n  uint32 REQUIRED id\n}\n\noutbound Res {\n  uint32 REQUIRED id\n}\n\noutbound Other {\n  uint32 REQUIRED id\n}\n"
+
+	old := mustParse(t, messages+"service S {\n  rpc A(Req) returns (Res)\n  rpc B(Req) returns (Res)\n}")
+	new := mustParse(t, messages+"service S {\n  rpc A(Req) returns (Other)\n  rpc C(Req) returns (Res)\n}")
+
+	expected := map[ChangeKind]string{
+		MethodChanged: "rpc S.A",
+		MethodRemoved: "rpc S.B",
+		MethodAdded:   "rpc S.C",
+	}
+
+	changes := checkCompatibility(t, old, new)
+
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+
+	for _, change := range changes {
+		if expected[change.Kind] != change.Signature {
+			t.Errorf("unexpected change: %v", change)
+		}
+
+		if change.Breaking() != (change.Kind != MethodAdded) {
+			t.Errorf("unexpected classification: %v", change)
+		}
+	}
+}
+
+func TestCompatibilityOptions(t *testing.T) {
+	const message = "inbound A {\n  uint32 REQUIRED x\n}"
+
+	old := mustParse(t, message)
+
+	changes := checkCompatibility(t, old, mustParse(t, "@compress @maxSize(2)\n"+message))
+
+	if c, ok := findChange(changes, CompressionChanged, ""); !ok || c.Readers != Breaking || c.Writers != Breaking {
+		t.Errorf("expected compressing the message to break readers and writers, got %v", changes)
+	}
+
+	if c, ok := findChange(changes, MaxSizeChanged, ""); !ok || c.Readers != Safe || c.Writers != Breaking {
+		t.Errorf("expected limiting the size to break writers, got %v", changes)
+	}
+
+	limited := mustParse(t, "@maxSize(64)\n"+message)
+
+	tests := []struct {
+		old, new         Schema
+		readers, writers Compatibility
+	}{
+		{limited, mustParse(t, "@maxSize(32)\n"+message), Safe, Breaking},
+		{limited, mustParse(t, "@maxSize(128)\n"+message), Breaking, Safe},
+		{limited, old, Breaking, Safe},
+	}
+
+	for _, test := range tests {
+		changes := checkCompatibility(t, test.old, test.new)
+		c, ok := findChange(changes, MaxSizeChanged, "")
+
+		if !ok || len(changes) != 1 || c.Readers != test.readers || c.Writers != test.writers {
+			t.Errorf("expected readers: %s, writers: %s, got %v", test.readers.ToString(), test.writers.ToString(), changes)
+		}
+	}
+
+	changes = checkCompatibility(t, old, mustParse(t, "@priority(high)\n"+message))
+
+	if _, ok := findChange(changes, PriorityChanged, ""); !ok || HasBreakingChanges(changes) {
+		t.Errorf("expected a safe priority change, got %v", changes)
+	}
+}
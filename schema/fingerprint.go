@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// The fingerprint is a SHA-256 over a canonical encoding of every registered descriptor in ID order,
+// followed by the RPC methods by name. Any change to IDs, directions, names, message options, field types, extras,
+// optionality, aliases or methods changes the fingerprint.
+
+func appendString(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+
+	return append(b, 0)
+}
+
+func appendCanonicalMessage(b []byte, message SchemaMessage) []byte {
+	b = binary.LittleEndian.AppendUint16(b, uint16(message.Direction))
+	b = appendString(b, message.Name)
+	b = appendBool(b, message.Extensible)
+	b = binary.LittleEndian.AppendUint32(b, message.Options.MaxSize)
+	b = binary.LittleEndian.AppendUint16(b, uint16(message.Options.Priority))
+	b = appendBool(b, message.Options.Compress)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(message.Fields)))
+
+	for _, field := range message.Fields {
+		b = appendCanonicalField(b, field)
+	}
+
+	return b
+}
+
+func appendCanonicalField(b []byte, field MessageField) []byte {
+	b = appendString(b, field.Name)
+	b = binary.LittleEndian.AppendUint16(b, uint16(field.Type))
+	b = appendBool(b, field.Optional)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(field.Aliases)))
+
+	for _, alias := range field.Aliases {
+		b = appendString(b, alias)
+	}
+
+	switch e := field.Extra.(type) {
+	case int:
+		b = append(b, 'n')
+		b = binary.LittleEndian.AppendUint32(b, uint32(e))
+	case MessageField:
+		b = append(b, 'f')
+		b = appendCanonicalField(b, e)
+	case SchemaMessage:
+		b = append(b, 'm')
+		b = appendCanonicalMessage(b, e)
+	case MessageDescriptor:
+		b = append(b, 'm')
+		b = appendCanonicalMessage(b, e.Message)
+	default:
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+func (r *MessageDescriptorRegistry) computeFingerprint() [32]byte {
+	ids := make([]uint32, 0, len(r.Descriptors))
+
+	for id := range r.Descriptors {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var b []byte
+
+	for _, id := range ids {
+		descriptor := r.Descriptors[id]
+
+		b = binary.LittleEndian.AppendUint32(b, id)
+		b = appendBool(b, descriptor.Internal)
+		b = appendCanonicalMessage(b, descriptor.Message)
+	}
+
+	names := make([]string, 0, len(r.Methods))
+
+	for name := range r.Methods {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		method := r.Methods[name]
+
+		b = appendString(b, method.Name)
+		b = binary.LittleEndian.AppendUint32(b, method.Request)
+		b = binary.LittleEndian.AppendUint32(b, method.Response)
+		b = appendBool(b, method.ClientStream)
+		b = appendBool(b, method.ServerStream)
+	}
+
+	return sha256.Sum256(b)
+}
+
+// Fingerprint returns a stable hash of every descriptor registered so far, peers that computed the same
+// fingerprint are guaranteed to agree on the entire schema
+func (r *MessageDescriptorRegistry) Fingerprint() [32]byte {
+	return r.fingerprint
+}
+
+func appendLayout(b []byte, message SchemaMessage) []byte {
+	// embedded objects are encoded in place of their field, so they are hashed flattened
+	fields, _ := flattenFields(message)
+
+	b = appendBool(b, message.Extensible)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(fields)))
+
+	for _, field := range fields {
+		b = appendFieldLayout(b, field)
+	}
+
+	return b
+}
+
+func appendFieldLayout(b []byte, field MessageField) []byte {
+	b = appendString(b, field.Name)
+	b = binary.LittleEndian.AppendUint16(b, uint16(field.Type))
+	b = appendBool(b, field.Optional)
+
+	switch e := field.Extra.(type) {
+	case int:
+		b = append(b, 'n')
+		b = binary.LittleEndian.AppendUint32(b, uint32(e))
+	case MessageField:
+		b = append(b, 'f')
+		b = appendFieldLayout(b, e)
+	case SchemaMessage:
+		b = append(b, 'm')
+		b = appendLayout(b, e)
+	case MessageDescriptor:
+		b = append(b, 'm')
+		b = appendLayout(b, e.Message)
+	default:
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+// Layout returns a hash of how a message is laid out on the wire: the names, order, types and optionality of its
+// fields and nested objects. Code generated for a message is only used for descriptors with the same layout.
+func (m SchemaMessage) Layout() uint64 {
+	h := fnv.New64a()
+	h.Write(appendLayout(nil, m))
+
+	return h.Sum64()
+}
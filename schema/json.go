@@ -0,0 +1,330 @@
+package schema
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// The JSON representation is meant for tooling that doesn't want to parse the DSL. Enums are written as
+// strings, and the Extra of a field is written under a key naming its kind: length for binary(N), element
+// for the element of an array, object for an object and descriptor for a registered object.
+
+var ErrInvalidJSON = errors.New("invalid schema JSON")
+
+var jsonFieldTypes = map[FieldType]string{
+	TypeFixedBinary:   "fixed_binary",
+	TypeDynamicBinary: "binary",
+	TypeLongBinary:    "long_binary",
+	TypeUInt64:        "uint64",
+	TypeInt64:         "int64",
+	TypeUInt32:        "uint32",
+	TypeInt32:         "int32",
+	TypeUInt16:        "uint16",
+	TypeInt16:         "int16",
+	TypeObject:        "object",
+	TypeArray:         "array",
+}
+
+func (f FieldType) MarshalText() ([]byte, error) {
+	name, exists := jsonFieldTypes[f]
+
+	if !exists {
+		return nil, fmt.Errorf("%w: unknown field type %d", ErrInvalidJSON, f)
+	}
+
+	return []byte(name), nil
+}
+
+func (f *FieldType) UnmarshalText(text []byte) error {
+	for fieldType, name := range jsonFieldTypes {
+		if name == string(text) {
+			*f = fieldType
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: unknown field type %q", ErrInvalidJSON, text)
+}
+
+func (d MessageDirection) MarshalText() ([]byte, error) {
+	name := d.ToString()
+
+	if name == "" {
+		return nil, fmt.Errorf("%w: unknown direction %d", ErrInvalidJSON, d)
+	}
+
+	return []byte(name), nil
+}
+
+func (d *MessageDirection) UnmarshalText(text []byte) error {
+	for direction := InboundMessage; direction <= ObjectDef; direction++ {
+		if direction.ToString() == string(text) {
+			*d = direction
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: unknown direction %q", ErrInvalidJSON, text)
+}
+
+func (p Priority) MarshalText() ([]byte, error) {
+	name := p.ToString()
+
+	if name == "" {
+		return nil, fmt.Errorf("%w: unknown priority %d", ErrInvalidJSON, p)
+	}
+
+	return []byte(name), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	for priority := PriorityNormal; priority <= PriorityHigh; priority++ {
+		if priority.ToString() == string(text) {
+			*p = priority
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: unknown priority %q", ErrInvalidJSON, text)
+}
+
+type fieldJSON struct {
+	Name       string             `json:"name,omitempty"`
+	Type       FieldType          `json:"type"`
+	Optional   bool               `json:"optional,omitempty"`
+	Deprecated bool               `json:"deprecated,omitempty"`
+	Aliases    []string           `json:"aliases,omitempty"`
+	Embedded   bool               `json:"embedded,omitempty"`
+	TypeAlias  string             `json:"typeAlias,omitempty"`
+	Length     *int               `json:"length,omitempty"`
+	Element    *MessageField      `json:"element,omitempty"`
+	Object     *SchemaMessage     `json:"object,omitempty"`
+	Descriptor *MessageDescriptor `json:"descriptor,omitempty"`
+}
+
+func (f MessageField) MarshalJSON() ([]byte, error) {
+	res := fieldJSON{
+		Name:       f.Name,
+		Type:       f.Type,
+		Optional:   f.Optional,
+		Deprecated: f.Deprecated,
+		Aliases:    f.Aliases,
+		Embedded:   f.Embedded,
+		TypeAlias:  f.TypeAlias,
+	}
+
+	switch e := f.Extra.(type) {
+	case nil:
+	case int:
+		res.Length = &e
+	case MessageField:
+		res.Element = &e
+	case SchemaMessage:
+		res.Object = &e
+	case MessageDescriptor:
+		res.Descriptor = &e
+	default:
+		return nil, fmt.Errorf("%w: unsupported extra of field %s: %T", ErrInvalidJSON, f.Name, f.Extra)
+	}
+
+	return json.Marshal(res)
+}
+
+func (f *MessageField) UnmarshalJSON(data []byte) error {
+	var res fieldJSON
+
+	err := json.Unmarshal(data, &res)
+
+	if err != nil {
+		return err
+	}
+
+	field := MessageField{
+		Name:       res.Name,
+		Type:       res.Type,
+		Optional:   res.Optional,
+		Deprecated: res.Deprecated,
+		Aliases:    res.Aliases,
+		Embedded:   res.Embedded,
+		TypeAlias:  res.TypeAlias,
+	}
+
+	extras := 0
+
+	if res.Length != nil {
+		field.Extra = *res.Length
+		extras++
+	}
+
+	if res.Element != nil {
+		field.Extra = *res.Element
+		extras++
+	}
+
+	if res.Object != nil {
+		field.Extra = *res.Object
+		extras++
+	}
+
+	if res.Descriptor != nil {
+		field.Extra = *res.Descriptor
+		extras++
+	}
+
+	if extras > 1 {
+		return fmt.Errorf("%w: field %s has more than one extra", ErrInvalidJSON, res.Name)
+	}
+
+	err = checkExtra(field)
+
+	if err != nil {
+		return err
+	}
+
+	*f = field
+
+	return nil
+}
+
+// checkExtra reports whether the extra of a field is of the kind its type requires
+func checkExtra(f MessageField) error {
+	ok := true
+
+	switch f.Type {
+	case TypeFixedBinary:
+		{
+			len, isInt := f.Extra.(int)
+			ok = isInt && len >= 0
+		}
+	case TypeObject:
+		{
+			switch f.Extra.(type) {
+			case SchemaMessage, MessageDescriptor:
+			default:
+				ok = false
+			}
+		}
+	case TypeArray:
+		{
+			switch f.Extra.(type) {
+			case MessageField, SchemaMessage, MessageDescriptor:
+			default:
+				ok = false
+			}
+		}
+	default:
+		ok = f.Extra == nil
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: invalid extra for %s field %s", ErrInvalidJSON, f.Type.ToString(), f.Name)
+	}
+
+	return nil
+}
+
+type registryJSON struct {
+	Descriptors []MessageDescriptor `json:"descriptors"`
+	Methods     []RPCMethod         `json:"methods,omitempty"`
+	Types       []TypeAlias         `json:"types,omitempty"`
+	Constants   []Constant          `json:"constants,omitempty"`
+	Fingerprint string              `json:"fingerprint"`
+}
+
+// MarshalJSON writes the descriptors in ID order, the methods by name and the fingerprint as hex
+func (r MessageDescriptorRegistry) MarshalJSON() ([]byte, error) {
+	res := registryJSON{
+		Descriptors: make([]MessageDescriptor, 0, len(r.Descriptors)),
+		Methods:     make([]RPCMethod, 0, len(r.Methods)),
+		Types:       r.Types,
+		Constants:   r.Constants,
+		Fingerprint: hex.EncodeToString(r.fingerprint[:]),
+	}
+
+	for _, descriptor := range r.Descriptors {
+		res.Descriptors = append(res.Descriptors, descriptor)
+	}
+
+	sort.Slice(res.Descriptors, func(i, j int) bool { return res.Descriptors[i].ID < res.Descriptors[j].ID })
+
+	for _, method := range r.Methods {
+		res.Methods = append(res.Methods, method)
+	}
+
+	sort.Slice(res.Methods, func(i, j int) bool { return res.Methods[i].Name < res.Methods[j].Name })
+
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON replaces the registry with the one described by the JSON, keeping the IDs of its descriptors.
+// Handlers are not part of the JSON and have to be registered again. The fingerprint is recomputed and must
+// match the one in the JSON, if any.
+func (r *MessageDescriptorRegistry) UnmarshalJSON(data []byte) error {
+	var res registryJSON
+
+	err := json.Unmarshal(data, &res)
+
+	if err != nil {
+		return err
+	}
+
+	registry := MessageDescriptorRegistry{}
+	registry.ensureDescriptors()
+
+	for _, descriptor := range res.Descriptors {
+		if _, exists := registry.Descriptors[descriptor.ID]; exists {
+			return fmt.Errorf("%w: duplicate descriptor ID: %d", ErrInvalidJSON, descriptor.ID)
+		}
+
+		if descriptor.OptionalCount != descriptor.Message.CountOptional() {
+			return fmt.Errorf("%w: wrong optional count of %s", ErrInvalidJSON, descriptor.Message.Signature())
+		}
+
+		signatureMap := registry.UserSignatureMap
+
+		if descriptor.Internal {
+			signatureMap = registry.InternalSignatureMap
+			registry.RegisteredInternal = true
+		} else {
+			registry.RegisteredUser = true
+		}
+
+		err = handleSignatures(signatureMap, descriptor.Message, descriptor.ID)
+
+		if err != nil {
+			return err
+		}
+
+		descriptor.Layout = descriptor.Message.Layout()
+		registry.Descriptors[descriptor.ID] = descriptor
+
+		if descriptor.ID >= registry.idCounter {
+			registry.idCounter = descriptor.ID + 1
+		}
+	}
+
+	for _, method := range res.Methods {
+		err = registry.registerMethod(method)
+
+		if err != nil {
+			return err
+		}
+
+		registry.RegisteredUser = true
+	}
+
+	registry.Types = res.Types
+	registry.Constants = res.Constants
+	registry.fingerprint = registry.computeFingerprint()
+
+	if res.Fingerprint != "" && res.Fingerprint != hex.EncodeToString(registry.fingerprint[:]) {
+		return fmt.Errorf("%w: fingerprint doesn't match the descriptors", ErrInvalidJSON)
+	}
+
+	*r = registry
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const jsonTestSchema = `
+package shop
+
+const NameLength = 16
+
+type Name = binary(NameLength)
+
+object Item {
+  Name REQUIRED name
+  uint32 REQUIRED price aka cost
+}
+
+@maxSize(1MiB) @compress
+inbound Order {
+  array(Item) REQUIRED items
+  array(int16) OPTIONAL coupons
+  long_binary OPTIONAL note
+}
+
+@priority(high) @extensible
+outbound Receipt {
+  uint64 REQUIRED total
+}
+
+service Orders {
+  rpc Place(Order) returns (Receipt)
+  rpc Watch(Order) returns (stream Receipt)
+}
+`
+
+func TestSchemaJSON(t *testing.T) {
+	s, err := Parse([]byte(jsonTestSchema))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`"type":"fixed_binary","length":16`, `"element":{"type":"int16"}`, `"options":{"maxSize":1048576,"compress":true}`, `"priority":"high"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected JSON to contain %s: %s", expected, data)
+		}
+	}
+
+	var decoded Schema
+
+	err = json.Unmarshal(data, &decoded)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := newTestRegistry(t)
+	restored := newTestRegistry(t)
+
+	if err := original.RegisterSchema(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restored.RegisterSchema(decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if original.Fingerprint() != restored.Fingerprint() {
+		t.Error("expected the decoded schema to register with the same fingerprint")
+	}
+}
+
+func TestRegistryJSON(t *testing.T) {
+	s, err := Parse([]byte(jsonTestSchema))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTestRegistry(t)
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(r)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded MessageDescriptorRegistry
+
+	err = json.Unmarshal(data, &decoded)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Fingerprint() != r.Fingerprint() {
+		t.Error("expected the decoded registry to have the same fingerprint")
+	}
+
+	if decoded.UserSignatureMap["inbound shop.Order"] != r.UserSignatureMap["inbound shop.Order"] || !decoded.Methods["shop.Orders.Watch"].ServerStream {
+		t.Errorf("unexpected decoded registry: %+v", decoded)
+	}
+
+	if !decoded.RegisteredInternal || !decoded.RegisteredUser {
+		t.Error("expected the decoded registry to be registered")
+	}
+
+	// tampering with a descriptor is caught by the fingerprint
+	tampered := strings.Replace(string(data), `"name":"price"`, `"name":"cost"`, 1)
+
+	if err := json.Unmarshal([]byte(tampered), &decoded); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("expected a fingerprint mismatch, got %v", err)
+	}
+}
+
+func TestFieldJSONErrors(t *testing.T) {
+	cases := []string{
+		`{"name":"x","type":"fixed_binary"}`,
+		`{"name":"x","type":"uint32","length":4}`,
+		`{"name":"x","type":"array","length":4,"element":{"type":"int16"}}`,
+		`{"name":"x","type":"float"}`,
+	}
+
+	for _, src := range cases {
+		var field MessageField
+
+		if err := json.Unmarshal([]byte(src), &field); !errors.Is(err, ErrInvalidJSON) {
+			t.Errorf("expected %s to be rejected, got %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenPunct
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	column int
+}
+
+type ParseError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	path := e.Path
+
+	if path == "" {
+		path = "<schema>"
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", path, e.Line, e.Column, e.Message)
+}
+
+type lexer struct {
+	path   string
+	src    []byte
+	pos    int
+	line   int
+	column int
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *lexer) errorf(line, column int, format string, args ...any) error {
+	return &ParseError{
+		Path:    l.path,
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (l *lexer) advance() {
+	if l.src[l.pos] == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
+	l.pos++
+}
+
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.advance()
+		case c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '/':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance()
+			}
+		case c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '*':
+			line, column := l.line, l.column
+
+			l.advance()
+			l.advance()
+
+			for {
+				if l.pos+1 >= len(l.src) {
+					return l.errorf(line, column, "unterminated comment")
+				}
+
+				if l.src[l.pos] == '*' && l.src[l.pos+1] == '/' {
+					l.advance()
+					l.advance()
+					break
+				}
+
+				l.advance()
+			}
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (l *lexer) next() (token, error) {
+	err := l.skipSpace()
+
+	if err != nil {
+		return token{}, err
+	}
+
+	tok := token{
+		line:   l.line,
+		column: l.column,
+	}
+
+	if l.pos >= len(l.src) {
+		tok.kind = tokenEOF
+		return tok, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+
+	switch {
+	case isIdentStart(c):
+		{
+			for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+				l.advance()
+			}
+
+			tok.kind = tokenIdent
+			tok.text = string(l.src[start:l.pos])
+		}
+	case isDigit(c):
+		{
+			for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+				l.advance()
+			}
+
+			tok.kind = tokenNumber
+			tok.text = string(l.src[start:l.pos])
+		}
+	case c == '"':
+		{
+			l.advance()
+
+			for {
+				if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+					return token{}, l.errorf(tok.line, tok.column, "unterminated string")
+				}
+
+				if l.src[l.pos] == '\\' && l.pos+1 < len(l.src) {
+					l.advance()
+				} else if l.src[l.pos] == '"' {
+					l.advance()
+					break
+				}
+
+				l.advance()
+			}
+
+			str, err := strconv.Unquote(string(l.src[start:l.pos]))
+
+			if err != nil {
+				return token{}, l.errorf(tok.line, tok.column, "invalid string: %v", err)
+			}
+
+			tok.kind = tokenString
+			tok.text = str
+		}
+	default:
+		{
+			switch c {
+			case '{', '}', '(', ')', ',', '@', '=', ';', '.':
+				l.advance()
+
+				tok.kind = tokenPunct
+				tok.text = string(c)
+			default:
+				return token{}, l.errorf(tok.line, tok.column, "unexpected character %q", c)
+			}
+		}
+	}
+
+	return tok, nil
+}
+
+func tokenize(path string, src []byte) ([]token, error) {
+	l := lexer{
+		path:   path,
+		src:    src,
+		line:   1,
+		column: 1,
+	}
+
+	var tokens []token
+
+	for {
+		tok, err := l.next()
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, tok)
+
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
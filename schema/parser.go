@@ -0,0 +1,1413 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type typeExpr struct {
+	tok     token
+	name    string
+	arg     *typeExpr // element type of array(T)
+	size    token     // length of binary(N), a number or a constant
+	hasSize bool
+}
+
+type methodDecl struct {
+	tok          token
+	name         string
+	request      token
+	response     token
+	clientStream bool
+	serverStream bool
+}
+
+type serviceDecl struct {
+	tok     token
+	name    string
+	methods []methodDecl
+}
+
+type typeDecl struct {
+	tok  token
+	name string
+	typ  typeExpr
+}
+
+type annotation struct {
+	tok  token
+	name string
+	args []token
+}
+
+type fieldDecl struct {
+	tok        token
+	typ        typeExpr
+	name       string
+	optional   bool
+	deprecated bool
+	aliases    []token
+	include    bool // include Name, the fields of the object are spliced in
+}
+
+type messageDecl struct {
+	tok        token
+	direction  MessageDirection
+	name       string
+	fields     []fieldDecl
+	deprecated bool
+	extensible bool
+	reserved   []string
+	options    MessageOptions
+}
+
+type parser struct {
+	path     string
+	tokens   []token
+	pos      int
+	messages []*messageDecl
+	objects  map[string]*messageDecl
+	resolved map[string]SchemaMessage
+	types    map[string]*typeDecl
+	aliases  []*typeDecl
+	services []*serviceDecl
+	consts   map[string]uint64
+	loader   *loader
+	imports  []Schema
+	schema   Schema
+}
+
+// loader parses schema files and the files they import, every file is parsed once
+type loader struct {
+	files   map[string]Schema // absolute path to parsed schema
+	loading []string          // files that are being parsed, to detect import cycles
+}
+
+var directionKeywords = map[string]MessageDirection{
+	"inbound":  InboundMessage,
+	"outbound": OutboundMessage,
+	"duplex":   DuplexMessage,
+	"object":   ObjectDef,
+}
+
+var primitiveTypes = map[string]FieldType{
+	"long_binary": TypeLongBinary,
+	"uint64":      TypeUInt64,
+	"int64":       TypeInt64,
+	"uint32":      TypeUInt32,
+	"int32":       TypeInt32,
+	"uint16":      TypeUInt16,
+	"int16":       TypeInt16,
+}
+
+func Parse(src []byte) (Schema, error) {
+	l := loader{files: make(map[string]Schema)}
+	return l.parse("", "", src)
+}
+
+// ParseFile parses a schema file, imports are resolved relative to the directory of the file
+func ParseFile(path string) (Schema, error) {
+	l := loader{files: make(map[string]Schema)}
+	return l.load(path)
+}
+
+func (l *loader) load(path string) (Schema, error) {
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return Schema{}, err
+	}
+
+	if s, exists := l.files[abs]; exists {
+		return s, nil
+	}
+
+	if slices.Contains(l.loading, abs) {
+		return Schema{}, fmt.Errorf("import cycle: %s", strings.Join(append(l.loading, abs), " -> "))
+	}
+
+	src, err := os.ReadFile(path)
+
+	if err != nil {
+		return Schema{}, err
+	}
+
+	l.loading = append(l.loading, abs)
+	s, err := l.parse(path, abs, src)
+	l.loading = l.loading[:len(l.loading)-1]
+
+	if err != nil {
+		return Schema{}, err
+	}
+
+	l.files[abs] = s
+
+	return s, nil
+}
+
+func (l *loader) parse(path, abs string, src []byte) (Schema, error) {
+	tokens, err := tokenize(path, src)
+
+	if err != nil {
+		return Schema{}, err
+	}
+
+	p := parser{
+		path:     path,
+		tokens:   tokens,
+		objects:  make(map[string]*messageDecl),
+		resolved: make(map[string]SchemaMessage),
+		types:    make(map[string]*typeDecl),
+		consts:   make(map[string]uint64),
+		loader:   l,
+		schema:   Schema{Path: abs},
+	}
+
+	err = p.parseFile()
+
+	if err != nil {
+		return Schema{}, err
+	}
+
+	err = p.resolve()
+
+	if err != nil {
+		return Schema{}, err
+	}
+
+	return p.schema, nil
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	l := lexer{path: p.path}
+	return l.errorf(tok.line, tok.column, format, args...)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+func (p *parser) isPunct(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokenPunct && tok.text == text
+}
+
+func (p *parser) acceptPunct(text string) bool {
+	if p.isPunct(text) {
+		p.next()
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expectPunct(text string) (token, error) {
+	tok := p.next()
+
+	if tok.kind != tokenPunct || tok.text != text {
+		return tok, p.errorf(tok, "expected %q, found %s", text, describe(tok))
+	}
+
+	return tok, nil
+}
+
+func (p *parser) expectIdent() (token, error) {
+	tok := p.next()
+
+	if tok.kind != tokenIdent {
+		return tok, p.errorf(tok, "expected identifier, found %s", describe(tok))
+	}
+
+	return tok, nil
+}
+
+// expectQualifiedIdent reads an identifier that may be qualified with a package, e.g. billing.Charge
+func (p *parser) expectQualifiedIdent() (token, error) {
+	tok, err := p.expectIdent()
+
+	if err != nil {
+		return tok, err
+	}
+
+	for p.acceptPunct(".") {
+		part, err := p.expectIdent()
+
+		if err != nil {
+			return tok, err
+		}
+
+		tok.text += "." + part.text
+	}
+
+	return tok, nil
+}
+
+func (p *parser) qualify(name string) string {
+	return QualifiedName(p.schema.Package, name)
+}
+
+// localName strips the package of the schema being parsed from a name
+func (p *parser) localName(name string) string {
+	if p.schema.Package == "" {
+		return name
+	}
+
+	return strings.TrimPrefix(name, p.schema.Package+".")
+}
+
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of file"
+	case tokenString:
+		return strconv.Quote(tok.text)
+	default:
+		return "'" + tok.text + "'"
+	}
+}
+
+func (p *parser) parseFile() error {
+	for p.peek().kind != tokenEOF {
+		annotations, err := p.parseAnnotations()
+
+		if err != nil {
+			return err
+		}
+
+		tok, err := p.expectIdent()
+
+		if err != nil {
+			return err
+		}
+
+		if tok.text == "reserved" {
+			if len(annotations) > 0 {
+				return p.errorf(annotations[0].tok, "annotations are not allowed on reserved")
+			}
+
+			err = p.parseSchemaReserved()
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if tok.text == "package" || tok.text == "import" {
+			if len(annotations) > 0 {
+				return p.errorf(annotations[0].tok, "annotations are not allowed on %s", tok.text)
+			}
+
+			if tok.text == "package" {
+				err = p.parsePackage(tok)
+			} else {
+				err = p.parseImport()
+			}
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if tok.text == "service" {
+			if len(annotations) > 0 {
+				return p.errorf(annotations[0].tok, "annotations are not allowed on service")
+			}
+
+			err = p.parseService()
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if tok.text == "type" || tok.text == "const" {
+			if len(annotations) > 0 {
+				return p.errorf(annotations[0].tok, "annotations are not allowed on %s", tok.text)
+			}
+
+			if tok.text == "type" {
+				err = p.parseTypeAlias()
+			} else {
+				err = p.parseConst()
+			}
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		direction, ok := directionKeywords[tok.text]
+
+		if !ok {
+			return p.errorf(tok, "expected declaration, found %s", describe(tok))
+		}
+
+		err = p.parseMessage(tok, direction, annotations)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) parseAnnotations() ([]annotation, error) {
+	var annotations []annotation
+
+	for p.isPunct("@") {
+		at := p.next()
+
+		name, err := p.expectIdent()
+
+		if err != nil {
+			return nil, err
+		}
+
+		a := annotation{
+			tok:  at,
+			name: name.text,
+		}
+
+		if p.acceptPunct("(") {
+			for !p.isPunct(")") {
+				if len(a.args) > 0 {
+					_, err = p.expectPunct(",")
+
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				arg := p.next()
+
+				if arg.kind == tokenEOF || arg.kind == tokenPunct {
+					return nil, p.errorf(arg, "expected annotation argument, found %s", describe(arg))
+				}
+
+				a.args = append(a.args, arg)
+			}
+
+			p.next()
+		}
+
+		annotations = append(annotations, a)
+	}
+
+	return annotations, nil
+}
+
+// package name;
+func (p *parser) parsePackage(tok token) error {
+	if p.schema.Package != "" || len(p.imports) > 0 || len(p.messages) > 0 || len(p.aliases) > 0 || len(p.services) > 0 || len(p.consts) > 0 || len(p.schema.ReservedNames) > 0 {
+		return p.errorf(tok, "package must be declared once, before any other declaration")
+	}
+
+	name, err := p.expectQualifiedIdent()
+
+	if err != nil {
+		return err
+	}
+
+	p.schema.Package = name.text
+	p.acceptPunct(";")
+
+	return nil
+}
+
+// import "path";
+func (p *parser) parseImport() error {
+	tok := p.next()
+
+	if tok.kind != tokenString {
+		return p.errorf(tok, "expected import path, found %s", describe(tok))
+	}
+
+	path := tok.text
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(p.path), path)
+	}
+
+	imported, err := p.loader.load(path)
+
+	if err != nil {
+		var parseErr *ParseError
+
+		if errors.As(err, &parseErr) {
+			return err
+		}
+
+		return p.errorf(tok, "%s", err.Error())
+	}
+
+	p.imports = append(p.imports, imported)
+	p.schema.Imports = append(p.schema.Imports, imported)
+	p.acceptPunct(";")
+
+	return nil
+}
+
+// service Name { rpc Method(Request) returns (Response); }
+func (p *parser) parseService() error {
+	name, err := p.expectIdent()
+
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range p.services {
+		if existing.name == name.text {
+			return p.errorf(name, "duplicate service: %s", name.text)
+		}
+	}
+
+	decl := &serviceDecl{
+		tok:  name,
+		name: name.text,
+	}
+
+	_, err = p.expectPunct("{")
+
+	if err != nil {
+		return err
+	}
+
+	for !p.acceptPunct("}") {
+		method, err := p.parseMethod()
+
+		if err != nil {
+			return err
+		}
+
+		for _, existing := range decl.methods {
+			if existing.name == method.name {
+				return p.errorf(method.tok, "duplicate method: %s.%s", decl.name, method.name)
+			}
+		}
+
+		decl.methods = append(decl.methods, method)
+	}
+
+	p.services = append(p.services, decl)
+
+	return nil
+}
+
+func (p *parser) parseMethod() (methodDecl, error) {
+	tok, err := p.expectIdent()
+
+	if err != nil {
+		return methodDecl{}, err
+	}
+
+	if tok.text != "rpc" {
+		return methodDecl{}, p.errorf(tok, "expected rpc, found %s", describe(tok))
+	}
+
+	name, err := p.expectIdent()
+
+	if err != nil {
+		return methodDecl{}, err
+	}
+
+	method := methodDecl{
+		tok:  name,
+		name: name.text,
+	}
+
+	method.request, method.clientStream, err = p.parseMessageRef()
+
+	if err != nil {
+		return method, err
+	}
+
+	returns, err := p.expectIdent()
+
+	if err != nil {
+		return method, err
+	}
+
+	if returns.text != "returns" {
+		return method, p.errorf(returns, "expected returns, found %s", describe(returns))
+	}
+
+	method.response, method.serverStream, err = p.parseMessageRef()
+
+	if err != nil {
+		return method, err
+	}
+
+	p.acceptPunct(";")
+
+	return method, nil
+}
+
+// (Message) or (stream Message)
+func (p *parser) parseMessageRef() (token, bool, error) {
+	_, err := p.expectPunct("(")
+
+	if err != nil {
+		return token{}, false, err
+	}
+
+	stream := false
+
+	// stream is only a keyword when followed by the message name, so a message can still be called stream
+	if tok := p.peek(); tok.kind == tokenIdent && tok.text == "stream" && p.tokens[p.pos+1].kind == tokenIdent {
+		p.next()
+		stream = true
+	}
+
+	name, err := p.expectQualifiedIdent()
+
+	if err != nil {
+		return name, stream, err
+	}
+
+	_, err = p.expectPunct(")")
+
+	return name, stream, err
+}
+
+func (p *parser) isTypeName(name string) bool {
+	_, primitive := primitiveTypes[name]
+	_, alias := p.types[name]
+
+	return primitive || alias || name == "binary" || name == "array"
+}
+
+// type Name = T
+func (p *parser) parseTypeAlias() error {
+	name, err := p.expectIdent()
+
+	if err != nil {
+		return err
+	}
+
+	if p.isTypeName(name.text) {
+		return p.errorf(name, "duplicate type: %s", name.text)
+	}
+
+	_, err = p.expectPunct("=")
+
+	if err != nil {
+		return err
+	}
+
+	typ, err := p.parseType()
+
+	if err != nil {
+		return err
+	}
+
+	decl := &typeDecl{
+		tok:  name,
+		name: name.text,
+		typ:  typ,
+	}
+
+	p.types[decl.name] = decl
+	p.aliases = append(p.aliases, decl)
+
+	return nil
+}
+
+// const Name = N
+//
+// Constants are usable wherever the DSL takes a number, which is binary(N) and @maxSize(N). The DSL has no
+// field constraints (such as a maximum length) that could take them.
+func (p *parser) parseConst() error {
+	name, err := p.expectIdent()
+
+	if err != nil {
+		return err
+	}
+
+	if _, exists := p.consts[name.text]; exists {
+		return p.errorf(name, "duplicate constant: %s", name.text)
+	}
+
+	_, err = p.expectPunct("=")
+
+	if err != nil {
+		return err
+	}
+
+	tok := p.next()
+
+	if tok.kind != tokenNumber {
+		return p.errorf(tok, "expected number, found %s", describe(tok))
+	}
+
+	value, err := strconv.ParseUint(tok.text, 0, 64)
+
+	if err != nil {
+		return p.errorf(tok, "invalid number %q", tok.text)
+	}
+
+	p.consts[name.text] = value
+	p.schema.Constants = append(p.schema.Constants, Constant{
+		Name:  p.qualify(name.text),
+		Value: value,
+	})
+
+	return nil
+}
+
+// number returns the value of a number or a constant
+func (p *parser) number(tok token, bitSize int) (uint64, error) {
+	switch tok.kind {
+	case tokenNumber:
+		{
+			num, err := strconv.ParseUint(tok.text, 0, bitSize)
+
+			if err != nil {
+				return 0, p.errorf(tok, "invalid number %q", tok.text)
+			}
+
+			return num, nil
+		}
+	case tokenIdent:
+		{
+			num, exists := p.lookupConst(tok.text)
+
+			if !exists {
+				return 0, p.errorf(tok, "unknown constant: %s", tok.text)
+			}
+
+			if bitSize < 64 && num >= 1<<bitSize {
+				return 0, p.errorf(tok, "constant %s is out of range", tok.text)
+			}
+
+			return num, nil
+		}
+	default:
+		return 0, p.errorf(tok, "expected number or constant, found %s", describe(tok))
+	}
+}
+
+// @maxSize(N), @priority(low|normal|high) or @compress
+func (p *parser) parseMessageOption(options *MessageOptions, a annotation) error {
+	args := 1
+
+	if a.name == "compress" {
+		args = 0
+	}
+
+	if len(a.args) != args {
+		return p.errorf(a.tok, "@%s takes %d argument(s), found %d", a.name, args, len(a.args))
+	}
+
+	switch a.name {
+	case "maxSize":
+		{
+			size, err := p.size(a.args[0])
+
+			if err != nil {
+				return err
+			}
+
+			if size == 0 {
+				return p.errorf(a.args[0], "@maxSize must be greater than 0")
+			}
+
+			options.MaxSize = size
+		}
+	case "priority":
+		{
+			arg := a.args[0]
+
+			switch arg.text {
+			case "low":
+				options.Priority = PriorityLow
+			case "normal":
+				options.Priority = PriorityNormal
+			case "high":
+				options.Priority = PriorityHigh
+			default:
+				return p.errorf(arg, "expected low, normal or high, found %s", describe(arg))
+			}
+		}
+	case "compress":
+		options.Compress = true
+	}
+
+	return nil
+}
+
+var sizeUnits = map[string]uint64{
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+}
+
+// size returns a number of bytes written as a number, a constant or a number with a KiB, MiB or GiB suffix
+func (p *parser) size(tok token) (uint32, error) {
+	if tok.kind == tokenNumber {
+		for unit, scale := range sizeUnits {
+			digits, found := strings.CutSuffix(tok.text, unit)
+
+			if !found {
+				continue
+			}
+
+			num, err := strconv.ParseUint(digits, 10, 32)
+
+			if err != nil || num*scale > 1<<32-1 {
+				return 0, p.errorf(tok, "invalid size %q", tok.text)
+			}
+
+			return uint32(num * scale), nil
+		}
+	}
+
+	num, err := p.number(tok, 32)
+
+	return uint32(num), err
+}
+
+func (p *parser) parseSchemaReserved() error {
+	for {
+		tok := p.next()
+
+		switch tok.kind {
+		case tokenNumber:
+			{
+				id, err := strconv.ParseUint(tok.text, 0, 32)
+
+				if err != nil {
+					return p.errorf(tok, "invalid message ID %q", tok.text)
+				}
+
+				p.schema.ReservedIDs = append(p.schema.ReservedIDs, uint32(id))
+			}
+		case tokenIdent:
+			p.schema.ReservedNames = append(p.schema.ReservedNames, p.qualify(tok.text))
+		default:
+			return p.errorf(tok, "expected message ID or name, found %s", describe(tok))
+		}
+
+		if !p.acceptPunct(",") {
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseMessage(tok token, direction MessageDirection, annotations []annotation) error {
+	name, err := p.expectIdent()
+
+	if err != nil {
+		return err
+	}
+
+	decl := &messageDecl{
+		tok:       tok,
+		direction: direction,
+		name:      name.text,
+	}
+
+	for _, a := range annotations {
+		switch a.name {
+		case "deprecated":
+			decl.deprecated = true
+		case "extensible":
+			decl.extensible = true
+		case "maxSize", "priority", "compress":
+			{
+				if direction == ObjectDef {
+					return p.errorf(a.tok, "@%s is not allowed on objects", a.name)
+				}
+
+				err = p.parseMessageOption(&decl.options, a)
+
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			return p.errorf(a.tok, "unknown message annotation @%s", a.name)
+		}
+	}
+
+	if tok := p.peek(); tok.kind == tokenIdent && tok.text == "extends" {
+		p.next()
+
+		// extends A is the same as an include A before the first field
+		include, err := p.parseInclude()
+
+		if err != nil {
+			return err
+		}
+
+		decl.fields = append(decl.fields, include)
+	}
+
+	_, err = p.expectPunct("{")
+
+	if err != nil {
+		return err
+	}
+
+	for !p.acceptPunct("}") {
+		if p.peek().kind == tokenEOF {
+			return p.errorf(p.peek(), "expected '}', found end of file")
+		}
+
+		err = p.parseMember(decl)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if direction == ObjectDef {
+		if _, exists := p.objects[decl.name]; exists {
+			return p.errorf(name, "duplicate object: %s", decl.name)
+		}
+
+		p.objects[decl.name] = decl
+	}
+
+	p.messages = append(p.messages, decl)
+
+	return nil
+}
+
+func (p *parser) parseMember(decl *messageDecl) error {
+	annotations, err := p.parseAnnotations()
+
+	if err != nil {
+		return err
+	}
+
+	tok := p.peek()
+
+	if tok.kind == tokenIdent && tok.text == "reserved" {
+		p.next()
+
+		if len(annotations) > 0 {
+			return p.errorf(annotations[0].tok, "annotations are not allowed on reserved")
+		}
+
+		for {
+			name, err := p.expectIdent()
+
+			if err != nil {
+				return err
+			}
+
+			decl.reserved = append(decl.reserved, name.text)
+
+			if !p.acceptPunct(",") {
+				return nil
+			}
+		}
+	}
+
+	if tok.kind == tokenIdent && tok.text == "include" {
+		p.next()
+
+		if len(annotations) > 0 {
+			return p.errorf(annotations[0].tok, "annotations are not allowed on include")
+		}
+
+		include, err := p.parseInclude()
+
+		if err != nil {
+			return err
+		}
+
+		decl.fields = append(decl.fields, include)
+
+		return nil
+	}
+
+	typ, err := p.parseType()
+
+	if err != nil {
+		return err
+	}
+
+	modifier, err := p.expectIdent()
+
+	if err != nil {
+		return err
+	}
+
+	field := fieldDecl{
+		tok: typ.tok,
+		typ: typ,
+	}
+
+	switch modifier.text {
+	case "REQUIRED":
+		field.optional = false
+	case "OPTIONAL":
+		field.optional = true
+	default:
+		return p.errorf(modifier, "expected REQUIRED or OPTIONAL, found %s", describe(modifier))
+	}
+
+	name, err := p.expectIdent()
+
+	if err != nil {
+		return err
+	}
+
+	field.name = name.text
+
+	if tok := p.peek(); tok.kind == tokenIdent && tok.text == "aka" {
+		p.next()
+
+		for {
+			alias, err := p.expectIdent()
+
+			if err != nil {
+				return err
+			}
+
+			field.aliases = append(field.aliases, alias)
+
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+
+	for _, a := range annotations {
+		switch a.name {
+		case "deprecated":
+			field.deprecated = true
+		default:
+			return p.errorf(a.tok, "unknown field annotation @%s", a.name)
+		}
+	}
+
+	names := append([]token{name}, field.aliases...)
+
+	for i, n := range names {
+		if decl.hasFieldName(n.text) || slices.ContainsFunc(names[:i], func(t token) bool { return t.text == n.text }) {
+			return p.errorf(n, "duplicate field: %s.%s", decl.name, n.text)
+		}
+	}
+
+	decl.fields = append(decl.fields, field)
+
+	return nil
+}
+
+func (p *parser) parseInclude() (fieldDecl, error) {
+	name, err := p.expectQualifiedIdent()
+
+	if err != nil {
+		return fieldDecl{}, err
+	}
+
+	return fieldDecl{
+		tok:     name,
+		typ:     typeExpr{tok: name, name: name.text},
+		name:    name.text,
+		include: true,
+	}, nil
+}
+
+// hasFieldName reports whether name is already used by a field or an alias,
+// fields of included objects are only known once they are resolved
+func (decl *messageDecl) hasFieldName(name string) bool {
+	for _, field := range decl.fields {
+		if field.include {
+			continue
+		}
+
+		if field.name == name {
+			return true
+		}
+
+		for _, alias := range field.aliases {
+			if alias.text == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *parser) parseType() (typeExpr, error) {
+	tok, err := p.expectQualifiedIdent()
+
+	if err != nil {
+		return typeExpr{}, err
+	}
+
+	typ := typeExpr{
+		tok:  tok,
+		name: tok.text,
+	}
+
+	switch tok.text {
+	case "binary":
+		{
+			if !p.acceptPunct("(") {
+				return typ, nil
+			}
+
+			size := p.peek()
+
+			if size.kind == tokenIdent {
+				size, err = p.expectQualifiedIdent()
+			} else {
+				p.next()
+			}
+
+			if err != nil {
+				return typ, err
+			}
+
+			if size.kind != tokenNumber && size.kind != tokenIdent {
+				return typ, p.errorf(size, "expected number or constant, found %s", describe(size))
+			}
+
+			typ.size = size
+			typ.hasSize = true
+
+			_, err = p.expectPunct(")")
+
+			if err != nil {
+				return typ, err
+			}
+		}
+	case "array":
+		{
+			_, err := p.expectPunct("(")
+
+			if err != nil {
+				return typ, err
+			}
+
+			elem, err := p.parseType()
+
+			if err != nil {
+				return typ, err
+			}
+
+			typ.arg = &elem
+
+			_, err = p.expectPunct(")")
+
+			if err != nil {
+				return typ, err
+			}
+		}
+	}
+
+	return typ, nil
+}
+
+func (p *parser) resolve() error {
+	for _, decl := range p.aliases {
+		if _, exists := p.objects[decl.name]; exists {
+			return p.errorf(decl.tok, "duplicate type: %s", decl.name)
+		}
+
+		field, err := p.resolveAlias(decl, map[string]bool{})
+
+		if err != nil {
+			return err
+		}
+
+		p.schema.Types = append(p.schema.Types, TypeAlias{
+			Name:  p.qualify(decl.name),
+			Field: field,
+		})
+	}
+
+	for _, decl := range p.messages {
+		message, err := p.resolveMessage(decl, map[string]bool{})
+
+		if err != nil {
+			return err
+		}
+
+		p.schema.Messages = append(p.schema.Messages, message)
+	}
+
+	for _, decl := range p.services {
+		service := Service{
+			Name:    p.qualify(decl.name),
+			Methods: make([]Method, 0, len(decl.methods)),
+		}
+
+		for _, mDecl := range decl.methods {
+			request, err := p.resolveMessageRef(mDecl.request)
+
+			if err != nil {
+				return err
+			}
+
+			response, err := p.resolveMessageRef(mDecl.response)
+
+			if err != nil {
+				return err
+			}
+
+			service.Methods = append(service.Methods, Method{
+				Name:         mDecl.name,
+				Request:      request,
+				Response:     response,
+				ClientStream: mDecl.clientStream,
+				ServerStream: mDecl.serverStream,
+			})
+		}
+
+		p.schema.Services = append(p.schema.Services, service)
+	}
+
+	return nil
+}
+
+// resolveMessageRef returns the qualified name of a message declared by the schema or a directly imported schema
+func (p *parser) resolveMessageRef(tok token) (string, error) {
+	local := p.localName(tok.text)
+
+	for _, decl := range p.messages {
+		if decl.name == local && decl.direction != ObjectDef {
+			return p.qualify(local), nil
+		}
+	}
+
+	for _, imported := range p.imports {
+		for _, message := range imported.Messages {
+			if message.Name == tok.text && message.Direction != ObjectDef {
+				return message.Name, nil
+			}
+		}
+	}
+
+	return "", p.errorf(tok, "unknown message: %s", tok.text)
+}
+
+func (p *parser) resolveMessage(decl *messageDecl, visiting map[string]bool) (SchemaMessage, error) {
+	message := SchemaMessage{
+		Direction:  decl.direction,
+		Name:       p.qualify(decl.name),
+		Fields:     make([]MessageField, 0, len(decl.fields)),
+		Deprecated: decl.deprecated,
+		Reserved:   decl.reserved,
+		Extensible: decl.extensible,
+		Options:    decl.options,
+	}
+
+	for _, fDecl := range decl.fields {
+		field, err := p.resolveType(fDecl.typ, visiting)
+
+		if err != nil {
+			return message, err
+		}
+
+		if fDecl.include {
+			if field.Type != TypeObject {
+				return message, p.errorf(fDecl.tok, "only objects can be included, found %s", fDecl.name)
+			}
+
+			field.Name = fDecl.name
+			field.Embedded = true
+
+			message.Fields = append(message.Fields, field)
+
+			continue
+		}
+
+		field.Name = fDecl.name
+		field.Optional = fDecl.optional
+		field.Deprecated = fDecl.deprecated
+
+		for _, alias := range fDecl.aliases {
+			field.Aliases = append(field.Aliases, alias.text)
+		}
+
+		message.Fields = append(message.Fields, field)
+	}
+
+	_, err := flattenFields(message)
+
+	if err != nil {
+		return message, p.errorf(decl.tok, "%s", err.Error())
+	}
+
+	return message, nil
+}
+
+func (p *parser) resolveObject(tok token, name string, visiting map[string]bool) (SchemaMessage, error) {
+	if message, exists := p.resolved[name]; exists {
+		return message, nil
+	}
+
+	decl, exists := p.objects[name]
+
+	if !exists {
+		return SchemaMessage{}, p.errorf(tok, "unknown type: %s", name)
+	}
+
+	if visiting[name] {
+		return SchemaMessage{}, p.errorf(tok, "object %s contains itself", name)
+	}
+
+	visiting[name] = true
+	message, err := p.resolveMessage(decl, visiting)
+	delete(visiting, name)
+
+	if err != nil {
+		return message, err
+	}
+
+	p.resolved[name] = message
+
+	return message, nil
+}
+
+func (p *parser) resolveAlias(decl *typeDecl, visiting map[string]bool) (MessageField, error) {
+	if visiting[decl.name] {
+		return MessageField{}, p.errorf(decl.tok, "type %s refers to itself", decl.name)
+	}
+
+	visiting[decl.name] = true
+	field, err := p.resolveType(decl.typ, visiting)
+	delete(visiting, decl.name)
+
+	return field, err
+}
+
+func (p *parser) resolveType(typ typeExpr, visiting map[string]bool) (MessageField, error) {
+	switch typ.name {
+	case "binary":
+		{
+			if typ.hasSize {
+				size, err := p.number(typ.size, 31)
+
+				if err != nil {
+					return MessageField{}, err
+				}
+
+				return MessageField{Type: TypeFixedBinary, Extra: int(size)}, nil
+			}
+
+			return MessageField{Type: TypeDynamicBinary}, nil
+		}
+	case "array":
+		{
+			elem, err := p.resolveType(*typ.arg, visiting)
+
+			if err != nil {
+				return elem, err
+			}
+
+			if elem.Type == TypeArray {
+				return elem, p.errorf(typ.arg.tok, "nested arrays are not supported")
+			}
+
+			if elem.Type == TypeObject {
+				return MessageField{Type: TypeArray, Extra: elem.Extra}, nil
+			}
+
+			return MessageField{Type: TypeArray, Extra: elem}, nil
+		}
+	}
+
+	if fType, ok := primitiveTypes[typ.name]; ok {
+		return MessageField{Type: fType}, nil
+	}
+
+	local := p.localName(typ.name)
+
+	if decl, ok := p.types[local]; ok {
+		field, err := p.resolveAlias(decl, visiting)
+
+		if err != nil {
+			return field, err
+		}
+
+		field.TypeAlias = p.qualify(decl.name)
+
+		return field, nil
+	}
+
+	if _, ok := p.objects[local]; ok {
+		object, err := p.resolveObject(typ.tok, local, visiting)
+
+		if err != nil {
+			return MessageField{}, err
+		}
+
+		return MessageField{Type: TypeObject, Extra: object}, nil
+	}
+
+	if field, ok := p.importedType(typ.name); ok {
+		return field, nil
+	}
+
+	return MessageField{}, p.errorf(typ.tok, "unknown type: %s", typ.name)
+}
+
+// importedType looks up a type alias or an object declared by a directly imported schema
+func (p *parser) importedType(name string) (MessageField, bool) {
+	for _, imported := range p.imports {
+		for _, alias := range imported.Types {
+			if alias.Name == name {
+				field := alias.Field
+				field.TypeAlias = alias.Name
+
+				return field, true
+			}
+		}
+
+		for _, message := range imported.Messages {
+			if message.Direction == ObjectDef && message.Name == name {
+				return MessageField{Type: TypeObject, Extra: message}, true
+			}
+		}
+	}
+
+	return MessageField{}, false
+}
+
+// lookupConst looks up a constant of the schema being parsed or of a directly imported schema
+func (p *parser) lookupConst(name string) (uint64, bool) {
+	if value, exists := p.consts[p.localName(name)]; exists {
+		return value, true
+	}
+
+	for _, imported := range p.imports {
+		for _, constant := range imported.Constants {
+			if constant.Name == name {
+				return constant.Value, true
+			}
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,332 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+// shared between requests
+object User {
+  uint64 REQUIRED id
+  binary(32) REQUIRED hash
+  @deprecated
+  binary OPTIONAL nickname
+  reserved email
+}
+
+reserved 40, OldMessage
+
+@deprecated
+inbound GetUsers {
+  array(uint64) REQUIRED ids
+}
+
+outbound Users {
+  array(User) REQUIRED users
+  User OPTIONAL owner
+}
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(s.Messages))
+	}
+
+	user := s.Messages[0]
+
+	if user.Signature() != "object User" || len(user.Fields) != 3 {
+		t.Errorf("unexpected object: %+v", user)
+	}
+
+	if user.Fields[1].Type != TypeFixedBinary || user.Fields[1].Extra != 32 {
+		t.Errorf("expected binary(32), got %s", user.Fields[1].TypeString())
+	}
+
+	if !user.Fields[2].Optional || !user.Fields[2].Deprecated {
+		t.Errorf("expected nickname to be optional and deprecated")
+	}
+
+	if len(user.Reserved) != 1 || user.Reserved[0] != "email" {
+		t.Errorf("unexpected reserved fields: %v", user.Reserved)
+	}
+
+	if len(s.ReservedIDs) != 1 || s.ReservedIDs[0] != 40 || len(s.ReservedNames) != 1 || s.ReservedNames[0] != "OldMessage" {
+		t.Errorf("unexpected reserved: %v %v", s.ReservedIDs, s.ReservedNames)
+	}
+
+	getUsers := s.Messages[1]
+
+	if !getUsers.Deprecated || getUsers.Fields[0].TypeString() != "array(uint64)" {
+		t.Errorf("unexpected message: %+v", getUsers)
+	}
+
+	users := s.Messages[2]
+
+	if users.Fields[0].TypeString() != "array(User)" || users.Fields[1].TypeString() != "User" {
+		t.Errorf("unexpected fields: %s, %s", users.Fields[0].TypeString(), users.Fields[1].TypeString())
+	}
+
+	if users.Fields[1].Type != TypeObject || len(users.Fields[1].Extra.(SchemaMessage).Fields) != 3 {
+		t.Errorf("expected object field to contain User")
+	}
+}
+
+func TestParseInternal(t *testing.T) {
+	_, err := ParseFile("../internal.schema")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"inbound A {\n  int32 REQUIRED x\n  Unknown REQUIRED y\n}":                        "3:3: unknown type: Unknown",
+		"inbound A {\n  int32 MAYBE x\n}":                                                 "2:9: expected REQUIRED or OPTIONAL",
+		"object A {\n  A REQUIRED self\n}":                                                "2:3: object A contains itself",
+		"inbound A {\n  int32 REQUIRED x\n  int16 OPTIONAL x\n}":                          "3:18: duplicate field: A.x",
+		"inbound A {\n  int32 REQUIRED x\n  int16 OPTIONAL y aka x\n}":                    "3:24: duplicate field: A.x",
+		"object H {\n  int32 REQUIRED x\n}\ninbound A extends H {\n  int16 OPTIONAL x\n}": "4:1: duplicate field: A.x",
+		"inbound A {\n  include int32\n}":                                                 "2:11: only objects can be included",
+		"type A = B\ntype B = A":                                                          "1:6: type A refers to itself",
+		"inbound A {\n  binary(Size) REQUIRED x\n}":                                       "2:10: unknown constant: Size",
+		"type int32 = int64":                                                              "1:6: duplicate type: int32",
+		"service S {\n  rpc Get(Missing) returns (Missing)\n}":                            "2:11: unknown message: Missing",
+		"@fast inbound A {}":                                                              "1:1: unknown message annotation @fast",
+		"inbound A {":                                                                     "1:12: expected '}'",
+		"@maxSize(4XiB) inbound A {}":                                                     "1:10: invalid number \"4XiB\"",
+		"@priority(urgent) inbound A {}":                                                  "1:11: expected low, normal or high",
+		"@compress object A {}":                                                           "1:1: @compress is not allowed on objects",
+	}
+
+	for src, expected := range cases {
+		_, err := Parse([]byte(src))
+
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error containing %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestParseMessageOptions(t *testing.T) {
+	s, err := Parse([]byte(`
+const Limit = 256
+
+@maxSize(4MiB) @priority(high) @compress
+inbound Upload {
+  long_binary REQUIRED data
+}
+
+@maxSize(Limit) @priority(low)
+outbound Progress {
+  uint32 REQUIRED done
+}
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []MessageOptions{
+		{MaxSize: 4 << 20, Priority: PriorityHigh, Compress: true},
+		{MaxSize: 256, Priority: PriorityLow},
+	}
+
+	for i, message := range s.Messages {
+		if message.Options != expected[i] {
+			t.Errorf("unexpected options of %s: %+v", message.Name, message.Options)
+		}
+	}
+}
+
+func TestParseDefinitions(t *testing.T) {
+	s, err := Parse([]byte(`
+inbound SetName {
+  Hash REQUIRED user
+  binary(MaxNameLen) REQUIRED name
+  array(Hash) OPTIONAL friends
+}
+
+const MaxNameLen = 64
+type Hash = binary(HashLen)
+const HashLen = 32
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := s.Messages[0].Fields
+
+	if fields[0].TypeString() != "binary(32)" || fields[0].TypeAlias != "Hash" {
+		t.Errorf("expected user to be a Hash, got %s (%q)", fields[0].TypeString(), fields[0].TypeAlias)
+	}
+
+	if fields[1].TypeString() != "binary(64)" || fields[2].TypeString() != "array(binary(32))" {
+		t.Errorf("unexpected types: %s, %s", fields[1].TypeString(), fields[2].TypeString())
+	}
+
+	if len(s.Types) != 1 || s.Types[0].Name != "Hash" || s.Types[0].Field.Extra != 32 {
+		t.Errorf("unexpected types: %+v", s.Types)
+	}
+
+	if len(s.Constants) != 2 || s.Constants[0] != (Constant{Name: "MaxNameLen", Value: 64}) {
+		t.Errorf("unexpected constants: %+v", s.Constants)
+	}
+}
+
+func writeSchemaFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, src := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+var multiFileSchema = map[string]string{
+	"common.schema": `
+package common;
+
+const MaxNameLen = 64
+type Hash = binary(32)
+
+object Header {
+  uint32 REQUIRED requestId
+}
+`,
+	"billing.schema": `
+package billing;
+import "common.schema";
+
+inbound Charge {
+  include common.Header
+  common.Hash REQUIRED account
+  binary(common.MaxNameLen) REQUIRED name
+}
+
+outbound Receipt {
+  common.Header REQUIRED header
+}
+`,
+	"accounts.schema": `
+package accounts
+import "common.schema"
+
+inbound GetAccount {
+  common.Hash REQUIRED account
+}
+`,
+}
+
+func TestParseImports(t *testing.T) {
+	dir := writeSchemaFiles(t, multiFileSchema)
+
+	s, err := ParseFile(filepath.Join(dir, "billing.schema"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Package != "billing" || len(s.Imports) != 1 || s.Imports[0].Package != "common" {
+		t.Fatalf("unexpected packages: %q imports %d", s.Package, len(s.Imports))
+	}
+
+	charge := s.Messages[0]
+
+	if charge.Signature() != "inbound billing.Charge" {
+		t.Errorf("unexpected signature: %s", charge.Signature())
+	}
+
+	if charge.Fields[1].TypeAlias != "common.Hash" || charge.Fields[2].TypeString() != "binary(64)" {
+		t.Errorf("unexpected fields: %+v", charge.Fields)
+	}
+
+	if header := s.Messages[1].Fields[0]; header.TypeString() != "common.Header" {
+		t.Errorf("expected a common.Header, got %s", header.TypeString())
+	}
+}
+
+func TestParseImportCycle(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"a.schema": "import \"b.schema\"",
+		"b.schema": "import \"a.schema\"",
+	})
+
+	_, err := ParseFile(filepath.Join(dir, "a.schema"))
+
+	if err == nil || !strings.Contains(err.Error(), "import cycle") {
+		t.Errorf("expected an import cycle, got %v", err)
+	}
+}
+
+func TestParseServices(t *testing.T) {
+	s, err := Parse([]byte(`
+package accounts
+
+inbound GetUser {
+  uint64 REQUIRED id
+}
+
+outbound User {
+  binary REQUIRED name
+}
+
+service Accounts {
+  rpc GetUser(GetUser) returns (User);
+  rpc Whoami(accounts.GetUser) returns (User)
+  rpc Watch(stream GetUser) returns (stream User)
+}
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Services) != 1 || len(s.Services[0].Methods) != 3 {
+		t.Fatalf("unexpected services: %+v", s.Services)
+	}
+
+	service := s.Services[0]
+	method := service.Methods[0]
+
+	if service.MethodName(method) != "accounts.Accounts.GetUser" || method.Request != "accounts.GetUser" || method.Response != "accounts.User" {
+		t.Errorf("unexpected method: %s %+v", service.MethodName(method), method)
+	}
+
+	r := newTestRegistry(t)
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registered := r.Methods["accounts.Accounts.GetUser"]
+
+	if registered.Request != r.UserSignatureMap["inbound accounts.GetUser"] || registered.Response != r.UserSignatureMap["outbound accounts.User"] {
+		t.Errorf("unexpected registered method: %+v", registered)
+	}
+
+	if registered.Streaming() {
+		t.Errorf("expected GetUser not to stream: %+v", registered)
+	}
+
+	if watch := r.Methods["accounts.Accounts.Watch"]; !watch.ClientStream || !watch.ServerStream {
+		t.Errorf("expected Watch to stream both ways: %+v", watch)
+	}
+}
@@ -0,0 +1,270 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Struct fields are described with `ipc:"name,option,..."` tags, the name is the field name in the schema.
+// Options are optional, which marks the field OPTIONAL, and type=T, which sets the wire type of the field
+// to T as written in the DSL (e.g. type=uint16 or type=long_binary) instead of the one derived from the Go type.
+// Untagged fields are left out, except for embedded structs, whose fields are included like with include.
+
+var ErrInvalidStruct = errors.New("struct can't be described by a schema")
+
+// StructTag returns the schema field name and the options of an ipc struct tag
+func StructTag(tag string) (string, []string) {
+	name, opts, _ := strings.Cut(tag, ",")
+
+	if opts == "" {
+		return name, nil
+	}
+
+	return name, strings.Split(opts, ",")
+}
+
+// FromStruct returns a message with the fields of the struct T
+func FromStruct[T any](direction MessageDirection, name string) (SchemaMessage, error) {
+	t := reflect.TypeFor[T]()
+
+	if t.Kind() != reflect.Struct {
+		return SchemaMessage{}, fmt.Errorf("%w: %s is not a struct", ErrInvalidStruct, t)
+	}
+
+	message, err := structMessage(t, make(map[reflect.Type]bool))
+
+	if err != nil {
+		return message, err
+	}
+
+	message.Direction = direction
+	message.Name = name
+
+	_, err = flattenFields(message)
+
+	if err != nil {
+		return message, fmt.Errorf("%w: %v", ErrInvalidStruct, err)
+	}
+
+	return message, nil
+}
+
+func structMessage(t reflect.Type, visiting map[reflect.Type]bool) (SchemaMessage, error) {
+	message := SchemaMessage{
+		Direction: ObjectDef,
+		Name:      t.Name(),
+		Fields:    make([]MessageField, 0, t.NumField()),
+	}
+
+	if visiting[t] {
+		return message, fmt.Errorf("%w: %s contains itself", ErrInvalidStruct, t)
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sField := t.Field(i)
+		name, opts := StructTag(sField.Tag.Get("ipc"))
+
+		if name == "" {
+			if !sField.Anonymous || sField.Type.Kind() != reflect.Struct {
+				continue
+			}
+
+			embedded, err := structMessage(sField.Type, visiting)
+
+			if err != nil {
+				return message, err
+			}
+
+			message.Fields = append(message.Fields, MessageField{
+				Name:     sField.Name,
+				Type:     TypeObject,
+				Extra:    embedded,
+				Embedded: true,
+			})
+
+			continue
+		}
+
+		field, err := structField(sField, opts, visiting)
+
+		if err != nil {
+			return message, err
+		}
+
+		field.Name = name
+		message.Fields = append(message.Fields, field)
+	}
+
+	return message, nil
+}
+
+func structField(sField reflect.StructField, opts []string, visiting map[reflect.Type]bool) (MessageField, error) {
+	var field MessageField
+	var wireType string
+
+	for _, opt := range opts {
+		switch {
+		case opt == "optional":
+			field.Optional = true
+		case strings.HasPrefix(opt, "type="):
+			wireType = strings.TrimPrefix(opt, "type=")
+		default:
+			return field, fmt.Errorf("%w: unknown option of %s: %s", ErrInvalidStruct, sField.Name, opt)
+		}
+	}
+
+	if wireType == "" {
+		typed, err := goFieldType(sField.Type, visiting)
+
+		if err != nil {
+			return field, fmt.Errorf("%w (field %s)", err, sField.Name)
+		}
+
+		typed.Optional = field.Optional
+
+		return typed, nil
+	}
+
+	typed, err := parseWireType(wireType)
+
+	if err != nil {
+		return field, fmt.Errorf("%w (field %s)", err, sField.Name)
+	}
+
+	if !fitsGoType(typed, sField.Type) {
+		return field, fmt.Errorf("%w: %s can't be encoded as %s (field %s)", ErrInvalidStruct, sField.Type, wireType, sField.Name)
+	}
+
+	typed.Optional = field.Optional
+
+	return typed, nil
+}
+
+// goFieldType derives the wire type of a Go type, integers without a wire type of the same size need type=
+func goFieldType(t reflect.Type, visiting map[reflect.Type]bool) (MessageField, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return MessageField{Type: TypeDynamicBinary}, nil
+	case reflect.Uint64, reflect.Uint:
+		return MessageField{Type: TypeUInt64}, nil
+	case reflect.Int64, reflect.Int:
+		return MessageField{Type: TypeInt64}, nil
+	case reflect.Uint32:
+		return MessageField{Type: TypeUInt32}, nil
+	case reflect.Int32:
+		return MessageField{Type: TypeInt32}, nil
+	case reflect.Uint16:
+		return MessageField{Type: TypeUInt16}, nil
+	case reflect.Int16:
+		return MessageField{Type: TypeInt16}, nil
+	case reflect.Array:
+		{
+			if t.Elem().Kind() == reflect.Uint8 {
+				return MessageField{Type: TypeFixedBinary, Extra: t.Len()}, nil
+			}
+		}
+	case reflect.Struct:
+		{
+			object, err := structMessage(t, visiting)
+
+			if err != nil {
+				return MessageField{}, err
+			}
+
+			return MessageField{Type: TypeObject, Extra: object}, nil
+		}
+	case reflect.Slice:
+		{
+			if t.Elem().Kind() == reflect.Uint8 {
+				return MessageField{Type: TypeDynamicBinary}, nil
+			}
+
+			elem, err := goFieldType(t.Elem(), visiting)
+
+			if err != nil {
+				return elem, err
+			}
+
+			if elem.Type == TypeArray {
+				return elem, fmt.Errorf("%w: nested arrays are not supported: %s", ErrInvalidStruct, t)
+			}
+
+			if elem.Type == TypeObject {
+				return MessageField{Type: TypeArray, Extra: elem.Extra}, nil
+			}
+
+			return MessageField{Type: TypeArray, Extra: elem}, nil
+		}
+	}
+
+	return MessageField{}, fmt.Errorf("%w: no wire type for %s", ErrInvalidStruct, t)
+}
+
+// parseWireType parses a primitive type, binary, binary(N) or an array of those
+func parseWireType(s string) (MessageField, error) {
+	if fType, ok := primitiveTypes[s]; ok {
+		return MessageField{Type: fType}, nil
+	}
+
+	if s == "binary" {
+		return MessageField{Type: TypeDynamicBinary}, nil
+	}
+
+	if size, ok := strings.CutPrefix(s, "binary("); ok && strings.HasSuffix(size, ")") {
+		n, err := strconv.ParseUint(strings.TrimSuffix(size, ")"), 10, 31)
+
+		if err != nil {
+			return MessageField{}, fmt.Errorf("%w: invalid binary size: %s", ErrInvalidStruct, s)
+		}
+
+		return MessageField{Type: TypeFixedBinary, Extra: int(n)}, nil
+	}
+
+	if elemType, ok := strings.CutPrefix(s, "array("); ok && strings.HasSuffix(elemType, ")") {
+		elem, err := parseWireType(strings.TrimSuffix(elemType, ")"))
+
+		if err != nil {
+			return elem, err
+		}
+
+		if elem.Type == TypeArray {
+			return elem, fmt.Errorf("%w: nested arrays are not supported: %s", ErrInvalidStruct, s)
+		}
+
+		return MessageField{Type: TypeArray, Extra: elem}, nil
+	}
+
+	return MessageField{}, fmt.Errorf("%w: unknown wire type: %s", ErrInvalidStruct, s)
+}
+
+// fitsGoType reports whether values of t can be encoded and decoded as field
+func fitsGoType(field MessageField, t reflect.Type) bool {
+	kind := t.Kind()
+
+	switch field.Type {
+	case TypeFixedBinary:
+		{
+			if kind == reflect.Array {
+				return t.Elem().Kind() == reflect.Uint8 && t.Len() == field.Extra.(int)
+			}
+
+			return kind == reflect.String || (kind == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+		}
+	case TypeDynamicBinary, TypeLongBinary:
+		return kind == reflect.String || ((kind == reflect.Slice || kind == reflect.Array) && t.Elem().Kind() == reflect.Uint8)
+	case TypeUInt64, TypeUInt32, TypeUInt16:
+		return kind >= reflect.Uint && kind <= reflect.Uint64
+	case TypeInt64, TypeInt32, TypeInt16:
+		return kind >= reflect.Int && kind <= reflect.Int64
+	case TypeArray:
+		return kind == reflect.Slice && fitsGoType(field.Extra.(MessageField), t.Elem())
+	default:
+		return false
+	}
+}
@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type reflectAudit struct {
+	Trace [16]byte `ipc:"trace"`
+}
+
+type reflectItem struct {
+	Name  string `ipc:"name"`
+	Price uint32 `ipc:"price"`
+}
+
+type reflectOrder struct {
+	reflectAudit
+	ID      uint64        `ipc:"id"`
+	Items   []reflectItem `ipc:"items"`
+	Coupons []int16       `ipc:"coupons,optional"`
+	Note    []byte        `ipc:"note,optional,type=long_binary"`
+	Flags   uint8         `ipc:"flags,type=uint16"`
+	cache   string
+}
+
+func TestFromStruct(t *testing.T) {
+	message, err := FromStruct[reflectOrder](InboundMessage, "Order")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := SchemaMessage{
+		Direction: InboundMessage,
+		Name:      "Order",
+		Fields: []MessageField{
+			{
+				Name:     "reflectAudit",
+				Type:     TypeObject,
+				Embedded: true,
+				Extra: SchemaMessage{
+					Direction: ObjectDef,
+					Name:      "reflectAudit",
+					Fields:    []MessageField{{Name: "trace", Type: TypeFixedBinary, Extra: 16}},
+				},
+			},
+			{Name: "id", Type: TypeUInt64},
+			{
+				Name: "items",
+				Type: TypeArray,
+				Extra: SchemaMessage{
+					Direction: ObjectDef,
+					Name:      "reflectItem",
+					Fields: []MessageField{
+						{Name: "name", Type: TypeDynamicBinary},
+						{Name: "price", Type: TypeUInt32},
+					},
+				},
+			},
+			{Name: "coupons", Type: TypeArray, Extra: MessageField{Type: TypeInt16}, Optional: true},
+			{Name: "note", Type: TypeLongBinary, Optional: true},
+			{Name: "flags", Type: TypeUInt16},
+		},
+	}
+
+	if !reflect.DeepEqual(message, expected) {
+		t.Fatalf("unexpected message:\n%+v\nexpected:\n%+v", message, expected)
+	}
+
+	err = newTestRegistry(t).RegisterSchema(Schema{Messages: []SchemaMessage{message}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromStructErrors(t *testing.T) {
+	type recursive struct {
+		Children []recursive `ipc:"children"`
+	}
+
+	type narrow struct {
+		Flags uint8 `ipc:"flags"`
+	}
+
+	type wrongType struct {
+		Name string `ipc:"name,type=uint32"`
+	}
+
+	type unknownOption struct {
+		Name string `ipc:"name,required"`
+	}
+
+	type duplicate struct {
+		reflectAudit
+		Trace []byte `ipc:"trace"`
+	}
+
+	cases := map[string]error{}
+
+	_, cases["recursive"] = FromStruct[recursive](InboundMessage, "A")
+	_, cases["narrow"] = FromStruct[narrow](InboundMessage, "A")
+	_, cases["wrongType"] = FromStruct[wrongType](InboundMessage, "A")
+	_, cases["unknownOption"] = FromStruct[unknownOption](InboundMessage, "A")
+	_, cases["duplicate"] = FromStruct[duplicate](InboundMessage, "A")
+	_, cases["notStruct"] = FromStruct[string](InboundMessage, "A")
+
+	for name, err := range cases {
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Errorf("expected %s to be rejected, got %v", name, err)
+		}
+	}
+}
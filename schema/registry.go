@@ -3,6 +3,8 @@ package schema
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 )
 
 type Conn interface {}
@@ -15,10 +17,11 @@ type Reader interface {
 type HandlerFunc func(r Reader, c Conn) error
 
 type MessageDescriptor struct {
-	ID            uint32
-	Message       SchemaMessage
-	OptionalCount uint32
-	Internal      bool
+	ID            uint32 `json:"id"`
+	Message       SchemaMessage `json:"message"`
+	OptionalCount uint32 `json:"optionalCount"`
+	Internal      bool `json:"internal,omitempty"`
+	Layout        uint64 `json:"-"` // layout hash of Message, 0 if unknown
 	Handler       HandlerFunc `json:"-"`
 }
 
@@ -34,9 +37,16 @@ func (m MessageDescriptor) OptFlagLength() uint32 {
 	return count
 }
 
+// Extensible messages are prefixed by the length of the message (uint32) and the length of the optional flags (uint8)
+const ExtensibleHeaderSize = 5
+
 func (m MessageDescriptor) GetFixedSize() uint32 {
 	var accum uint32 = m.OptFlagLength()
 
+	if m.Message.Extensible {
+		accum += ExtensibleHeaderSize
+	}
+
 	for _, field := range m.Message.Fields {
 		accum += field.Type.GetFixedSize(field.Extra)
 	}
@@ -51,6 +61,13 @@ type MessageDescriptorRegistry struct {
 	Descriptors          map[uint32]MessageDescriptor
 	UserSignatureMap     map[string]uint32 // Maps User-defined Message Signature to Message Descriptor ID
 	InternalSignatureMap map[string]uint32 // Maps Internal Message Signature to Message Descriptor ID
+	Types                []TypeAlias       // type aliases of the user schema, with objects resolved to descriptors
+	Constants            []Constant
+	Methods              map[string]RPCMethod // Maps method name (Service.Method) to its request and response
+	fingerprint          [32]byte
+	reservedIDs          []uint32
+	reservedNames        []string
+	registeredPaths      map[string]bool
 }
 
 var ErrAlreadyRegistered = errors.New("schema is already registered")
@@ -61,11 +78,61 @@ func (r *MessageDescriptorRegistry) ensureDescriptors() {
 		r.Descriptors = make(map[uint32]MessageDescriptor)
 		r.UserSignatureMap = make(map[string]uint32)
 		r.InternalSignatureMap = make(map[string]uint32)
+		r.Methods = make(map[string]RPCMethod)
+	}
+}
+
+// RPCMethod is a service method resolved to the descriptor IDs of its request and response
+type RPCMethod struct {
+	Name         string `json:"name"`
+	Request      uint32 `json:"request"`
+	Response     uint32 `json:"response"`
+	ClientStream bool `json:"clientStream,omitempty"`
+	ServerStream bool `json:"serverStream,omitempty"`
+}
+
+// Streaming reports whether the method is called as a stream rather than with a single request and response
+func (m RPCMethod) Streaming() bool {
+	return m.ClientStream || m.ServerStream
+}
+
+// lookupMessage finds a user-defined message by name that can be sent in the given direction
+func (r *MessageDescriptorRegistry) lookupMessage(name string, direction MessageDirection) (uint32, bool) {
+	id, exists := r.UserSignatureMap[Signature(direction, name)]
+
+	if exists {
+		return id, true
 	}
+
+	id, exists = r.UserSignatureMap[Signature(DuplexMessage, name)]
+
+	return id, exists
+}
+
+func (r *MessageDescriptorRegistry) registerMethod(method RPCMethod) error {
+	if _, exists := r.Methods[method.Name]; exists {
+		return fmt.Errorf("duplicate method: %s", method.Name)
+	}
+
+	request, exists := r.Descriptors[method.Request]
+
+	if !exists || (request.Message.Direction != InboundMessage && request.Message.Direction != DuplexMessage) {
+		return fmt.Errorf("request of %s must be an inbound or duplex message", method.Name)
+	}
+
+	response, exists := r.Descriptors[method.Response]
+
+	if !exists || (response.Message.Direction != OutboundMessage && response.Message.Direction != DuplexMessage) {
+		return fmt.Errorf("response of %s must be an outbound or duplex message", method.Name)
+	}
+
+	r.Methods[method.Name] = method
+
+	return nil
 }
 
 func registerSignature(signatureMap map[string]uint32, direction MessageDirection, name string, id uint32) error {
-	signature := fmt.Sprintf("%s %s", direction.ToString(), name)
+	signature := Signature(direction, name)
 
 	_, exists := signatureMap[signature]
 
@@ -102,9 +169,62 @@ func handleSignatures(signatureMap map[string]uint32, message SchemaMessage, id
 	return nil
 }
 
-func resolveMessageFields(message *SchemaMessage) {
+// flattenFields splices the fields of embedded objects into the message in their place
+func flattenFields(message SchemaMessage) ([]MessageField, error) {
+	if !slices.ContainsFunc(message.Fields, func(f MessageField) bool { return f.Embedded }) {
+		return message.Fields, nil
+	}
+
+	fields := make([]MessageField, 0, len(message.Fields))
+
+	for _, field := range message.Fields {
+		if !field.Embedded {
+			fields = append(fields, field)
+			continue
+		}
+
+		embedded, ok := nestedMessage(field.Extra)
+
+		if !ok || field.Type != TypeObject {
+			return nil, fmt.Errorf("embedded field must be an object: %s.%s", message.Name, field.Name)
+		}
+
+		embeddedFields, err := flattenFields(embedded)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, embeddedFields...)
+	}
+
+	for i, field := range fields {
+		for _, other := range fields[:i] {
+			if other.HasName(field.Name) || slices.ContainsFunc(field.Aliases, other.HasName) {
+				return nil, fmt.Errorf("duplicate field: %s.%s", message.Name, field.Name)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+func resolveMessageFields(message *SchemaMessage) error {
+	fields, err := flattenFields(*message)
+
+	if err != nil {
+		return err
+	}
+
+	// copy the fields, the caller's schema must stay untouched so that it can be registered again
+	message.Fields = slices.Clone(fields)
+
 	for idx, field := range message.Fields {
 		if field.Type == TypeObject || field.Type == TypeArray {
+			if _, resolved := field.Extra.(MessageDescriptor); resolved {
+				continue
+			}
+
 			subMessage, ok := field.Extra.(SchemaMessage)
 
 			if !ok {
@@ -114,7 +234,11 @@ func resolveMessageFields(message *SchemaMessage) {
 				continue
 			}
 
-			resolveMessageFields(&subMessage)
+			err = resolveMessageFields(&subMessage)
+
+			if err != nil {
+				return err
+			}
 
 			field.Extra = MessageDescriptor{
 				ID:            0,
@@ -127,34 +251,253 @@ func resolveMessageFields(message *SchemaMessage) {
 			message.Fields[idx] = field
 		}
 	}
+
+	return nil
 }
 
-func (r *MessageDescriptorRegistry) RegisterSchema(schema Schema) error {
-	if r.RegisteredUser {
-		return ErrAlreadyRegistered
+func checkReservedFields(message SchemaMessage) error {
+	for _, field := range message.Fields {
+		if slices.Contains(message.Reserved, field.Name) {
+			return fmt.Errorf("reserved field name: %s.%s", message.Name, field.Name)
+		}
+
+		for _, alias := range field.Aliases {
+			if slices.Contains(message.Reserved, alias) {
+				return fmt.Errorf("reserved field name: %s.%s (alias of %s)", message.Name, alias, field.Name)
+			}
+		}
+
+		if field.Type == TypeObject || field.Type == TypeArray {
+			subMessage, ok := nestedMessage(field.Extra)
+
+			if !ok {
+				continue
+			}
+
+			err := checkReservedFields(subMessage)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *MessageDescriptorRegistry) nextID() uint32 {
+	for slices.Contains(r.reservedIDs, r.idCounter) {
+		r.idCounter++
 	}
 
+	id := r.idCounter
+	r.idCounter++
+
+	return id
+}
+
+// reserve records the reserved IDs and names of a schema file, they apply to the files registered after it as
+// well and must not be used by a file registered before it
+func (r *MessageDescriptorRegistry) reserve(schema Schema) error {
+	for _, id := range schema.ReservedIDs {
+		if descriptor, exists := r.Descriptors[id]; exists {
+			return fmt.Errorf("reserved message ID: %d is already used by %s", id, descriptor.Message.Signature())
+		}
+	}
+
+	for _, descriptor := range r.Descriptors {
+		if !descriptor.Internal && slices.Contains(schema.ReservedNames, descriptor.Message.Name) {
+			return fmt.Errorf("reserved message name: %s is already registered", descriptor.Message.Name)
+		}
+	}
+
+	r.reservedIDs = append(r.reservedIDs, schema.ReservedIDs...)
+	r.reservedNames = append(r.reservedNames, schema.ReservedNames...)
+
+	return nil
+}
+
+// RegisterSchema registers the messages of a schema after the ones registered so far, it can be called once per schema file.
+// Imported schemas are registered first, files that are already registered are skipped. If any file fails to register,
+// the registry is left as it was before the call.
+func (r *MessageDescriptorRegistry) RegisterSchema(schema Schema) error {
 	if !r.RegisteredInternal {
 		return ErrInternalNotRegistered
 	}
 
 	r.ensureDescriptors()
 
+	// the slices are only appended to, restoring their lengths is enough
+	saved := *r
+	saved.Descriptors = maps.Clone(r.Descriptors)
+	saved.UserSignatureMap = maps.Clone(r.UserSignatureMap)
+	saved.Methods = maps.Clone(r.Methods)
+	saved.registeredPaths = maps.Clone(r.registeredPaths)
+
+	err := r.registerSchema(schema)
+
+	if err != nil {
+		*r = saved
+	}
+
+	return err
+}
+
+func (r *MessageDescriptorRegistry) registerSchema(schema Schema) error {
+	for _, imported := range schema.Imports {
+		err := r.registerSchema(imported)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if schema.Path != "" {
+		if r.registeredPaths == nil {
+			r.registeredPaths = make(map[string]bool)
+		}
+
+		if r.registeredPaths[schema.Path] {
+			return nil
+		}
+
+		r.registeredPaths[schema.Path] = true
+	}
+
+	err := r.reserve(schema)
+
+	if err != nil {
+		return err
+	}
+
 	for _, message := range schema.Messages {
-		id := r.idCounter
-		r.idCounter++
+		if slices.Contains(r.reservedNames, message.Name) {
+			return fmt.Errorf("reserved message name: %s", message.Name)
+		}
 
-		resolveMessageFields(&message)
+		err := resolveMessageFields(&message)
+
+		if err != nil {
+			return err
+		}
+
+		err = checkReservedFields(message)
+
+		if err != nil {
+			return err
+		}
+
+		id := r.nextID()
 
 		r.Descriptors[id] = MessageDescriptor{
 			ID:            id,
 			Message:       message,
 			OptionalCount: message.CountOptional(),
 			Internal:      false,
+			Layout:        message.Layout(),
 			Handler:       nil,
 		}
 
-		err := handleSignatures(r.UserSignatureMap, message, id)
+		err = handleSignatures(r.UserSignatureMap, message, id)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, alias := range schema.Types {
+		message := SchemaMessage{
+			Name:   alias.Name,
+			Fields: []MessageField{alias.Field},
+		}
+
+		err := resolveMessageFields(&message)
+
+		if err != nil {
+			return err
+		}
+
+		alias.Field = message.Fields[0]
+		r.Types = append(r.Types, alias)
+	}
+
+	r.Constants = append(r.Constants, schema.Constants...)
+
+	for _, service := range schema.Services {
+		for _, method := range service.Methods {
+			name := service.MethodName(method)
+
+			request, exists := r.lookupMessage(method.Request, InboundMessage)
+
+			if !exists {
+				return fmt.Errorf("unknown request message of %s: %s", name, method.Request)
+			}
+
+			response, exists := r.lookupMessage(method.Response, OutboundMessage)
+
+			if !exists {
+				return fmt.Errorf("unknown response message of %s: %s", name, method.Response)
+			}
+
+			err := r.registerMethod(RPCMethod{
+				Name:         name,
+				Request:      request,
+				Response:     response,
+				ClientStream: method.ClientStream,
+				ServerStream: method.ServerStream,
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	r.RegisteredUser = true
+	r.fingerprint = r.computeFingerprint()
+
+	return nil
+}
+
+// RegisterPeerSchema registers the user-defined descriptors and methods a peer advertised, keeping the peer's IDs
+func (r *MessageDescriptorRegistry) RegisterPeerSchema(descriptors []MessageDescriptor, methods []RPCMethod) error {
+	if r.RegisteredUser {
+		return ErrAlreadyRegistered
+	}
+
+	if !r.RegisteredInternal {
+		return ErrInternalNotRegistered
+	}
+
+	r.ensureDescriptors()
+
+	for _, descriptor := range descriptors {
+		if descriptor.Internal {
+			// internal messages are defined by the protocol version, not the peer
+			continue
+		}
+
+		if _, exists := r.Descriptors[descriptor.ID]; exists {
+			return fmt.Errorf("duplicate descriptor ID: %d", descriptor.ID)
+		}
+
+		descriptor.Handler = nil
+		descriptor.Layout = descriptor.Message.Layout()
+		r.Descriptors[descriptor.ID] = descriptor
+
+		err := handleSignatures(r.UserSignatureMap, descriptor.Message, descriptor.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if descriptor.ID >= r.idCounter {
+			r.idCounter = descriptor.ID + 1
+		}
+	}
+
+	for _, method := range methods {
+		err := r.registerMethod(method)
 
 		if err != nil {
 			return err
@@ -162,6 +505,7 @@ func (r *MessageDescriptorRegistry) RegisterSchema(schema Schema) error {
 	}
 
 	r.RegisteredUser = true
+	r.fingerprint = r.computeFingerprint()
 
 	return nil
 }
@@ -177,17 +521,22 @@ func (r *MessageDescriptorRegistry) RegisterInternal() error {
 		id := r.idCounter
 		r.idCounter++
 
-		resolveMessageFields(&message)
+		err := resolveMessageFields(&message)
+
+		if err != nil {
+			return err
+		}
 
 		r.Descriptors[id] = MessageDescriptor{
 			ID:            id,
 			Message:       message,
 			OptionalCount: message.CountOptional(),
 			Internal:      true,
+			Layout:        message.Layout(),
 			Handler:       nil,
 		}
 
-		err := handleSignatures(r.InternalSignatureMap, message, id)
+		err = handleSignatures(r.InternalSignatureMap, message, id)
 
 		if err != nil {
 			return err
@@ -195,6 +544,7 @@ func (r *MessageDescriptorRegistry) RegisterInternal() error {
 	}
 
 	r.RegisteredInternal = true
+	r.fingerprint = r.computeFingerprint()
 
 	return nil
 }
@@ -0,0 +1,276 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestRegistry(t *testing.T) *MessageDescriptorRegistry {
+	r := &MessageDescriptorRegistry{}
+
+	err := r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestReservedIDsAreSkipped(t *testing.T) {
+	r := newTestRegistry(t)
+
+	// user-defined messages are numbered after the internal ones
+	first := uint32(len(InternalSchema.Messages))
+
+	err := r.RegisterSchema(Schema{
+		Messages: []SchemaMessage{
+			{Direction: InboundMessage, Name: "A"},
+			{Direction: InboundMessage, Name: "B"},
+		},
+		ReservedIDs: []uint32{first, first + 2},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id := r.UserSignatureMap["inbound A"]; id != first+1 {
+		t.Errorf("expected inbound A to have ID %d, got %d", first+1, id)
+	}
+
+	if id := r.UserSignatureMap["inbound B"]; id != first+3 {
+		t.Errorf("expected inbound B to have ID %d, got %d", first+3, id)
+	}
+}
+
+func TestReservedNamesAreRejected(t *testing.T) {
+	err := newTestRegistry(t).RegisterSchema(Schema{
+		Messages:      []SchemaMessage{{Direction: OutboundMessage, Name: "Legacy"}},
+		ReservedNames: []string{"Legacy"},
+	})
+
+	if err == nil {
+		t.Error("expected reserved message name to be rejected")
+	}
+
+	err = newTestRegistry(t).RegisterSchema(Schema{
+		Messages: []SchemaMessage{
+			{
+				Direction: InboundMessage,
+				Name:      "User",
+				Fields: []MessageField{
+					{
+						Name: "profile",
+						Type: TypeObject,
+						Extra: SchemaMessage{
+							Fields:   []MessageField{{Name: "nick", Type: TypeDynamicBinary}},
+							Reserved: []string{"nick"},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err == nil {
+		t.Error("expected reserved nested field name to be rejected")
+	}
+}
+
+func TestReservedAcrossFiles(t *testing.T) {
+	first := uint32(len(InternalSchema.Messages))
+
+	tests := []struct {
+		name    string
+		files   []Schema
+		message string
+	}{
+		{
+			name: "name reserved by an earlier file",
+			files: []Schema{
+				{Path: "a.schema", ReservedNames: []string{"Legacy"}},
+				{Path: "b.schema", Messages: []SchemaMessage{{Direction: InboundMessage, Name: "Legacy"}}},
+			},
+			message: "reserved message name: Legacy",
+		},
+		{
+			name: "name registered by an earlier file",
+			files: []Schema{
+				{Path: "a.schema", Messages: []SchemaMessage{{Direction: InboundMessage, Name: "Legacy"}}},
+				{Path: "b.schema", ReservedNames: []string{"Legacy"}},
+			},
+			message: "reserved message name: Legacy is already registered",
+		},
+		{
+			name: "ID used by an earlier file",
+			files: []Schema{
+				{Path: "a.schema", Messages: []SchemaMessage{{Direction: InboundMessage, Name: "A"}}},
+				{Path: "b.schema", ReservedIDs: []uint32{first}},
+			},
+			message: fmt.Sprintf("reserved message ID: %d is already used by inbound A", first),
+		},
+		{
+			name: "ID used by an internal message",
+			files: []Schema{
+				{Path: "a.schema", ReservedIDs: []uint32{0}},
+			},
+			message: "reserved message ID: 0 is already used by inbound Hello",
+		},
+	}
+
+	for _, test := range tests {
+		r := newTestRegistry(t)
+
+		var err error
+
+		for _, file := range test.files {
+			err = r.RegisterSchema(file)
+
+			if err != nil {
+				break
+			}
+		}
+
+		if err == nil || err.Error() != test.message {
+			t.Errorf("%s: expected %q, got %v", test.name, test.message, err)
+		}
+	}
+
+	// IDs reserved by an earlier file are skipped in later ones
+	r := newTestRegistry(t)
+
+	for _, file := range []Schema{{Path: "a.schema", ReservedIDs: []uint32{first}}, {Path: "b.schema", Messages: []SchemaMessage{{Direction: InboundMessage, Name: "A"}}}} {
+		err := r.RegisterSchema(file)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if id := r.UserSignatureMap["inbound A"]; id != first+1 {
+		t.Errorf("expected inbound A to have ID %d, got %d", first+1, id)
+	}
+}
+
+func TestRegisterSchemaIsAtomic(t *testing.T) {
+	first := uint32(len(InternalSchema.Messages))
+
+	a := Schema{Path: "a.schema", Messages: []SchemaMessage{{Direction: InboundMessage, Name: "A"}}}
+
+	// a.schema registers before b.schema fails on the ID it took
+	b := Schema{
+		Path:        "b.schema",
+		Imports:     []Schema{a},
+		ReservedIDs: []uint32{first},
+		Messages:    []SchemaMessage{{Direction: InboundMessage, Name: "B"}},
+	}
+
+	r := newTestRegistry(t)
+	fingerprint := r.Fingerprint()
+
+	err := r.RegisterSchema(b)
+
+	if err == nil {
+		t.Fatal("expected b.schema to fail")
+	}
+
+	if len(r.Descriptors) != int(first) || len(r.UserSignatureMap) != 0 || len(r.reservedIDs) != 0 || r.RegisteredUser || r.Fingerprint() != fingerprint {
+		t.Errorf("expected the registry to be left as it was, got %d descriptors, %v, reserved %v", len(r.Descriptors), r.UserSignatureMap, r.reservedIDs)
+	}
+
+	// a.schema isn't considered registered
+	err = r.RegisterSchema(a)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, exists := r.UserSignatureMap["inbound A"]; !exists || id != first {
+		t.Errorf("expected inbound A to have ID %d, got %d", first, id)
+	}
+}
+
+func TestEmbeddedObjectsAreFlattened(t *testing.T) {
+	s, err := Parse([]byte(`
+object Header {
+  uint32 REQUIRED requestId
+  binary OPTIONAL tenant
+}
+
+object Traced extends Header {
+  uint64 REQUIRED traceId
+}
+
+inbound Charge {
+  uint64 REQUIRED amount
+  include Traced
+  binary OPTIONAL note
+}
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTestRegistry(t)
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	charge := r.Descriptors[r.UserSignatureMap["inbound Charge"]]
+
+	var names []string
+
+	for _, field := range charge.Message.Fields {
+		names = append(names, field.Name)
+	}
+
+	if !slices.Equal(names, []string{"amount", "requestId", "tenant", "traceId", "note"}) {
+		t.Errorf("unexpected fields: %v", names)
+	}
+
+	if charge.OptionalCount != 2 {
+		t.Errorf("expected 2 optional fields, got %d", charge.OptionalCount)
+	}
+
+	// the parsed schema keeps the embedded field for code generation
+	if field := s.Messages[2].Fields[1]; !field.Embedded || field.Name != "Traced" {
+		t.Errorf("expected an embedded Traced field, got %+v", field)
+	}
+}
+
+func TestRegisterMultipleFiles(t *testing.T) {
+	dir := writeSchemaFiles(t, multiFileSchema)
+	r := newTestRegistry(t)
+
+	for _, name := range []string{"billing.schema", "accounts.schema"} {
+		s, err := ParseFile(filepath.Join(dir, name))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.RegisterSchema(s)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, signature := range []string{"object common.Header", "inbound billing.Charge", "outbound billing.Receipt", "inbound accounts.GetAccount"} {
+		if _, exists := r.UserSignatureMap[signature]; !exists {
+			t.Errorf("expected %s to be registered", signature)
+		}
+	}
+
+	// common.schema is imported twice but only registered once
+	if len(r.UserSignatureMap) != 4 || len(r.Types) != 1 {
+		t.Errorf("unexpected registrations: %v", r.UserSignatureMap)
+	}
+}
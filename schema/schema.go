@@ -1,5 +1,12 @@
 package schema
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
 type MessageDirection int
 
 const (
@@ -25,16 +32,118 @@ func (d MessageDirection) ToString() string {
 }
 
 type MessageField struct {
-	Name     string
-	Type     FieldType
-	Extra    any
-	Optional bool
+	Name       string
+	Type       FieldType
+	Extra      any
+	Optional   bool
+	Deprecated bool
+	Aliases    []string // previous names of the field, still accepted when matching by name
+	Embedded   bool     // the fields of the object are spliced into the message in place of this field
+	TypeAlias  string   // name of the type alias the field was declared with, empty if none
+}
+
+// HasName reports whether name is the field name or one of its aliases
+func (f MessageField) HasName(name string) bool {
+	return f.Name == name || slices.Contains(f.Aliases, name)
+}
+
+// TypeString returns the field type as written in the schema DSL, e.g. binary(32) or array(int16)
+func (f MessageField) TypeString() string {
+	switch f.Type {
+	case TypeFixedBinary:
+		{
+			len, ok := f.Extra.(int)
+
+			if !ok {
+				return "binary(?)"
+			}
+
+			return fmt.Sprintf("binary(%d)", len)
+		}
+	case TypeObject:
+		return objectTypeName(f.Extra)
+	case TypeArray:
+		{
+			elem, ok := f.Extra.(MessageField)
+
+			if ok {
+				return fmt.Sprintf("array(%s)", elem.TypeString())
+			}
+
+			return fmt.Sprintf("array(%s)", objectTypeName(f.Extra))
+		}
+	default:
+		return f.Type.ToString()
+	}
+}
+
+// ObjectName returns the name of the object referenced by an object field or an array of objects,
+// and is empty for anonymous objects and all other field types
+func (f MessageField) ObjectName() string {
+	switch e := f.Extra.(type) {
+	case SchemaMessage:
+		return e.Name
+	case MessageDescriptor:
+		return e.Message.Name
+	default:
+		return ""
+	}
+}
+
+func objectTypeName(extra any) string {
+	name := MessageField{Extra: extra}.ObjectName()
+
+	if name == "" {
+		return "object"
+	}
+
+	return name
 }
 
 type SchemaMessage struct {
-	Direction MessageDirection
-	Name      string
-	Fields    []MessageField
+	Direction  MessageDirection `json:"direction"`
+	Name       string           `json:"name,omitempty"`
+	Fields     []MessageField   `json:"fields"`
+	Deprecated bool             `json:"deprecated,omitempty"`
+	Reserved   []string         `json:"reserved,omitempty"`   // field names that were retired and must not be reused
+	Extensible bool             `json:"extensible,omitempty"` // encoded with a length prefix, so fields can be appended without breaking older peers
+	Options    MessageOptions   `json:"options,omitzero"`
+}
+
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityLow
+	PriorityHigh
+)
+
+func (p Priority) ToString() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return ""
+	}
+}
+
+// MessageOptions are declared with @maxSize, @priority and @compress, the zero value uses the connection defaults
+type MessageOptions struct {
+	MaxSize  uint32   `json:"maxSize,omitempty"`  // largest accepted payload in bytes, after decompression, 0 means the connection's limit
+	Priority Priority `json:"priority,omitzero"`  // messages with a higher priority are written first when the connection is busy
+	Compress bool     `json:"compress,omitempty"` // the payload is deflate compressed on the wire
+}
+
+func Signature(direction MessageDirection, name string) string {
+	return fmt.Sprintf("%s %s", direction.ToString(), name)
+}
+
+func (m SchemaMessage) Signature() string {
+	return Signature(m.Direction, m.Name)
 }
 
 func (m SchemaMessage) CountOptional() uint32 {
@@ -49,8 +158,143 @@ func (m SchemaMessage) CountOptional() uint32 {
 	return i
 }
 
+// TypeAlias is a named type declared with type Name = T, fields using it are resolved to the aliased type
+type TypeAlias struct {
+	Name  string       `json:"name"`
+	Field MessageField `json:"field"` // the aliased type, without a name
+}
+
+// Constant is a named number declared with const Name = N, the parser resolves it in binary(N) and @maxSize(N)
+type Constant struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
 type Schema struct {
-	Messages []SchemaMessage
+	Package       string          `json:"package,omitempty"` // names declared by the schema are qualified with the package, e.g. billing.Charge
+	Path          string          `json:"path,omitempty"`    // file the schema was parsed from, a file is only registered once
+	Imports       []Schema        `json:"imports,omitempty"`
+	Messages      []SchemaMessage `json:"messages"`
+	ReservedNames []string        `json:"reservedNames,omitempty"` // message names that were retired and must not be reused
+	ReservedIDs   []uint32        `json:"reservedIds,omitempty"`   // descriptor IDs of retired messages, these are skipped when assigning IDs
+	Types         []TypeAlias     `json:"types,omitempty"`
+	Constants     []Constant      `json:"constants,omitempty"`
+	Services      []Service       `json:"services,omitempty"`
+}
+
+// Service groups RPC methods, which pair a request message with the response message it is answered with
+type Service struct {
+	Name    string   `json:"name"`
+	Methods []Method `json:"methods"`
+}
+
+type Method struct {
+	Name         string `json:"name"`
+	Request      string `json:"request"`                // name of an inbound or duplex message
+	Response     string `json:"response"`               // name of an outbound or duplex message
+	ClientStream bool   `json:"clientStream,omitempty"` // the caller sends any number of requests
+	ServerStream bool   `json:"serverStream,omitempty"` // the method answers with any number of responses
+}
+
+// ToString returns the method as written in the schema DSL, e.g. rpc Tail(TailReq) returns (stream LogLine)
+func (m Method) ToString() string {
+	return fmt.Sprintf("rpc %s(%s) returns (%s)", m.Name, streamRef(m.ClientStream, m.Request), streamRef(m.ServerStream, m.Response))
+}
+
+func streamRef(stream bool, name string) string {
+	if stream {
+		return "stream " + name
+	}
+
+	return name
+}
+
+// MethodName returns the name methods are called by, e.g. Accounts.GetUser
+func (s Service) MethodName(m Method) string {
+	return s.Name + "." + m.Name
+}
+
+// walk calls fn for the schema and everything it imports, imports first and every file once
+func (s Schema) walk(fn func(Schema), seen map[string]bool) {
+	if s.Path != "" {
+		if seen[s.Path] {
+			return
+		}
+
+		seen[s.Path] = true
+	}
+
+	for _, imported := range s.Imports {
+		imported.walk(fn, seen)
+	}
+
+	fn(s)
+}
+
+// Files returns the schema and every file it imports, imports first and every file once
+func (s Schema) Files() []Schema {
+	var files []Schema
+
+	s.walk(func(s Schema) {
+		files = append(files, s)
+	}, map[string]bool{})
+
+	return files
+}
+
+// AllMessages returns the messages of the schema and everything it imports, imports first
+func (s Schema) AllMessages() []SchemaMessage {
+	var messages []SchemaMessage
+
+	s.walk(func(s Schema) {
+		messages = append(messages, s.Messages...)
+	}, map[string]bool{})
+
+	return messages
+}
+
+// AllServices returns the services of the schema and everything it imports, imports first
+func (s Schema) AllServices() []Service {
+	var services []Service
+
+	s.walk(func(s Schema) {
+		services = append(services, s.Services...)
+	}, map[string]bool{})
+
+	return services
+}
+
+// PascalName turns a declared name into the type name generated code uses, e.g. full_name becomes FullName
+// and shop.PlaceOrder becomes ShopPlaceOrder
+func PascalName(name string) string {
+	var b strings.Builder
+
+	upper := true
+
+	for _, c := range name {
+		if c == '.' || c == '_' {
+			upper = true
+			continue
+		}
+
+		if upper {
+			b.WriteRune(unicode.ToUpper(c))
+			upper = false
+		} else {
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
+
+// QualifiedName prefixes a name with its package, if any
+func QualifiedName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+
+	return pkg + "." + name
 }
 
 // Inbound and Outbound Hello must both be ID 0 and 1 respectively, never change this
@@ -73,6 +317,12 @@ var InternalSchema = Schema{
 					Extra:    nil,
 					Optional: false,
 				},
+				{
+					Name:     "fingerprint",
+					Type:     TypeFixedBinary,
+					Extra:    32,
+					Optional: true,
+				},
 			},
 		},
 
@@ -92,6 +342,12 @@ var InternalSchema = Schema{
 					Extra:    nil,
 					Optional: false,
 				},
+				{
+					Name:     "fingerprint",
+					Type:     TypeFixedBinary,
+					Extra:    32,
+					Optional: false,
+				},
 				{
 					Name: "schema",
 					Type: TypeArray,
@@ -115,6 +371,18 @@ var InternalSchema = Schema{
 								Extra:    nil,
 								Optional: false,
 							},
+							{
+								Name:     "flags",
+								Type:     TypeUInt16,
+								Extra:    nil,
+								Optional: false,
+							},
+							{
+								Name:     "maxSize",
+								Type:     TypeUInt32,
+								Extra:    nil,
+								Optional: false,
+							},
 							{
 								Name:     "name",
 								Type:     TypeDynamicBinary,
@@ -150,6 +418,12 @@ var InternalSchema = Schema{
 											Extra:    nil,
 											Optional: false,
 										},
+										{
+											Name:     "aliases",
+											Type:     TypeArray,
+											Extra:    MessageField{Type: TypeDynamicBinary},
+											Optional: false,
+										},
 									},
 								},
 								Optional: false,
@@ -158,6 +432,81 @@ var InternalSchema = Schema{
 					},
 					Optional: false,
 				},
+				{
+					Name: "methods",
+					Type: TypeArray,
+					Extra: SchemaMessage{
+						Fields: []MessageField{
+							{
+								Name:     "name",
+								Type:     TypeDynamicBinary,
+								Extra:    nil,
+								Optional: false,
+							},
+							{
+								Name:     "request",
+								Type:     TypeUInt32,
+								Extra:    nil,
+								Optional: false,
+							},
+							{
+								Name:     "response",
+								Type:     TypeUInt32,
+								Extra:    nil,
+								Optional: false,
+							},
+						},
+					},
+					Optional: false,
+				},
+				{
+					Name: "types",
+					Type: TypeArray,
+					Extra: SchemaMessage{
+						Fields: []MessageField{
+							{
+								Name:     "name",
+								Type:     TypeDynamicBinary,
+								Extra:    nil,
+								Optional: false,
+							},
+							{
+								Name:     "type",
+								Type:     TypeUInt16,
+								Extra:    nil,
+								Optional: false,
+							},
+							{
+								Name:     "extra",
+								Type:     TypeLongBinary,
+								Extra:    nil,
+								Optional: false,
+							},
+						},
+					},
+					Optional: true,
+				},
+				{
+					Name: "constants",
+					Type: TypeArray,
+					Extra: SchemaMessage{
+						Fields: []MessageField{
+							{
+								Name:     "name",
+								Type:     TypeDynamicBinary,
+								Extra:    nil,
+								Optional: false,
+							},
+							{
+								Name:     "value",
+								Type:     TypeUInt64,
+								Extra:    nil,
+								Optional: false,
+							},
+						},
+					},
+					Optional: true,
+				},
 			},
 		},
 
@@ -186,5 +535,119 @@ var InternalSchema = Schema{
 				},
 			},
 		},
+
+		{
+			Direction: InboundMessage,
+			Name:      "RpcRequest",
+			Fields: []MessageField{
+				{
+					Name:     "callId",
+					Type:     TypeUInt32,
+					Extra:    nil,
+					Optional: false,
+				},
+				{
+					Name:     "method",
+					Type:     TypeDynamicBinary,
+					Extra:    nil,
+					Optional: false,
+				},
+				{
+					Name:     "payload",
+					Type:     TypeLongBinary,
+					Extra:    nil,
+					Optional: false,
+				},
+			},
+		},
+
+		{
+			Direction: OutboundMessage,
+			Name:      "RpcResponse",
+			Fields: []MessageField{
+				{
+					Name:     "callId",
+					Type:     TypeUInt32,
+					Extra:    nil,
+					Optional: false,
+				},
+				{
+					Name:     "payload",
+					Type:     TypeLongBinary,
+					Extra:    nil,
+					Optional: false,
+				},
+			},
+		},
+
+		{
+			Direction: OutboundMessage,
+			Name:      "RpcError",
+			Fields: []MessageField{
+				{
+					Name:     "callId",
+					Type:     TypeUInt32,
+					Extra:    nil,
+					Optional: false,
+				},
+				{
+					Name:     "message",
+					Type:     TypeDynamicBinary,
+					Extra:    nil,
+					Optional: false,
+				},
+			},
+		},
+
+		{
+			Direction: DuplexMessage,
+			Name:      "StreamData",
+			Fields: []MessageField{
+				{
+					Name:     "callId",
+					Type:     TypeUInt32,
+					Extra:    nil,
+					Optional: false,
+				},
+				{
+					Name:     "payload",
+					Type:     TypeLongBinary,
+					Extra:    nil,
+					Optional: false,
+				},
+			},
+		},
+
+		{
+			Direction: DuplexMessage,
+			Name:      "StreamEnd",
+			Fields: []MessageField{
+				{
+					Name:     "callId",
+					Type:     TypeUInt32,
+					Extra:    nil,
+					Optional: false,
+				},
+			},
+		},
+
+		{
+			Direction: DuplexMessage,
+			Name:      "StreamError",
+			Fields: []MessageField{
+				{
+					Name:     "callId",
+					Type:     TypeUInt32,
+					Extra:    nil,
+					Optional: false,
+				},
+				{
+					Name:     "message",
+					Type:     TypeDynamicBinary,
+					Extra:    nil,
+					Optional: false,
+				},
+			},
+		},
 	},
 }
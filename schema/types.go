@@ -16,6 +16,33 @@ const (
 	TypeArray
 )
 
+func (f FieldType) ToString() string {
+	switch f {
+	case TypeFixedBinary, TypeDynamicBinary:
+		return "binary"
+	case TypeLongBinary:
+		return "long_binary"
+	case TypeUInt64:
+		return "uint64"
+	case TypeInt64:
+		return "int64"
+	case TypeUInt32:
+		return "uint32"
+	case TypeInt32:
+		return "int32"
+	case TypeUInt16:
+		return "uint16"
+	case TypeInt16:
+		return "int16"
+	case TypeObject:
+		return "object"
+	case TypeArray:
+		return "array"
+	default:
+		return ""
+	}
+}
+
 func (f FieldType) GetFixedSize(extra any) uint32 {
 	switch f {
 	case TypeFixedBinary: // binary(N): return N
@@ -28,16 +55,13 @@ func (f FieldType) GetFixedSize(extra any) uint32 {
 	case TypeLongBinary: // long_binary: return 4 for the length-prefix
 		return 4
 
-	case TypeUInt64:
-	case TypeInt64:
+	case TypeUInt64, TypeInt64:
 		return 8
 
-	case TypeUInt32:
-	case TypeInt32:
+	case TypeUInt32, TypeInt32:
 		return 4
 		
-	case TypeUInt16:
-	case TypeInt16:
+	case TypeUInt16, TypeInt16:
 		return 2
 	
 	case TypeObject:
@@ -6,8 +6,10 @@ import (
 	"log"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/benjamin-larsen/goschemaipc/encoder"
 	"github.com/benjamin-larsen/goschemaipc/schema"
 )
 
@@ -20,10 +22,22 @@ const (
 
 type Server struct {
 	Schema schema.Schema
+	SchemaPath string // schema file registered after Schema, along with the files it imports
 	Listener net.Listener
 	MessageOverflowPolicy MessageOverflowPolicy
-	MaxMessageSize uint32
+	MaxMessageSize uint32 // largest payload accepted from clients unless a message sets @maxSize, 0 means unlimited
+	// With encoder.DecodeCopy []byte fields of requests are copied out of the payload, and the payloads of
+	// messages are read into a buffer of the connection that is reused for the next one
+	DecodeMode encoder.DecodeMode
 	Registry schema.MessageDescriptorRegistry
+	OnDeprecated func(descriptor schema.MessageDescriptor, field string) // called when a deprecated message or field is received, logs if nil
+	ExportDefinitions bool // advertise the type aliases and constants of the schema in Hello
+	deprecatedUsage sync.Map // map[string]*atomic.Uint64
+	helloSchema []helloDescriptor
+	helloMethods []helloMethod
+	rpcHandlers map[string]rpcHandler
+	helloTypes []helloTypeAlias
+	helloConstants []helloConstant
 }
 
 func (s *Server) Init() {
@@ -42,6 +56,42 @@ func (s *Server) Init() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if s.SchemaPath != "" {
+		parsed, err := schema.ParseFile(s.SchemaPath)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = s.Registry.RegisterSchema(parsed)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	s.helloSchema, err = buildHelloSchema(&s.Registry)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.helloMethods = buildHelloMethods(&s.Registry)
+
+	if s.ExportDefinitions {
+		s.helloTypes, s.helloConstants, err = helloDefinitions(&s.Registry)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	s.registerInternal("inbound Hello", s.handleHello)
+	s.registerInternal("inbound RpcRequest", s.handleRPCRequest)
+	s.registerInternal("inbound StreamData", s.handleStreamData)
+	s.registerInternal("inbound StreamEnd", s.handleStreamEnd)
+	s.registerInternal("inbound StreamError", s.handleStreamError)
 }
 
 func (s *Server) Register(signature string, handler schema.HandlerFunc) {
@@ -155,4 +205,6 @@ func (s *Server) HandleConnection(netConn net.Conn) {
 			break
 		}
 	}
+
+	c.abortStreams()
 }
\ No newline at end of file
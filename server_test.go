@@ -0,0 +1,205 @@
+package schemaipc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var testSchema = schema.Schema{
+	Messages: []schema.SchemaMessage{
+		{
+			Direction: schema.InboundMessage,
+			Name:      "Echo",
+			Fields: []schema.MessageField{
+				{Name: "data", Type: schema.TypeDynamicBinary},
+				{Name: "hash", Type: schema.TypeFixedBinary, Extra: 4, Optional: true},
+			},
+		},
+	},
+}
+
+func newTestServer(t *testing.T) *Server {
+	s := &Server{
+		Schema:         testSchema,
+		MaxMessageSize: 1024,
+	}
+
+	s.Init()
+
+	return s
+}
+
+func writeTestMessage(t *testing.T, conn net.Conn, descriptor schema.MessageDescriptor, v any) {
+	payload, err := encoder.Encode(descriptor, v)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var header [8]byte
+
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], descriptor.ID)
+
+	_, err = conn.Write(append(header[:], payload...))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestMessage(t *testing.T, conn net.Conn) (uint32, []byte) {
+	var header [8]byte
+
+	_, err := io.ReadFull(conn, header[:])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint32(header[:4]))
+
+	_, err = io.ReadFull(conn, payload)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return binary.LittleEndian.Uint32(header[4:]), payload
+}
+
+func handshake(t *testing.T, s *Server, conn net.Conn, fingerprint []byte) helloOutbound {
+	writeTestMessage(t, conn, s.Registry.Descriptors[0], helloInbound{
+		MinVersion:  ProtocolVersion,
+		CurrVersion: ProtocolVersion,
+		Fingerprint: fingerprint,
+	})
+
+	id, payload := readTestMessage(t, conn)
+
+	if id != 1 {
+		t.Fatalf("expected outbound Hello, got message %d", id)
+	}
+
+	var hello helloOutbound
+
+	reader := encoder.NewReader(payload, s.Registry.Descriptors[1])
+	err := reader.Decode(&hello)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hello
+}
+
+func TestHandshake(t *testing.T) {
+	s := newTestServer(t)
+	fingerprint := s.Registry.Fingerprint()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	hello := handshake(t, s, client, nil)
+
+	if hello.Fingerprint != fingerprint {
+		t.Error("fingerprint doesn't match the registry")
+	}
+
+	if len(hello.Schema) != len(s.Registry.Descriptors)-2 {
+		t.Fatalf("expected %d descriptors, got %d", len(s.Registry.Descriptors)-2, len(hello.Schema))
+	}
+
+	echo := hello.Schema[len(hello.Schema)-1]
+
+	if string(echo.Name) != "Echo" || len(echo.Fields) != 2 {
+		t.Fatalf("unexpected descriptor: %+v", echo)
+	}
+
+	if extra := binary.LittleEndian.Uint32(echo.Fields[1].Extra); extra != 4 {
+		t.Errorf("expected fixed binary extra of 4, got %d", extra)
+	}
+}
+
+func TestHandshakeCachedSchema(t *testing.T) {
+	s := newTestServer(t)
+	fingerprint := s.Registry.Fingerprint()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go s.HandleConnection(server)
+
+	hello := handshake(t, s, client, fingerprint[:])
+
+	if len(hello.Schema) != 0 {
+		t.Errorf("expected schema to be omitted, got %d descriptors", len(hello.Schema))
+	}
+}
+
+func TestFingerprintChangesWithSchema(t *testing.T) {
+	a := newTestServer(t)
+	b := newTestServer(t)
+
+	if a.Registry.Fingerprint() != b.Registry.Fingerprint() {
+		t.Error("fingerprint isn't stable")
+	}
+
+	changed := &Server{
+		Schema: schema.Schema{
+			Messages: []schema.SchemaMessage{
+				{
+					Direction: schema.InboundMessage,
+					Name:      "Echo",
+					Fields: []schema.MessageField{
+						{Name: "data", Type: schema.TypeDynamicBinary},
+						{Name: "hash", Type: schema.TypeFixedBinary, Extra: 8, Optional: true},
+					},
+				},
+			},
+		},
+	}
+
+	changed.Init()
+
+	if a.Registry.Fingerprint() == changed.Registry.Fingerprint() {
+		t.Error("fingerprint didn't change with the schema")
+	}
+}
+
+func TestReportDeprecatedLogsOnce(t *testing.T) {
+	s := newTestServer(t)
+	descriptor := s.Registry.Descriptors[s.Registry.UserSignatureMap["inbound Echo"]]
+
+	var logged bytes.Buffer
+
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	for range 3 {
+		s.reportDeprecated(descriptor, "hash")
+	}
+
+	s.reportDeprecated(descriptor, "")
+
+	if n := strings.Count(logged.String(), "Received deprecated"); n != 2 {
+		t.Errorf("expected one log line per key, got %d:\n%s", n, logged.String())
+	}
+
+	usage := s.DeprecatedUsage()
+
+	if usage["inbound Echo.hash"] != 3 || usage["inbound Echo"] != 1 {
+		t.Errorf("unexpected usage: %v", usage)
+	}
+}
@@ -0,0 +1,558 @@
+package schemaipc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+var ErrStreamingMethod = errors.New("method is streaming, it must be opened as a stream")
+var ErrUnaryMethod = errors.New("method isn't streaming")
+var ErrNotStreaming = errors.New("method doesn't stream in this direction")
+var ErrStreamClosed = errors.New("stream is closed")
+var ErrNoResponse = errors.New("method returned without a response")
+
+// requests and responses buffered per stream before the connection stops reading
+const streamBufferSize = 16
+
+type streamData struct {
+	CallID  uint32 `ipc:"callId"`
+	Payload []byte `ipc:"payload"`
+}
+
+type streamEnd struct {
+	CallID uint32 `ipc:"callId"`
+}
+
+type streamError struct {
+	CallID  uint32 `ipc:"callId"`
+	Message []byte `ipc:"message"`
+}
+
+// streamQueue delivers the payloads received on a streaming call in order. push and end are only
+// called by the goroutine reading the connection, next and abandon by the receiver of the stream.
+type streamQueue struct {
+	method    string
+	items     chan []byte
+	done      chan struct{} // closed once the receiver is gone, so push never blocks on it
+	closeDone sync.Once
+	err       error
+	ended     bool
+}
+
+func newStreamQueue(method string) *streamQueue {
+	return &streamQueue{
+		method: method,
+		items:  make(chan []byte, streamBufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+func (q *streamQueue) push(payload []byte) {
+	if q.ended {
+		return
+	}
+
+	select {
+	case q.items <- payload:
+	case <-q.done:
+	}
+}
+
+func (q *streamQueue) end(err error) {
+	if q.ended {
+		return
+	}
+
+	q.ended = true
+	q.err = err
+	close(q.items)
+}
+
+// abandon is called once the receiver stops receiving, payloads that still arrive are dropped
+func (q *streamQueue) abandon() {
+	q.closeDone.Do(func() {
+		close(q.done)
+	})
+}
+
+func (q *streamQueue) abandoned() bool {
+	select {
+	case <-q.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// next returns the next payload, io.EOF once the peer ended the stream or ErrStreamClosed once it's abandoned
+func (q *streamQueue) next() ([]byte, error) {
+	if q.abandoned() {
+		return nil, ErrStreamClosed
+	}
+
+	select {
+	case payload, ok := <-q.items:
+		{
+			if !ok {
+				if q.err != nil {
+					return nil, q.err
+				}
+
+				return nil, io.EOF
+			}
+
+			return payload, nil
+		}
+	case <-q.done:
+		return nil, ErrStreamClosed
+	}
+}
+
+// ServerStream is the server side of a streaming call, it receives the requests of the call and sends its responses
+type ServerStream[Req, Res any] struct {
+	conn     *Conn
+	callID   uint32
+	method   schema.RPCMethod
+	request  schema.MessageDescriptor
+	response schema.MessageDescriptor
+	queue    *streamQueue
+	received bool
+	sent     bool
+}
+
+func (s *ServerStream[Req, Res]) Conn() *Conn {
+	return s.conn
+}
+
+// Recv returns the next request, or io.EOF once the client has ended its side of the call
+func (s *ServerStream[Req, Res]) Recv() (*Req, error) {
+	if s.received && !s.method.ClientStream {
+		return nil, io.EOF
+	}
+
+	payload, err := s.queue.next()
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.received = true
+
+	payload, err = decodePayload(s.request, payload, messageLimit(s.request, s.conn.server.MaxMessageSize))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var req Req
+
+	reader := encoder.NewReader(payload, s.request)
+	reader.SetMode(s.conn.server.DecodeMode)
+	err = reader.Decode(&req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// Send sends a response, methods that don't stream responses must send exactly one
+func (s *ServerStream[Req, Res]) Send(res Res) error {
+	if s.sent && !s.method.ServerStream {
+		return ErrNotStreaming
+	}
+
+	payload, err := encodePayload(s.response, res)
+
+	if err != nil {
+		return err
+	}
+
+	s.sent = true
+
+	return s.conn.sendEnvelope("outbound StreamData", s.response, streamData{
+		CallID:  s.callID,
+		Payload: payload,
+	})
+}
+
+// RegisterStream registers the handler of a streaming method (Service.Method), it panics if Req or Res
+// can't be decoded from the requests or encoded as the responses of the method. The handler runs in its own
+// goroutine and the call ends when it returns, with a StreamError if it returned an error.
+func RegisterStream[Req, Res any](s *Server, method string, handler func(stream *ServerStream[Req, Res]) error) {
+	m, request, response := s.checkRPCHandler(method, reflect.TypeFor[Req](), reflect.TypeFor[Res]())
+
+	if !m.Streaming() {
+		s := fmt.Sprintf("method (%s) isn't streaming, use RegisterRPC", method)
+		panic(s)
+	}
+
+	s.rpcHandlers[method] = func(callID uint32, payload []byte, c *Conn) error {
+		queue := c.openStream(callID, method)
+
+		if queue == nil {
+			return c.sendStreamError(callID, fmt.Sprintf("call %d is already open", callID))
+		}
+
+		stream := &ServerStream[Req, Res]{
+			conn:     c,
+			callID:   callID,
+			method:   m,
+			request:  request,
+			response: response,
+			queue:    queue,
+		}
+
+		go func() {
+			err := handler(stream)
+
+			c.closeStream(callID)
+
+			if err == nil && !m.ServerStream && !stream.sent {
+				err = ErrNoResponse
+			}
+
+			if err != nil {
+				c.sendStreamError(callID, err.Error())
+				return
+			}
+
+			c.sendInternal("outbound StreamEnd", streamEnd{
+				CallID: callID,
+			})
+		}()
+
+		return nil
+	}
+}
+
+func (c *Conn) openStream(callID uint32, method string) *streamQueue {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	if c.streams == nil {
+		c.streams = make(map[uint32]*streamQueue)
+	}
+
+	if c.streams[callID] != nil {
+		return nil
+	}
+
+	queue := newStreamQueue(method)
+	c.streams[callID] = queue
+
+	return queue
+}
+
+func (c *Conn) stream(callID uint32) *streamQueue {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	return c.streams[callID]
+}
+
+// closeStream is called once the handler of the stream returned
+func (c *Conn) closeStream(callID uint32) {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	c.streams[callID].abandon()
+	delete(c.streams, callID)
+}
+
+// abortStreams fails the streams whose handlers are still running once the connection is gone
+func (c *Conn) abortStreams() {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	for _, queue := range c.streams {
+		queue.end(ErrCallAborted)
+	}
+}
+
+func (c *Conn) sendStreamError(callID uint32, message string) error {
+	return c.sendInternal("outbound StreamError", streamError{
+		CallID:  callID,
+		Message: []byte(message),
+	})
+}
+
+func (s *Server) handleStreamData(r schema.Reader, conn schema.Conn) error {
+	c := conn.(*Conn)
+
+	if c.state != ConnEstablished {
+		return ErrInvalidDescriptor
+	}
+
+	var data streamData
+
+	err := r.Decode(&data)
+
+	if err != nil {
+		return err
+	}
+
+	// data for calls that already finished is dropped
+	if queue := c.stream(data.CallID); queue != nil {
+		queue.push(data.Payload)
+	}
+
+	return nil
+}
+
+func (s *Server) handleStreamEnd(r schema.Reader, conn schema.Conn) error {
+	c := conn.(*Conn)
+
+	if c.state != ConnEstablished {
+		return ErrInvalidDescriptor
+	}
+
+	var end streamEnd
+
+	err := r.Decode(&end)
+
+	if err != nil {
+		return err
+	}
+
+	if queue := c.stream(end.CallID); queue != nil {
+		queue.end(nil)
+	}
+
+	return nil
+}
+
+func (s *Server) handleStreamError(r schema.Reader, conn schema.Conn) error {
+	c := conn.(*Conn)
+
+	if c.state != ConnEstablished {
+		return ErrInvalidDescriptor
+	}
+
+	var res streamError
+
+	err := r.Decode(&res)
+
+	if err != nil {
+		return err
+	}
+
+	// the client gave up on the call, the handler sees the error on its next Recv
+	if queue := c.stream(res.CallID); queue != nil {
+		queue.end(&RPCError{Method: queue.method, Message: string(res.Message)})
+	}
+
+	return nil
+}
+
+// ClientStream is the client side of a streaming call. Send and CloseSend may be called concurrently with Recv,
+// but not with each other. Run waits for unread responses, so Recv must be called until it returns an error
+// or the stream must be closed with Close.
+type ClientStream struct {
+	client *Client
+	callID uint32
+	method schema.RPCMethod
+	queue  *streamQueue
+	sent   bool
+	closed bool
+}
+
+// OpenStream opens a streaming call of a service method (Service.Method).
+// Responses are received by Run, which must be running for the call to complete.
+func (c *Client) OpenStream(method string) (*ClientStream, error) {
+	if c.peer == nil {
+		return nil, ErrNotEstablished
+	}
+
+	m, exists := c.peer.Methods[method]
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMethod, method)
+	}
+
+	if !m.Streaming() {
+		return nil, fmt.Errorf("%w: %s", ErrUnaryMethod, method)
+	}
+
+	queue := newStreamQueue(method)
+
+	callID, err := c.addCall(nil, queue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request := c.peer.Descriptors[m.Request]
+
+	// the requests follow as StreamData
+	err = c.writeMessage(carrying(c.internalDescriptor("inbound RpcRequest"), request), rpcRequest{
+		CallID:  callID,
+		Method:  []byte(method),
+		Payload: []byte{},
+	})
+
+	if err != nil {
+		c.takeStream(callID)
+		return nil, err
+	}
+
+	return &ClientStream{
+		client: c,
+		callID: callID,
+		method: m,
+		queue:  queue,
+	}, nil
+}
+
+// Send sends a request, methods that don't stream requests must send exactly one
+func (s *ClientStream) Send(req any) error {
+	if s.closed || s.queue.abandoned() {
+		return ErrStreamClosed
+	}
+
+	if s.sent && !s.method.ClientStream {
+		return ErrNotStreaming
+	}
+
+	request := s.client.peer.Descriptors[s.method.Request]
+
+	payload, err := encodePayload(request, req)
+
+	if err != nil {
+		return err
+	}
+
+	s.sent = true
+
+	return s.client.writeMessage(carrying(s.client.internalDescriptor("inbound StreamData"), request), streamData{
+		CallID:  s.callID,
+		Payload: payload,
+	})
+}
+
+// CloseSend tells the server that no more requests are coming
+func (s *ClientStream) CloseSend() error {
+	if s.closed || s.queue.abandoned() {
+		return nil
+	}
+
+	s.closed = true
+
+	return s.client.writeMessage(s.client.internalDescriptor("inbound StreamEnd"), streamEnd{
+		CallID: s.callID,
+	})
+}
+
+// Close abandons the call, it may be called concurrently with Recv. The server is told to end the call with
+// a StreamError, responses that are still on their way are dropped and Recv returns ErrStreamClosed.
+func (s *ClientStream) Close() error {
+	s.queue.abandon()
+
+	// the call already finished
+	if s.client.takeStream(s.callID) == nil {
+		return nil
+	}
+
+	return s.client.writeMessage(s.client.internalDescriptor("inbound StreamError"), streamError{
+		CallID:  s.callID,
+		Message: []byte(ErrStreamClosed.Error()),
+	})
+}
+
+// Recv decodes the next response into res, it returns io.EOF once the call finished
+// and an *RPCError if the server ended the call with an error
+func (s *ClientStream) Recv(res any) error {
+	payload, err := s.queue.next()
+
+	if err != nil {
+		return err
+	}
+
+	response := s.client.peer.Descriptors[s.method.Response]
+
+	payload, err = decodePayload(response, payload, messageLimit(response, s.client.MaxMessageSize))
+
+	if err != nil {
+		return err
+	}
+
+	reader := encoder.NewReader(payload, response)
+	reader.SetMode(s.client.DecodeMode)
+
+	return reader.Decode(res)
+}
+
+func (c *Client) stream(callID uint32) *streamQueue {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+
+	return c.streams[callID]
+}
+
+func (c *Client) takeStream(callID uint32) *streamQueue {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+
+	queue := c.streams[callID]
+	delete(c.streams, callID)
+
+	return queue
+}
+
+func (c *Client) handleStreamMessage(descriptor schema.MessageDescriptor, payload []byte) error {
+	reader := encoder.NewReader(payload, descriptor)
+
+	switch descriptor.Message.Name {
+	case "StreamData":
+		{
+			var data streamData
+
+			err := reader.Decode(&data)
+
+			if err != nil {
+				return err
+			}
+
+			if queue := c.stream(data.CallID); queue != nil {
+				queue.push(data.Payload)
+			}
+		}
+	case "StreamEnd":
+		{
+			var end streamEnd
+
+			err := reader.Decode(&end)
+
+			if err != nil {
+				return err
+			}
+
+			if queue := c.takeStream(end.CallID); queue != nil {
+				queue.end(nil)
+			}
+		}
+	case "StreamError":
+		{
+			var res streamError
+
+			err := reader.Decode(&res)
+
+			if err != nil {
+				return err
+			}
+
+			if queue := c.takeStream(res.CallID); queue != nil {
+				queue.end(&RPCError{Method: queue.method, Message: string(res.Message)})
+			}
+		}
+	}
+
+	return nil
+}
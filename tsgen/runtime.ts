@@ -0,0 +1,465 @@
+export class IPCError extends Error {}
+
+// RemoteError is a protocol error sent by the server
+export class RemoteError extends Error {}
+
+// RPCError is returned by a method handler on the server, the connection stays usable
+export class RPCError extends Error {
+  constructor(readonly method: string, message: string) {
+    super(`rpc ${method}: ${message}`);
+  }
+}
+
+interface MessageStart {
+  header: number;
+  optList: number;
+  extensible: boolean;
+}
+
+export class Writer {
+  private buf = new Uint8Array(64);
+  private view = new DataView(this.buf.buffer);
+  private length = 0;
+
+  // grow reserves n zeroed bytes and returns their offset, buf and view must only be read after it
+  private grow(n: number): number {
+    const pos = this.length;
+
+    if (pos + n > this.buf.length) {
+      let size = this.buf.length * 2;
+
+      while (size < pos + n) {
+        size *= 2;
+      }
+
+      const next = new Uint8Array(size);
+      next.set(this.buf.subarray(0, pos));
+
+      this.buf = next;
+      this.view = new DataView(next.buffer);
+    }
+
+    this.length = pos + n;
+    this.buf.fill(0, pos, pos + n);
+
+    return pos;
+  }
+
+  bytes(): Uint8Array {
+    return this.buf.slice(0, this.length);
+  }
+
+  uint64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigUint64(pos, v, true);
+  }
+
+  int64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigInt64(pos, v, true);
+  }
+
+  uint32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setUint32(pos, v, true);
+  }
+
+  int32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setInt32(pos, v, true);
+  }
+
+  uint16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setUint16(pos, v, true);
+  }
+
+  int16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setInt16(pos, v, true);
+  }
+
+  private raw(b: Uint8Array): void {
+    const pos = this.grow(b.length);
+    this.buf.set(b, pos);
+  }
+
+  fixedBinary(b: Uint8Array, n: number): void {
+    if (b.length !== n) {
+      throw new IPCError("fixed binary field: wrong length");
+    }
+
+    this.raw(b);
+  }
+
+  binary(b: Uint8Array): void {
+    if (b.length > 0xffff) {
+      throw new IPCError("binary field: length too long (must not be more than 65,535 bytes)");
+    }
+
+    this.uint16(b.length);
+    this.raw(b);
+  }
+
+  longBinary(b: Uint8Array): void {
+    this.uint32(b.length);
+    this.raw(b);
+  }
+
+  arrayLen(n: number): void {
+    if (n > 0xffff) {
+      throw new IPCError("array field: length too long (must not be more than 65,535 elements)");
+    }
+
+    this.uint16(n);
+  }
+
+  beginMessage(optionalCount: number, extensible: boolean): MessageStart {
+    const optBytes = Math.ceil(optionalCount / 8);
+    let header = 0;
+
+    if (extensible) {
+      if (optBytes > 255) {
+        throw new IPCError("extensible message: too many optional fields (must not be more than 2,040)");
+      }
+
+      header = this.grow(5);
+      this.buf[header + 4] = optBytes;
+    }
+
+    return { header, optList: this.grow(optBytes), extensible };
+  }
+
+  setOptional(start: MessageStart, n: number): void {
+    this.buf[start.optList + (n >> 3)] |= 1 << (n & 7);
+  }
+
+  endMessage(start: MessageStart): void {
+    if (start.extensible) {
+      this.view.setUint32(start.header, this.length - start.header - 4, true);
+    }
+  }
+}
+
+interface MessageFrame {
+  optList: Uint8Array;
+  optBytes: number;
+  opt: number;
+  extensible: boolean;
+  end: number;
+  outerLen: number;
+}
+
+export class Reader {
+  private readonly view: DataView;
+  private pos = 0;
+  private len: number;
+
+  constructor(private readonly buf: Uint8Array) {
+    this.view = new DataView(buf.buffer, buf.byteOffset, buf.byteLength);
+    this.len = buf.length;
+  }
+
+  private take(n: number): number {
+    if (n > this.len - this.pos) {
+      throw new IPCError("out of bounds");
+    }
+
+    const pos = this.pos;
+    this.pos += n;
+
+    return pos;
+  }
+
+  uint64(): bigint {
+    return this.view.getBigUint64(this.take(8), true);
+  }
+
+  int64(): bigint {
+    return this.view.getBigInt64(this.take(8), true);
+  }
+
+  uint32(): number {
+    return this.view.getUint32(this.take(4), true);
+  }
+
+  int32(): number {
+    return this.view.getInt32(this.take(4), true);
+  }
+
+  uint16(): number {
+    return this.view.getUint16(this.take(2), true);
+  }
+
+  int16(): number {
+    return this.view.getInt16(this.take(2), true);
+  }
+
+  // binary fields are copied, so they stay valid after the payload is released
+  fixedBinary(n: number): Uint8Array {
+    const pos = this.take(n);
+    return this.buf.slice(pos, pos + n);
+  }
+
+  binary(): Uint8Array {
+    return this.fixedBinary(this.uint16());
+  }
+
+  longBinary(): Uint8Array {
+    return this.fixedBinary(this.uint32());
+  }
+
+  arrayLen(): number {
+    return this.uint16();
+  }
+
+  beginMessage(optionalCount: number, extensible: boolean): MessageFrame {
+    const frame: MessageFrame = {
+      optList: new Uint8Array(0),
+      optBytes: Math.ceil(optionalCount / 8),
+      opt: 0,
+      extensible,
+      end: 0,
+      outerLen: 0,
+    };
+
+    if (extensible) {
+      const bodyLen = this.uint32();
+
+      if (bodyLen > this.len - this.pos) {
+        throw new IPCError("out of bounds");
+      }
+
+      frame.end = this.pos + bodyLen;
+      frame.outerLen = this.len;
+      this.len = frame.end;
+
+      // the sender might know about more or less optional fields than we do
+      frame.optBytes = this.buf[this.take(1)];
+    }
+
+    frame.optList = this.fixedBinary(frame.optBytes);
+
+    return frame;
+  }
+
+  present(frame: MessageFrame): boolean {
+    const opt = frame.opt++;
+
+    return opt < frame.optBytes * 8 && (frame.optList[opt >> 3] & (1 << (opt & 7))) !== 0;
+  }
+
+  checkRequired(frame: MessageFrame): void {
+    if (frame.extensible && this.pos === frame.end) {
+      throw new IPCError("required field is missing from message");
+    }
+  }
+
+  endMessage(frame: MessageFrame): void {
+    if (frame.extensible) {
+      // skip trailing fields sent by a peer with a newer schema
+      this.pos = frame.end;
+      this.len = frame.outerLen;
+    }
+  }
+}
+
+// compressed messages are raw deflate streams
+async function transform(data: Uint8Array, stream: GenericTransformStream): Promise<Uint8Array> {
+  const piped = new Blob([data]).stream().pipeThrough(stream);
+  return new Uint8Array(await new Response(piped).arrayBuffer());
+}
+
+function deflate(data: Uint8Array): Promise<Uint8Array> {
+  return transform(data, new CompressionStream("deflate-raw"));
+}
+
+function inflate(data: Uint8Array): Promise<Uint8Array> {
+  return transform(data, new DecompressionStream("deflate-raw"));
+}
+
+const textEncoder = new TextEncoder();
+const textDecoder = new TextDecoder();
+
+function sameBytes(a: Uint8Array, b: Uint8Array): boolean {
+  return a.length === b.length && a.every((v, i) => v === b[i]);
+}
+
+// Transport carries the frames of a connection, e.g. a socket or a WebSocket
+export interface Transport {
+  write(data: Uint8Array): void;
+  close(): void;
+}
+
+interface PendingCall {
+  method: string;
+  resolve(payload: Uint8Array): void;
+  reject(err: Error): void;
+}
+
+export class BaseClient {
+  private pending = new Uint8Array(0);
+  private queue: Promise<void> = Promise.resolve();
+  private readonly handlers = new Map<number, (payload: Uint8Array) => Promise<void> | void>();
+  private readonly calls = new Map<number, PendingCall>();
+  private lastCallId = 0;
+  private hello?: { resolve(): void; reject(err: Error): void };
+
+  // called with errors that can't be returned to a caller, such as a failing handler
+  onError: (err: Error) => void = (err) => console.error(err);
+
+  constructor(protected readonly transport: Transport) {}
+
+  // receive is fed the bytes read from the transport, frames are dispatched in order
+  receive(chunk: Uint8Array): void {
+    const buf = new Uint8Array(this.pending.length + chunk.length);
+    buf.set(this.pending);
+    buf.set(chunk, this.pending.length);
+
+    let off = 0;
+
+    while (buf.length - off >= 8) {
+      const header = new DataView(buf.buffer, off, 8);
+      const length = header.getUint32(0, true);
+      const id = header.getUint32(4, true);
+
+      if (buf.length - off - 8 < length) {
+        break;
+      }
+
+      const payload = buf.slice(off + 8, off + 8 + length);
+      off += 8 + length;
+
+      this.queue = this.queue.then(() => this.dispatch(id, payload)).catch((err) => this.onError(err));
+    }
+
+    this.pending = buf.slice(off);
+  }
+
+  // handshake sends Hello and resolves once the server confirmed that it has the same schema
+  handshake(): Promise<void> {
+    return new Promise((resolve, reject) => {
+      this.hello = { resolve, reject };
+
+      const w = new Writer();
+
+      writeInternalInboundHello(w, {
+        minVersion: MIN_PROTOCOL_VERSION,
+        currVersion: PROTOCOL_VERSION,
+        fingerprint: FINGERPRINT,
+      });
+
+      this.writeFrame(InternalId.InboundHello, w.bytes());
+    });
+  }
+
+  close(): void {
+    for (const call of this.calls.values()) {
+      call.reject(new IPCError("call aborted: connection closed"));
+    }
+
+    this.calls.clear();
+    this.transport.close();
+  }
+
+  private writeFrame(id: number, payload: Uint8Array): void {
+    const frame = new Uint8Array(8 + payload.length);
+    const header = new DataView(frame.buffer);
+
+    header.setUint32(0, payload.length, true);
+    header.setUint32(4, id, true);
+    frame.set(payload, 8);
+
+    this.transport.write(frame);
+  }
+
+  private async dispatch(id: number, payload: Uint8Array): Promise<void> {
+    switch (id) {
+      case InternalId.OutboundHello:
+        return this.handleHello(readInternalOutboundHello(new Reader(payload)));
+      case InternalId.OutboundProtocolError: {
+        const err = new RemoteError(`protocol error: ${textDecoder.decode(readInternalOutboundProtocolError(new Reader(payload)).message)}`);
+
+        if (this.hello) {
+          this.hello.reject(err);
+          this.hello = undefined;
+          return;
+        }
+
+        throw err;
+      }
+      case InternalId.OutboundRpcResponse: {
+        const res = readInternalOutboundRpcResponse(new Reader(payload));
+        this.takeCall(res.callId)?.resolve(res.payload);
+        return;
+      }
+      case InternalId.OutboundRpcError: {
+        const res = readInternalOutboundRpcError(new Reader(payload));
+        const call = this.takeCall(res.callId);
+        call?.reject(new RPCError(call.method, textDecoder.decode(res.message)));
+        return;
+      }
+    }
+
+    const handler = this.handlers.get(id);
+
+    if (handler) {
+      await handler(payload);
+    }
+  }
+
+  private handleHello(hello: InternalOutboundHello): void {
+    const pending = this.hello;
+    this.hello = undefined;
+
+    if (!pending) {
+      throw new IPCError("server sent an unexpected message");
+    }
+
+    if (hello.currVersion < MIN_PROTOCOL_VERSION || hello.minVersion > PROTOCOL_VERSION) {
+      pending.reject(new IPCError("peer protocol version is not supported"));
+    } else if (!sameBytes(hello.fingerprint, FINGERPRINT)) {
+      pending.reject(new IPCError("server schema doesn't match the client schema"));
+    } else {
+      pending.resolve();
+    }
+  }
+
+  private takeCall(callId: number): PendingCall | undefined {
+    const call = this.calls.get(callId);
+    this.calls.delete(callId);
+
+    return call;
+  }
+
+  protected async sendMessage(id: number, payload: Uint8Array, compress: boolean): Promise<void> {
+    this.writeFrame(id, compress ? await deflate(payload) : payload);
+  }
+
+  protected handle(id: number, compress: boolean, handler: (payload: Uint8Array) => Promise<void> | void): void {
+    this.handlers.set(id, async (payload) => handler(compress ? await inflate(payload) : payload));
+  }
+
+  protected async call(method: string, payload: Uint8Array, requestCompress: boolean, responseCompress: boolean): Promise<Uint8Array> {
+    this.lastCallId = (this.lastCallId + 1) >>> 0;
+
+    const callId = this.lastCallId;
+
+    if (requestCompress) {
+      payload = await deflate(payload);
+    }
+
+    const result = new Promise<Uint8Array>((resolve, reject) => {
+      this.calls.set(callId, { method, resolve, reject });
+    });
+
+    const w = new Writer();
+
+    writeInternalInboundRpcRequest(w, { callId, method: textEncoder.encode(method), payload });
+    this.writeFrame(InternalId.InboundRpcRequest, w.bytes());
+
+    const res = await result;
+
+    return responseCompress ? inflate(res) : res;
+  }
+}
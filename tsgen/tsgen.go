@@ -0,0 +1,743 @@
+// Package tsgen generates a TypeScript client from a schema: an interface for every message and object,
+// functions that encode and decode them with DataView in the wire format, and a Client class that performs
+// the Hello handshake, dispatches received messages to typed handlers and calls the unary methods of the
+// services. The generated file has no dependencies, the transport (a socket, a WebSocket or Electron IPC)
+// is supplied by the application.
+package tsgen
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// runtime.ts holds the Writer, Reader and BaseClient classes the generated code is built on
+//
+//go:embed runtime.ts
+var runtime string
+
+// the protocol versions the runtime implements, see ProtocolVersion and MinProtocolVersion in schemaipc
+const (
+	protocolVersion    = 1
+	minProtocolVersion = 1
+)
+
+// the internal messages used by the runtime
+var internalSignatures = []string{
+	"inbound Hello",
+	"outbound Hello",
+	"outbound ProtocolError",
+	"inbound RpcRequest",
+	"outbound RpcResponse",
+	"outbound RpcError",
+}
+
+// identifiers declared by the runtime, schema names must not generate them
+var reserved = []string{
+	"IPCError", "RemoteError", "RPCError", "Writer", "Reader", "Transport", "BaseClient", "Client",
+	"MessageId", "PROTOCOL_VERSION", "MIN_PROTOCOL_VERSION", "FINGERPRINT",
+}
+
+type Options struct {
+	Source string // file the schema was read from, named in the header of the generated file
+}
+
+type generator struct {
+	pkg   string            // package of the schema, stripped from the names it declares
+	names map[string]string // TypeScript identifier to the schema name it was derived from
+	buf   strings.Builder
+	depth int // indentation of the next line
+	tmp   int // counter for the names of temporary variables
+}
+
+// Generate returns the TypeScript source for the schema and everything it imports
+func Generate(s schema.Schema, opts Options) ([]byte, error) {
+	g := generator{
+		pkg:   s.Package,
+		names: make(map[string]string),
+	}
+
+	for _, ident := range reserved {
+		g.names[ident] = ident
+	}
+
+	for _, message := range schema.InternalSchema.Messages {
+		g.names[internalName(message)] = internalName(message)
+	}
+
+	schemas := s.Files()
+
+	err := g.checkNames(schemas)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	g.buf.WriteString("// Code generated by schemagen")
+
+	if opts.Source != "" {
+		fmt.Fprintf(&g.buf, " from %s", opts.Source)
+	}
+
+	g.buf.WriteString(". DO NOT EDIT.\n\n")
+
+	fmt.Fprintf(&g.buf, "export const PROTOCOL_VERSION = %d;\nexport const MIN_PROTOCOL_VERSION = %d;\n\n", protocolVersion, minProtocolVersion)
+
+	g.fingerprint(r.Fingerprint())
+	g.messageIDs(schemas, &r)
+
+	g.buf.WriteString(runtime)
+	g.line("")
+
+	for _, file := range schemas {
+		g.constants(file.Constants)
+		g.typeAliases(file.Types)
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			g.message(message, g.tsName(message.Name), false)
+		}
+	}
+
+	for _, message := range schema.InternalSchema.Messages {
+		for _, signature := range internalSignatures {
+			if message.Signature() == signature {
+				g.message(message, internalName(message), true)
+			}
+		}
+	}
+
+	g.client(schemas)
+
+	return []byte(g.buf.String()), nil
+}
+
+// tsName returns the TypeScript identifier of a declared name, names from other packages keep their package as a prefix
+func (g *generator) tsName(name string) string {
+	if g.pkg != "" {
+		name = strings.TrimPrefix(name, g.pkg+".")
+	}
+
+	return schema.PascalName(name)
+}
+
+// internalName is the identifier of an internal message, Hello exists in both directions
+func internalName(message schema.SchemaMessage) string {
+	return "Internal" + schema.PascalName(message.Direction.ToString()) + message.Name
+}
+
+func (g *generator) declare(ident, name string) error {
+	if existing, exists := g.names[ident]; exists && existing != name {
+		if existing == ident && name != ident {
+			return fmt.Errorf("%s generates the TypeScript identifier %s, which is used by the runtime", name, ident)
+		}
+
+		return fmt.Errorf("%s and %s both generate the TypeScript identifier %s", existing, name, ident)
+	}
+
+	g.names[ident] = name
+
+	return nil
+}
+
+// checkNames reports declarations that would generate the same TypeScript identifier
+func (g *generator) checkNames(schemas []schema.Schema) error {
+	for _, file := range schemas {
+		for _, constant := range file.Constants {
+			err := g.declare(g.tsName(constant.Name), constant.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, alias := range file.Types {
+			err := g.declare(g.tsName(alias.Name), alias.Name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// messages are declared by signature, an inbound and an outbound message can't share a name
+		for _, message := range file.Messages {
+			err := g.declare(g.tsName(message.Name), message.Signature())
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// line writes a line of code at the current indentation, a line ending with { opens a block and one
+// starting with } closes it
+func (g *generator) line(format string, args ...any) {
+	code := fmt.Sprintf(format, args...)
+
+	if strings.HasPrefix(code, "}") {
+		g.depth--
+	}
+
+	if code != "" {
+		g.buf.WriteString(strings.Repeat("  ", g.depth))
+	}
+
+	g.buf.WriteString(code)
+	g.buf.WriteString("\n")
+
+	if strings.HasSuffix(code, "{") {
+		g.depth++
+	}
+}
+
+func (g *generator) fingerprint(fingerprint [32]byte) {
+	bytes := make([]string, len(fingerprint))
+
+	for i, b := range fingerprint {
+		bytes[i] = fmt.Sprint(b)
+	}
+
+	g.line("// FINGERPRINT identifies the schema the client was generated from, handshake fails if the server has another one")
+	g.line("export const FINGERPRINT = new Uint8Array([%s]);", strings.Join(bytes, ", "))
+	g.line("")
+}
+
+// directions returns the directions a message is registered under
+func directions(message schema.SchemaMessage) []schema.MessageDirection {
+	switch message.Direction {
+	case schema.InboundMessage, schema.OutboundMessage:
+		return []schema.MessageDirection{message.Direction}
+	case schema.DuplexMessage:
+		return []schema.MessageDirection{schema.InboundMessage, schema.OutboundMessage}
+	default:
+		return nil
+	}
+}
+
+func (g *generator) messageIDs(schemas []schema.Schema, r *schema.MessageDescriptorRegistry) {
+	g.line("const InternalId = {")
+
+	for _, signature := range internalSignatures {
+		id := r.InternalSignatureMap[signature]
+		message := r.Descriptors[id].Message
+
+		g.line("%s: %d,", schema.PascalName(strings.Fields(signature)[0])+message.Name, id)
+	}
+
+	g.line("} as const;")
+	g.line("")
+
+	g.line("// MessageId holds the descriptor ID of every message, the same as the server assigns")
+	g.line("export const MessageId = {")
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			dirs := directions(message)
+
+			if len(dirs) == 0 {
+				continue
+			}
+
+			g.line("%s: %d,", g.tsName(message.Name), r.UserSignatureMap[schema.Signature(dirs[0], message.Name)])
+		}
+	}
+
+	g.line("} as const;")
+	g.line("")
+}
+
+func (g *generator) constants(constants []schema.Constant) {
+	for _, constant := range constants {
+		// integers above 2^53 can't be represented by a number
+		if constant.Value > 1<<53-1 {
+			g.line("export const %s = %dn;", g.tsName(constant.Name), constant.Value)
+		} else {
+			g.line("export const %s = %d;", g.tsName(constant.Name), constant.Value)
+		}
+	}
+
+	if len(constants) > 0 {
+		g.line("")
+	}
+}
+
+func (g *generator) typeAliases(aliases []schema.TypeAlias) {
+	for _, alias := range aliases {
+		g.line("export type %s = %s;", g.tsName(alias.Name), g.tsType(alias.Field))
+		g.line("")
+	}
+}
+
+func nestedMessage(extra any) schema.SchemaMessage {
+	switch e := extra.(type) {
+	case schema.MessageDescriptor:
+		return e.Message
+	case schema.SchemaMessage:
+		return e
+	default:
+		return schema.SchemaMessage{}
+	}
+}
+
+// flatten returns the fields of a message with the fields of embedded objects spliced in, the same as they
+// appear on the wire and in the generated interfaces
+func flatten(fields []schema.MessageField) []schema.MessageField {
+	var res []schema.MessageField
+
+	for _, field := range fields {
+		if field.Embedded {
+			res = append(res, flatten(nestedMessage(field.Extra).Fields)...)
+		} else {
+			res = append(res, field)
+		}
+	}
+
+	return res
+}
+
+// tsType returns the TypeScript type of a field, 64-bit integers are bigints as they don't fit a number
+func (g *generator) tsType(field schema.MessageField) string {
+	if field.TypeAlias != "" {
+		return g.tsName(field.TypeAlias)
+	}
+
+	switch field.Type {
+	case schema.TypeFixedBinary, schema.TypeDynamicBinary, schema.TypeLongBinary:
+		return "Uint8Array"
+	case schema.TypeUInt64, schema.TypeInt64:
+		return "bigint"
+	case schema.TypeObject:
+		return g.objectType(nestedMessage(field.Extra))
+	case schema.TypeArray:
+		{
+			var elem string
+
+			if elemField, ok := field.Extra.(schema.MessageField); ok {
+				elem = g.tsType(elemField)
+			} else {
+				elem = g.objectType(nestedMessage(field.Extra))
+			}
+
+			if strings.ContainsAny(elem, " {") {
+				return "Array<" + elem + ">"
+			}
+
+			return elem + "[]"
+		}
+	default:
+		return "number"
+	}
+}
+
+func (g *generator) objectType(message schema.SchemaMessage) string {
+	if message.Name != "" {
+		return g.tsName(message.Name)
+	}
+
+	var members []string
+
+	for _, field := range flatten(message.Fields) {
+		members = append(members, g.member(field))
+	}
+
+	return "{ " + strings.Join(members, "; ") + " }"
+}
+
+func (g *generator) member(field schema.MessageField) string {
+	if field.Optional {
+		return fmt.Sprintf("%s?: %s", field.Name, g.tsType(field))
+	}
+
+	return fmt.Sprintf("%s: %s", field.Name, g.tsType(field))
+}
+
+// message writes the interface of a message or object and the functions that write and read it, user
+// messages also get exported encode and decode functions
+func (g *generator) message(message schema.SchemaMessage, name string, internal bool) {
+	var extends []string
+	var fields []schema.MessageField
+
+	// included objects are extended, their fields are spliced into the message on the wire
+	for _, field := range message.Fields {
+		if field.Embedded && field.ObjectName() != "" {
+			extends = append(extends, g.tsType(field))
+		} else if field.Embedded {
+			fields = append(fields, flatten(nestedMessage(field.Extra).Fields)...)
+		} else {
+			fields = append(fields, field)
+		}
+	}
+
+	export := "export "
+
+	switch {
+	case internal:
+		export = ""
+		g.line("// %s is the internal message %s", name, message.Signature())
+	case message.Direction == schema.ObjectDef:
+		g.line("// %s is the object %s", name, message.Name)
+	default:
+		g.line("// %s is the %s message %s", name, message.Direction.ToString(), message.Name)
+	}
+
+	if message.Deprecated {
+		g.line("/** @deprecated the message is deprecated in the schema */")
+	}
+
+	if len(extends) > 0 {
+		g.line("%sinterface %s extends %s {", export, name, strings.Join(extends, ", "))
+	} else {
+		g.line("%sinterface %s {", export, name)
+	}
+
+	for _, field := range fields {
+		if field.Deprecated {
+			g.line("/** @deprecated */")
+		}
+
+		g.line("%s;", g.member(field))
+	}
+
+	g.line("}")
+	g.line("")
+
+	// the client only writes inbound internal messages and reads outbound ones
+	if !internal || message.Direction == schema.InboundMessage {
+		g.tmp = 0
+		g.line("function write%s(w: Writer, m: %s): void {", name, name)
+		g.writeObject("m", message)
+		g.line("}")
+		g.line("")
+	}
+
+	if !internal || message.Direction == schema.OutboundMessage {
+		g.tmp = 0
+		g.line("function read%s(r: Reader): %s {", name, name)
+		g.line("const m = {} as %s;", name)
+		g.readObject("m", message)
+		g.line("return m;")
+		g.line("}")
+		g.line("")
+	}
+
+	if internal || message.Direction == schema.ObjectDef {
+		return
+	}
+
+	g.line("export function encode%s(m: %s): Uint8Array {", name, name)
+	g.line("const w = new Writer();")
+	g.line("write%s(w, m);", name)
+	g.line("return w.bytes();")
+	g.line("}")
+	g.line("")
+
+	g.line("export function decode%s(payload: Uint8Array): %s {", name, name)
+	g.line("return read%s(new Reader(payload));", name)
+	g.line("}")
+	g.line("")
+}
+
+func (g *generator) nextTmp() int {
+	g.tmp++
+	return g.tmp
+}
+
+// countOptional counts the optional fields of a message, including those of included objects
+func countOptional(fields []schema.MessageField) int {
+	var count int
+
+	for _, field := range flatten(fields) {
+		if field.Optional {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (g *generator) writeObject(expr string, message schema.SchemaMessage) {
+	fields := flatten(message.Fields)
+	start := fmt.Sprintf("start%d", g.nextTmp())
+
+	g.line("const %s = w.beginMessage(%d, %t);", start, countOptional(message.Fields), message.Extensible)
+
+	var opt int
+
+	for _, field := range fields {
+		value := expr + "." + field.Name
+
+		if !field.Optional {
+			g.writeValue(value, field)
+			continue
+		}
+
+		g.line("if (%s !== undefined) {", value)
+		g.line("w.setOptional(%s, %d);", start, opt)
+		g.writeValue(value, field)
+		g.line("}")
+
+		opt++
+	}
+
+	g.line("w.endMessage(%s);", start)
+}
+
+func (g *generator) writeValue(expr string, field schema.MessageField) {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		g.line("w.fixedBinary(%s, %d);", expr, field.Extra.(int))
+	case schema.TypeDynamicBinary:
+		g.line("w.binary(%s);", expr)
+	case schema.TypeLongBinary:
+		g.line("w.longBinary(%s);", expr)
+	case schema.TypeUInt64:
+		g.line("w.uint64(%s);", expr)
+	case schema.TypeInt64:
+		g.line("w.int64(%s);", expr)
+	case schema.TypeUInt32:
+		g.line("w.uint32(%s);", expr)
+	case schema.TypeInt32:
+		g.line("w.int32(%s);", expr)
+	case schema.TypeUInt16:
+		g.line("w.uint16(%s);", expr)
+	case schema.TypeInt16:
+		g.line("w.int16(%s);", expr)
+	case schema.TypeObject:
+		g.writeNested(expr, nestedMessage(field.Extra))
+	case schema.TypeArray:
+		{
+			item := fmt.Sprintf("item%d", g.nextTmp())
+
+			g.line("w.arrayLen(%s.length);", expr)
+			g.line("for (const %s of %s) {", item, expr)
+
+			if elem, ok := field.Extra.(schema.MessageField); ok {
+				g.writeValue(item, elem)
+			} else if object := nestedMessage(field.Extra); object.Name == "" {
+				g.writeObject(item, object)
+			} else {
+				g.writeNested(item, object)
+			}
+
+			g.line("}")
+		}
+	}
+}
+
+func (g *generator) writeNested(expr string, message schema.SchemaMessage) {
+	if message.Name != "" {
+		g.line("write%s(w, %s);", g.tsName(message.Name), expr)
+		return
+	}
+
+	g.line("{")
+	g.writeObject(expr, message)
+	g.line("}")
+}
+
+func (g *generator) readObject(expr string, message schema.SchemaMessage) {
+	frame := fmt.Sprintf("frame%d", g.nextTmp())
+
+	g.line("const %s = r.beginMessage(%d, %t);", frame, countOptional(message.Fields), message.Extensible)
+
+	for _, field := range flatten(message.Fields) {
+		value := expr + "." + field.Name
+
+		if !field.Optional {
+			if message.Extensible {
+				g.line("r.checkRequired(%s);", frame)
+			}
+
+			g.readValue(value, field)
+			continue
+		}
+
+		g.line("if (r.present(%s)) {", frame)
+		g.readValue(value, field)
+		g.line("}")
+	}
+
+	g.line("r.endMessage(%s);", frame)
+}
+
+// readValue writes the code that reads a field and assigns it to target
+func (g *generator) readValue(target string, field schema.MessageField) {
+	switch field.Type {
+	case schema.TypeFixedBinary:
+		g.line("%s = r.fixedBinary(%d);", target, field.Extra.(int))
+	case schema.TypeDynamicBinary:
+		g.line("%s = r.binary();", target)
+	case schema.TypeLongBinary:
+		g.line("%s = r.longBinary();", target)
+	case schema.TypeUInt64:
+		g.line("%s = r.uint64();", target)
+	case schema.TypeInt64:
+		g.line("%s = r.int64();", target)
+	case schema.TypeUInt32:
+		g.line("%s = r.uint32();", target)
+	case schema.TypeInt32:
+		g.line("%s = r.int32();", target)
+	case schema.TypeUInt16:
+		g.line("%s = r.uint16();", target)
+	case schema.TypeInt16:
+		g.line("%s = r.int16();", target)
+	case schema.TypeObject:
+		g.readNested(target, nestedMessage(field.Extra))
+	case schema.TypeArray:
+		{
+			tmp := g.nextTmp()
+			n := fmt.Sprintf("n%d", tmp)
+			arr := fmt.Sprintf("arr%d", tmp)
+			i := fmt.Sprintf("i%d", tmp)
+
+			var elemType string
+
+			elem, isField := field.Extra.(schema.MessageField)
+
+			if isField {
+				elemType = g.tsType(elem)
+			} else {
+				elemType = g.objectType(nestedMessage(field.Extra))
+			}
+
+			g.line("const %s = r.arrayLen();", n)
+			g.line("const %s = new Array<%s>(%s);", arr, elemType, n)
+			g.line("for (let %s = 0; %s < %s; %s++) {", i, i, n, i)
+
+			item := fmt.Sprintf("%s[%s]", arr, i)
+
+			if isField {
+				g.readValue(item, elem)
+			} else if object := nestedMessage(field.Extra); object.Name == "" {
+				g.readAnonymous(item, object)
+			} else {
+				g.readNested(item, object)
+			}
+
+			g.line("}")
+			g.line("%s = %s;", target, arr)
+		}
+	}
+}
+
+func (g *generator) readNested(target string, message schema.SchemaMessage) {
+	if message.Name != "" {
+		g.line("%s = read%s(r);", target, g.tsName(message.Name))
+		return
+	}
+
+	g.line("{")
+	g.readAnonymous(target, message)
+	g.line("}")
+}
+
+// readAnonymous reads an anonymous object into a temporary variable, which needs a block of its own
+func (g *generator) readAnonymous(target string, message schema.SchemaMessage) {
+	obj := fmt.Sprintf("obj%d", g.nextTmp())
+
+	g.line("const %s = {} as %s;", obj, g.objectType(message))
+	g.readObject(obj, message)
+	g.line("%s = %s;", target, obj)
+}
+
+// lowerName turns a type name into a method name, e.g. PlaceOrder becomes placeOrder
+func lowerName(name string) string {
+	if name == "" {
+		return name
+	}
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func compressed(message schema.SchemaMessage) bool {
+	return message.Options.Compress
+}
+
+func (g *generator) client(schemas []schema.Schema) {
+	messages := make(map[string]schema.SchemaMessage)
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			messages[message.Name] = message
+		}
+	}
+
+	g.line("// Client sends and receives the messages of the schema, call handshake before anything else")
+	g.line("export class Client extends BaseClient {")
+
+	first := true
+
+	separate := func() {
+		if !first {
+			g.line("")
+		}
+
+		first = false
+	}
+
+	for _, file := range schemas {
+		for _, message := range file.Messages {
+			name := g.tsName(message.Name)
+
+			for _, direction := range directions(message) {
+				separate()
+
+				if direction == schema.InboundMessage {
+					g.line("// send%s sends %s to the server", name, schema.Signature(direction, message.Name))
+					g.line("send%s(m: %s): Promise<void> {", name, name)
+					g.line("return this.sendMessage(MessageId.%s, encode%s(m), %t);", name, name, compressed(message))
+				} else {
+					g.line("// on%s sets the handler of %s, messages are handled one at a time in the order they arrive", name, schema.Signature(direction, message.Name))
+					g.line("on%s(handler: (m: %s) => Promise<void> | void): void {", name, name)
+					g.line("this.handle(MessageId.%s, %t, (payload) => handler(decode%s(payload)));", name, compressed(message), name)
+				}
+
+				g.line("}")
+			}
+		}
+	}
+
+	for _, file := range schemas {
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				separate()
+
+				if method.ClientStream || method.ServerStream {
+					g.line("// %s is a streaming method, which the TypeScript client doesn't support yet", service.MethodName(method))
+					continue
+				}
+
+				request := messages[method.Request]
+				response := messages[method.Response]
+
+				g.line("// %s calls %s", lowerName(g.tsName(service.Name))+schema.PascalName(method.Name), method.ToString())
+				g.line("async %s(req: %s): Promise<%s> {", lowerName(g.tsName(service.Name))+schema.PascalName(method.Name), g.tsName(method.Request), g.tsName(method.Response))
+				g.line("const res = await this.call(%q, encode%s(req), %t, %t);", service.MethodName(method), g.tsName(method.Request), compressed(request), compressed(response))
+				g.line("return decode%s(res);", g.tsName(method.Response))
+				g.line("}")
+			}
+		}
+	}
+
+	g.line("}")
+}
@@ -0,0 +1,149 @@
+package tsgen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+func TestGenerateIsUpToDate(t *testing.T) {
+	files := map[string]string{
+		"../examples/shop/shop.schema": "../examples/shop/shop.ts",
+		"../golden/golden.schema":      "../golden/ts/golden.ts",
+	}
+
+	for source, generated := range files {
+		s, err := schema.ParseFile(source)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, err := Generate(s, Options{Source: source[strings.LastIndex(source, "/")+1:]})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := os.ReadFile(generated)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(code, expected) {
+			t.Errorf("%s is out of date, run go generate ./examples/shop ./golden", strings.TrimPrefix(generated, "../"))
+		}
+	}
+}
+
+func TestGenerateMatchesRegistry(t *testing.T) {
+	s, err := schema.ParseFile("../examples/shop/shop.schema")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := schema.MessageDescriptorRegistry{}
+
+	err = r.RegisterInternal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.RegisterSchema(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := r.Fingerprint()
+	bytes := make([]string, len(fingerprint))
+
+	for i, b := range fingerprint {
+		bytes[i] = fmt.Sprint(b)
+	}
+
+	expected := []string{
+		fmt.Sprintf("export const FINGERPRINT = new Uint8Array([%s]);", strings.Join(bytes, ", ")),
+		fmt.Sprintf("  PlaceOrder: %d,", r.UserSignatureMap["inbound shop.PlaceOrder"]),
+		fmt.Sprintf("  Ping: %d,", r.UserSignatureMap["inbound shop.Ping"]),
+		fmt.Sprintf("  OutboundHello: %d,", r.InternalSignatureMap["outbound Hello"]),
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(code), line+"\n") {
+			t.Errorf("expected the generated code to contain %q", line)
+		}
+	}
+}
+
+func TestGenerateNameCollision(t *testing.T) {
+	tests := map[string]string{
+		"inbound user_name {\n  uint32 REQUIRED id\n}\n\noutbound UserName {\n  uint32 REQUIRED id\n}": "inbound user_name and outbound UserName",
+		"inbound Status {\n  uint32 REQUIRED id\n}\n\noutbound Status {\n  uint32 REQUIRED id\n}":      "inbound Status and outbound Status",
+		"outbound Client {\n  uint32 REQUIRED id\n}":                                                   "used by the runtime",
+	}
+
+	for source, message := range tests {
+		s, err := schema.Parse([]byte(source))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = Generate(s, Options{})
+
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q, got %v", message, err)
+		}
+	}
+}
+
+func TestGenerateCountsIncludedOptionals(t *testing.T) {
+	s, err := schema.Parse([]byte(`
+object Flags {
+  uint16 OPTIONAL a
+  uint16 OPTIONAL b
+  uint16 OPTIONAL c
+  uint16 OPTIONAL d
+  uint16 OPTIONAL e
+  uint16 OPTIONAL f
+  uint16 OPTIONAL g
+  uint16 OPTIONAL h
+}
+
+inbound Update {
+  include Flags
+  uint16 OPTIONAL i
+}
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := Generate(s, Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the optional flags of Update take two bytes, as i is the ninth optional field
+	for _, line := range []string{"const start1 = w.beginMessage(9, false);", "const frame1 = r.beginMessage(9, false);", "w.setOptional(start1, 8);"} {
+		if !strings.Contains(string(code), line) {
+			t.Errorf("expected the generated code to contain %q", line)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package schemaipc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/benjamin-larsen/goschemaipc/encoder"
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// RegisterMessage registers a handler for an inbound message that receives it decoded into a T,
+// it panics if T can't be decoded from the message
+func RegisterMessage[T any](s *Server, signature string, handler func(msg *T, c *Conn) error) {
+	if id, exists := s.Registry.UserSignatureMap[signature]; exists {
+		err := encoder.CheckType(s.Registry.Descriptors[id], reflect.TypeFor[T]())
+
+		if err != nil {
+			s := fmt.Sprintf("message (%s): %v", signature, err)
+			panic(s)
+		}
+	}
+
+	s.Register(signature, func(r schema.Reader, c schema.Conn) error {
+		var msg T
+
+		err := r.Decode(&msg)
+
+		if err != nil {
+			return err
+		}
+
+		return handler(&msg, c.(*Conn))
+	})
+}
+
+// HandleMessage registers a handler for an outbound message that receives it decoded into a T
+func HandleMessage[T any](c *Client, signature string, handler func(msg *T, c *Client) error) {
+	c.Handle(signature, func(r schema.Reader, conn schema.Conn) error {
+		var msg T
+
+		err := r.Decode(&msg)
+
+		if err != nil {
+			return err
+		}
+
+		return handler(&msg, conn.(*Client))
+	})
+}
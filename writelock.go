@@ -0,0 +1,54 @@
+package schemaipc
+
+import (
+	"sync"
+
+	"github.com/benjamin-larsen/goschemaipc/schema"
+)
+
+// writeLock serializes the writes of a connection, when it is released it is handed to the waiting
+// writer with the highest priority, writers of the same priority are served in order
+type writeLock struct {
+	mu      sync.Mutex
+	locked  bool
+	waiters [schema.PriorityHigh + 1][]chan struct{}
+}
+
+// lower priorities are served last
+var priorityOrder = []schema.Priority{schema.PriorityHigh, schema.PriorityNormal, schema.PriorityLow}
+
+func (l *writeLock) Lock(priority schema.Priority) {
+	l.mu.Lock()
+
+	if !l.locked {
+		l.locked = true
+		l.mu.Unlock()
+		return
+	}
+
+	ready := make(chan struct{})
+	l.waiters[priority] = append(l.waiters[priority], ready)
+	l.mu.Unlock()
+
+	<-ready
+}
+
+func (l *writeLock) Unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, priority := range priorityOrder {
+		waiters := l.waiters[priority]
+
+		if len(waiters) == 0 {
+			continue
+		}
+
+		// the lock stays locked and passes to the waiter
+		close(waiters[0])
+		l.waiters[priority] = waiters[1:]
+		return
+	}
+
+	l.locked = false
+}